@@ -69,9 +69,15 @@ type SimulatedBackend struct {
 
 	events *filters.EventSystem // Event system for filtering log events live
 
-	config *params.ChainConfig
+	config   *params.ChainConfig
+	generate blockGenerator // Assembles the pending block on top of the head
+	dpos     *simulatedDpos // Dpos engine and validator keys, nil for ethash backends
 }
 
+// blockGenerator assembles the block following parent which includes the given
+// transactions, with its timestamp shifted by offset seconds.
+type blockGenerator func(parent *types.Block, offset int64, txs types.Transactions) (*types.Block, error)
+
 // NewSimulatedBackendWithDatabase creates a new binding backend based on the given database
 // and uses a simulated blockchain for testing purposes.
 // A simulated backend always uses chainID 1337.
@@ -86,6 +92,7 @@ func NewSimulatedBackendWithDatabase(database ethdb.Database, alloc core.Genesis
 		config:     genesis.Config,
 		events:     filters.NewEventSystem(&filterBackend{database, blockchain}, false),
 	}
+	backend.generate = backend.generateEthash
 	backend.rollback()
 	return backend
 }
@@ -124,12 +131,28 @@ func (b *SimulatedBackend) Rollback() {
 }
 
 func (b *SimulatedBackend) rollback() {
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(int, *core.BlockGen) {})
-
-	b.pendingBlock = blocks[0]
+	block, err := b.generate(b.blockchain.CurrentBlock(), 0, nil)
+	if err != nil {
+		panic(err) // This cannot happen unless the simulator is wrong, fail in that case
+	}
+	b.pendingBlock = block
 	b.pendingState, _ = state.New(b.pendingBlock.Root(), b.blockchain.StateCache(), nil)
 }
 
+// generateEthash assembles the next block with the fake ethash engine. Invalid
+// transactions cause a panic.
+func (b *SimulatedBackend) generateEthash(parent *types.Block, offset int64, txs types.Transactions) (*types.Block, error) {
+	blocks, _ := core.GenerateChain(b.config, parent, ethash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
+		if offset != 0 {
+			block.OffsetTime(offset)
+		}
+		for _, tx := range txs {
+			block.AddTxWithChain(b.blockchain, tx)
+		}
+	})
+	return blocks[0], nil
+}
+
 // stateByBlockNumber retrieves a state by a given blocknumber.
 func (b *SimulatedBackend) stateByBlockNumber(ctx context.Context, blockNumber *big.Int) (*state.StateDB, error) {
 	if blockNumber == nil || blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) == 0 {
@@ -531,6 +554,12 @@ func (b *SimulatedBackend) callContract(ctx context.Context, call ethereum.CallM
 	if call.GasPrice == nil {
 		call.GasPrice = big.NewInt(1)
 	}
+	if call.GasFeeCap == nil {
+		call.GasFeeCap = call.GasPrice
+	}
+	if call.GasTipCap == nil {
+		call.GasTipCap = call.GasPrice
+	}
 	if call.Gas == 0 {
 		call.Gas = 50000000
 	}
@@ -572,15 +601,15 @@ func (b *SimulatedBackend) SendTransaction(ctx context.Context, tx *types.Transa
 	}
 
 	// Include tx in chain.
-	blocks, _ := core.GenerateChain(b.config, block, ethash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
-		for _, tx := range b.pendingBlock.Transactions() {
-			block.AddTxWithChain(b.blockchain, tx)
-		}
-		block.AddTxWithChain(b.blockchain, tx)
-	})
+	txs := append(types.Transactions{}, b.pendingBlock.Transactions()...)
+	txs = append(txs, tx)
+	pending, err := b.generate(block, 0, txs)
+	if err != nil {
+		return err
+	}
 	stateDB, _ := b.blockchain.State()
 
-	b.pendingBlock = blocks[0]
+	b.pendingBlock = pending
 	b.pendingState, _ = state.New(b.pendingBlock.Root(), stateDB.Database(), nil)
 	return nil
 }
@@ -690,12 +719,13 @@ func (b *SimulatedBackend) AdjustTime(adjustment time.Duration) error {
 		return errors.New("Could not adjust time on non-empty block")
 	}
 
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
-		block.OffsetTime(int64(adjustment.Seconds()))
-	})
+	block, err := b.generate(b.blockchain.CurrentBlock(), int64(adjustment.Seconds()), nil)
+	if err != nil {
+		return err
+	}
 	stateDB, _ := b.blockchain.State()
 
-	b.pendingBlock = blocks[0]
+	b.pendingBlock = block
 	b.pendingState, _ = state.New(b.pendingBlock.Root(), stateDB.Database(), nil)
 
 	return nil
@@ -714,8 +744,11 @@ type callMsg struct {
 func (m callMsg) From() common.Address         { return m.CallMsg.From }
 func (m callMsg) Nonce() uint64                { return 0 }
 func (m callMsg) CheckNonce() bool             { return false }
+func (m callMsg) IsSystemCall() bool           { return false }
 func (m callMsg) To() *common.Address          { return m.CallMsg.To }
 func (m callMsg) GasPrice() *big.Int           { return m.CallMsg.GasPrice }
+func (m callMsg) GasFeeCap() *big.Int          { return m.CallMsg.GasFeeCap }
+func (m callMsg) GasTipCap() *big.Int          { return m.CallMsg.GasTipCap }
 func (m callMsg) Gas() uint64                  { return m.CallMsg.Gas }
 func (m callMsg) Value() *big.Int              { return m.CallMsg.Value }
 func (m callMsg) Data() []byte                 { return m.CallMsg.Data }
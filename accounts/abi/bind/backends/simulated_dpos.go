@@ -0,0 +1,331 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"sort"
+
+	"PureChain/accounts"
+	"PureChain/common"
+	"PureChain/consensus/dpos"
+	"PureChain/consensus/misc"
+	"PureChain/core"
+	"PureChain/core/rawdb"
+	"PureChain/core/state"
+	"PureChain/core/types"
+	"PureChain/core/vm"
+	"PureChain/crypto"
+	"PureChain/eth/filters"
+	"PureChain/ethdb"
+	"PureChain/params"
+)
+
+const (
+	// SimulatedDposEpoch is the default epoch length of a simulated dpos chain.
+	SimulatedDposEpoch = 10
+
+	// SimulatedDposPeriod is the block period of a simulated dpos chain in seconds.
+	SimulatedDposPeriod = 3
+)
+
+var (
+	errNotDposBackend  = errors.New("simulatedBackend is not running the dpos engine")
+	errNoDposValidator = errors.New("no registered validator can seal the next block")
+	errNoGenesisKeys   = errors.New("simulated dpos backend requires at least one validator key")
+)
+
+// simulatedDpos is the dpos engine of a simulated backend together with the keys
+// of the validators the backend seals blocks with.
+type simulatedDpos struct {
+	engine *dpos.Dpos
+	keys   map[common.Address]*ecdsa.PrivateKey
+}
+
+// NewSimulatedDposBackendWithDatabase creates a new binding backend based on the
+// given database running the dpos engine. The genesis deploys the dpos system
+// contracts and seals the given validators, which are initialized by the first
+// block. A zero epoch selects SimulatedDposEpoch.
+//
+// Blocks are assembled through the engine and sealed deterministically: every
+// block is signed by the registered validator allowed to seal soonest and is
+// timestamped exactly that many seconds after its parent.
+func NewSimulatedDposBackendWithDatabase(database ethdb.Database, alloc core.GenesisAlloc, gasLimit uint64, epoch uint64, validators ...*ecdsa.PrivateKey) (*SimulatedBackend, error) {
+	if epoch == 0 {
+		epoch = SimulatedDposEpoch
+	}
+	return newSimulatedDposBackend(database, simulatedDposConfig(epoch), alloc, gasLimit, validators...)
+}
+
+// simulatedDposConfig returns the chain config of a simulated dpos chain with
+// every fork enabled from genesis.
+func simulatedDposConfig(epoch uint64) *params.ChainConfig {
+	return &params.ChainConfig{
+		ChainID:             big.NewInt(1337),
+		HomesteadBlock:      big.NewInt(0),
+		EIP150Block:         big.NewInt(0),
+		EIP155Block:         big.NewInt(0),
+		EIP158Block:         big.NewInt(0),
+		ByzantiumBlock:      big.NewInt(0),
+		ConstantinopleBlock: big.NewInt(0),
+		PetersburgBlock:     big.NewInt(0),
+		IstanbulBlock:       big.NewInt(0),
+		MuirGlacierBlock:    big.NewInt(0),
+		BerlinBlock:         big.NewInt(0),
+		LondonBlock:         big.NewInt(0),
+		RamanujanBlock:      big.NewInt(0),
+		NielsBlock:          big.NewInt(0),
+		MirrorSyncBlock:     big.NewInt(0),
+		Dpos:                &params.DposConfig{Period: SimulatedDposPeriod, Epoch: epoch},
+	}
+}
+
+// SimulatedDposGenesis returns the genesis of a simulated dpos chain created
+// with the same parameters, allowing other nodes to import the blocks sealed by
+// a simulated backend. A zero epoch selects SimulatedDposEpoch.
+func SimulatedDposGenesis(alloc core.GenesisAlloc, gasLimit uint64, epoch uint64, validators ...*ecdsa.PrivateKey) *core.Genesis {
+	if epoch == 0 {
+		epoch = SimulatedDposEpoch
+	}
+	return simulatedDposGenesis(simulatedDposConfig(epoch), alloc, gasLimit, validators...)
+}
+
+// simulatedDposGenesis assembles the genesis of a simulated dpos chain with the
+// given chain config.
+func simulatedDposGenesis(config *params.ChainConfig, alloc core.GenesisAlloc, gasLimit uint64, validators ...*ecdsa.PrivateKey) *core.Genesis {
+	// Deploy the system contracts shipped with the devnet genesis
+	genesisAlloc := make(core.GenesisAlloc, len(alloc))
+	for addr, account := range core.DefaultDevnetGenesisBlock().Alloc {
+		if len(account.Code) > 0 {
+			genesisAlloc[addr] = account
+		}
+	}
+	for addr, account := range alloc {
+		genesisAlloc[addr] = account
+	}
+	// Seal the validators into the genesis, sorted as the engine expects them
+	keys := make(map[common.Address]*ecdsa.PrivateKey, len(validators))
+	for _, key := range validators {
+		keys[crypto.PubkeyToAddress(key.PublicKey)] = key
+	}
+	extra := make([]byte, 32)
+	for _, addr := range sortedAddresses(keys) {
+		extra = append(extra, addr.Bytes()...)
+	}
+	extra = append(extra, make([]byte, crypto.SignatureLength)...)
+
+	return &core.Genesis{
+		Config:     config,
+		GasLimit:   gasLimit,
+		ExtraData:  extra,
+		Difficulty: big.NewInt(1),
+		Alloc:      genesisAlloc,
+	}
+}
+
+// newSimulatedDposBackend creates a simulated dpos backend with the given chain
+// config, see NewSimulatedDposBackendWithDatabase.
+func newSimulatedDposBackend(database ethdb.Database, config *params.ChainConfig, alloc core.GenesisAlloc, gasLimit uint64, validators ...*ecdsa.PrivateKey) (*SimulatedBackend, error) {
+	if len(validators) == 0 {
+		return nil, errNoGenesisKeys
+	}
+	genesis := simulatedDposGenesis(config, alloc, gasLimit, validators...)
+	block, err := genesis.Commit(database)
+	if err != nil {
+		return nil, err
+	}
+	engine := dpos.New(config, database, nil, block.Hash())
+
+	blockchain, err := core.NewBlockChain(database, nil, genesis.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	engine.SetStateFn(blockchain.StateAt)
+
+	backend := &SimulatedBackend{
+		database:   database,
+		blockchain: blockchain,
+		config:     genesis.Config,
+		events:     filters.NewEventSystem(&filterBackend{database, blockchain}, false),
+		dpos: &simulatedDpos{
+			engine: engine,
+			keys:   make(map[common.Address]*ecdsa.PrivateKey),
+		},
+	}
+	for _, key := range validators {
+		backend.registerValidator(key)
+	}
+	backend.generate = backend.generateDpos
+	backend.rollback()
+	return backend, nil
+}
+
+// NewSimulatedDposBackend creates a new binding backend running the dpos engine
+// on top of a simulated blockchain, see NewSimulatedDposBackendWithDatabase.
+// A simulated backend always uses chainID 1337.
+func NewSimulatedDposBackend(alloc core.GenesisAlloc, gasLimit uint64, epoch uint64, validators ...*ecdsa.PrivateKey) (*SimulatedBackend, error) {
+	return NewSimulatedDposBackendWithDatabase(rawdb.NewMemoryDatabase(), alloc, gasLimit, epoch, validators...)
+}
+
+// Dpos returns the dpos engine of the backend, or nil if it runs ethash.
+func (b *SimulatedBackend) Dpos() *dpos.Dpos {
+	if b.dpos == nil {
+		return nil
+	}
+	return b.dpos.engine
+}
+
+// RegisterValidator adds the key of a validator the backend may seal blocks with,
+// e.g. one that joined through the validator factory contract. The validator is
+// only selected to seal once the engine considers it part of the active set.
+func (b *SimulatedBackend) RegisterValidator(key *ecdsa.PrivateKey) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.dpos == nil {
+		return errNotDposBackend
+	}
+	b.registerValidator(key)
+	return nil
+}
+
+func (b *SimulatedBackend) registerValidator(key *ecdsa.PrivateKey) {
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	b.dpos.keys[addr] = key
+
+	signer := types.LatestSignerForChainID(b.config.ChainID)
+	b.dpos.engine.Authorize(addr,
+		func(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+			return crypto.Sign(crypto.Keccak256(data), key)
+		},
+		func(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+			return types.SignTx(tx, signer, key)
+		},
+	)
+}
+
+// AdvanceEpoch imports the pending block followed by empty blocks until the head
+// is an epoch block, i.e. until the validator set of the next epoch is applied.
+// It returns the number of the epoch block.
+func (b *SimulatedBackend) AdvanceEpoch() (uint64, error) {
+	if b.dpos == nil {
+		return 0, errNotDposBackend
+	}
+	epoch := b.config.Dpos.Epoch
+	for {
+		b.Commit()
+		if number := b.blockchain.CurrentBlock().NumberU64(); number%epoch == 0 {
+			return number, nil
+		}
+	}
+}
+
+// generateDpos assembles and seals the next block with the dpos engine.
+func (b *SimulatedBackend) generateDpos(parent *types.Block, offset int64, txs types.Transactions) (*types.Block, error) {
+	var (
+		chain  = b.blockchain
+		engine = b.dpos.engine
+	)
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:   core.CalcGasLimit(parent, parent.GasLimit(), parent.GasLimit()),
+	}
+	if b.config.IsLondon(header.Number) {
+		header.BaseFee = misc.CalcBaseFee(b.config, parent.Header())
+	}
+	// Pick the registered validator allowed to seal soonest
+	schedule, err := engine.ScheduleValidators(chain, parent.Header(), sortedAddresses(b.dpos.keys))
+	if err != nil {
+		return nil, err
+	}
+	if schedule.Validator == (common.Address{}) {
+		return nil, errNoDposValidator
+	}
+	var backOff uint64
+	for _, slot := range schedule.Slots {
+		if slot.Selected {
+			backOff = slot.BackOff
+		}
+	}
+	if !engine.Authorize(schedule.Validator, nil, nil) {
+		return nil, errNoDposValidator
+	}
+	if err := engine.Prepare(chain, header); err != nil {
+		return nil, err
+	}
+	// Replace the wall clock timestamp set by the engine with the simulated one
+	header.Time = uint64(int64(parent.Time()+SimulatedDposPeriod+backOff) + offset)
+
+	statedb, err := state.New(parent.Root(), chain.StateCache(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := engine.PreHandle(chain, header, statedb); err != nil {
+		return nil, err
+	}
+	var (
+		gp       = new(core.GasPool).AddGas(header.GasLimit)
+		receipts = make([]*types.Receipt, 0, len(txs))
+	)
+	for i, tx := range txs {
+		if err := engine.ValidateTx(tx, header, statedb); err != nil {
+			return nil, err
+		}
+		statedb.Prepare(tx.Hash(), common.Hash{}, i)
+		receipt, err := core.ApplyTransaction(b.config, chain, &header.Coinbase, gp, statedb, header, tx, &header.GasUsed, vm.Config{})
+		if err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, receipt)
+	}
+	block, _, err := engine.FinalizeAndAssemble(chain, header, statedb, txs, nil, receipts)
+	if err != nil {
+		return nil, err
+	}
+	// Write the state so the pending block can be queried before its import
+	root, err := statedb.Commit(b.config.IsEIP158(header.Number))
+	if err != nil {
+		return nil, err
+	}
+	if err := statedb.Database().TrieDB().Commit(root, false, nil); err != nil {
+		return nil, err
+	}
+	// Seal the block with the key of the selected validator
+	sealed := block.Header()
+	sig, err := crypto.Sign(dpos.SealHash(sealed, b.config.ChainID).Bytes(), b.dpos.keys[schedule.Validator])
+	if err != nil {
+		return nil, err
+	}
+	copy(sealed.Extra[len(sealed.Extra)-crypto.SignatureLength:], sig)
+	return block.WithSeal(sealed), nil
+}
+
+// sortedAddresses returns the addresses of the given keys in ascending order.
+func sortedAddresses(keys map[common.Address]*ecdsa.PrivateKey) []common.Address {
+	addrs := make([]common.Address, 0, len(keys))
+	for addr := range keys {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	return addrs
+}
@@ -0,0 +1,246 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"PureChain/common"
+	"PureChain/consensus"
+	"PureChain/consensus/dpos/systemcontract"
+	"PureChain/core"
+	"PureChain/core/rawdb"
+	"PureChain/core/types"
+	"PureChain/crypto"
+	"PureChain/params"
+)
+
+func TestSimulatedDposBackend(t *testing.T) {
+	var (
+		valKey, _  = crypto.GenerateKey()
+		userKey, _ = crypto.GenerateKey()
+		user       = crypto.PubkeyToAddress(userKey.PublicKey)
+		validator  = crypto.PubkeyToAddress(valKey.PublicKey)
+		recipient  = common.HexToAddress("0xb0b")
+		funds      = new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))
+	)
+	sim, err := NewSimulatedDposBackend(core.GenesisAlloc{user: {Balance: funds}}, 10000000, 4, valKey)
+	if err != nil {
+		t.Fatalf("failed to create dpos backend: %v", err)
+	}
+	defer sim.Close()
+
+	ctx := context.Background()
+	for _, addr := range []common.Address{systemcontract.ValidatorFactoryContractAddr, systemcontract.ProviderFactoryContractAddr, systemcontract.AddressListContractAddr} {
+		if code, err := sim.CodeAt(ctx, addr, nil); err != nil || len(code) == 0 {
+			t.Fatalf("system contract %x not deployed: %v", addr, err)
+		}
+	}
+	// Send a transfer and make sure it's sealed by the validator
+	head, _ := sim.HeaderByNumber(ctx, nil)
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   sim.config.ChainID,
+		Nonce:     0,
+		GasTipCap: big.NewInt(params.GWei),
+		GasFeeCap: new(big.Int).Add(head.BaseFee, big.NewInt(2*params.GWei)),
+		Gas:       params.TxGas,
+		To:        &recipient,
+		Value:     big.NewInt(1),
+	})
+	tx, _ = types.SignTx(tx, types.LatestSignerForChainID(sim.config.ChainID), userKey)
+	if err := sim.SendTransaction(ctx, tx); err != nil {
+		t.Fatalf("failed to send transaction: %v", err)
+	}
+	sim.Commit()
+
+	receipt, err := sim.TransactionReceipt(ctx, tx.Hash())
+	if err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("transaction not executed: %v", err)
+	}
+	block, _ := sim.BlockByNumber(ctx, nil)
+	if block.Coinbase() != validator {
+		t.Errorf("block sealed by %x, want %x", block.Coinbase(), validator)
+	}
+	if want := head.Time + SimulatedDposPeriod; block.Time() != want {
+		t.Errorf("block time mismatch: have %d, want %d", block.Time(), want)
+	}
+	if balance, _ := sim.BalanceAt(ctx, recipient, nil); balance.Cmp(common.Big1) != 0 {
+		t.Errorf("recipient balance mismatch: have %v, want 1", balance)
+	}
+	// Advance to the next epoch, checkpointing the validator set
+	number, err := sim.AdvanceEpoch()
+	if err != nil {
+		t.Fatalf("failed to advance epoch: %v", err)
+	}
+	if number != 4 {
+		t.Errorf("epoch block mismatch: have %d, want 4", number)
+	}
+	validators, err := sim.Dpos().Validators(sim.Blockchain(), sim.Blockchain().CurrentHeader())
+	if err != nil {
+		t.Fatalf("failed to retrieve validators: %v", err)
+	}
+	if len(validators) != 1 || validators[0] != validator {
+		t.Errorf("validator set mismatch: have %x, want [%x]", validators, validator)
+	}
+	// Ethash backends can't register validators
+	eth := NewSimulatedBackend(core.GenesisAlloc{}, 10000000)
+	defer eth.Close()
+	if err := eth.RegisterValidator(valKey); err != errNotDposBackend {
+		t.Errorf("ethash validator registration error mismatch: have %v, want %v", err, errNotDposBackend)
+	}
+}
+
+// Tests that the zero priced calls of a validator into the system contracts, like
+// the PoR challenges, are sealed and imported on both sides of the London fork.
+func TestSimulatedDposSystemCallsAcrossLondon(t *testing.T) {
+	valKey, _ := crypto.GenerateKey()
+	validator := crypto.PubkeyToAddress(valKey.PublicKey)
+
+	config := simulatedDposConfig(SimulatedDposEpoch)
+	config.LondonBlock = big.NewInt(3)
+	sim, err := newSimulatedDposBackend(rawdb.NewMemoryDatabase(), config, core.GenesisAlloc{}, 10000000, valKey)
+	if err != nil {
+		t.Fatalf("failed to create dpos backend: %v", err)
+	}
+	defer sim.Close()
+
+	var (
+		ctx    = context.Background()
+		signer = types.LatestSignerForChainID(config.ChainID)
+		data   = crypto.Keccak256([]byte("validatorNotSubmitResult(address)"))[:4]
+	)
+	data = append(data, common.LeftPadBytes(validator.Bytes(), 32)...)
+
+	for number := uint64(1); number <= 5; number++ {
+		nonce, _ := sim.PendingNonceAt(ctx, validator)
+		to := systemcontract.ValidatorFactoryContractAddr
+		tx, _ := types.SignTx(types.NewTransaction(nonce, to, new(big.Int), 1000000, new(big.Int), data), signer, valKey)
+		if err := sim.SendTransaction(ctx, tx); err != nil {
+			t.Fatalf("block #%d: failed to send system call: %v", number, err)
+		}
+		sim.Commit()
+
+		block, _ := sim.BlockByNumber(ctx, nil)
+		if have := block.NumberU64(); have != number {
+			t.Fatalf("head mismatch: have #%d, want #%d", have, number)
+		}
+		if (block.BaseFee() != nil) != config.IsLondon(block.Number()) {
+			t.Fatalf("block #%d: base fee presence mismatch: %v", number, block.BaseFee())
+		}
+		if len(block.Transactions()) != 1 || block.Transactions()[0].Hash() != tx.Hash() {
+			t.Fatalf("block #%d: system call not included", number)
+		}
+		if receipt, err := sim.TransactionReceipt(ctx, tx.Hash()); err != nil || receipt.GasUsed == 0 {
+			t.Fatalf("block #%d: system call not executed: %v", number, err)
+		}
+	}
+}
+
+// Tests that after London the tip of a transaction is swept by Finalize and the
+// collected base fee is shared out between the team and the validator contract,
+// burning the remainder.
+func TestSimulatedDposBaseFeeDistribution(t *testing.T) {
+	var (
+		valKey, _  = crypto.GenerateKey()
+		userKey, _ = crypto.GenerateKey()
+		user       = crypto.PubkeyToAddress(userKey.PublicKey)
+		recipient  = common.HexToAddress("0xb0b")
+		funds      = new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))
+	)
+	config := simulatedDposConfig(SimulatedDposEpoch)
+	config.Dpos.BaseFeeTeamPercent = 20
+	config.Dpos.BaseFeeValidatorPercent = 30
+	sim, err := newSimulatedDposBackend(rawdb.NewMemoryDatabase(), config, core.GenesisAlloc{user: {Balance: funds}}, 10000000, valKey)
+	if err != nil {
+		t.Fatalf("failed to create dpos backend: %v", err)
+	}
+	defer sim.Close()
+
+	var (
+		ctx    = context.Background()
+		signer = types.LatestSignerForChainID(config.ChainID)
+		tip    = big.NewInt(params.GWei)
+	)
+	transfer := func(nonce uint64) {
+		head, _ := sim.HeaderByNumber(ctx, nil)
+		tx, _ := types.SignTx(types.NewTx(&types.DynamicFeeTx{
+			ChainID:   config.ChainID,
+			Nonce:     nonce,
+			GasTipCap: tip,
+			GasFeeCap: new(big.Int).Add(new(big.Int).Mul(head.BaseFee, common.Big2), tip),
+			Gas:       params.TxGas,
+			To:        &recipient,
+			Value:     big.NewInt(1),
+		}), signer, userKey)
+		if err := sim.SendTransaction(ctx, tx); err != nil {
+			t.Fatalf("failed to send transaction: %v", err)
+		}
+		sim.Commit()
+	}
+	// Fees are collected from the first block on, but only shared out by the
+	// engine from the fourth one, sweeping the fees of the earlier blocks too.
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		transfer(nonce)
+	}
+	parent, _ := sim.HeaderByNumber(ctx, nil)
+	transfer(3)
+
+	header, _ := sim.HeaderByNumber(ctx, nil)
+	var (
+		gas     = new(big.Int).SetUint64(params.TxGas)
+		tipFee  = new(big.Int).Mul(gas, tip)
+		baseFee = new(big.Int).Mul(gas, header.BaseFee)
+	)
+	tips, _ := sim.BalanceAt(ctx, consensus.SystemAddress, parent.Number)
+	if want := new(big.Int).Mul(tipFee, big.NewInt(3)); tips.Cmp(want) != 0 {
+		t.Fatalf("collected tips mismatch: have %v, want %v", tips, want)
+	}
+	baseFees, _ := sim.BalanceAt(ctx, consensus.BaseFeeAddress, parent.Number)
+	if baseFees.Sign() == 0 {
+		t.Fatalf("no base fee collected")
+	}
+	sweptTips := new(big.Int).Add(tips, tipFee)
+	sharedBaseFees := new(big.Int).Add(baseFees, baseFee)
+
+	// The sender pays the base fee and the tip, the swept tips land on the zero
+	// address and the unshared half of the base fee is burnt. The expected
+	// changes accumulate as the team address may be unset on a fresh chain.
+	want := make(map[common.Address]*big.Int)
+	credit := func(addr common.Address, amount *big.Int) {
+		if want[addr] == nil {
+			want[addr] = new(big.Int)
+		}
+		want[addr].Add(want[addr], amount)
+	}
+	credit(user, new(big.Int).Neg(new(big.Int).Add(new(big.Int).Add(baseFee, tipFee), common.Big1)))
+	credit(recipient, common.Big1)
+	credit(common.Address{}, sweptTips)
+	credit(header.TeamAddress, new(big.Int).Div(new(big.Int).Mul(sharedBaseFees, big.NewInt(20)), big.NewInt(100)))
+	credit(systemcontract.ValidatorFactoryContractAddr, new(big.Int).Div(new(big.Int).Mul(sharedBaseFees, big.NewInt(30)), big.NewInt(100)))
+	credit(consensus.SystemAddress, new(big.Int).Neg(tips))
+	credit(consensus.BaseFeeAddress, new(big.Int).Neg(baseFees))
+
+	for addr, change := range want {
+		before, _ := sim.BalanceAt(ctx, addr, parent.Number)
+		after, _ := sim.BalanceAt(ctx, addr, header.Number)
+		if have := new(big.Int).Sub(after, before); have.Cmp(change) != 0 {
+			t.Errorf("%x: balance change mismatch: have %v, want %v", addr, have, change)
+		}
+	}
+}
@@ -72,7 +72,8 @@ var tomlSettings = toml.Config{
 }
 
 type ethstatsConfig struct {
-	URL string `toml:",omitempty"`
+	URL      string `toml:",omitempty"`
+	INIChain bool   `toml:",omitempty"`
 }
 
 type gethConfig struct {
@@ -133,6 +134,9 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 	if ctx.GlobalIsSet(utils.EthStatsURLFlag.Name) {
 		cfg.Ethstats.URL = ctx.GlobalString(utils.EthStatsURLFlag.Name)
 	}
+	if ctx.GlobalIsSet(utils.EthStatsINIChainFlag.Name) {
+		cfg.Ethstats.INIChain = ctx.GlobalBool(utils.EthStatsINIChainFlag.Name)
+	}
 	applyMetricConfig(ctx, &cfg)
 
 	return stack, cfg
@@ -162,7 +166,7 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 	}
 	// Add the Ethereum Stats daemon if requested.
 	if cfg.Ethstats.URL != "" {
-		utils.RegisterEthStatsService(stack, backend, cfg.Ethstats.URL)
+		utils.RegisterEthStatsService(stack, backend, cfg.Ethstats.URL, cfg.Ethstats.INIChain)
 	}
 	return stack, backend
 }
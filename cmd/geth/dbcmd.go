@@ -18,6 +18,7 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
@@ -62,6 +63,7 @@ Remove blockchain and state databases`,
 			dbPutCmd,
 			dbGetSlotsCmd,
 			dbDumpFreezerIndex,
+			dbMigrateCmd,
 		},
 	}
 	dbInspectCmd = cli.Command{
@@ -178,6 +180,25 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		},
 		Description: "This command displays information about the freezer index.",
 	}
+	dbMigrateCmd = cli.Command{
+		Action:    utils.MigrateFlags(dbMigrate),
+		Name:      "migrate",
+		Usage:     "Migrate the chain database to another key-value engine",
+		ArgsUsage: "<engine>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.SyncModeFlag,
+			utils.MainnetFlag,
+			utils.TestnetFlag,
+			utils.DevnetFlag,
+			utils.CacheFlag,
+			utils.CacheDatabaseFlag,
+		},
+		Description: `This command copies all the key-value data of the chain database into a
+new database of the given engine ('leveldb' or 'bolt') and replaces the old one with
+it. The ancient chain segments are not touched. The node must not be running.
+An interrupted migration is resumed by running the command again.`,
+	}
 )
 
 func removeDB(ctx *cli.Context) error {
@@ -490,3 +511,146 @@ func freezerInspect(ctx *cli.Context) error {
 	}
 	return nil
 }
+
+// dbMigrate copies the chain database into a new key-value store of the given
+// engine and swaps it in place of the old one.
+func dbMigrate(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	target := ctx.Args().Get(0)
+	if target != rawdb.DBLeveldb && target != rawdb.DBBolt {
+		return fmt.Errorf("unknown database engine %q, allowed 'leveldb' or 'bolt'", target)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	name := "chaindata"
+	if ctx.GlobalString(utils.SyncModeFlag.Name) == "light" {
+		name = "lightchaindata"
+	}
+	var (
+		path    = stack.ResolvePath(name)
+		tmp     = path + ".migrate"
+		old     = path + ".old"
+		cache   = ctx.GlobalInt(utils.CacheFlag.Name) * ctx.GlobalInt(utils.CacheDatabaseFlag.Name) / 100
+		handles = utils.MakeDatabaseHandles()
+	)
+	// Finish an interrupted swap of a completed copy before anything else
+	if common.FileExist(old) || common.FileExist(filepath.Join(tmp, migrationMarker)) {
+		log.Info("Resuming interrupted database migration", "path", path)
+		if err := swapDatabase(path, tmp, old); err != nil {
+			return err
+		}
+		log.Info("Database migrated", "path", path, "engine", rawdb.PreexistingDatabase(path))
+		return nil
+	}
+	source := rawdb.PreexistingDatabase(path)
+	switch {
+	case source == "":
+		return fmt.Errorf("no database found at %s", path)
+	case source == target:
+		return fmt.Errorf("database at %s already uses %s", path, target)
+	case common.FileExist(tmp):
+		return fmt.Errorf("unfinished migration found at %s, remove it first", tmp)
+	}
+	src, err := rawdb.NewKeyValueStore(source, path, cache, handles, "", true)
+	if err != nil {
+		return err
+	}
+	dst, err := rawdb.NewKeyValueStore(target, tmp, cache, handles, "", false)
+	if err != nil {
+		src.Close()
+		return err
+	}
+	log.Info("Migrating database", "path", path, "from", source, "to", target)
+	var (
+		it      = src.NewIterator(nil, nil)
+		batch   = dst.NewBatch()
+		count   uint64
+		size    common.StorageSize
+		start   = time.Now()
+		logged  = time.Now()
+		copyErr error
+	)
+	for it.Next() {
+		if copyErr = batch.Put(it.Key(), it.Value()); copyErr != nil {
+			break
+		}
+		count++
+		size += common.StorageSize(len(it.Key()) + len(it.Value()))
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if copyErr = batch.Write(); copyErr != nil {
+				break
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Migrating database", "items", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if copyErr == nil {
+		copyErr = it.Error()
+	}
+	if copyErr == nil {
+		copyErr = batch.Write()
+	}
+	it.Release()
+	src.Close()
+	dst.Close()
+
+	if copyErr != nil {
+		os.RemoveAll(tmp)
+		return copyErr
+	}
+	// Mark the copy complete, so an interrupted swap can be resumed, then swap
+	// the new database in place of the old one
+	if err := ioutil.WriteFile(filepath.Join(tmp, migrationMarker), nil, 0600); err != nil {
+		return err
+	}
+	if err := swapDatabase(path, tmp, old); err != nil {
+		return err
+	}
+	log.Info("Database migrated", "path", path, "engine", target, "items", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// migrationMarker is the file marking a completely copied database in the
+// migration folder.
+const migrationMarker = "MIGRATED"
+
+// swapDatabase replaces the database folder with the completely copied one of
+// the migration, carrying over subfolders such as the ancient store. The swap
+// is made of atomic renames: the subfolders are moved into the migration folder,
+// the old database folder is renamed out of the way and the migration folder is
+// renamed in its place. The old database is only deleted at the end, so an
+// interrupted swap can be resumed by running it again.
+func swapDatabase(database string, migration string, old string) error {
+	if !common.FileExist(old) {
+		dirs, err := ioutil.ReadDir(database)
+		if err != nil {
+			return err
+		}
+		for _, info := range dirs {
+			if info.IsDir() {
+				if err := os.Rename(filepath.Join(database, info.Name()), filepath.Join(migration, info.Name())); err != nil {
+					return err
+				}
+			}
+		}
+		if err := os.Rename(database, old); err != nil {
+			return err
+		}
+	}
+	if common.FileExist(migration) {
+		if err := os.Rename(migration, database); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(filepath.Join(database, migrationMarker)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.RemoveAll(old)
+}
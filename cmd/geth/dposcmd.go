@@ -0,0 +1,116 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"PureChain/cmd/utils"
+	"PureChain/consensus/dpos"
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	dposFromFlag = cli.Uint64Flag{
+		Name:  "from",
+		Usage: "First block of the range (default = 1024 blocks below the head)",
+	}
+	dposToFlag = cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Last block of the range (default = head)",
+	}
+	dposCommand = cli.Command{
+		Name:      "dpos",
+		Usage:     "A set of commands to inspect the dpos consensus",
+		ArgsUsage: "",
+		Category:  "MISCELLANEOUS COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Name:     "provider-stats",
+				Usage:    "Replay the provider selection over a block range and report its fairness",
+				Action:   utils.MigrateFlags(dposProviderStats),
+				Category: "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.SyncModeFlag,
+					utils.MainnetFlag,
+					utils.TestnetFlag,
+					utils.DevnetFlag,
+					dposFromFlag,
+					dposToFlag,
+				},
+				Description: `
+geth dpos provider-stats --from <block> --to <block>
+replays the provider selection of every block in the range on top of the
+historical provider contract state, and compares the number of blocks each
+provider was selected for with the number expected from its voting power.
+The fairness of the selection is summarized by Pearson's chi-square statistic
+and its p-value. The state of the whole range must be available, so ranges
+older than the last 128 blocks need an archive node. At most 10000 blocks are
+replayed at once.
+`,
+			},
+		},
+	}
+)
+
+func dposProviderStats(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack)
+	defer db.Close()
+	defer chain.Stop()
+
+	engine, ok := chain.Engine().(*dpos.Dpos)
+	if !ok {
+		return errors.New("chain is not running the dpos consensus")
+	}
+	to := chain.CurrentHeader().Number.Uint64()
+	if ctx.IsSet(dposToFlag.Name) {
+		to = ctx.Uint64(dposToFlag.Name)
+	}
+	var from uint64
+	if to >= 1024 {
+		from = to - 1023
+	}
+	if ctx.IsSet(dposFromFlag.Name) {
+		from = ctx.Uint64(dposFromFlag.Name)
+	}
+	stats, err := engine.ProviderSelectionStats(chain, from, to)
+	if err != nil {
+		return err
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Provider", "Observed", "Replayed", "Expected"})
+	for _, sel := range stats.Providers {
+		table.Append([]string{sel.Provider.Hex(), fmt.Sprint(sel.Observed), fmt.Sprint(sel.Replayed), fmt.Sprintf("%.2f", sel.Expected)})
+	}
+	table.Render()
+
+	fmt.Printf("Blocks:             %d (#%d - #%d)\n", stats.NumBlocks, stats.From, stats.To)
+	fmt.Printf("Unassigned:         %d\n", stats.Unassigned)
+	fmt.Printf("Mismatches:         %d\n", stats.Mismatches)
+	fmt.Printf("Chi-square:         %.4f\n", stats.ChiSquare)
+	fmt.Printf("Degrees of freedom: %d\n", stats.DegreesOfFreedom)
+	fmt.Printf("P-value:            %.4f\n", stats.PValue)
+	return nil
+}
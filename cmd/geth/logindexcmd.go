@@ -0,0 +1,203 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"PureChain/cmd/utils"
+	"PureChain/common"
+	"PureChain/core"
+	"PureChain/core/rawdb"
+	"PureChain/core/types"
+	"PureChain/ethdb"
+	"PureChain/event"
+	"PureChain/log"
+	"PureChain/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// logIndexRebuildStall is the time after which a rebuild not indexing any new
+// section is considered failed.
+const logIndexRebuildStall = time.Minute
+
+var (
+	logIndexCommand = cli.Command{
+		Name:      "logindex",
+		Usage:     "A set of commands to manage the address and topic log index",
+		ArgsUsage: "",
+		Category:  "DATABASE COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Name:     "status",
+				Usage:    "Show the progress of the log index",
+				Action:   utils.MigrateFlags(logIndexStatus),
+				Category: "DATABASE COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.SyncModeFlag,
+					utils.MainnetFlag,
+					utils.TestnetFlag,
+					utils.DevnetFlag,
+					utils.LogIndexFlag,
+				},
+				Description: `
+geth logindex status
+shows how many sections of the canonical chain the address and topic log index
+covers, compared to the number of sections available for indexing.
+`,
+			},
+			{
+				Name:     "rebuild",
+				Usage:    "Delete the log index and rebuild it from the stored receipts",
+				Action:   utils.MigrateFlags(logIndexRebuild),
+				Category: "DATABASE COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.SyncModeFlag,
+					utils.MainnetFlag,
+					utils.TestnetFlag,
+					utils.DevnetFlag,
+					utils.CacheFlag,
+					utils.CacheDatabaseFlag,
+				},
+				Description: `
+geth logindex rebuild
+deletes the address and topic log index and indexes the canonical chain again
+from the stored receipts. The node must not be running. Nodes started with
+--logindex resume an interrupted rebuild in the background.
+`,
+			},
+		},
+	}
+)
+
+// staticChain is a chain not being imported into, feeding the current head into
+// an indexer running offline.
+type staticChain struct {
+	head *types.Header
+}
+
+// CurrentHeader implements core.ChainIndexerChain, returning the fixed head.
+func (c *staticChain) CurrentHeader() *types.Header {
+	return c.head
+}
+
+// SubscribeChainHeadEvent implements core.ChainIndexerChain, never delivering
+// any events.
+func (c *staticChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// readHeadHeader retrieves the header of the current head block.
+func readHeadHeader(db ethdb.Database) (*types.Header, error) {
+	block := rawdb.ReadHeadBlock(db)
+	if block == nil {
+		return nil, errors.New("no head block found")
+	}
+	return block.Header(), nil
+}
+
+// logIndexSections returns the number of sections the log index can cover given
+// the chain head.
+func logIndexSections(head uint64) uint64 {
+	if head+1 < params.LogIndexConfirms {
+		return 0
+	}
+	return (head + 1 - params.LogIndexConfirms) / params.LogIndexBlocks
+}
+
+func logIndexStatus(ctx *cli.Context) error {
+	stack, config := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	head, err := readHeadHeader(db)
+	if err != nil {
+		return err
+	}
+	indexer := core.NewLogIndexer(db, params.LogIndexBlocks, params.LogIndexConfirms)
+	defer indexer.Close()
+
+	var (
+		stored, _, _ = indexer.Sections()
+		available    = logIndexSections(head.Number.Uint64())
+	)
+	fmt.Printf("Enabled:        %v\n", config.Eth.LogIndex)
+	fmt.Printf("Section size:   %d blocks\n", params.LogIndexBlocks)
+	fmt.Printf("Chain head:     #%d [%x..]\n", head.Number, head.Hash().Bytes()[:4])
+	fmt.Printf("Indexed:        %d/%d sections\n", stored, available)
+	if stored > 0 {
+		fmt.Printf("Indexed blocks: #0 - #%d\n", stored*params.LogIndexBlocks-1)
+	}
+	return nil
+}
+
+func logIndexRebuild(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	head, err := readHeadHeader(db)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	rawdb.DeleteLogIndex(db)
+	log.Info("Deleted log index", "elapsed", common.PrettyDuration(time.Since(start)))
+
+	indexer := core.NewLogIndexer(db, params.LogIndexBlocks, params.LogIndexConfirms)
+	defer indexer.Close()
+	indexer.Start(&staticChain{head: head})
+
+	var (
+		target   = logIndexSections(head.Number.Uint64())
+		logged   = time.Now()
+		last     uint64
+		progress = time.Now()
+	)
+	for {
+		stored, _, _ := indexer.Sections()
+		if stored >= target {
+			break
+		}
+		// The indexer doesn't retry failed sections without new heads, bail out
+		if stored > last {
+			last, progress = stored, time.Now()
+		} else if time.Since(progress) > logIndexRebuildStall {
+			return fmt.Errorf("log index rebuild stalled at section %d", stored)
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Rebuilding log index", "sections", stored, "total", target, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	log.Info("Rebuilt log index", "sections", target, "head", head.Number, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// statsserver runs a minimal local ethstats server for testing the stats
+// reporting of nodes, including the INIChain extension reports.
+//
+// Nodes connect with --ethstats name:secret@host:port, the latest reports of
+// every node are served as JSON on the root path.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"PureChain/log"
+)
+
+func main() {
+	var (
+		listenAddr = flag.String("addr", "localhost:3000", "listen address")
+		secret     = flag.String("secret", "", "secret the nodes have to log in with")
+		verbosity  = flag.Int("verbosity", int(log.LvlInfo), "log verbosity (0-5)")
+	)
+	flag.Parse()
+
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+	glogger.Verbosity(log.Lvl(*verbosity))
+	log.Root().SetHandler(glogger)
+
+	log.Info("Starting stats server", "addr", *listenAddr)
+	if err := http.ListenAndServe(*listenAddr, newServer(*secret)); err != nil {
+		fmt.Fprintf(os.Stderr, "Fatal: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"PureChain/log"
+	"github.com/gorilla/websocket"
+)
+
+// nodeState is the latest report of every kind received from a single node.
+type nodeState struct {
+	Info    json.RawMessage            `json:"info"`
+	Reports map[string]json.RawMessage `json:"reports"` // Latest report by emit kind (block, stats, inichain, ...)
+	Updated time.Time                  `json:"updated"`
+}
+
+// server is a minimal ethstats server, accepting node logins on /api and
+// serving the latest reports of the nodes on every other path.
+type server struct {
+	secret   string
+	upgrader websocket.Upgrader
+
+	lock  sync.RWMutex
+	nodes map[string]*nodeState
+}
+
+// newServer creates a stats server accepting the nodes logging in with secret.
+func newServer(secret string) *server {
+	return &server{
+		secret: secret,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		nodes: make(map[string]*nodeState),
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/api" {
+		s.serveNode(w, r)
+		return
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.nodes); err != nil {
+		log.Warn("Failed to serve node reports", "err", err)
+	}
+}
+
+// emitMsg is the envelope of every message exchanged with the nodes.
+type emitMsg struct {
+	Emit []json.RawMessage `json:"emit"`
+}
+
+// serveNode handles the websocket connection of a single node until it breaks.
+func (s *server) serveNode(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Warn("Failed to upgrade stats connection", "err", err)
+		return
+	}
+	defer conn.Close()
+
+	// Authenticate the node before accepting any reports
+	var hello emitMsg
+	if err := conn.ReadJSON(&hello); err != nil || len(hello.Emit) != 2 || kind(hello.Emit[0]) != "hello" {
+		log.Warn("Invalid stats login", "remote", r.RemoteAddr, "err", err)
+		return
+	}
+	var auth struct {
+		ID     string          `json:"id"`
+		Info   json.RawMessage `json:"info"`
+		Secret string          `json:"secret"`
+	}
+	if err := json.Unmarshal(hello.Emit[1], &auth); err != nil || auth.Secret != s.secret {
+		log.Warn("Unauthorized stats login", "remote", r.RemoteAddr, "id", auth.ID)
+		return
+	}
+	if err := conn.WriteJSON(map[string][]string{"emit": {"ready"}}); err != nil {
+		return
+	}
+	s.lock.Lock()
+	s.nodes[auth.ID] = &nodeState{Info: auth.Info, Reports: make(map[string]json.RawMessage), Updated: time.Now()}
+	s.lock.Unlock()
+
+	log.Info("Node logged in", "id", auth.ID, "remote", r.RemoteAddr)
+	defer log.Info("Node disconnected", "id", auth.ID)
+
+	for {
+		var msg emitMsg
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		if len(msg.Emit) != 2 {
+			continue
+		}
+		switch command := kind(msg.Emit[0]); command {
+		case "node-ping":
+			var ping struct {
+				ClientTime string `json:"clientTime"`
+			}
+			json.Unmarshal(msg.Emit[1], &ping)
+			pong := map[string][]interface{}{
+				"emit": {"node-pong", map[string]string{
+					"clientTime": ping.ClientTime,
+					"serverTime": time.Now().String(),
+				}},
+			}
+			if err := conn.WriteJSON(pong); err != nil {
+				return
+			}
+		default:
+			log.Debug("Received stats report", "id", auth.ID, "kind", command)
+
+			s.lock.Lock()
+			node := s.nodes[auth.ID]
+			node.Reports[command] = msg.Emit[1]
+			node.Updated = time.Now()
+			s.lock.Unlock()
+		}
+	}
+}
+
+// kind decodes the kind of an emitted message, returning an empty string if
+// it isn't a JSON string.
+func kind(raw json.RawMessage) string {
+	var command string
+	json.Unmarshal(raw, &command)
+	return command
+}
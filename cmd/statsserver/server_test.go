@@ -0,0 +1,90 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestServerReports(t *testing.T) {
+	srv := httptest.NewServer(newServer("secret"))
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api"
+
+	// Logins with a wrong secret must be rejected
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("failed to dial server: %v", err)
+	}
+	conn.WriteJSON(map[string][]interface{}{"emit": {"hello", map[string]string{"id": "bad", "secret": "wrong"}}})
+	var ack map[string][]string
+	if err := conn.ReadJSON(&ack); err == nil {
+		t.Fatalf("unauthorized login acknowledged: %v", ack)
+	}
+	conn.Close()
+
+	// Log in properly and exchange a ping and an extension report
+	conn, _, err = websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("failed to dial server: %v", err)
+	}
+	defer conn.Close()
+
+	conn.WriteJSON(map[string][]interface{}{"emit": {"hello", map[string]string{"id": "node", "secret": "secret"}}})
+	if err := conn.ReadJSON(&ack); err != nil || len(ack["emit"]) != 1 || ack["emit"][0] != "ready" {
+		t.Fatalf("login not acknowledged: %v, %v", ack, err)
+	}
+	conn.WriteJSON(map[string][]interface{}{"emit": {"node-ping", map[string]string{"id": "node", "clientTime": "now"}}})
+	var pong map[string][]interface{}
+	if err := conn.ReadJSON(&pong); err != nil || len(pong["emit"]) != 2 || pong["emit"][0] != "node-pong" {
+		t.Fatalf("ping not answered: %v, %v", pong, err)
+	}
+	report := map[string]interface{}{"id": "node", "inichain": map[string]interface{}{"number": 1, "inTurn": true}}
+	conn.WriteJSON(map[string][]interface{}{"emit": {"inichain", report}})
+
+	// Wait for the report to show up on the JSON endpoint
+	for i := 0; ; i++ {
+		res, err := http.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("failed to retrieve reports: %v", err)
+		}
+		var nodes map[string]*nodeState
+		err = json.NewDecoder(res.Body).Decode(&nodes)
+		res.Body.Close()
+		if err != nil {
+			t.Fatalf("failed to decode reports: %v", err)
+		}
+		if node := nodes["node"]; node != nil && node.Reports["inichain"] != nil {
+			if _, ok := nodes["bad"]; ok {
+				t.Fatalf("unauthorized node tracked")
+			}
+			break
+		}
+		if i == 50 {
+			t.Fatalf("extension report not recorded: %v", nodes)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -39,6 +39,7 @@ import (
 	"PureChain/common/fdlimit"
 	"PureChain/consensus"
 	"PureChain/consensus/clique"
+	"PureChain/consensus/dpos"
 	"PureChain/consensus/ethash"
 	"PureChain/core"
 	"PureChain/core/rawdb"
@@ -125,6 +126,10 @@ var (
 		Name:  "datadir.ancient",
 		Usage: "Data directory for ancient chain segments (default = inside chaindata)",
 	}
+	DBEngineFlag = cli.StringFlag{
+		Name:  "db.engine",
+		Usage: "Backing database implementation to use ('leveldb' or 'bolt', default = engine of the existing database or leveldb)",
+	}
 	MinFreeDiskSpaceFlag = DirectoryFlag{
 		Name:  "datadir.minfreedisk",
 		Usage: "Minimum free disk space in MB, once reached triggers auto shut down (default = --cache.gc converted to MB, 0 = disabled)",
@@ -228,6 +233,28 @@ var (
 		Usage: "Number of recent blocks to maintain transactions index for (default = about one year, 0 = entire chain)",
 		Value: ethconfig.Defaults.TxLookupLimit,
 	}
+	HistoryTransactionsFlag = cli.Uint64Flag{
+		Name:  "history.transactions",
+		Usage: "Number of recent blocks to retain transactions (block bodies) for (default = 0, entire chain)",
+		Value: ethconfig.Defaults.HistoryTransactions,
+	}
+	HistoryReceiptsFlag = cli.Uint64Flag{
+		Name:  "history.receipts",
+		Usage: "Number of recent blocks to retain receipts for (default = 0, entire chain)",
+		Value: ethconfig.Defaults.HistoryReceipts,
+	}
+	HistoryStateFlag = cli.BoolFlag{
+		Name:  "history.state",
+		Usage: "Archive the state diffs of all blocks to serve historical state (requires snapshot)",
+	}
+	LogIndexFlag = cli.BoolFlag{
+		Name:  "logindex",
+		Usage: "Maintain an address and topic index of the logs to speed up log filtering over large ranges",
+	}
+	TxHistoryFlag = cli.BoolFlag{
+		Name:  "txhistory",
+		Usage: "Maintain an index of the transactions and rewards of every account (enables eth_getTransactionsByAddress). On dpos chains every indexed block replays the 100 round reward distribution, reading a header per round and writing about 200 reward entries for the team, coinbases and providers. Only the minted rewards are indexed, not the fee shares",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -350,6 +377,16 @@ var (
 		Usage: "Time interval to regenerate the local transaction journal",
 		Value: core.DefaultTxPoolConfig.Rejournal,
 	}
+	TxPoolRemoteJournalFlag = cli.StringFlag{
+		Name:  "txpool.remotejournal",
+		Usage: "Disk journal for the whole transaction pool to survive node restarts (empty disables)",
+		Value: core.DefaultTxPoolConfig.RemoteJournal,
+	}
+	TxPoolRemoteRejournalFlag = cli.DurationFlag{
+		Name:  "txpool.remoterejournal",
+		Usage: "Time interval to snapshot the whole transaction pool into the remote journal",
+		Value: core.DefaultTxPoolConfig.RemoteRejournal,
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
@@ -385,6 +422,11 @@ var (
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: ethconfig.Defaults.TxPool.Lifetime,
 	}
+	TxPoolPolicyFlag = cli.StringFlag{
+		Name:  "txpool.policy",
+		Usage: `Transaction pool admission and eviction policy ("default" or "dpos")`,
+		Value: "default",
+	}
 	// Performance tuning settings
 	CacheFlag = cli.IntFlag{
 		Name:  "cache",
@@ -466,6 +508,10 @@ var (
 		Name:  "por.challenge.commit.url",
 		Usage: "An intermediate used for interaction when doing POR challenges",
 	}
+	DposVoteKeyFlag = cli.StringFlag{
+		Name:  "dpos.votekey",
+		Usage: "File holding the hex encoded BLS key to cast dpos fast finality votes with",
+	}
 	MinerEtherbaseFlag = cli.StringFlag{
 		Name:  "miner.etherbase",
 		Usage: "Public address for block mining rewards (default = first account)",
@@ -524,11 +570,32 @@ var (
 		Usage: "Sets a cap on transaction fee (in ether) that can be sent via the RPC APIs (0 = no cap)",
 		Value: ethconfig.Defaults.RPCTxFeeCap,
 	}
+	RPCBatchLimitFlag = cli.IntFlag{
+		Name:  "rpc.batchlimit",
+		Usage: "Maximum number of calls in a JSON-RPC batch served via HTTP or WS (0 = no limit)",
+	}
+	RPCResponseMaxSizeFlag = cli.IntFlag{
+		Name:  "rpc.responsemaxsize",
+		Usage: "Maximum size in bytes of a JSON-RPC response or batch of responses served via HTTP or WS (0 = no limit)",
+	}
+	RPCRateLimitFlag = cli.Float64Flag{
+		Name:  "rpc.ratelimit",
+		Usage: "Maximum number of JSON-RPC calls per second and client served via HTTP or WS (0 = no limit)",
+	}
+	RPCRateBurstFlag = cli.IntFlag{
+		Name:  "rpc.rateburst",
+		Usage: "Number of JSON-RPC calls a client may burst above the rate limit",
+		Value: 1,
+	}
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "ethstats",
 		Usage: "Reporting URL of a ethstats service (nodename:secret@host:port)",
 	}
+	EthStatsINIChainFlag = cli.BoolFlag{
+		Name:  "ethstats.inichain",
+		Usage: "Send the INIChain extension reports (provider, rates, validators, PoR challenges) to the ethstats service",
+	}
 	FakePoWFlag = cli.BoolFlag{
 		Name:  "fakepow",
 		Usage: "Disables proof-of-work verification",
@@ -580,6 +647,10 @@ var (
 		Usage: "HTTP path path prefix on which JSON-RPC is served. Use '/' to serve on all paths.",
 		Value: "",
 	}
+	HTTPJWTAuthFlag = cli.BoolFlag{
+		Name:  "http.jwt",
+		Usage: "Require JWT authentication (HS256, secret from --rpc.jwtsecret) on the HTTP-RPC server",
+	}
 	GraphQLEnabledFlag = cli.BoolFlag{
 		Name:  "graphql",
 		Usage: "Enable GraphQL on the HTTP-RPC server. Note that GraphQL can only be started if an HTTP server is started as well.",
@@ -623,6 +694,14 @@ var (
 		Usage: "HTTP path prefix on which JSON-RPC is served. Use '/' to serve on all paths.",
 		Value: "",
 	}
+	WSJWTAuthFlag = cli.BoolFlag{
+		Name:  "ws.jwt",
+		Usage: "Require JWT authentication (HS256, secret from --rpc.jwtsecret) on the WS-RPC server",
+	}
+	JWTSecretFlag = cli.StringFlag{
+		Name:  "rpc.jwtsecret",
+		Usage: "Path to the hex encoded JWT secret authenticating RPC requests (default = inside the datadir, generated if missing)",
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -1013,6 +1092,9 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(HTTPPathPrefixFlag.Name) {
 		cfg.HTTPPathPrefix = ctx.GlobalString(HTTPPathPrefixFlag.Name)
 	}
+	if ctx.GlobalIsSet(HTTPJWTAuthFlag.Name) {
+		cfg.HTTPJWTAuth = ctx.GlobalBool(HTTPJWTAuthFlag.Name)
+	}
 	if ctx.GlobalIsSet(AllowUnprotectedTxs.Name) {
 		cfg.AllowUnprotectedTxs = ctx.GlobalBool(AllowUnprotectedTxs.Name)
 	}
@@ -1029,6 +1111,26 @@ func setGraphQL(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setRPCLimits applies the request limits and the authentication secret of the
+// HTTP and WebSocket RPC interfaces from the set command line flags.
+func setRPCLimits(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCBatchLimitFlag.Name) {
+		cfg.RPCBatchLimit = ctx.GlobalInt(RPCBatchLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCResponseMaxSizeFlag.Name) {
+		cfg.RPCResponseMaxSize = ctx.GlobalInt(RPCResponseMaxSizeFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateLimitFlag.Name) {
+		cfg.RPCRateLimit = ctx.GlobalFloat64(RPCRateLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateBurstFlag.Name) {
+		cfg.RPCRateBurst = ctx.GlobalInt(RPCRateBurstFlag.Name)
+	}
+	if ctx.GlobalIsSet(JWTSecretFlag.Name) {
+		cfg.JWTSecret = ctx.GlobalString(JWTSecretFlag.Name)
+	}
+}
+
 // setWS creates the WebSocket RPC listener interface string from the set
 // command line flags, returning empty if the HTTP endpoint is disabled.
 func setWS(ctx *cli.Context, cfg *node.Config) {
@@ -1053,6 +1155,9 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(WSPathPrefixFlag.Name) {
 		cfg.WSPathPrefix = ctx.GlobalString(WSPathPrefixFlag.Name)
 	}
+	if ctx.GlobalIsSet(WSJWTAuthFlag.Name) {
+		cfg.WSJWTAuth = ctx.GlobalBool(WSJWTAuthFlag.Name)
+	}
 }
 
 // setIPC creates an IPC path configuration from the set command line flags,
@@ -1270,6 +1375,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setHTTP(ctx, cfg)
 	setGraphQL(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCLimits(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 	setDataDir(ctx, cfg)
 	setSmartCard(ctx, cfg)
@@ -1278,6 +1384,13 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 		cfg.ExternalSigner = ctx.GlobalString(ExternalSignerFlag.Name)
 	}
 
+	if ctx.GlobalIsSet(DBEngineFlag.Name) {
+		engine := ctx.GlobalString(DBEngineFlag.Name)
+		if engine != rawdb.DBLeveldb && engine != rawdb.DBBolt {
+			Fatalf("Invalid choice for db.engine '%s', allowed 'leveldb' or 'bolt'", engine)
+		}
+		cfg.DBEngine = engine
+	}
 	if ctx.GlobalIsSet(KeyStoreDirFlag.Name) {
 		cfg.KeyStoreDir = ctx.GlobalString(KeyStoreDirFlag.Name)
 	}
@@ -1386,6 +1499,12 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolRemoteJournalFlag.Name) {
+		cfg.RemoteJournal = ctx.GlobalString(TxPoolRemoteJournalFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolRemoteRejournalFlag.Name) {
+		cfg.RemoteRejournal = ctx.GlobalDuration(TxPoolRemoteRejournalFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1539,6 +1658,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		ctx.GlobalSet(TxLookupLimitFlag.Name, "0")
 		log.Warn("Disable transaction unindexing for archive node")
 	}
+	if ctx.GlobalString(GCModeFlag.Name) == "archive" && (ctx.GlobalUint64(HistoryTransactionsFlag.Name) != 0 || ctx.GlobalUint64(HistoryReceiptsFlag.Name) != 0) {
+		Fatalf("Chain history pruning is not supported for archive nodes")
+	}
 	if ctx.GlobalIsSet(LightServeFlag.Name) && ctx.GlobalUint64(TxLookupLimitFlag.Name) != 0 {
 		log.Warn("LES server cannot serve old transaction status and cannot connect below les/4 protocol version if transaction lookup index is limited")
 	}
@@ -1549,6 +1671,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	setEtherbase(ctx, ks, cfg)
 	setGPO(ctx, &cfg.GPO, ctx.GlobalString(SyncModeFlag.Name) == "light")
 	setTxPool(ctx, &cfg.TxPool)
+	if ctx.GlobalIsSet(TxPoolPolicyFlag.Name) {
+		cfg.TxPoolPolicy = ctx.GlobalString(TxPoolPolicyFlag.Name)
+	}
 	setEthash(ctx, cfg)
 	setMiner(ctx, &cfg.Miner)
 	setWhitelist(ctx, cfg)
@@ -1599,6 +1724,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(PorFlag.Name) {
 		cfg.Por = ctx.GlobalBool(PorFlag.Name)
 	}
+	if ctx.GlobalIsSet(DposVoteKeyFlag.Name) {
+		cfg.DposVoteKey = ctx.GlobalString(DposVoteKeyFlag.Name)
+	}
 	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
 	}
@@ -1620,6 +1748,21 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.GlobalIsSet(HistoryTransactionsFlag.Name) {
+		cfg.HistoryTransactions = ctx.GlobalUint64(HistoryTransactionsFlag.Name)
+	}
+	if ctx.GlobalIsSet(HistoryReceiptsFlag.Name) {
+		cfg.HistoryReceipts = ctx.GlobalUint64(HistoryReceiptsFlag.Name)
+	}
+	if ctx.GlobalIsSet(HistoryStateFlag.Name) {
+		cfg.HistoryState = ctx.GlobalBool(HistoryStateFlag.Name)
+	}
+	if ctx.GlobalIsSet(LogIndexFlag.Name) {
+		cfg.LogIndex = ctx.GlobalBool(LogIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxHistoryFlag.Name) {
+		cfg.TxHistory = ctx.GlobalBool(TxHistoryFlag.Name)
+	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
 	}
@@ -1827,8 +1970,8 @@ func RegisterEthService(stack *node.Node, cfg *ethconfig.Config) (ethapi.Backend
 
 // RegisterEthStatsService configures the Ethereum Stats daemon and adds it to
 // the given node.
-func RegisterEthStatsService(stack *node.Node, backend ethapi.Backend, url string) {
-	if err := ethstats.New(stack, backend, backend.Engine(), url); err != nil {
+func RegisterEthStatsService(stack *node.Node, backend ethapi.Backend, url string, inichain bool) {
+	if err := ethstats.New(stack, backend, backend.Engine(), url, inichain); err != nil {
 		Fatalf("Failed to register the Ethereum Stats service: %v", err)
 	}
 }
@@ -1885,7 +2028,7 @@ func SplitTagsFlag(tagsFlag string) map[string]string {
 	return tagsMap
 }
 
-// MakeChainDatabase open an LevelDB using the flags passed to the client and will hard crash if it fails.
+// MakeChainDatabase open the chain database using the flags passed to the client and will hard crash if it fails.
 func MakeChainDatabase(ctx *cli.Context, stack *node.Node, readonly bool) ethdb.Database {
 	var (
 		cache   = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
@@ -1935,13 +2078,15 @@ func MakeGenesis(ctx *cli.Context) *core.Genesis {
 func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chainDb ethdb.Database) {
 	var err error
 	chainDb = MakeChainDatabase(ctx, stack, false) // TODO(rjl493456442) support read-only database
-	config, _, err := core.SetupGenesisBlock(chainDb, MakeGenesis(ctx))
+	config, genesisHash, err := core.SetupGenesisBlock(chainDb, MakeGenesis(ctx))
 	if err != nil {
 		Fatalf("%v", err)
 	}
 	var engine consensus.Engine
 	if config.Clique != nil {
 		engine = clique.New(config.Clique, chainDb)
+	} else if config.Dpos != nil {
+		engine = dpos.New(config, chainDb, nil, genesisHash)
 	} else {
 		engine = ethash.NewFaker()
 		if !ctx.GlobalBool(FakePoWFlag.Name) {
@@ -1993,6 +2138,9 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	if err != nil {
 		Fatalf("Can't create BlockChain: %v", err)
 	}
+	if dposEngine, ok := engine.(*dpos.Dpos); ok {
+		dposEngine.SetStateFn(chain.StateAt)
+	}
 	return chain, chainDb
 }
 
@@ -30,6 +30,10 @@ import (
 
 var (
 	SystemAddress = common.HexToAddress("0xffffFFFfFFffffffffffffffFfFFFfffFFFfFFfE")
+
+	// BaseFeeAddress collects the base fee of the transactions after London for
+	// the engines sharing it out instead of burning it entirely.
+	BaseFeeAddress = common.HexToAddress("0xffffFFFfFFffffffffffffffFfFFFfffFFFfFFfD")
 )
 
 // ChainHeaderReader defines a small collection of methods needed to access the local
@@ -154,6 +158,122 @@ type PoSA interface {
 	IsLocalBlock(header *types.Header) bool
 }
 
+// FastFinality is a consensus engine whose validators attest blocks with votes,
+// making blocks justified and finalized ahead of the probabilistic finality.
+type FastFinality interface {
+	Engine
+
+	// GetJustifiedNumberAndHash returns the latest justified block as of the
+	// given header.
+	GetJustifiedNumberAndHash(chain ChainHeaderReader, header *types.Header) (uint64, common.Hash, error)
+
+	// GetFinalizedHeader returns the latest finalized header as of the given
+	// header.
+	GetFinalizedHeader(chain ChainHeaderReader, header *types.Header) *types.Header
+}
+
+// BalanceChangeKind is the reason of a balance change applied by a consensus
+// engine outside of any transaction.
+type BalanceChangeKind string
+
+const (
+	BalanceChangeReward      BalanceChangeKind = "REWARD"       // Block reward minted to an account
+	BalanceChangeUncleReward BalanceChangeKind = "UNCLE_REWARD" // Uncle inclusion reward minted to an account
+	BalanceChangeLock        BalanceChangeKind = "LOCK"         // Balance locked until released by later rewards
+	BalanceChangeUnlock      BalanceChangeKind = "UNLOCK"       // Previously locked balance released
+	BalanceChangeFeeSweep    BalanceChangeKind = "FEE_SWEEP"    // Fees collected on the system address moved out
+	BalanceChangeBaseFee     BalanceChangeKind = "BASE_FEE"     // Share of the collected base fee paid to an account
+	BalanceChangeBurn        BalanceChangeKind = "BURN"         // Share of the collected base fee destroyed
+)
+
+// BalanceTracer is notified of the balance changes a consensus engine applies
+// while finalizing a block. Minted rewards originate from the zero address and
+// lock changes have the locked account as both source and destination.
+type BalanceTracer interface {
+	CaptureBalanceChange(kind BalanceChangeKind, from, to common.Address, value *big.Int)
+}
+
+// TracedChainHeaderReader is a chain reader carrying the tracer the engine has
+// to notify of its balance changes.
+type TracedChainHeaderReader interface {
+	ChainHeaderReader
+
+	// BalanceTracer retrieves the tracer of the engine balance changes.
+	BalanceTracer() BalanceTracer
+}
+
+// BalanceTracerOf returns the balance tracer carried by the chain reader, or
+// nil if the balance changes are not traced.
+func BalanceTracerOf(chain ChainHeaderReader) BalanceTracer {
+	if traced, ok := chain.(TracedChainHeaderReader); ok {
+		return traced.BalanceTracer()
+	}
+	return nil
+}
+
+// tracedChainHeaderReader attaches a balance tracer to a chain reader.
+type tracedChainHeaderReader struct {
+	ChainHeaderReader
+	tracer BalanceTracer
+}
+
+func (c *tracedChainHeaderReader) BalanceTracer() BalanceTracer {
+	return c.tracer
+}
+
+// WithBalanceTracer wraps the chain reader to carry the given balance tracer.
+func WithBalanceTracer(chain ChainHeaderReader, tracer BalanceTracer) TracedChainHeaderReader {
+	return &tracedChainHeaderReader{ChainHeaderReader: chain, tracer: tracer}
+}
+
+// RewardReporter is a consensus engine able to report the rewards it credits
+// while finalizing a block from the headers alone, without executing the block.
+type RewardReporter interface {
+	// BlockRewards reports the reward credits of the block to the tracer. Balance
+	// changes depending on the state, like fee distributions, are not reported.
+	BlockRewards(chain ChainHeaderReader, header *types.Header, uncles []*types.Header, tracer BalanceTracer) error
+}
+
+// CheckpointTruster is a consensus engine able to start verifying headers from
+// a trusted checkpoint instead of the genesis, as light clients do.
+type CheckpointTruster interface {
+	// TrustCheckpoint marks the given header as trusted without verifying its
+	// ancestry. Callers must authenticate it first, e.g. through a CHT proof.
+	TrustCheckpoint(checkpoint *params.EngineCheckpoint)
+}
+
+// SimulatedChainHeaderReader is a chain reader extending the chain with the
+// headers of simulated blocks. These are neither sealed nor persisted, so the
+// reader holds their post state. Engines only rely on it through the entry
+// points of BlockSimulator, the other methods treat it like any chain reader.
+type SimulatedChainHeaderReader interface {
+	ChainHeaderReader
+
+	// Simulated reports whether the header is the one of a simulated block.
+	Simulated(header *types.Header) bool
+
+	// SimulatedState retrieves a copy of the post state of a simulated block, or
+	// nil if the header is not simulated.
+	SimulatedState(header *types.Header) *state.StateDB
+}
+
+// BlockSimulator is a consensus engine able to prepare and finalize simulated
+// blocks, skipping the checks relying on seals and on signed system transactions.
+// The checks are only relaxed through these entry points, never while importing
+// blocks.
+type BlockSimulator interface {
+	// PrepareSimulated is the counterpart of Prepare for simulated blocks. The
+	// coinbase, the time and the number of the header are left alone and the
+	// seal is left empty.
+	PrepareSimulated(chain SimulatedChainHeaderReader, header *types.Header) error
+
+	// FinalizeSimulated is the counterpart of Finalize for simulated blocks. The
+	// system transactions the engine would expect to be signed are executed on
+	// their behalf and appended to the transactions and receipts.
+	FinalizeSimulated(chain SimulatedChainHeaderReader, header *types.Header, state *state.StateDB, txs *[]*types.Transaction,
+		receipts *[]*types.Receipt, usedGas *uint64) error
+}
+
 type StateReader interface {
 	GetState(addr common.Address, hash common.Hash) common.Hash
 }
@@ -101,47 +101,48 @@ type status struct {
 // - the number of validators,
 // - the percentage of in-turn blocks
 func (api *API) Status() (*status, error) {
-	var (
-		numBlocks = uint64(64)
-		header    = api.chain.CurrentHeader()
-		diff      = uint64(0)
-		optimals  = 0
-	)
-	snap, err := api.dpos.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	report, err := api.dpos.livenessReport(api.chain, defaultLivenessWindow)
 	if err != nil {
 		return nil, err
 	}
-	var (
-		validators = snap.validators()
-		end        = header.Number.Uint64()
-		start      = end - numBlocks
-	)
-	if numBlocks > end {
-		start = 1
-		numBlocks = end - start
-	}
 	signStatus := make(map[common.Address]int)
-	for _, s := range validators {
-		signStatus[s] = 0
-	}
-	for n := start; n < end; n++ {
-		h := api.chain.GetHeaderByNumber(n)
-		if h == nil {
-			return nil, fmt.Errorf("missing block %d", n)
-		}
-		if h.Difficulty.Cmp(diffInTurn) == 0 {
-			optimals++
-		}
-		diff += h.Difficulty.Uint64()
-		sealer, err := api.dpos.Author(h)
-		if err != nil {
-			return nil, err
-		}
-		signStatus[sealer]++
+	for val, liveness := range report.Validators {
+		signStatus[val] = int(liveness.InTurn + liveness.OutOfTurn)
 	}
 	return &status{
-		InturnPercent: float64(100*optimals) / float64(numBlocks),
+		InturnPercent: report.InturnPercent,
 		SigningStatus: signStatus,
-		NumBlocks:     numBlocks,
+		NumBlocks:     report.NumBlocks,
 	}, nil
 }
+
+// GetValidatorLiveness returns the in-turn, out-of-turn and missed slot counts
+// of every validator over the last window blocks (64 if not specified).
+func (api *API) GetValidatorLiveness(window *uint64) (*LivenessReport, error) {
+	if window == nil {
+		return api.dpos.livenessReport(api.chain, defaultLivenessWindow)
+	}
+	return api.dpos.livenessReport(api.chain, *window)
+}
+
+// GetProviderSelectionStats replays the provider selection over the block range
+// [from, to] (the last 1024 blocks by default) and reports the observed and
+// expected selection frequency of every provider. Replaying needs the historical
+// state of the range, so older ranges are only available on archive nodes.
+func (api *API) GetProviderSelectionStats(from *rpc.BlockNumber, to *rpc.BlockNumber) (*ProviderSelectionStats, error) {
+	end := api.chain.CurrentHeader().Number.Uint64()
+	if to != nil && *to >= 0 {
+		end = uint64(to.Int64())
+	}
+	start := uint64(0)
+	if end >= 1024 {
+		start = end - 1023
+	}
+	if from != nil && *from >= 0 {
+		start = uint64(from.Int64())
+	}
+	if start > end {
+		return nil, fmt.Errorf("invalid block range: from %d > to %d", start, end)
+	}
+	return api.dpos.ProviderSelectionStats(api.chain, start, end)
+}
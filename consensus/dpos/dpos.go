@@ -34,12 +34,15 @@ import (
 	"PureChain/consensus/misc"
 	"PureChain/core"
 	"PureChain/core/forkid"
+	"PureChain/core/rawdb"
 	"PureChain/core/state"
 	"PureChain/core/systemcontracts"
 	"PureChain/core/types"
 	"PureChain/core/vm"
 	"PureChain/crypto"
+	"PureChain/crypto/bls12381"
 	"PureChain/ethdb"
+	"PureChain/event"
 	"PureChain/internal/ethapi"
 	"PureChain/log"
 	"PureChain/params"
@@ -115,7 +118,7 @@ const (
 
 var (
 	uncleHash  = types.CalcUncleHash(nil) // Always Keccak256(RLP([])) as uncles are meaningless outside of PoW.
-	diffInTurn = big.NewInt(2)            // Block difficulty for in-turn signatures
+	DiffInTurn = big.NewInt(2)            // Block difficulty for in-turn signatures
 	diffNoTurn = big.NewInt(1)            // Block difficulty for out-of-turn signatures
 	// 100 native token
 	maxSystemBalance = new(big.Int).Mul(big.NewInt(100), big.NewInt(params.Ether))
@@ -350,10 +353,15 @@ type Dpos struct {
 	genesisHash common.Hash
 	db          ethdb.Database // Database to store and retrieve snapshot checkpoints
 
-	recentSnaps *lru.ARCCache // Snapshots for recent block to speed up
-	signatures  *lru.ARCCache // Signatures of recent blocks to speed up mining
-	blacklists  *lru.ARCCache // Blacklist snapshots for recent blocks to speed up transactions validation
-	blLock      sync.Mutex    // Make sure only get blacklist once for each block
+	recentSnaps *lru.ARCCache      // Snapshots for recent block to speed up
+	signatures  *lru.ARCCache      // Signatures of recent blocks to speed up mining
+	liveness    *livenessTracker   // Sealing outcome of recent slots for liveness reporting
+	livenessSub event.Subscription // Chain head subscription exporting the liveness metrics
+	blacklists  *lru.ARCCache      // Blacklist snapshots for recent blocks to speed up transactions validation
+	blLock      sync.Mutex         // Make sure only get blacklist once for each block
+
+	checkpoints     map[common.Hash]uint64 // Trusted epoch headers the snapshots are bootstrapped from
+	checkpointsLock sync.RWMutex           // Protects the trusted checkpoints
 
 	proposals map[common.Address]bool // Current list of proposals we are pushing
 
@@ -365,6 +373,9 @@ type Dpos struct {
 	signFns   map[common.Address]SignerFn
 	signTxFns map[common.Address]SignerTxFn
 
+	voteKeys map[common.Address]*bls12381.SecretKey // BLS vote keys of the local validators
+	votePool *VotePool                              // Pool of fast finality votes to aggregate into attestations
+
 	lock            sync.RWMutex       // Protects the signer fields
 	abi             map[string]abi.ABI // Interactive with system contracts
 	ethAPI          *ethapi.PublicBlockChainAPI
@@ -423,6 +434,8 @@ func New(
 		ethAPI:          ethAPI,
 		recentSnaps:     recentSnaps,
 		signatures:      signatures,
+		liveness:        newLivenessTracker(),
+		checkpoints:     make(map[common.Hash]uint64),
 		validatorSetABI: vABI,
 		slashABI:        sABI,
 		blacklists:      blacklists,
@@ -431,11 +444,34 @@ func New(
 		signer:          types.NewEIP155Signer(chainConfig.ChainID),
 		signTxFns:       make(map[common.Address]SignerTxFn, 0),
 		signFns:         make(map[common.Address]SignerFn, 0),
+		voteKeys:        make(map[common.Address]*bls12381.SecretKey),
 	}
 
 	return c
 }
 
+// TrustCheckpoint implements consensus.CheckpointTruster, trusting the validator
+// set of the given epoch header without verifying its ancestry.
+func (p *Dpos) TrustCheckpoint(checkpoint *params.EngineCheckpoint) {
+	if checkpoint.Number%p.config.Epoch != 0 {
+		log.Warn("Ignoring non-epoch dpos checkpoint", "number", checkpoint.Number, "hash", checkpoint.Hash)
+		return
+	}
+	p.checkpointsLock.Lock()
+	defer p.checkpointsLock.Unlock()
+
+	p.checkpoints[checkpoint.Hash] = checkpoint.Number
+}
+
+// trustedCheckpoint reports whether the given header is a trusted checkpoint.
+func (p *Dpos) trustedCheckpoint(number uint64, hash common.Hash) bool {
+	p.checkpointsLock.RLock()
+	defer p.checkpointsLock.RUnlock()
+
+	trusted, ok := p.checkpoints[hash]
+	return ok && trusted == number
+}
+
 func (p *Dpos) IsSystemTransaction(tx *types.Transaction, header *types.Header) (bool, error) {
 	// deploy a contract
 	if tx.To() == nil {
@@ -464,6 +500,38 @@ func (p *Dpos) SetStateFn(fn StateFn) {
 	p.stateFn = fn
 }
 
+// simulatedChain wraps the chain reader handed to the simulation entry points of
+// the engine. Being unexported, it marks the only chain readers whose simulated
+// headers are trusted, whatever the type of the readers given to the other
+// methods of the engine.
+type simulatedChain struct {
+	consensus.SimulatedChainHeaderReader
+}
+
+// BalanceTracer retrieves the tracer of the engine balance changes carried by
+// the wrapped chain reader, if any.
+func (c *simulatedChain) BalanceTracer() consensus.BalanceTracer {
+	return consensus.BalanceTracerOf(c.SimulatedChainHeaderReader)
+}
+
+// simulatedHeader reports whether the header is a simulated one of a chain
+// reader handed to a simulation entry point.
+func simulatedHeader(chain consensus.ChainHeaderReader, header *types.Header) bool {
+	sim, ok := chain.(*simulatedChain)
+	return ok && sim.Simulated(header)
+}
+
+// stateAt retrieves the post state of the given header, which simulated chains
+// hold themselves as it is never persisted.
+func (p *Dpos) stateAt(chain consensus.ChainHeaderReader, header *types.Header) (*state.StateDB, error) {
+	if sim, ok := chain.(*simulatedChain); ok {
+		if statedb := sim.SimulatedState(header); statedb != nil {
+			return statedb, nil
+		}
+	}
+	return p.stateFn(header.Root)
+}
+
 // Author implements consensus.Engine, returning the SystemAddress
 func (p *Dpos) Author(header *types.Header) (common.Address, error) {
 	return header.Coinbase, nil
@@ -521,7 +589,11 @@ func (p *Dpos) verifyHeader(chain consensus.ChainHeaderReader, header *types.Hea
 	isEpoch := number%p.config.Epoch == 0
 
 	// Ensure that the extra-data contains a signer list on checkpoint, but none otherwise
-	signersBytes := len(header.Extra) - extraVanity - extraSeal
+	validators, err := validatorBytes(p.chainConfig, header)
+	if err != nil {
+		return err
+	}
+	signersBytes := len(validators)
 	if !isEpoch && signersBytes != 0 {
 		return errExtraValidators
 	}
@@ -605,7 +677,15 @@ func (p *Dpos) verifyCascadingFields(chain consensus.ChainHeaderReader, header *
 	if uint64(diff) >= limit || header.GasLimit < params.MinGasLimit {
 		return fmt.Errorf("invalid gas limit: have %d, want %d += %d", header.GasLimit, parent.GasLimit, limit)
 	}
+	// Verify the base fee against the parent
+	if err := misc.VerifyEip1559Header(chain.Config(), parent, header); err != nil {
+		return err
+	}
 
+	// Verify the vote key registration and the attestation of the parent
+	if err := p.verifyFinalityExtra(header, parent, snap); err != nil {
+		return err
+	}
 	// All basic checks passed, verify the seal and return
 	return p.verifySeal(chain, header, parents)
 }
@@ -614,8 +694,9 @@ func (p *Dpos) verifyCascadingFields(chain consensus.ChainHeaderReader, header *
 func (p *Dpos) snapshot(chain consensus.ChainHeaderReader, number uint64, hash common.Hash, parents []*types.Header) (*Snapshot, error) {
 	// Search for a snapshot in memory or on disk for checkpoints
 	var (
-		headers []*types.Header
-		snap    *Snapshot
+		headers   []*types.Header
+		simulated []*types.Header
+		snap      *Snapshot
 	)
 
 	for snap == nil {
@@ -625,31 +706,34 @@ func (p *Dpos) snapshot(chain consensus.ChainHeaderReader, number uint64, hash c
 			break
 		}
 
-		// If an on-disk checkpoint snapshot can be found, use that
-		if number%checkpointInterval == 0 {
-			if s, err := loadSnapshot(p.config, p.signatures, p.db, hash, p.ethAPI); err == nil {
+		// If an on-disk checkpoint snapshot can be found, use that. Snapshots of
+		// trusted checkpoints are stored regardless of the checkpoint interval.
+		if number%checkpointInterval == 0 || number%p.config.Epoch == 0 {
+			if s, err := loadSnapshot(p.config, p.signatures, p.liveness, p.db, hash, p.ethAPI); err == nil {
 				log.Trace("Loaded snapshot from disk", "number", number, "hash", hash)
 				snap = s
 				break
 			}
 		}
 
-		// If we're at the genesis, snapshot the initial state.
-		if number == 0 {
-			checkpoint := chain.GetHeaderByNumber(number)
+		// If we're at the genesis, snapshot the initial state. Alternatively if we're
+		// at a trusted epoch checkpoint (light client CHT), snapshot its validator set.
+		if number == 0 || p.trustedCheckpoint(number, hash) {
+			checkpoint := chain.GetHeader(hash, number)
 			if checkpoint != nil {
 				// get checkpoint data
-				hash := checkpoint.Hash()
-
-				validatorBytes := checkpoint.Extra[extraVanity : len(checkpoint.Extra)-extraSeal]
+				checkpointBytes, err := validatorBytes(p.chainConfig, checkpoint)
+				if err != nil {
+					return nil, err
+				}
 				// get validators from headers
-				validators, err := ParseValidators(validatorBytes)
+				validators, err := ParseValidators(checkpointBytes)
 				if err != nil {
 					return nil, err
 				}
 
 				// new snap shot
-				snap = newSnapshot(p.config, p.signatures, number, hash, validators, p.ethAPI)
+				snap = newSnapshot(p.config, p.signatures, p.liveness, number, hash, validators, p.ethAPI)
 				if err := snap.store(p.db); err != nil {
 					return nil, err
 				}
@@ -674,7 +758,15 @@ func (p *Dpos) snapshot(chain consensus.ChainHeaderReader, number uint64, hash c
 				return nil, consensus.ErrUnknownAncestor
 			}
 		}
-		headers = append(headers, header)
+		// Simulated headers are unsigned, apply them apart on top of the chain
+		if simulatedHeader(chain, header) {
+			if len(headers) > 0 {
+				return nil, consensus.ErrUnknownAncestor
+			}
+			simulated = append(simulated, header)
+		} else {
+			headers = append(headers, header)
+		}
 		number, hash = number-1, header.ParentHash
 	}
 
@@ -688,7 +780,7 @@ func (p *Dpos) snapshot(chain consensus.ChainHeaderReader, number uint64, hash c
 		headers[i], headers[len(headers)-1-i] = headers[len(headers)-1-i], headers[i]
 	}
 
-	snap, err := snap.apply(headers, chain, parents, p.chainConfig.ChainID)
+	snap, err := snap.apply(headers, chain, parents, p.chainConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -701,6 +793,13 @@ func (p *Dpos) snapshot(chain consensus.ChainHeaderReader, number uint64, hash c
 		}
 		log.Trace("Stored snapshot to disk", "number", snap.Number, "hash", snap.Hash)
 	}
+	// Simulated snapshots are thrown away with the simulation, never cache them
+	if len(simulated) > 0 {
+		for i := 0; i < len(simulated)/2; i++ {
+			simulated[i], simulated[len(simulated)-1-i] = simulated[len(simulated)-1-i], simulated[i]
+		}
+		return snap.applySimulated(simulated, p.chainConfig)
+	}
 	return snap, err
 }
 
@@ -761,7 +860,7 @@ func (p *Dpos) verifySeal(chain consensus.ChainHeaderReader, header *types.Heade
 	// Ensure that the difficulty corresponds to the turn-ness of the signer
 	if !p.fakeDiff {
 		inturn := snap.inturn(signer)
-		if inturn && header.Difficulty.Cmp(diffInTurn) != 0 {
+		if inturn && header.Difficulty.Cmp(DiffInTurn) != 0 {
 			return errWrongDifficulty
 		}
 		if !inturn && header.Difficulty.Cmp(diffNoTurn) != 0 {
@@ -782,7 +881,7 @@ func (p *Dpos) CheckHasInTurn(chain consensus.ChainHeaderReader, coinbases []com
 	for _, coinbase := range coinbases {
 
 		difficulty := CalcDifficulty(snap, coinbase)
-		if difficulty.Cmp(diffInTurn) == 0 {
+		if difficulty.Cmp(DiffInTurn) == 0 {
 			log.Info("find in turn account")
 			return coinbase
 		}
@@ -818,24 +917,12 @@ func (p *Dpos) Prepare(chain consensus.ChainHeaderReader, header *types.Header)
 	if totalVote.Cmp(common.Big0) > 0 {
 		parentHeader := chain.GetHeaderByHash(header.ParentHash)
 		if parentHeader != nil {
-			calRlp, err := rlp.EncodeToBytes([]interface{}{parentHeader.Root, header.ParentHash, parentHeader.Coinbase, parentHeader.Time})
+			provider, err := selectProvider(parentHeader, providerDetailData)
 			if err != nil {
 				return err
 			}
-
-			calHash := crypto.Keccak256(calRlp)
-			magicNumber := big.NewInt(0).SetBytes(calHash)
-			magicNumber.Mod(magicNumber, totalVote)
-			currentVote := big.NewInt(0)
-
-			for _, v := range providerDetailData {
-				currentVote.Add(currentVote, v.VotingPower)
-				if magicNumber.Cmp(currentVote) < 0 {
-					header.Provider = v.ProviderAddress
-					log.Info("Choose provider", "provider", v.ProviderAddress, "votepower", v.VotingPower, "currentVote", currentVote)
-					break
-				}
-			}
+			header.Provider = provider
+			log.Info("Choose provider", "provider", provider, "totalVote", totalVote)
 		} else {
 
 			header.Provider = common.Address{}
@@ -871,6 +958,10 @@ func (p *Dpos) Prepare(chain consensus.ChainHeaderReader, header *types.Header)
 		}
 	}
 
+	// add an empty fast finality payload, filled in when assembling the block
+	if hasFinalityExtra(p.chainConfig, header) {
+		header.Extra = append(header.Extra, make([]byte, extraFinalityLen)...)
+	}
 	// add extra seal space
 	header.Extra = append(header.Extra, make([]byte, extraSeal)...)
 
@@ -890,6 +981,65 @@ func (p *Dpos) Prepare(chain consensus.ChainHeaderReader, header *types.Header)
 	return nil
 }
 
+// PrepareSimulated implements consensus.BlockSimulator, setting the provider
+// and the distribution of the rewards, and filling the extra-data with the new
+// validators at epochs like Prepare does.
+func (p *Dpos) PrepareSimulated(sim consensus.SimulatedChainHeaderReader, header *types.Header) error {
+	var (
+		chain  = &simulatedChain{sim}
+		number = header.Number.Uint64()
+	)
+
+	providers, err := p.getProviderInfo(chain, header)
+	if err != nil {
+		log.Debug("getProviderInfo error", "error", err)
+	}
+	totalVote := big.NewInt(0)
+	for _, k := range providers {
+		totalVote.Add(totalVote, k.VotingPower)
+	}
+	header.Provider = common.Address{}
+	if totalVote.Cmp(common.Big0) > 0 {
+		if parent := chain.GetHeaderByHash(header.ParentHash); parent != nil {
+			if header.Provider, err = selectProvider(parent, providers); err != nil {
+				return err
+			}
+		}
+	}
+	header.TeamRate, header.ValidatorRate = p.getDistributeRate(chain, header)
+	header.TeamAddress, _ = p.getTeamAddress(chain, header)
+
+	header.Extra = make([]byte, extraVanity-nextForkHashSize)
+	nextForkHash := forkid.NextForkHash(p.chainConfig, p.genesisHash, number)
+	header.Extra = append(header.Extra, nextForkHash[:]...)
+
+	if number%p.config.Epoch == 0 {
+		newValidators, err := p.getTopValidators(chain, header)
+		if err != nil {
+			return err
+		}
+		for _, validator := range newValidators {
+			header.Extra = append(header.Extra, validator.Bytes()...)
+		}
+	}
+	if hasFinalityExtra(p.chainConfig, header) {
+		header.Extra = append(header.Extra, make([]byte, extraFinalityLen)...)
+	}
+	header.Extra = append(header.Extra, make([]byte, extraSeal)...)
+	header.MixDigest = common.Hash{}
+
+	return nil
+}
+
+// FinalizeSimulated implements consensus.BlockSimulator, finalizing the block
+// like Finalize does while executing the passed governance proposals in place
+// of the signed system transactions.
+func (p *Dpos) FinalizeSimulated(sim consensus.SimulatedChainHeaderReader, header *types.Header, state *state.StateDB, txs *[]*types.Transaction,
+	receipts *[]*types.Receipt, usedGas *uint64) error {
+	systemTxs := make([]*types.Transaction, 0)
+	return p.Finalize(&simulatedChain{sim}, header, state, txs, nil, receipts, &systemTxs, usedGas, true)
+}
+
 // Finalize implements consensus.Engine, ensuring no uncles are set, nor block
 // rewards given.
 func (p *Dpos) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs *[]*types.Transaction,
@@ -914,7 +1064,7 @@ func (p *Dpos) Finalize(chain consensus.ChainHeaderReader, header *types.Header,
 	//tmp_root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
 	//log.Error("finalize root hash3", "block", header.Number.String(), "hash", tmp_root.String())
 	/*
-		if header.Difficulty.Cmp(diffInTurn) != 0 {
+		if header.Difficulty.Cmp(DiffInTurn) != 0 {
 			if err := p.tryPunishValidator(chain, header, state); err != nil {
 				return err
 			}
@@ -959,21 +1109,17 @@ func (p *Dpos) Finalize(chain consensus.ChainHeaderReader, header *types.Header,
 			return err
 		}
 
-		validatorsBytes := make([]byte, len(newValidators)*common.AddressLength)
-
 		//newValidators, err := p.getCurrentValidators(header.ParentHash)
 		//if err != nil {
 		//	return err
 		//}
 		// sort validator by address
 		//sort.Sort(validatorsAscending(newValidators))
-		//validatorsBytes := make([]byte, len(newValidators)*validatorBytesLength)
-		for i, validator := range newValidators {
-			copy(validatorsBytes[i*validatorBytesLength:], validator.Bytes())
+		equal, err := equalValidatorBytes(p.chainConfig, header, newValidators)
+		if err != nil {
+			return err
 		}
-
-		extraSuffix := len(header.Extra) - extraSeal
-		if !bytes.Equal(header.Extra[extraVanity:extraSuffix], validatorsBytes) {
+		if !equal {
 			return errMismatchingEpochValidators
 		}
 	}
@@ -994,7 +1140,9 @@ func (p *Dpos) Finalize(chain consensus.ChainHeaderReader, header *types.Header,
 		if err != nil {
 			return err
 		}
-		if proposalCount != uint32(len(*systemTxs)) {
+		// Simulated blocks carry no signed system transactions, stand in for them
+		_, simulated := chain.(*simulatedChain)
+		if !simulated && proposalCount != uint32(len(*systemTxs)) {
 			return errInvalidSysGovCount
 		}
 		// Due to the logics of the finish operation of contract `governance`, when finishing a proposal which
@@ -1007,8 +1155,16 @@ func (p *Dpos) Finalize(chain consensus.ChainHeaderReader, header *types.Header,
 				return err
 			}
 			// execute the system governance Proposal
-			tx := (*systemTxs)[int(i)]
-			receipt, err := p.replayProposal(chain, header, state, prop, len(*txs), tx)
+			var (
+				tx      *types.Transaction
+				receipt *types.Receipt
+			)
+			if simulated {
+				tx, receipt, err = p.simulateProposal(chain, header, state, prop, len(*txs))
+			} else {
+				tx = (*systemTxs)[int(i)]
+				receipt, err = p.replayProposal(chain, header, state, prop, len(*txs), tx)
+			}
 			if err != nil {
 				return err
 			}
@@ -1049,23 +1205,11 @@ func (p *Dpos) Finalize(chain consensus.ChainHeaderReader, header *types.Header,
 			parentHeader := chain.GetHeaderByHash(header.ParentHash)
 
 			if parentHeader != nil {
-				calRlp, err := rlp.EncodeToBytes([]interface{}{parentHeader.Root, header.ParentHash, parentHeader.Coinbase, parentHeader.Time})
+				tmpProvider, err = selectProvider(parentHeader, providerLuckyData)
 				if err != nil {
 					return err
 				}
-
-				calHash := crypto.Keccak256(calRlp)
-				magicNumber := big.NewInt(0).SetBytes(calHash)
-				magicNumber.Mod(magicNumber, totalVote)
-				currentVote := big.NewInt(0)
-				for _, v := range providerLuckyData {
-					currentVote.Add(currentVote, v.VotingPower)
-					if magicNumber.Cmp(currentVote) < 0 {
-						tmpProvider.SetBytes(v.ProviderAddress.Bytes())
-						log.Debug("Check provider", "header Number", header.Number.String(), "provider", v.ProviderAddress, "votepower", v.VotingPower, "currentVote", currentVote)
-						break
-					}
-				}
+				log.Debug("Check provider", "header Number", header.Number.String(), "provider", tmpProvider)
 				if header.Provider.String() != tmpProvider.String() {
 					log.Error("invalid provider", "provider", header.Provider.String(), "expect provider", tmpProvider.String())
 					return errInvalidProvider
@@ -1089,7 +1233,7 @@ func (p *Dpos) Finalize(chain consensus.ChainHeaderReader, header *types.Header,
 		log.Info("skip state unused check!")
 	}
 
-	//if header.Difficulty.Cmp(diffInTurn) != 0 {
+	//if header.Difficulty.Cmp(DiffInTurn) != 0 {
 	//		spoiledVal := snap.supposeValidator()
 	//		signedRecently := false
 	//		for _, recent := range snap.Recents {
@@ -1251,6 +1395,10 @@ func (p *Dpos) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *ty
 		}
 	}
 
+	// Register the local vote key and attest the parent if the votes reached a quorum
+	if err := p.assembleFinalityExtra(chain, header); err != nil {
+		return nil, nil, err
+	}
 	// No block rewards in PoA, so the state remains as is and uncles are dropped
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
 	header.UncleHash = types.CalcUncleHash(nil)
@@ -1272,7 +1420,7 @@ func (p *Dpos) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *ty
 	//			log.Error("init contract failed")
 	//		}
 	//	}
-	//	if header.Difficulty.Cmp(diffInTurn) != 0 {
+	//	if header.Difficulty.Cmp(DiffInTurn) != 0 {
 	//		number := header.Number.Uint64()
 	//		snap, err := p.snapshot(chain, number-1, header.ParentHash, nil)
 	//		if err != nil {
@@ -1340,7 +1488,51 @@ func getBlockReward(blockNumber uint64) *big.Int {
 	return blockReward
 }
 
+// traceBalanceChange notifies the tracer, if any, of a balance change applied
+// by the engine.
+func traceBalanceChange(tracer consensus.BalanceTracer, kind consensus.BalanceChangeKind, from, to common.Address, value *big.Int) {
+	if tracer != nil && value.Sign() > 0 {
+		tracer.CaptureBalanceChange(kind, from, to, new(big.Int).Set(value))
+	}
+}
+
+// BlockRewards implements consensus.RewardReporter, replaying the block reward
+// distribution on an empty state. The fee sweeps and base fee shares depend on
+// the collected fees and are not reported.
+func (p *Dpos) BlockRewards(chain consensus.ChainHeaderReader, header *types.Header, uncles []*types.Header, tracer consensus.BalanceTracer) error {
+	if header.Number.Cmp(common.Big3) <= 0 {
+		return nil
+	}
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	if err != nil {
+		return err
+	}
+	return p.trySendBlockReward(consensus.WithBalanceTracer(chain, tracer), header, statedb)
+}
+
+// distributeBaseFee shares out the base fee collected after London between the
+// team and the validator contract, burning the remainder.
+func (p *Dpos) distributeBaseFee(tracer consensus.BalanceTracer, header *types.Header, state *state.StateDB) {
+	baseFee := state.GetBalance(consensus.BaseFeeAddress)
+	if baseFee.Sign() <= 0 {
+		return
+	}
+	teamPart := new(big.Int).Div(new(big.Int).Mul(baseFee, new(big.Int).SetUint64(p.config.BaseFeeTeamPercent)), big.NewInt(100))
+	validatorPart := new(big.Int).Div(new(big.Int).Mul(baseFee, new(big.Int).SetUint64(p.config.BaseFeeValidatorPercent)), big.NewInt(100))
+	burnt := new(big.Int).Sub(baseFee, teamPart)
+	burnt.Sub(burnt, validatorPart)
+
+	state.AddBalance(header.TeamAddress, teamPart)
+	traceBalanceChange(tracer, consensus.BalanceChangeBaseFee, consensus.BaseFeeAddress, header.TeamAddress, teamPart)
+	state.AddBalance(systemcontract.ValidatorFactoryContractAddr, validatorPart)
+	traceBalanceChange(tracer, consensus.BalanceChangeBaseFee, consensus.BaseFeeAddress, systemcontract.ValidatorFactoryContractAddr, validatorPart)
+	traceBalanceChange(tracer, consensus.BalanceChangeBurn, consensus.BaseFeeAddress, common.Address{}, burnt)
+
+	state.SetBalance(consensus.BaseFeeAddress, common.Big0)
+}
+
 func (p *Dpos) trySendBlockReward(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB) error {
+	tracer := consensus.BalanceTracerOf(chain)
 	fee := state.GetBalance(consensus.SystemAddress)
 	/*
 		if fee.Cmp(common.Big0) <= 0 {
@@ -1366,11 +1558,17 @@ func (p *Dpos) trySendBlockReward(chain consensus.ChainHeaderReader, header *typ
 				if (yestHeader.Provider != common.Address{}) {
 					state.AddBalance(yestHeader.Provider, lastReward)
 					state.AddLockBalance(yestHeader.Provider, lastReward)
+					traceBalanceChange(tracer, consensus.BalanceChangeReward, common.Address{}, yestHeader.Provider, lastReward)
+					traceBalanceChange(tracer, consensus.BalanceChangeLock, yestHeader.Provider, yestHeader.Provider, lastReward)
 				}
 				state.AddBalance(TeamAddress, teamPartReward)
 				state.AddLockBalance(TeamAddress, teamPartReward)
+				traceBalanceChange(tracer, consensus.BalanceChangeReward, common.Address{}, TeamAddress, teamPartReward)
+				traceBalanceChange(tracer, consensus.BalanceChangeLock, TeamAddress, TeamAddress, teamPartReward)
 				state.AddBalance(yestHeader.Coinbase, validatorPartReward)
 				state.AddLockBalance(yestHeader.Coinbase, validatorPartReward)
+				traceBalanceChange(tracer, consensus.BalanceChangeReward, common.Address{}, yestHeader.Coinbase, validatorPartReward)
+				traceBalanceChange(tracer, consensus.BalanceChangeLock, yestHeader.Coinbase, yestHeader.Coinbase, validatorPartReward)
 
 			}
 			//log.Info("distribute reward ", "teamPartReward", teamPartReward, "validatorPartReward", validatorPartReward, "lastReward", lastReward, "lastHeader.Provider", yestHeader.Provider, "lastHeader.Number", yestBlockNumber)
@@ -1392,24 +1590,33 @@ func (p *Dpos) trySendBlockReward(chain consensus.ChainHeaderReader, header *typ
 					if lastReward.Cmp(common.Big0) > 0 {
 						if (lastHeader.Provider != common.Address{}) {
 							state.AddBalance(lastHeader.Provider, lastReward)
+							traceBalanceChange(tracer, consensus.BalanceChangeReward, common.Address{}, lastHeader.Provider, lastReward)
 							if state.GetLockBalance(lastHeader.Provider).Cmp(lastReward) > 0 {
 								state.SubLockBalance(lastHeader.Provider, lastReward)
+								traceBalanceChange(tracer, consensus.BalanceChangeUnlock, lastHeader.Provider, lastHeader.Provider, lastReward)
 							} else {
+								traceBalanceChange(tracer, consensus.BalanceChangeUnlock, lastHeader.Provider, lastHeader.Provider, state.GetLockBalance(lastHeader.Provider))
 								state.SetLockBalance(lastHeader.Provider, common.Big0)
 							}
 
 						}
 						state.AddBalance(TeamAddress, teamPartReward)
+						traceBalanceChange(tracer, consensus.BalanceChangeReward, common.Address{}, TeamAddress, teamPartReward)
 						if state.GetLockBalance(TeamAddress).Cmp(teamPartReward) > 0 {
 							state.SubLockBalance(TeamAddress, teamPartReward)
+							traceBalanceChange(tracer, consensus.BalanceChangeUnlock, TeamAddress, TeamAddress, teamPartReward)
 						} else {
+							traceBalanceChange(tracer, consensus.BalanceChangeUnlock, TeamAddress, TeamAddress, state.GetLockBalance(TeamAddress))
 							state.SetLockBalance(TeamAddress, common.Big0)
 						}
 
 						state.AddBalance(lastHeader.Coinbase, validatorPartReward)
+						traceBalanceChange(tracer, consensus.BalanceChangeReward, common.Address{}, lastHeader.Coinbase, validatorPartReward)
 						if state.GetLockBalance(lastHeader.Coinbase).Cmp(validatorPartReward) > 0 {
 							state.SubLockBalance(lastHeader.Coinbase, validatorPartReward)
+							traceBalanceChange(tracer, consensus.BalanceChangeUnlock, lastHeader.Coinbase, lastHeader.Coinbase, validatorPartReward)
 						} else {
+							traceBalanceChange(tracer, consensus.BalanceChangeUnlock, lastHeader.Coinbase, lastHeader.Coinbase, state.GetLockBalance(lastHeader.Coinbase))
 							state.SetLockBalance(lastHeader.Coinbase, common.Big0)
 						}
 
@@ -1425,8 +1632,10 @@ func (p *Dpos) trySendBlockReward(chain consensus.ChainHeaderReader, header *typ
 	}
 
 	state.AddBalance(common.Address{}, fee)
+	traceBalanceChange(tracer, consensus.BalanceChangeFeeSweep, consensus.SystemAddress, common.Address{}, fee)
 	// reset fee
 	state.SetBalance(consensus.SystemAddress, common.Big0)
+	p.distributeBaseFee(tracer, header, state)
 
 	//method := "distributeBlockReward"
 	//data, err := p.abi[systemcontract.DposFactoryContractName].Pack(method)
@@ -1436,7 +1645,7 @@ func (p *Dpos) trySendBlockReward(chain consensus.ChainHeaderReader, header *typ
 	//}
 	//
 	//nonce := state.GetNonce(header.Coinbase)
-	//msg := types.NewMessage(header.Coinbase, systemcontract.GetValidatorAddr(header.Number, p.chainConfig), nonce, reward, math.MaxUint64, new(big.Int), data, nil, true)
+	//msg := types.NewMessage(header.Coinbase, systemcontract.GetValidatorAddr(header.Number, p.chainConfig), nonce, reward, math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, true)
 	//
 	//if _, err := vmcaller.ExecuteMsg(msg, state, header, newChainContext(chain, p), p.chainConfig); err != nil {
 	//	return err
@@ -1623,7 +1832,7 @@ func (p *Dpos) initializeSystemContracts(chain consensus.ChainHeaderReader, head
 			return err
 		}
 		nonce := state.GetNonce(header.Coinbase)
-		msg := types.NewMessage(header.Coinbase, &contract.addr, nonce, new(big.Int), math.MaxUint64, new(big.Int), data, nil, true)
+		msg := types.NewMessage(header.Coinbase, &contract.addr, nonce, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, true)
 		//fmt.Println("init",contract.addr,data)
 		if result, err := vmcaller.ExecuteMsg(msg, state, header, newChainContext(chain, p), p.chainConfig); err != nil {
 			return err
@@ -1642,7 +1851,7 @@ func (p *Dpos) getTopValidators(chain consensus.ChainHeaderReader, header *types
 	if parent == nil {
 		return []common.Address{}, consensus.ErrUnknownAncestor
 	}
-	statedb, err := p.stateFn(parent.Root)
+	statedb, err := p.stateAt(chain, parent)
 	if err != nil {
 		return []common.Address{}, err
 	}
@@ -1654,7 +1863,7 @@ func (p *Dpos) getTopValidators(chain consensus.ChainHeaderReader, header *types
 		return []common.Address{}, err
 	}
 
-	msg := types.NewMessage(header.Coinbase, systemcontract.GetValidatorAddr(parent.Number, p.chainConfig), 0, new(big.Int), math.MaxUint64, new(big.Int), data, nil, false)
+	msg := types.NewMessage(header.Coinbase, systemcontract.GetValidatorAddr(parent.Number, p.chainConfig), 0, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, false)
 
 	// use parent
 	result, err := vmcaller.ExecuteMsg(msg, statedb, parent, newChainContext(chain, p), p.chainConfig)
@@ -1685,7 +1894,7 @@ func (p *Dpos) getDistributeRate(chain consensus.ChainHeaderReader, header *type
 		return 400, 1000
 	}
 
-	statedb, err := p.stateFn(parent.Root)
+	statedb, err := p.stateAt(chain, parent)
 	if err != nil {
 		return 400, 1000
 	}
@@ -1697,7 +1906,7 @@ func (p *Dpos) getDistributeRate(chain consensus.ChainHeaderReader, header *type
 		return 400, 1000
 	}
 
-	msg := types.NewMessage(header.Coinbase, systemcontract.GetValidatorAddr(parent.Number, p.chainConfig), 0, new(big.Int), math.MaxUint64, new(big.Int), data, nil, false)
+	msg := types.NewMessage(header.Coinbase, systemcontract.GetValidatorAddr(parent.Number, p.chainConfig), 0, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, false)
 
 	// use parent
 	result, err := vmcaller.ExecuteMsg(msg, statedb, parent, newChainContext(chain, p), p.chainConfig)
@@ -1726,7 +1935,7 @@ func (p *Dpos) getDistributeRate(chain consensus.ChainHeaderReader, header *type
 		return 400, 1000
 	}
 
-	msg = types.NewMessage(header.Coinbase, systemcontract.GetValidatorAddr(parent.Number, p.chainConfig), 0, new(big.Int), math.MaxUint64, new(big.Int), data, nil, false)
+	msg = types.NewMessage(header.Coinbase, systemcontract.GetValidatorAddr(parent.Number, p.chainConfig), 0, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, false)
 
 	// use parent
 	result, err = vmcaller.ExecuteMsg(msg, statedb, parent, newChainContext(chain, p), p.chainConfig)
@@ -1758,7 +1967,7 @@ func (p *Dpos) getTeamAddress(chain consensus.ChainHeaderReader, header *types.H
 		return common.Address{}, consensus.ErrUnknownAncestor
 	}
 
-	statedb, err := p.stateFn(parent.Root)
+	statedb, err := p.stateAt(chain, parent)
 	if err != nil {
 		return common.Address{}, err
 	}
@@ -1770,7 +1979,7 @@ func (p *Dpos) getTeamAddress(chain consensus.ChainHeaderReader, header *types.H
 		return common.Address{}, err
 	}
 
-	msg := types.NewMessage(header.Coinbase, systemcontract.GetValidatorAddr(parent.Number, p.chainConfig), 0, new(big.Int), math.MaxUint64, new(big.Int), data, nil, false)
+	msg := types.NewMessage(header.Coinbase, systemcontract.GetValidatorAddr(parent.Number, p.chainConfig), 0, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, false)
 
 	// use parent
 	result, err := vmcaller.ExecuteMsg(msg, statedb, parent, newChainContext(chain, p), p.chainConfig)
@@ -1800,11 +2009,36 @@ func (p *Dpos) getProviderInfo(chain consensus.ChainHeaderReader, header *types.
 	if parent == nil {
 		return []VoteInfo{}, consensus.ErrUnknownAncestor
 	}
-	statedb, err := p.stateFn(parent.Root)
+	statedb, err := p.stateAt(chain, parent)
 	if err != nil {
 		return []VoteInfo{}, err
 	}
+	return p.getProviderInfoAt(chain, parent, statedb, header.Coinbase)
+}
 
+// Providers retrieves the running providers together with their voting power
+// as of the state of the given header.
+func (p *Dpos) Providers(chain consensus.ChainHeaderReader, header *types.Header) ([]VoteInfo, error) {
+	statedb, err := p.stateAt(chain, header)
+	if err != nil {
+		return nil, err
+	}
+	return p.getProviderInfoAt(chain, header, statedb, header.Coinbase)
+}
+
+// Validators retrieves the validator set recorded in the snapshot of the given
+// header.
+func (p *Dpos) Validators(chain consensus.ChainHeaderReader, header *types.Header) ([]common.Address, error) {
+	snap, err := p.snapshot(chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return snap.validators(), nil
+}
+
+// getProviderInfoAt retrieves the running providers from the provider factory
+// contract, executing the call on top of the given parent state.
+func (p *Dpos) getProviderInfoAt(chain consensus.ChainHeaderReader, parent *types.Header, statedb *state.StateDB, coinbase common.Address) (rets []VoteInfo, err error) {
 	method := "getProviderInfo"
 	data, err := p.abi[systemcontract.ProviderFactoryContractName].Pack(method, big.NewInt(0), big.NewInt(0))
 	if err != nil {
@@ -1812,7 +2046,7 @@ func (p *Dpos) getProviderInfo(chain consensus.ChainHeaderReader, header *types.
 		return []VoteInfo{}, err
 	}
 
-	msg := types.NewMessage(header.Coinbase, &(systemcontract.ProviderFactoryContractAddr), 0, new(big.Int), math.MaxUint64, new(big.Int), data, nil, false)
+	msg := types.NewMessage(coinbase, &(systemcontract.ProviderFactoryContractAddr), 0, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, false)
 
 	// use parent
 	result, err := vmcaller.ExecuteMsg(msg, statedb, parent, newChainContext(chain, p), p.chainConfig)
@@ -1846,7 +2080,7 @@ func (p *Dpos) getProviderInfo(chain consensus.ChainHeaderReader, header *types.
 	//if !ok {
 	//	return []VoteInfo{}, errors.New("Invalid provider format")
 	//}
-	rets := make([]VoteInfo, 0, 0)
+	rets = make([]VoteInfo, 0, 0)
 
 	for _, oneProvider := range providers {
 		if oneProvider.Info.State != Running {
@@ -1889,7 +2123,7 @@ func (p *Dpos) getCanChallengeProvider(chain consensus.ChainHeaderReader, header
 	if parent == nil {
 		return nil, ok, consensus.ErrUnknownAncestor
 	}
-	statedb, err := p.stateFn(parent.Root)
+	statedb, err := p.stateAt(chain, parent)
 	if err != nil {
 		return nil, ok, err
 	}
@@ -1901,7 +2135,7 @@ func (p *Dpos) getCanChallengeProvider(chain consensus.ChainHeaderReader, header
 		return nil, ok, err
 	}
 
-	msg := types.NewMessage(header.Coinbase, &(systemcontract.ProviderFactoryContractAddr), 0, new(big.Int), math.MaxUint64, new(big.Int), data, nil, false)
+	msg := types.NewMessage(header.Coinbase, &(systemcontract.ProviderFactoryContractAddr), 0, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, false)
 
 	// use parent
 	result, err := vmcaller.ExecuteMsg(msg, statedb, parent, newChainContext(chain, p), p.chainConfig)
@@ -1976,7 +2210,7 @@ func (p *Dpos) getMaxChallengeTime(chain consensus.ChainHeaderReader, header *ty
 	if parent == nil {
 		return big.NewInt(720)
 	}
-	statedb, err := p.stateFn(parent.Root)
+	statedb, err := p.stateAt(chain, parent)
 	if err != nil {
 		return big.NewInt(720)
 	}
@@ -1987,7 +2221,7 @@ func (p *Dpos) getMaxChallengeTime(chain consensus.ChainHeaderReader, header *ty
 		return big.NewInt(720)
 	}
 
-	msg := types.NewMessage(header.Coinbase, &(systemcontract.ValidatorFactoryContractAddr), 0, new(big.Int), math.MaxUint64, new(big.Int), data, nil, false)
+	msg := types.NewMessage(header.Coinbase, &(systemcontract.ValidatorFactoryContractAddr), 0, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, false)
 
 	// use parent
 	result, err := vmcaller.ExecuteMsg(msg, statedb, parent, newChainContext(chain, p), p.chainConfig)
@@ -2009,7 +2243,7 @@ func (p *Dpos) whetherCanPor(chain consensus.ChainHeaderReader, header *types.He
 	if parent == nil {
 		return 0
 	}
-	statedb, err := p.stateFn(parent.Root)
+	statedb, err := p.stateAt(chain, parent)
 	if err != nil {
 		return 0
 	}
@@ -2020,7 +2254,7 @@ func (p *Dpos) whetherCanPor(chain consensus.ChainHeaderReader, header *types.He
 		return 0
 	}
 
-	msg := types.NewMessage(header.Coinbase, &(systemcontract.ValidatorFactoryContractAddr), 0, new(big.Int), math.MaxUint64, new(big.Int), data, nil, false)
+	msg := types.NewMessage(header.Coinbase, &(systemcontract.ValidatorFactoryContractAddr), 0, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, false)
 
 	// use parent
 	result, err := vmcaller.ExecuteMsg(msg, statedb, parent, newChainContext(chain, p), p.chainConfig)
@@ -2076,7 +2310,7 @@ func (p *Dpos) whetherCanPor(chain consensus.ChainHeaderReader, header *types.He
 
 		// call contract
 		nonce := state.GetNonce(header.Coinbase)
-		msg := types.NewMessage(header.Coinbase, systemcontract.GetValidatorAddr(header.Number, p.chainConfig), nonce, new(big.Int), math.MaxUint64, new(big.Int), data, nil, true)
+		msg := types.NewMessage(header.Coinbase, systemcontract.GetValidatorAddr(header.Number, p.chainConfig), nonce, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, true)
 		if _, err := vmcaller.ExecuteMsg(msg, state, header, newChainContext(chain, p), p.chainConfig); err != nil {
 			log.Error("Can't update validators to contract", "err", err)
 			return err
@@ -2096,7 +2330,7 @@ func (p *Dpos) punishValidator(val common.Address, chain consensus.ChainHeaderRe
 
 	// call contract
 	nonce := state.GetNonce(header.Coinbase)
-	msg := types.NewMessage(header.Coinbase, systemcontract.GetPunishAddr(header.Number, p.chainConfig), nonce, new(big.Int), math.MaxUint64, new(big.Int), data, nil, true)
+	msg := types.NewMessage(header.Coinbase, systemcontract.GetPunishAddr(header.Number, p.chainConfig), nonce, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, true)
 	if _, err := vmcaller.ExecuteMsg(msg, state, header, newChainContext(chain, p), p.chainConfig); err != nil {
 		log.Error("Can't punish validator", "err", err)
 		return err
@@ -2114,7 +2348,7 @@ func (p *Dpos) punishProvider(chain consensus.ChainHeaderReader, header *types.H
 	}
 	// call contract
 	nonce := state.GetNonce(header.Coinbase)
-	msg := types.NewMessage(header.Coinbase, &systemcontract.ProviderFactoryContractAddr, nonce, new(big.Int), math.MaxUint64, new(big.Int), data, nil, true)
+	msg := types.NewMessage(header.Coinbase, &systemcontract.ProviderFactoryContractAddr, nonce, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, true)
 	if _, err := vmcaller.ExecuteMsg(msg, state, header, newChainContext(chain, p), p.chainConfig); err != nil {
 		log.Error("Can't punish provider", "err", err)
 		return err
@@ -2134,7 +2368,7 @@ func (p *Dpos) chooseChallengeProvider(provider common.Address, seed *big.Int, c
 
 	// call contract
 	nonce := state.GetNonce(header.Coinbase)
-	msg := types.NewMessage(header.Coinbase, &systemcontract.ValidatorFactoryContractAddr, nonce, new(big.Int), math.MaxUint64, new(big.Int), data, nil, true)
+	msg := types.NewMessage(header.Coinbase, &systemcontract.ValidatorFactoryContractAddr, nonce, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, true)
 	if _, err := vmcaller.ExecuteMsg(msg, state, header, newChainContext(chain, p), p.chainConfig); err != nil {
 		log.Error("Can't execute por challenge", "err", err)
 		return err
@@ -2155,7 +2389,7 @@ func (p *Dpos) decreaseMissedBlocksCounter(chain consensus.ChainHeaderReader, he
 
 	// call contract
 	nonce := state.GetNonce(header.Coinbase)
-	msg := types.NewMessage(header.Coinbase, systemcontract.GetPunishAddr(header.Number, p.chainConfig), nonce, new(big.Int), math.MaxUint64, new(big.Int), data, nil, true)
+	msg := types.NewMessage(header.Coinbase, systemcontract.GetPunishAddr(header.Number, p.chainConfig), nonce, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, true)
 	if _, err := vmcaller.ExecuteMsg(msg, state, header, newChainContext(chain, p), p.chainConfig); err != nil {
 		log.Error("Can't decrease missed blocks counter for validator", "err", err)
 		return err
@@ -2194,6 +2428,37 @@ func (p *Dpos) Authorize(val common.Address, signFn SignerFn, signTxFn SignerTxF
 	return true
 }
 
+// SetVoteKey injects the BLS key the given validator signs fast finality votes
+// with. The key gets registered on chain with the next block sealed by it.
+func (p *Dpos) SetVoteKey(val common.Address, key *bls12381.SecretKey) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.voteKeys[val] = key
+}
+
+// SetVotePool injects the pool the votes are aggregated from when sealing.
+func (p *Dpos) SetVotePool(pool *VotePool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.votePool = pool
+}
+
+// LocalValidators returns the validators the engine holds signing keys of,
+// sorted by address.
+func (p *Dpos) LocalValidators() []common.Address {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	vals := make([]common.Address, 0, len(p.signFns))
+	for val := range p.signFns {
+		vals = append(vals, val)
+	}
+	sort.Sort(validatorsAscending(vals))
+	return vals
+}
+
 func (p *Dpos) SignSeed(header *types.Header, seed uint64) ([]byte, error) {
 
 	return p.signFns[header.Coinbase](accounts.Account{Address: header.Coinbase}, accounts.MimetypeDpos, []byte(strconv.FormatUint(seed, 16)))
@@ -2340,7 +2605,7 @@ func (p *Dpos) CalcDifficulty(chain consensus.ChainHeaderReader, time uint64, pa
 // current signer.
 func CalcDifficulty(snap *Snapshot, signer common.Address) *big.Int {
 	if snap.inturn(signer) {
-		return new(big.Int).Set(diffInTurn)
+		return new(big.Int).Set(DiffInTurn)
 	}
 	return new(big.Int).Set(diffNoTurn)
 }
@@ -2362,6 +2627,12 @@ func (p *Dpos) APIs(chain consensus.ChainHeaderReader) []rpc.API {
 
 // Close implements consensus.Engine. It's a noop for dpos as there are no background threads.
 func (p *Dpos) Close() error {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if p.livenessSub != nil {
+		p.livenessSub.Unsubscribe()
+	}
 	return nil
 }
 
@@ -2375,7 +2646,9 @@ func (p *Dpos) PreHandle(chain consensus.ChainHeaderReader, header *types.Header
 		p.chainConfig.ChainID = big.NewInt(100222)
 		p.signer = types.NewEIP155Signer(p.chainConfig.ChainID)
 	}
-	if p.chainConfig.IsBerlin(header.Number) {
+	if p.chainConfig.IsLondon(header.Number) {
+		p.signer = types.NewLondonSigner(p.chainConfig.ChainID)
+	} else if p.chainConfig.IsBerlin(header.Number) {
 		p.signer = types.NewEIP2930Signer(p.chainConfig.ChainID)
 	}
 	return nil
@@ -2491,7 +2764,7 @@ func (p *Dpos) getBlacklist(header *types.Header, parentState *state.StateDB) (m
 			return []common.Address{}, err
 		}
 
-		msg := types.NewMessage(header.Coinbase, &systemcontract.AddressListContractAddr, 0, new(big.Int), math.MaxUint64, new(big.Int), data, nil, false)
+		msg := types.NewMessage(header.Coinbase, &systemcontract.AddressListContractAddr, 0, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, false)
 
 		// Note: It's safe to use minimalChainContext for executing AddressListContract
 		result, err := vmcaller.ExecuteMsg(msg, parentState, header, newMinimalChainContext(p), p.chainConfig)
@@ -2809,7 +3082,7 @@ func SealHash(header *types.Header, chainId *big.Int) (hash common.Hash) {
 }
 
 func encodeSigHeader(w io.Writer, header *types.Header, chainId *big.Int) {
-	err := rlp.Encode(w, []interface{}{
+	enc := []interface{}{
 		chainId,
 		header.ParentHash,
 		header.UncleHash,
@@ -2826,8 +3099,11 @@ func encodeSigHeader(w io.Writer, header *types.Header, chainId *big.Int) {
 		header.Extra[:len(header.Extra)-65], // this will panic if extra is too short, should check before calling encodeSigHeader
 		header.MixDigest,
 		header.Nonce,
-	})
-	if err != nil {
+	}
+	if header.BaseFee != nil {
+		enc = append(enc, header.BaseFee)
+	}
+	if err := rlp.Encode(w, enc); err != nil {
 		panic("can't encode: " + err.Error())
 	}
 }
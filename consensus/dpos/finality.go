@@ -0,0 +1,332 @@
+package dpos
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"PureChain/common"
+	"PureChain/consensus"
+	"PureChain/core/types"
+	"PureChain/crypto/bls12381"
+	"PureChain/log"
+	"PureChain/params"
+	"PureChain/rlp"
+)
+
+const (
+	extraFinalityLen = 2 // Fixed number of extra-data bytes holding the length of the finality payload
+)
+
+var (
+	// errInvalidFinalityExtra is returned if the fast finality payload of the
+	// extra-data cannot be located or decoded.
+	errInvalidFinalityExtra = errors.New("invalid finality payload in extra-data")
+
+	// errInvalidVoteKey is returned if a vote key registration carries an invalid
+	// proof of possession or a key already used by another validator.
+	errInvalidVoteKey = errors.New("invalid vote key registration")
+
+	// errInvalidAttestation is returned if a vote attestation does not justify
+	// the parent block from the latest justified block with a valid quorum.
+	errInvalidAttestation = errors.New("invalid vote attestation")
+
+	// errTooManyAttesters is returned if an attestation is carried while the
+	// validator set is too large to be marked in the vote address bit set.
+	errTooManyAttesters = errors.New("validator set too large for vote attestations")
+)
+
+// finalityExtra is the fast finality payload carried in the header extra-data
+// after the FastFinality fork, between the validator list and the seal:
+//
+//	vanity | validators (epoch only) | rlp(finalityExtra) | payload length | seal
+type finalityExtra struct {
+	Registration *types.VoteKeyRegistration `rlp:"nil"`
+	Attestation  *types.VoteAttestation     `rlp:"nil"`
+}
+
+// hasFinalityExtra reports whether the header extra-data carries the fast
+// finality payload.
+func hasFinalityExtra(config *params.ChainConfig, header *types.Header) bool {
+	return header.Number.Sign() > 0 && config.IsFastFinality(header.Number)
+}
+
+// splitExtra returns the validator list and the encoded fast finality payload
+// of the header extra-data.
+func splitExtra(config *params.ChainConfig, header *types.Header) ([]byte, []byte, error) {
+	if len(header.Extra) < extraVanity+extraSeal {
+		return nil, nil, errMissingSignature
+	}
+	end := len(header.Extra) - extraSeal
+	if !hasFinalityExtra(config, header) {
+		return header.Extra[extraVanity:end], nil, nil
+	}
+	if end-extraVanity < extraFinalityLen {
+		return nil, nil, errInvalidFinalityExtra
+	}
+	size := int(binary.BigEndian.Uint16(header.Extra[end-extraFinalityLen : end]))
+	if end-extraVanity-extraFinalityLen < size {
+		return nil, nil, errInvalidFinalityExtra
+	}
+	start := end - extraFinalityLen - size
+	return header.Extra[extraVanity:start], header.Extra[start : end-extraFinalityLen], nil
+}
+
+// validatorBytes returns the validator list of the header extra-data.
+func validatorBytes(config *params.ChainConfig, header *types.Header) ([]byte, error) {
+	validators, _, err := splitExtra(config, header)
+	return validators, err
+}
+
+// parseFinalityExtra decodes the fast finality payload of the header, returning
+// an empty payload for headers before the fork.
+func parseFinalityExtra(config *params.ChainConfig, header *types.Header) (*finalityExtra, error) {
+	_, payload, err := splitExtra(config, header)
+	if err != nil {
+		return nil, err
+	}
+	extra := new(finalityExtra)
+	if len(payload) == 0 {
+		return extra, nil
+	}
+	if err := rlp.DecodeBytes(payload, extra); err != nil {
+		return nil, errInvalidFinalityExtra
+	}
+	return extra, nil
+}
+
+// setFinalityExtra replaces the fast finality payload of the header extra-data,
+// leaving the vanity, validator list and seal untouched.
+func setFinalityExtra(config *params.ChainConfig, header *types.Header, extra *finalityExtra) error {
+	validators, _, err := splitExtra(config, header)
+	if err != nil {
+		return err
+	}
+	var payload []byte
+	if extra.Registration != nil || extra.Attestation != nil {
+		if payload, err = rlp.EncodeToBytes(extra); err != nil {
+			return err
+		}
+	}
+	if len(payload) > math.MaxUint16 {
+		return errInvalidFinalityExtra
+	}
+	size := make([]byte, extraFinalityLen)
+	binary.BigEndian.PutUint16(size, uint16(len(payload)))
+
+	buf := make([]byte, 0, extraVanity+len(validators)+len(payload)+extraFinalityLen+extraSeal)
+	buf = append(buf, header.Extra[:extraVanity]...)
+	buf = append(buf, validators...)
+	buf = append(buf, payload...)
+	buf = append(buf, size...)
+	buf = append(buf, header.Extra[len(header.Extra)-extraSeal:]...)
+	header.Extra = buf
+	return nil
+}
+
+// justified returns the latest justified block of the snapshot, which is the
+// genesis block until the first attestation.
+func (p *Dpos) justified(snap *Snapshot) (uint64, common.Hash) {
+	if snap.Attestation != nil {
+		return snap.Attestation.TargetNumber, snap.Attestation.TargetHash
+	}
+	return 0, p.genesisHash
+}
+
+// verifyFinalityExtra checks the fast finality payload of the header against
+// the snapshot of its parent.
+func (p *Dpos) verifyFinalityExtra(header, parent *types.Header, snap *Snapshot) error {
+	extra, err := parseFinalityExtra(p.chainConfig, header)
+	if err != nil {
+		return err
+	}
+	if reg := extra.Registration; reg != nil {
+		for val, key := range snap.VoteKeys {
+			if key == reg.VoteAddress && val != header.Coinbase {
+				return errInvalidVoteKey
+			}
+		}
+		if err := reg.Verify(header.Coinbase); err != nil {
+			return errInvalidVoteKey
+		}
+	}
+	if extra.Attestation != nil {
+		return p.verifyAttestation(extra.Attestation, parent, snap)
+	}
+	return nil
+}
+
+// verifyAttestation checks that the attestation justifies parent from the latest
+// justified block of its snapshot, signed by more than 2/3 of the validators.
+func (p *Dpos) verifyAttestation(att *types.VoteAttestation, parent *types.Header, snap *Snapshot) error {
+	data := att.Data
+	if data == nil || data.TargetNumber != parent.Number.Uint64() || data.TargetHash != parent.Hash() {
+		return errInvalidAttestation
+	}
+	if number, hash := p.justified(snap); data.SourceNumber != number || data.SourceHash != hash {
+		return errInvalidAttestation
+	}
+	validators := snap.validators()
+	if len(validators) > types.MaxAttestedValidators {
+		return errTooManyAttesters
+	}
+	if len(validators) < types.MaxAttestedValidators && uint64(att.VoteAddressSet)>>uint(len(validators)) != 0 {
+		return errInvalidAttestation
+	}
+	if att.VoteAddressSet.Count()*3 <= len(validators)*2 {
+		return errInvalidAttestation
+	}
+	keys := make([][]byte, 0, att.VoteAddressSet.Count())
+	for i, val := range validators {
+		if !att.VoteAddressSet.Has(i) {
+			continue
+		}
+		key, ok := snap.VoteKeys[val]
+		if !ok {
+			return errInvalidAttestation
+		}
+		keys = append(keys, common.CopyBytes(key[:]))
+	}
+	hash := data.Hash()
+	if !bls12381.FastAggregateVerify(keys, hash[:], att.AggSignature[:]) {
+		return errInvalidAttestation
+	}
+	return nil
+}
+
+// assembleFinalityExtra fills the fast finality payload of a locally produced
+// header: the registration of the sealer's vote key if not known yet, and the
+// aggregation of the votes for the parent block if they reach a quorum.
+func (p *Dpos) assembleFinalityExtra(chain consensus.ChainHeaderReader, header *types.Header) error {
+	if !hasFinalityExtra(p.chainConfig, header) {
+		return nil
+	}
+	number := header.Number.Uint64()
+	snap, err := p.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	extra := new(finalityExtra)
+
+	p.lock.RLock()
+	key := p.voteKeys[header.Coinbase]
+	pool := p.votePool
+	p.lock.RUnlock()
+
+	if key != nil {
+		var pub types.BLSPublicKey
+		copy(pub[:], key.PublicKey())
+		if registered, ok := snap.VoteKeys[header.Coinbase]; !ok || registered != pub {
+			reg := &types.VoteKeyRegistration{VoteAddress: pub}
+			proof := types.VoteKeyProofHash(header.Coinbase, pub)
+			copy(reg.Proof[:], key.Sign(proof[:]))
+			extra.Registration = reg
+		}
+	}
+	if pool != nil {
+		extra.Attestation = p.aggregateVotes(snap, header.ParentHash, pool.FetchVotes(header.ParentHash))
+	}
+	return setFinalityExtra(p.chainConfig, header, extra)
+}
+
+// aggregateVotes builds an attestation out of the votes for the block hash if
+// the votes consistent with the snapshot reach a quorum.
+func (p *Dpos) aggregateVotes(snap *Snapshot, hash common.Hash, votes []*types.VoteEnvelope) *types.VoteAttestation {
+	number, justified := p.justified(snap)
+	validators := snap.validators()
+	if len(validators) > types.MaxAttestedValidators {
+		log.Warn("Validator set too large for vote attestations", "number", snap.Number, "validators", len(validators), "max", types.MaxAttestedValidators)
+		return nil
+	}
+
+	att := &types.VoteAttestation{Data: &types.VoteData{
+		SourceNumber: number,
+		SourceHash:   justified,
+		TargetNumber: snap.Number,
+		TargetHash:   hash,
+	}}
+	var sigs [][]byte
+	for _, vote := range votes {
+		if vote.Data == nil || *vote.Data != *att.Data {
+			continue
+		}
+		for i, val := range validators {
+			if key, ok := snap.VoteKeys[val]; ok && key == vote.VoteAddress && !att.VoteAddressSet.Has(i) {
+				att.VoteAddressSet.Set(i)
+				sigs = append(sigs, common.CopyBytes(vote.Signature[:]))
+				break
+			}
+		}
+	}
+	if len(sigs)*3 <= len(validators)*2 {
+		return nil
+	}
+	sig, err := bls12381.AggregateSignatures(sigs)
+	if err != nil {
+		log.Warn("Failed to aggregate votes", "number", snap.Number, "hash", hash, "err", err)
+		return nil
+	}
+	copy(att.AggSignature[:], sig)
+	return att
+}
+
+// GetJustifiedNumberAndHash returns the latest justified block as of the given
+// header.
+func (p *Dpos) GetJustifiedNumberAndHash(chain consensus.ChainHeaderReader, header *types.Header) (uint64, common.Hash, error) {
+	snap, err := p.snapshot(chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return 0, common.Hash{}, err
+	}
+	number, hash := p.justified(snap)
+	return number, hash, nil
+}
+
+// GetFinalizedHeader returns the latest finalized header as of the given header,
+// which is the genesis block until a justified block gets a justified child.
+func (p *Dpos) GetFinalizedHeader(chain consensus.ChainHeaderReader, header *types.Header) *types.Header {
+	snap, err := p.snapshot(chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil
+	}
+	if snap.FinalizedHash == (common.Hash{}) {
+		return chain.GetHeaderByNumber(0)
+	}
+	return chain.GetHeader(snap.FinalizedHash, snap.FinalizedNumber)
+}
+
+// applyFinalityExtra updates the vote keys and the justified and finalized
+// blocks of the snapshot with the fast finality payload of a header sealed by
+// validator.
+func (s *Snapshot) applyFinalityExtra(config *params.ChainConfig, header *types.Header, validator common.Address) error {
+	extra, err := parseFinalityExtra(config, header)
+	if err != nil {
+		return err
+	}
+	if extra.Registration != nil {
+		s.VoteKeys[validator] = extra.Registration.VoteAddress
+	}
+	if att := extra.Attestation; att != nil && att.Data != nil {
+		data := *att.Data
+		s.Attestation = &data
+		// A justified block with a justified direct child is finalized
+		if data.TargetNumber == data.SourceNumber+1 {
+			s.FinalizedNumber, s.FinalizedHash = data.SourceNumber, data.SourceHash
+		}
+	}
+	return nil
+}
+
+// equalValidatorBytes reports whether the validator list of the header matches
+// the given validators.
+func equalValidatorBytes(config *params.ChainConfig, header *types.Header, validators []common.Address) (bool, error) {
+	have, err := validatorBytes(config, header)
+	if err != nil {
+		return false, err
+	}
+	want := make([]byte, len(validators)*validatorBytesLength)
+	for i, validator := range validators {
+		copy(want[i*validatorBytesLength:], validator.Bytes())
+	}
+	return bytes.Equal(have, want), nil
+}
@@ -0,0 +1,118 @@
+package dpos
+
+import (
+	"crypto/rand"
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"PureChain/common"
+	"PureChain/core/types"
+	"PureChain/crypto/bls12381"
+	"PureChain/params"
+)
+
+func TestFinalityExtraRoundTrip(t *testing.T) {
+	config := &params.ChainConfig{FastFinalityBlock: big.NewInt(0)}
+	validators := []common.Address{randomAddress(), randomAddress()}
+
+	extra := make([]byte, extraVanity)
+	for _, val := range validators {
+		extra = append(extra, val.Bytes()...)
+	}
+	extra = append(extra, make([]byte, extraFinalityLen+extraSeal)...)
+	header := &types.Header{Number: big.NewInt(200), Extra: extra}
+
+	// A freshly prepared header carries an empty payload
+	payload, err := parseFinalityExtra(config, header)
+	assert.NoError(t, err)
+	assert.Nil(t, payload.Registration)
+	assert.Nil(t, payload.Attestation)
+
+	data := &types.VoteData{SourceNumber: 1, SourceHash: common.Hash{0x1}, TargetNumber: 2, TargetHash: common.Hash{0x2}}
+	assert.NoError(t, setFinalityExtra(config, header, &finalityExtra{Attestation: &types.VoteAttestation{VoteAddressSet: 3, Data: data}}))
+
+	payload, err = parseFinalityExtra(config, header)
+	assert.NoError(t, err)
+	assert.Equal(t, *data, *payload.Attestation.Data)
+	assert.Equal(t, types.ValidatorsBitSet(3), payload.Attestation.VoteAddressSet)
+
+	equal, err := equalValidatorBytes(config, header, validators)
+	assert.NoError(t, err)
+	assert.True(t, equal)
+
+	// Before the fork the whole extra-data between vanity and seal are validators
+	legacy := &params.ChainConfig{}
+	parsed, err := validatorBytes(legacy, &types.Header{Number: big.NewInt(200), Extra: extra})
+	assert.NoError(t, err)
+	assert.Len(t, parsed, 2*common.AddressLength+extraFinalityLen)
+}
+
+func TestVerifyAttestation(t *testing.T) {
+	var (
+		validators = make([]common.Address, 4)
+		keys       = make(map[common.Address]*bls12381.SecretKey)
+	)
+	for i := range validators {
+		validators[i] = randomAddress()
+	}
+	sort.Sort(validatorsAscending(validators))
+
+	snap := newSnapshot(&params.DposConfig{Epoch: 200}, nil, nil, 10, common.Hash{0xa}, validators, nil)
+	for _, val := range validators {
+		key, err := bls12381.GenerateKey(rand.Reader)
+		assert.NoError(t, err)
+		keys[val] = key
+
+		var pub types.BLSPublicKey
+		copy(pub[:], key.PublicKey())
+		snap.VoteKeys[val] = pub
+	}
+	genesis := common.Hash{0xee}
+	engine := &Dpos{chainConfig: &params.ChainConfig{FastFinalityBlock: big.NewInt(0)}, genesisHash: genesis}
+	parent := &types.Header{Number: big.NewInt(10), Extra: []byte("parent")}
+
+	sign := func(data *types.VoteData, signers int) []*types.VoteEnvelope {
+		var votes []*types.VoteEnvelope
+		hash := data.Hash()
+		for _, val := range validators[:signers] {
+			vote := &types.VoteEnvelope{VoteAddress: snap.VoteKeys[val], Data: data}
+			copy(vote.Signature[:], keys[val].Sign(hash[:]))
+			votes = append(votes, vote)
+		}
+		return votes
+	}
+	data := &types.VoteData{SourceNumber: 0, SourceHash: genesis, TargetNumber: 10, TargetHash: parent.Hash()}
+
+	// Two votes out of four validators are no quorum
+	assert.Nil(t, engine.aggregateVotes(snap, parent.Hash(), sign(data, 2)))
+
+	// Three votes out of four justify the parent
+	att := engine.aggregateVotes(snap, parent.Hash(), sign(data, 3))
+	assert.NotNil(t, att)
+	assert.Equal(t, 3, att.VoteAddressSet.Count())
+	assert.NoError(t, engine.verifyAttestation(att, parent, snap))
+
+	// Tampering with the signer set or the source breaks the attestation
+	bad := *att
+	bad.VoteAddressSet = 0xf
+	assert.Equal(t, errInvalidAttestation, engine.verifyAttestation(&bad, parent, snap))
+
+	bad = *att
+	bad.Data = &types.VoteData{SourceNumber: 1, SourceHash: genesis, TargetNumber: 10, TargetHash: parent.Hash()}
+	assert.Equal(t, errInvalidAttestation, engine.verifyAttestation(&bad, parent, snap))
+
+	// Applying a direct child attestation finalizes the source
+	header := &types.Header{Number: big.NewInt(11), Extra: make([]byte, extraVanity+extraFinalityLen+extraSeal)}
+	assert.NoError(t, setFinalityExtra(engine.chainConfig, header, &finalityExtra{Attestation: att}))
+	assert.NoError(t, snap.applyFinalityExtra(engine.chainConfig, header, validators[0]))
+	assert.Equal(t, *data, *snap.Attestation)
+
+	next := &types.VoteData{SourceNumber: 10, SourceHash: parent.Hash(), TargetNumber: 11, TargetHash: header.Hash()}
+	assert.NoError(t, setFinalityExtra(engine.chainConfig, header, &finalityExtra{Attestation: &types.VoteAttestation{Data: next}}))
+	assert.NoError(t, snap.applyFinalityExtra(engine.chainConfig, header, validators[0]))
+	assert.Equal(t, uint64(10), snap.FinalizedNumber)
+	assert.Equal(t, parent.Hash(), snap.FinalizedHash)
+}
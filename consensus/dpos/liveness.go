@@ -0,0 +1,308 @@
+package dpos
+
+import (
+	"fmt"
+	"sync"
+
+	"PureChain/common"
+	"PureChain/consensus"
+	"PureChain/core"
+	"PureChain/core/types"
+	"PureChain/metrics"
+)
+
+const (
+	livenessHistory       = 8192 // Number of recent blocks whose sealing outcome is kept in memory
+	defaultLivenessWindow = 64   // Number of blocks reported when no window is requested
+)
+
+var (
+	inturnSealCounter  = metrics.NewRegisteredCounter("dpos/liveness/inturn", nil)
+	outturnSealCounter = metrics.NewRegisteredCounter("dpos/liveness/outturn", nil)
+	missedSlotCounter  = metrics.NewRegisteredCounter("dpos/liveness/missed", nil)
+)
+
+// sealRecord is the sealing outcome of a single block slot.
+type sealRecord struct {
+	hash     common.Hash    // Hash of the block sealed in this slot
+	signer   common.Address // Validator that actually sealed the block
+	expected common.Address // Validator that was in-turn for the slot
+	inturn   bool           // Whether the block was sealed by the in-turn validator
+	missed   bool           // Whether the in-turn validator was eligible but did not seal
+}
+
+// livenessTracker keeps the sealing outcome of the recent block slots, fed by
+// the snapshot whenever headers are applied, and exports per validator metrics
+// for the blocks of the canonical chain.
+type livenessTracker struct {
+	records  map[uint64]map[common.Hash]*sealRecord // Sealing outcome of the observed blocks, siblings included
+	reported map[uint64]*sealRecord                 // Records of the canonical blocks exported into the metrics
+	head     uint64                                 // Number of the canonical head last exported
+	lock     sync.RWMutex
+}
+
+// newLivenessTracker creates an empty liveness tracker.
+func newLivenessTracker() *livenessTracker {
+	return &livenessTracker{
+		records:  make(map[uint64]map[common.Hash]*sealRecord),
+		reported: make(map[uint64]*sealRecord),
+	}
+}
+
+// observe records the sealing outcome of a validated header, given the
+// validators and the recent signers of the snapshot at its parent. Re-observing
+// an already known block is a noop. The metrics are left alone, as the header
+// may never become canonical.
+func (t *livenessTracker) observe(header *types.Header, signer common.Address, validators []common.Address, recents map[uint64]common.Address) {
+	if t == nil || len(validators) == 0 {
+		return
+	}
+	number := header.Number.Uint64()
+	hash := header.Hash()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.records[number][hash]; ok {
+		return
+	}
+	rec := &sealRecord{
+		hash:     hash,
+		signer:   signer,
+		expected: validators[number%uint64(len(validators))],
+	}
+	rec.inturn = rec.signer == rec.expected
+	if !rec.inturn {
+		// The in-turn validator only missed its slot if it was allowed to seal,
+		// mirroring the rules of tryPunishValidator. Signers old enough to be
+		// dropped from the recents at this block could seal again.
+		limit := uint64(len(validators)/2 + 1)
+
+		rec.missed = true
+		for seen, recent := range recents {
+			if recent == rec.expected && (number < limit || seen > number-limit) {
+				rec.missed = false
+				break
+			}
+		}
+	}
+	if t.records[number] == nil {
+		t.records[number] = make(map[common.Hash]*sealRecord)
+	}
+	t.records[number][hash] = rec
+	if number >= livenessHistory {
+		delete(t.records, number-livenessHistory)
+		delete(t.reported, number-livenessHistory)
+	}
+}
+
+// setHead exports the sealing outcome of the canonical chain ending at head
+// into the metrics, withdrawing the outcome of the blocks reorged out. Blocks
+// are exported once observed, the head itself may only be on the next call.
+func (t *livenessTracker) setHead(chain consensus.ChainHeaderReader, head *types.Header) {
+	number := head.Number.Uint64()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for n := number + 1; n <= t.head; n++ {
+		if rec := t.reported[n]; rec != nil {
+			rec.withdraw(n)
+			delete(t.reported, n)
+		}
+	}
+	t.head = number
+
+	for header := head; header != nil && header.Number.Uint64() > 0; {
+		n := header.Number.Uint64()
+		if number >= livenessHistory && n <= number-livenessHistory {
+			break
+		}
+		rec, old := t.records[n][header.Hash()], t.reported[n]
+		if rec == old && (rec != nil || n < number) {
+			break // The ancestors are exported already
+		}
+		if old != nil {
+			old.withdraw(n)
+			delete(t.reported, n)
+		}
+		if rec != nil {
+			rec.report(n)
+			t.reported[n] = rec
+		}
+		header = chain.GetHeader(header.ParentHash, n-1)
+	}
+}
+
+// report exports the sealing outcome into the metrics registry.
+func (r *sealRecord) report(number uint64) {
+	if r.inturn {
+		inturnSealCounter.Inc(1)
+		metrics.GetOrRegisterCounter(fmt.Sprintf("dpos/validator/%s/inturn", r.signer.Hex()), nil).Inc(1)
+	} else {
+		outturnSealCounter.Inc(1)
+		metrics.GetOrRegisterCounter(fmt.Sprintf("dpos/validator/%s/outturn", r.signer.Hex()), nil).Inc(1)
+	}
+	if r.missed {
+		missedSlotCounter.Inc(1)
+		metrics.GetOrRegisterCounter(fmt.Sprintf("dpos/validator/%s/missed", r.expected.Hex()), nil).Inc(1)
+	}
+	metrics.GetOrRegisterGauge(fmt.Sprintf("dpos/validator/%s/lastsealed", r.signer.Hex()), nil).Update(int64(number))
+}
+
+// withdraw reverts the counters of a previously exported sealing outcome whose
+// block was reorged out. The last sealed gauges are left as they are.
+func (r *sealRecord) withdraw(number uint64) {
+	if r.inturn {
+		inturnSealCounter.Dec(1)
+		metrics.GetOrRegisterCounter(fmt.Sprintf("dpos/validator/%s/inturn", r.signer.Hex()), nil).Dec(1)
+	} else {
+		outturnSealCounter.Dec(1)
+		metrics.GetOrRegisterCounter(fmt.Sprintf("dpos/validator/%s/outturn", r.signer.Hex()), nil).Dec(1)
+	}
+	if r.missed {
+		missedSlotCounter.Dec(1)
+		metrics.GetOrRegisterCounter(fmt.Sprintf("dpos/validator/%s/missed", r.expected.Hex()), nil).Dec(1)
+	}
+}
+
+// record retrieves the sealing outcome of the given block, if it is known.
+func (t *livenessTracker) record(number uint64, hash common.Hash) *sealRecord {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.records[number][hash]
+}
+
+// StartLivenessMetrics exports the liveness metrics of the canonical chain,
+// following its head until the engine is closed.
+func (p *Dpos) StartLivenessMetrics(chain voteChain) {
+	headCh := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	sub := chain.SubscribeChainHeadEvent(headCh)
+
+	p.lock.Lock()
+	p.livenessSub = sub
+	p.lock.Unlock()
+
+	go func() {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case ev := <-headCh:
+				p.liveness.setHead(chain, ev.Block.Header())
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+}
+
+// ValidatorLiveness is the sealing activity of a single validator within a
+// window of blocks.
+type ValidatorLiveness struct {
+	InTurn     uint64 `json:"inTurn"`     // Number of blocks sealed in-turn
+	OutOfTurn  uint64 `json:"outOfTurn"`  // Number of blocks sealed out-of-turn
+	Missed     uint64 `json:"missed"`     // Number of in-turn slots the validator did not seal
+	LastSealed uint64 `json:"lastSealed"` // Number of the last block sealed within the window
+}
+
+// LivenessReport is the sealing activity of the validator set over the block
+// range [From, To].
+type LivenessReport struct {
+	From          uint64                                `json:"from"`
+	To            uint64                                `json:"to"`
+	NumBlocks     uint64                                `json:"numBlocks"`
+	InturnPercent float64                               `json:"inturnPercent"`
+	Validators    map[common.Address]*ValidatorLiveness `json:"validators"`
+}
+
+// livenessReport aggregates the sealing outcome of the last window blocks of
+// the canonical chain. Slots that are not tracked yet (e.g. right after start
+// up) are backfilled from the snapshot of their parent.
+func (p *Dpos) livenessReport(chain consensus.ChainHeaderReader, window uint64) (*LivenessReport, error) {
+	if window == 0 {
+		window = defaultLivenessWindow
+	}
+	if window > livenessHistory {
+		return nil, fmt.Errorf("liveness window too large: have %d, max %d", window, livenessHistory)
+	}
+	header := chain.CurrentHeader()
+	end := header.Number.Uint64()
+	if window > end {
+		window = end
+	}
+	report := &LivenessReport{
+		From:       end - window + 1,
+		To:         end,
+		NumBlocks:  window,
+		Validators: make(map[common.Address]*ValidatorLiveness),
+	}
+	snap, err := p.snapshot(chain, end, header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, val := range snap.validators() {
+		report.Validators[val] = new(ValidatorLiveness)
+	}
+	entry := func(val common.Address) *ValidatorLiveness {
+		if report.Validators[val] == nil {
+			report.Validators[val] = new(ValidatorLiveness)
+		}
+		return report.Validators[val]
+	}
+	// Gather the headers of the window, oldest first, backfilling the slots not
+	// tracked yet (e.g. right after start up) from a single snapshot into a
+	// transient tracker, leaving the metrics untouched.
+	headers := make([]*types.Header, window)
+	for i := len(headers) - 1; i >= 0; i-- {
+		headers[i] = header
+		if i > 0 {
+			if header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1); header == nil {
+				return nil, fmt.Errorf("missing block %d", headers[i].Number.Uint64()-1)
+			}
+		}
+	}
+	backfill := newLivenessTracker()
+	for i, h := range headers {
+		if p.liveness.record(h.Number.Uint64(), h.Hash()) != nil {
+			continue
+		}
+		parent, err := p.snapshot(chain, h.Number.Uint64()-1, h.ParentHash, nil)
+		if err != nil {
+			return nil, err
+		}
+		parent = parent.copy()
+		parent.liveness = backfill
+		if _, err := parent.apply(headers[i:], chain, nil, p.chainConfig); err != nil {
+			return nil, err
+		}
+		break
+	}
+	inturns := uint64(0)
+	for _, h := range headers {
+		n := h.Number.Uint64()
+		rec := p.liveness.record(n, h.Hash())
+		if rec == nil {
+			if rec = backfill.record(n, h.Hash()); rec == nil {
+				return nil, fmt.Errorf("missing liveness record for block %d", n)
+			}
+		}
+		signer := entry(rec.signer)
+		if rec.inturn {
+			inturns++
+			signer.InTurn++
+		} else {
+			signer.OutOfTurn++
+		}
+		if signer.LastSealed < n {
+			signer.LastSealed = n
+		}
+		if rec.missed {
+			entry(rec.expected).Missed++
+		}
+	}
+	if report.NumBlocks > 0 {
+		report.InturnPercent = float64(100*inturns) / float64(report.NumBlocks)
+	}
+	return report, nil
+}
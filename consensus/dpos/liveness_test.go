@@ -0,0 +1,104 @@
+package dpos
+
+import (
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"PureChain/common"
+	"PureChain/core/types"
+)
+
+func TestLivenessTrackerObserve(t *testing.T) {
+	validators := []common.Address{randomAddress(), randomAddress(), randomAddress()}
+	sort.Sort(validatorsAscending(validators))
+	tracker := newLivenessTracker()
+
+	// Block 4 is sealed by its in-turn validator
+	in := &types.Header{Number: big.NewInt(4), Extra: []byte("in")}
+	tracker.observe(in, validators[1], validators, nil)
+	rec := tracker.record(4, in.Hash())
+	assert.NotNil(t, rec)
+	assert.True(t, rec.inturn)
+	assert.False(t, rec.missed)
+
+	// Block 5 is sealed out-of-turn while the in-turn validator was eligible
+	out := &types.Header{Number: big.NewInt(5), Extra: []byte("out")}
+	tracker.observe(out, validators[0], validators, map[uint64]common.Address{4: validators[1]})
+	rec = tracker.record(5, out.Hash())
+	assert.False(t, rec.inturn)
+	assert.True(t, rec.missed)
+	assert.Equal(t, validators[2], rec.expected)
+
+	// A sibling of block 5 is tracked alongside, its in-turn validator signed recently
+	sibling := &types.Header{Number: big.NewInt(5), Extra: []byte("sibling")}
+	tracker.observe(sibling, validators[0], validators, map[uint64]common.Address{4: validators[2]})
+	assert.True(t, tracker.record(5, out.Hash()).missed)
+	assert.False(t, tracker.record(5, sibling.Hash()).missed)
+}
+
+func TestLivenessTrackerSetHead(t *testing.T) {
+	validators := []common.Address{randomAddress(), randomAddress(), randomAddress()}
+	sort.Sort(validatorsAscending(validators))
+	tracker := newLivenessTracker()
+
+	// Blocks 1 to 5 are sealed in-turn, a sibling of block 5 out-of-turn
+	chain := &headerOnlyChain{headers: make(map[uint64]*types.Header)}
+	for n := uint64(1); n <= 5; n++ {
+		header := &types.Header{Number: new(big.Int).SetUint64(n)}
+		if parent := chain.headers[n-1]; parent != nil {
+			header.ParentHash = parent.Hash()
+		}
+		chain.headers[n] = header
+		tracker.observe(header, validators[n%3], validators, nil)
+	}
+	canonical := chain.headers[5]
+	sibling := &types.Header{Number: big.NewInt(5), ParentHash: canonical.ParentHash, Extra: []byte("sibling")}
+	tracker.observe(sibling, validators[0], validators, nil)
+	assert.Empty(t, tracker.reported)
+
+	// Only the canonical blocks are exported, and only once
+	tracker.setHead(chain, canonical)
+	tracker.setHead(chain, canonical)
+	assert.Len(t, tracker.reported, 5)
+	assert.Equal(t, tracker.record(5, canonical.Hash()), tracker.reported[5])
+
+	// The sibling replaces block 5 once it becomes canonical
+	chain.headers[5] = sibling
+	tracker.setHead(chain, sibling)
+	assert.Len(t, tracker.reported, 5)
+	assert.Equal(t, tracker.record(5, sibling.Hash()), tracker.reported[5])
+
+	// Rewinding the chain withdraws the blocks above the new head
+	tracker.setHead(chain, chain.headers[4])
+	assert.Len(t, tracker.reported, 4)
+	assert.Nil(t, tracker.reported[5])
+}
+
+func TestLivenessTrackerRecentsWindow(t *testing.T) {
+	validators := []common.Address{randomAddress(), randomAddress(), randomAddress()}
+	sort.Sort(validatorsAscending(validators))
+	tracker := newLivenessTracker()
+
+	inturn, outturn, missed := inturnSealCounter.Count(), outturnSealCounter.Count(), missedSlotCounter.Count()
+
+	// Block 6 is sealed out-of-turn; the in-turn validator signed block 4, which
+	// drops out of the recents at block 6, so it was eligible and missed the slot
+	header := &types.Header{Number: big.NewInt(6), Extra: []byte("window")}
+	tracker.observe(header, validators[1], validators, map[uint64]common.Address{4: validators[0], 5: validators[2]})
+	rec := tracker.record(6, header.Hash())
+	assert.Equal(t, validators[0], rec.expected)
+	assert.True(t, rec.missed)
+
+	// Block 7 is sealed out-of-turn; the in-turn validator signed block 6
+	header = &types.Header{Number: big.NewInt(7), Extra: []byte("window")}
+	tracker.observe(header, validators[0], validators, map[uint64]common.Address{5: validators[2], 6: validators[1]})
+	assert.False(t, tracker.record(7, header.Hash()).missed)
+
+	// Observing blocks alone doesn't export metrics
+	assert.Equal(t, inturn, inturnSealCounter.Count())
+	assert.Equal(t, outturn, outturnSealCounter.Count())
+	assert.Equal(t, missed, missedSlotCounter.Count())
+}
@@ -37,7 +37,7 @@ func (c *Dpos) getPassedProposalCount(chain consensus.ChainHeaderReader, header
 		return 0, err
 	}
 
-	msg := types.NewMessage(header.Coinbase, &systemcontract.SysGovContractAddr, 0, new(big.Int), math.MaxUint64, new(big.Int), data, nil, false)
+	msg := types.NewMessage(header.Coinbase, &systemcontract.SysGovContractAddr, 0, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, false)
 
 	// use parent
 	result, err := vmcaller.ExecuteMsg(msg, state, header, newChainContext(chain, c), c.chainConfig)
@@ -70,7 +70,7 @@ func (c *Dpos) getPassedProposalByIndex(chain consensus.ChainHeaderReader, heade
 		return nil, err
 	}
 
-	msg := types.NewMessage(header.Coinbase, &systemcontract.SysGovContractAddr, 0, new(big.Int), math.MaxUint64, new(big.Int), data, nil, false)
+	msg := types.NewMessage(header.Coinbase, &systemcontract.SysGovContractAddr, 0, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, false)
 
 	// use parent
 	result, err := vmcaller.ExecuteMsg(msg, state, header, newChainContext(chain, c), c.chainConfig)
@@ -97,7 +97,7 @@ func (c *Dpos) finishProposalById(chain consensus.ChainHeaderReader, header *typ
 		return err
 	}
 
-	msg := types.NewMessage(header.Coinbase, &systemcontract.SysGovContractAddr, 0, new(big.Int), math.MaxUint64, new(big.Int), data, nil, false)
+	msg := types.NewMessage(header.Coinbase, &systemcontract.SysGovContractAddr, 0, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, false)
 
 	// execute message without a transaction
 	state.Prepare(common.Hash{}, header.Hash(), 0)
@@ -159,6 +159,22 @@ func (c *Dpos) replayProposal(chain consensus.ChainHeaderReader, header *types.H
 	return receipt, nil
 }
 
+// simulateProposal executes a passed proposal in a simulated block, standing in
+// for the system governance transaction the coinbase would have signed.
+func (c *Dpos) simulateProposal(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, prop *Proposal, totalTxIndex int) (*types.Transaction, *types.Receipt, error) {
+	propRLP, err := rlp.EncodeToBytes(prop)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := state.GetNonce(header.Coinbase)
+	tx := types.NewTransaction(nonce, systemcontract.SysGovToAddr, prop.Value, header.GasLimit, new(big.Int), propRLP)
+	//add nonce for validator
+	state.SetNonce(header.Coinbase, nonce+1)
+	receipt := c.executeProposalMsg(chain, header, state, prop, totalTxIndex, tx.Hash(), common.Hash{})
+
+	return tx, receipt, nil
+}
+
 func (c *Dpos) executeProposalMsg(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, prop *Proposal, totalTxIndex int, txHash, bHash common.Hash) *types.Receipt {
 	var receipt *types.Receipt
 	action := prop.Action.Uint64()
@@ -187,7 +203,7 @@ func (c *Dpos) executeProposalMsg(chain consensus.ChainHeaderReader, header *typ
 // the returned value should not nil.
 func (c *Dpos) executeEvmCallProposal(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, prop *Proposal, totalTxIndex int, txHash, bHash common.Hash) *types.Receipt {
 	// actually run the governance message
-	msg := types.NewMessage(prop.From, &prop.To, 0, prop.Value, header.GasLimit, new(big.Int), prop.Data, nil, false)
+	msg := types.NewMessage(prop.From, &prop.To, 0, prop.Value, header.GasLimit, new(big.Int), new(big.Int), new(big.Int), prop.Data, nil, false)
 	state.Prepare(txHash, bHash, totalTxIndex)
 	_, err := vmcaller.ExecuteMsg(msg, state, header, newChainContext(chain, c), c.chainConfig)
 	state.Finalise(true)
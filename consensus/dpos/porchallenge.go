@@ -0,0 +1,90 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"math/big"
+
+	"PureChain/common"
+	"PureChain/consensus/dpos/systemcontract"
+	"PureChain/core/types"
+)
+
+// Kinds of PoR challenge system transactions.
+const (
+	PorChallengeCreate       = "challenge"    // A validator challenges a provider
+	PorChallengeFinish       = "finish"       // A validator submits the challenge outcome
+	PorChallengeNotSubmitted = "notSubmitted" // A validator failed to submit a challenge outcome in time
+)
+
+// Outcomes of finished PoR challenges, mirroring ValidatorFactory.ChallengeState.
+const (
+	PorChallengeSuccess uint8 = 2
+	PorChallengeFail    uint8 = 3
+)
+
+// PorChallenge is a decoded PoR challenge system transaction.
+type PorChallenge struct {
+	Kind      string         `json:"kind"`
+	Validator common.Address `json:"validator"`
+	Provider  common.Address `json:"provider"`
+	Seed      *big.Int       `json:"seed,omitempty"`
+	State     uint8          `json:"state,omitempty"` // Outcome of finished challenges
+}
+
+// porChallengeMethods maps the validator factory methods to challenge kinds.
+var porChallengeMethods = map[string]string{
+	"challengeProvider":        PorChallengeCreate,
+	"challengeFinish":          PorChallengeFinish,
+	"validatorNotSubmitResult": PorChallengeNotSubmitted,
+}
+
+// DecodePorChallenge decodes a PoR challenge system transaction, reporting false
+// if the transaction is not one.
+func (p *Dpos) DecodePorChallenge(tx *types.Transaction) (*PorChallenge, bool) {
+	if to := tx.To(); to == nil || *to != systemcontract.ValidatorFactoryContractAddr || len(tx.Data()) < 4 {
+		return nil, false
+	}
+	contract := p.abi[systemcontract.ValidatorFactoryContractName]
+	method, err := contract.MethodById(tx.Data()[:4])
+	if err != nil {
+		return nil, false
+	}
+	kind, ok := porChallengeMethods[method.Name]
+	if !ok {
+		return nil, false
+	}
+	args, err := method.Inputs.Unpack(tx.Data()[4:])
+	if err != nil || len(args) == 0 {
+		return nil, false
+	}
+	validator, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, false
+	}
+	challenge := &PorChallenge{Kind: kind, Validator: validator}
+	if challenge.Provider, ok = args[0].(common.Address); !ok {
+		return nil, false
+	}
+	if len(args) > 1 {
+		challenge.Seed, _ = args[1].(*big.Int)
+	}
+	if kind == PorChallengeFinish {
+		challenge.State, _ = args[len(args)-1].(uint8)
+	}
+	return challenge, true
+}
@@ -0,0 +1,60 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"PureChain/common"
+	"PureChain/consensus/dpos/systemcontract"
+	"PureChain/core/types"
+	"PureChain/crypto"
+)
+
+func TestDecodePorChallenge(t *testing.T) {
+	engine := &Dpos{abi: systemcontract.GetInteractiveABI()}
+	key, _ := crypto.GenerateKey()
+	validator := crypto.PubkeyToAddress(key.PublicKey)
+	provider := randomAddress()
+	signer := types.NewEIP155Signer(big.NewInt(1337))
+
+	sign := func(to common.Address, data []byte) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(0, to, common.Big0, defaultGasLimit, common.Big0, data), signer, key)
+		return tx
+	}
+	contract := engine.abi[systemcontract.ValidatorFactoryContractName]
+
+	data, _ := contract.Pack("challengeFinish", provider, big.NewInt(7), big.NewInt(3), big.NewInt(1), PorChallengeFail)
+	challenge, ok := engine.DecodePorChallenge(sign(systemcontract.ValidatorFactoryContractAddr, data))
+	assert.True(t, ok)
+	assert.Equal(t, &PorChallenge{Kind: PorChallengeFinish, Validator: validator, Provider: provider, Seed: big.NewInt(7), State: PorChallengeFail}, challenge)
+
+	data, _ = contract.Pack("validatorNotSubmitResult", provider)
+	challenge, ok = engine.DecodePorChallenge(sign(systemcontract.ValidatorFactoryContractAddr, data))
+	assert.True(t, ok)
+	assert.Equal(t, &PorChallenge{Kind: PorChallengeNotSubmitted, Validator: validator, Provider: provider}, challenge)
+
+	// Calls to other contracts or methods are not challenges
+	data, _ = contract.Pack("challengeFinish", provider, big.NewInt(7), big.NewInt(3), big.NewInt(1), PorChallengeFail)
+	_, ok = engine.DecodePorChallenge(sign(systemcontract.AddressListContractAddr, data))
+	assert.False(t, ok)
+	_, ok = engine.DecodePorChallenge(sign(systemcontract.ValidatorFactoryContractAddr, []byte{0x01, 0x02, 0x03, 0x04}))
+	assert.False(t, ok)
+}
@@ -0,0 +1,200 @@
+package dpos
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+
+	"PureChain/common"
+	"PureChain/consensus"
+	"PureChain/core/types"
+	"PureChain/crypto"
+	"PureChain/rlp"
+)
+
+// maxProviderStatsRange is the maximum number of blocks replayed by a single
+// provider selection stats request.
+const maxProviderStatsRange = 10000
+
+// selectProvider picks the provider of the block on top of parent, with a
+// probability proportional to its voting power. The zero address is returned
+// if no provider has any voting power.
+func selectProvider(parent *types.Header, providers []VoteInfo) (common.Address, error) {
+	totalVote := big.NewInt(0)
+	for _, k := range providers {
+		totalVote.Add(totalVote, k.VotingPower)
+	}
+	if totalVote.Sign() <= 0 {
+		return common.Address{}, nil
+	}
+	calRlp, err := rlp.EncodeToBytes([]interface{}{parent.Root, parent.Hash(), parent.Coinbase, parent.Time})
+	if err != nil {
+		return common.Address{}, err
+	}
+	magicNumber := new(big.Int).SetBytes(crypto.Keccak256(calRlp))
+	magicNumber.Mod(magicNumber, totalVote)
+
+	currentVote := big.NewInt(0)
+	for _, v := range providers {
+		currentVote.Add(currentVote, v.VotingPower)
+		if magicNumber.Cmp(currentVote) < 0 {
+			return v.ProviderAddress, nil
+		}
+	}
+	return common.Address{}, nil
+}
+
+// ProviderSelection is the selection frequency of a single provider over a
+// range of blocks.
+type ProviderSelection struct {
+	Provider common.Address `json:"provider"`
+	Observed uint64         `json:"observed"` // Number of blocks naming the provider in their header
+	Replayed uint64         `json:"replayed"` // Number of blocks the replayed selection picked the provider for
+	Expected float64        `json:"expected"` // Sum of the provider's share of the voting power over the range
+}
+
+// ProviderSelectionStats compares the observed provider selections over the
+// block range [From, To] with the frequencies expected from the voting power.
+type ProviderSelectionStats struct {
+	From             uint64               `json:"from"`
+	To               uint64               `json:"to"`
+	NumBlocks        uint64               `json:"numBlocks"`
+	Unassigned       uint64               `json:"unassigned"`       // Blocks without any eligible provider
+	Mismatches       uint64               `json:"mismatches"`       // Blocks whose provider differs from the replayed selection
+	ChiSquare        float64              `json:"chiSquare"`        // Pearson's statistic of observed vs expected selections
+	DegreesOfFreedom int                  `json:"degreesOfFreedom"` // Number of eligible providers minus one
+	PValue           float64              `json:"pValue"`           // Probability of a statistic at least as extreme under fair selection
+	Providers        []*ProviderSelection `json:"providers"`
+}
+
+// ProviderSelectionStats replays the provider selection of the canonical blocks
+// in [from, to] on top of the historical provider contract state, which thus
+// has to be available for all their parents. At most maxProviderStatsRange
+// blocks are replayed at once.
+func (p *Dpos) ProviderSelectionStats(chain consensus.ChainHeaderReader, from, to uint64) (*ProviderSelectionStats, error) {
+	if from == 0 {
+		from = 1 // The genesis block has no provider
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid block range: from %d > to %d", from, to)
+	}
+	if to-from >= maxProviderStatsRange {
+		return nil, fmt.Errorf("block range too large: have %d, max %d", to-from+1, maxProviderStatsRange)
+	}
+	stats := &ProviderSelectionStats{From: from, To: to}
+	providers := make(map[common.Address]*ProviderSelection)
+	entry := func(addr common.Address) *ProviderSelection {
+		if providers[addr] == nil {
+			providers[addr] = &ProviderSelection{Provider: addr}
+		}
+		return providers[addr]
+	}
+	for n := from; n <= to; n++ {
+		header := chain.GetHeaderByNumber(n)
+		if header == nil {
+			return nil, fmt.Errorf("missing block %d", n)
+		}
+		parent := chain.GetHeader(header.ParentHash, n-1)
+		if parent == nil {
+			return nil, fmt.Errorf("missing block %d", n-1)
+		}
+		infos, err := p.getProviderInfo(chain, header)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve providers of block %d: %v", n, err)
+		}
+		replayed, err := selectProvider(parent, infos)
+		if err != nil {
+			return nil, err
+		}
+		stats.NumBlocks++
+		if replayed != header.Provider {
+			stats.Mismatches++
+		}
+		if replayed == (common.Address{}) && header.Provider == (common.Address{}) {
+			stats.Unassigned++
+			continue
+		}
+		totalVote := big.NewInt(0)
+		for _, info := range infos {
+			totalVote.Add(totalVote, info.VotingPower)
+		}
+		if totalVote.Sign() > 0 {
+			total, _ := new(big.Float).SetInt(totalVote).Float64()
+			for _, info := range infos {
+				power, _ := new(big.Float).SetInt(info.VotingPower).Float64()
+				entry(info.ProviderAddress).Expected += power / total
+			}
+			entry(replayed).Replayed++
+		}
+		if header.Provider != (common.Address{}) {
+			entry(header.Provider).Observed++
+		}
+	}
+	for _, sel := range providers {
+		stats.Providers = append(stats.Providers, sel)
+		if sel.Expected > 0 {
+			diff := float64(sel.Observed) - sel.Expected
+			stats.ChiSquare += diff * diff / sel.Expected
+			stats.DegreesOfFreedom++
+		}
+	}
+	if stats.DegreesOfFreedom > 0 {
+		stats.DegreesOfFreedom--
+	}
+	stats.PValue = chiSquareSurvival(stats.ChiSquare, stats.DegreesOfFreedom)
+
+	sort.Slice(stats.Providers, func(i, j int) bool {
+		return bytes.Compare(stats.Providers[i].Provider[:], stats.Providers[j].Provider[:]) < 0
+	})
+	return stats, nil
+}
+
+// chiSquareSurvival returns the probability for a chi-square distributed
+// variable with k degrees of freedom to be at least x, i.e. the regularized
+// upper incomplete gamma function Q(k/2, x/2).
+func chiSquareSurvival(x float64, k int) float64 {
+	if k <= 0 || x <= 0 {
+		return 1
+	}
+	a, x := float64(k)/2, x/2
+	lg, _ := math.Lgamma(a)
+	prefix := math.Exp(-x + a*math.Log(x) - lg)
+
+	if x < a+1 {
+		// Series representation of the lower function P(a, x)
+		sum, del, ap := 1/a, 1/a, a
+		for i := 0; i < 1000; i++ {
+			ap++
+			del *= x / ap
+			sum += del
+			if math.Abs(del) < math.Abs(sum)*1e-15 {
+				break
+			}
+		}
+		return 1 - sum*prefix
+	}
+	// Continued fraction representation of Q(a, x), evaluated with Lentz's method
+	const tiny = 1e-300
+	b := x + 1 - a
+	c, d := 1/tiny, 1/b
+	h := d
+	for i := 1; i < 1000; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		if d = an*d + b; math.Abs(d) < tiny {
+			d = tiny
+		}
+		if c = b + an/c; math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < 1e-15 {
+			break
+		}
+	}
+	return prefix * h
+}
@@ -0,0 +1,61 @@
+package dpos
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"PureChain/common"
+	"PureChain/core/types"
+)
+
+func TestSelectProvider(t *testing.T) {
+	parent := &types.Header{Number: big.NewInt(7), Root: common.Hash{0x1}, Coinbase: randomAddress(), Time: 1000}
+
+	// Nobody is selected without voting power
+	provider, err := selectProvider(parent, []VoteInfo{{ProviderAddress: randomAddress(), VotingPower: big.NewInt(0)}})
+	assert.NoError(t, err)
+	assert.Equal(t, common.Address{}, provider)
+
+	// The only provider with voting power is always selected
+	lucky := randomAddress()
+	provider, err = selectProvider(parent, []VoteInfo{
+		{ProviderAddress: randomAddress(), VotingPower: big.NewInt(0)},
+		{ProviderAddress: lucky, VotingPower: big.NewInt(5)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, lucky, provider)
+}
+
+func TestProviderSelectionStatsRange(t *testing.T) {
+	var p Dpos
+
+	_, err := p.ProviderSelectionStats(nil, 10, 9)
+	assert.EqualError(t, err, "invalid block range: from 10 > to 9")
+
+	_, err = p.ProviderSelectionStats(nil, 1, maxProviderStatsRange+1)
+	assert.EqualError(t, err, fmt.Sprintf("block range too large: have %d, max %d", maxProviderStatsRange+1, maxProviderStatsRange))
+}
+
+func TestChiSquareSurvival(t *testing.T) {
+	tests := []struct {
+		x    float64
+		k    int
+		want float64
+	}{
+		{0, 3, 1},
+		{2, 2, math.Exp(-1)},
+		{3.841459, 1, 0.05},
+		{18.307038, 10, 0.05},
+		{6.634897, 1, 0.01},
+		{1.145476, 5, 0.95},
+	}
+	for i, tt := range tests {
+		if have := chiSquareSurvival(tt.x, tt.k); math.Abs(have-tt.want) > 1e-5 {
+			t.Errorf("test %d: survival mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package dpos
+
+import (
+	"bytes"
+
+	"PureChain/common"
+	"PureChain/consensus"
+	"PureChain/core/types"
+)
+
+// ValidatorSlot is the sealing status of a single local validator for the
+// block on top of a given parent.
+type ValidatorSlot struct {
+	Validator      common.Address `json:"validator"`
+	Authorized     bool           `json:"authorized"`     // Whether the validator is in the current validator set
+	Signable       bool           `json:"signable"`       // Whether a local signing key is available for the validator
+	SignedRecently bool           `json:"signedRecently"` // Whether the validator has to wait for others to seal
+	InTurn         bool           `json:"inTurn"`
+	BackOff        uint64         `json:"backOff"` // Seconds the validator has to wait after the block period
+	Selected       bool           `json:"selected"`
+}
+
+// eligible returns whether the validator is allowed to seal the block.
+func (s *ValidatorSlot) eligible() bool {
+	return s.Authorized && s.Signable && !s.SignedRecently
+}
+
+// ValidatorSchedule is the sealing schedule of the local validators for the
+// block on top of a given parent.
+type ValidatorSchedule struct {
+	Number     uint64           `json:"number"`
+	ParentHash common.Hash      `json:"parentHash"`
+	Validator  common.Address   `json:"validator"` // Validator selected to seal the block, zero if none is eligible
+	Slots      []*ValidatorSlot `json:"slots"`
+}
+
+// ScheduleValidators computes the sealing status of the given local validators
+// for the block on top of parent and selects the one to seal it: the in-turn
+// validator if it is local and eligible, otherwise the eligible validator with
+// the shortest back off time. The selection only depends on the parent, so
+// every recommit within a slot picks the same validator.
+func (p *Dpos) ScheduleValidators(chain consensus.ChainHeaderReader, parent *types.Header, candidates []common.Address) (*ValidatorSchedule, error) {
+	snap, err := p.snapshot(chain, parent.Number.Uint64(), parent.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	number := parent.Number.Uint64() + 1
+	schedule := &ValidatorSchedule{Number: number, ParentHash: parent.Hash()}
+
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	var best *ValidatorSlot
+	for _, val := range candidates {
+		slot := &ValidatorSlot{Validator: val}
+		_, slot.Authorized = snap.Validators[val]
+		_, slot.Signable = p.signFns[val]
+		for seen, recent := range snap.Recents {
+			if recent != val {
+				continue
+			}
+			if limit := uint64(len(snap.Validators)/2 + 1); number < limit || seen > number-limit {
+				slot.SignedRecently = true
+			}
+		}
+		if slot.Authorized {
+			slot.InTurn = snap.inturn(val)
+			slot.BackOff = backOffTime(snap, val)
+		}
+		schedule.Slots = append(schedule.Slots, slot)
+
+		if !slot.eligible() {
+			continue
+		}
+		if best == nil || slot.BackOff < best.BackOff ||
+			(slot.BackOff == best.BackOff && bytes.Compare(slot.Validator[:], best.Validator[:]) < 0) {
+			best = slot
+		}
+	}
+	if best != nil {
+		best.Selected = true
+		schedule.Validator = best.Validator
+	}
+	return schedule, nil
+}
@@ -0,0 +1,87 @@
+package dpos
+
+import (
+	"math/big"
+	"sort"
+	"testing"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/stretchr/testify/assert"
+
+	"PureChain/accounts"
+	"PureChain/common"
+	"PureChain/core/types"
+	"PureChain/params"
+)
+
+func TestScheduleValidators(t *testing.T) {
+	validators := make([]common.Address, 4)
+	for i := range validators {
+		validators[i] = randomAddress()
+	}
+	sort.Sort(validatorsAscending(validators))
+
+	parent := &types.Header{Number: big.NewInt(10), Extra: []byte("parent")}
+	snap := newSnapshot(&params.DposConfig{Epoch: 200}, nil, nil, 10, parent.Hash(), validators, nil)
+
+	recentSnaps, _ := lru.NewARC(inMemorySnapshots)
+	recentSnaps.Add(snap.Hash, snap)
+	engine := &Dpos{
+		config:      &params.DposConfig{Epoch: 200},
+		recentSnaps: recentSnaps,
+		signFns:     make(map[common.Address]SignerFn),
+	}
+	signFn := func(accounts.Account, string, []byte) ([]byte, error) { return nil, nil }
+	for _, val := range validators {
+		engine.signFns[val] = signFn
+	}
+	inturn := validators[11%len(validators)]
+
+	// The in-turn validator is picked whenever it is local
+	schedule, err := engine.ScheduleValidators(nil, parent, validators)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(11), schedule.Number)
+	assert.Equal(t, inturn, schedule.Validator)
+	assert.Len(t, schedule.Slots, len(validators))
+
+	// Otherwise the eligible validator with the shortest back off time
+	var others []common.Address
+	for _, val := range validators {
+		if val != inturn {
+			others = append(others, val)
+		}
+	}
+	schedule, err = engine.ScheduleValidators(nil, parent, others)
+	assert.NoError(t, err)
+
+	best := others[0]
+	for _, val := range others[1:] {
+		if backOffTime(snap, val) < backOffTime(snap, best) {
+			best = val
+		}
+	}
+	assert.Equal(t, best, schedule.Validator)
+	for _, slot := range schedule.Slots {
+		assert.Equal(t, slot.Validator == best, slot.Selected)
+		assert.False(t, slot.InTurn)
+	}
+
+	// Validators which signed recently or lack a signing key are skipped
+	snap.Recents[10] = inturn
+	delete(engine.signFns, best)
+
+	schedule, err = engine.ScheduleValidators(nil, parent, validators)
+	assert.NoError(t, err)
+	assert.NotEqual(t, inturn, schedule.Validator)
+	assert.NotEqual(t, best, schedule.Validator)
+	for _, slot := range schedule.Slots {
+		assert.Equal(t, slot.Validator == inturn, slot.SignedRecently)
+		assert.Equal(t, slot.Validator != best, slot.Signable)
+	}
+
+	// Unknown validators are never selected
+	schedule, err = engine.ScheduleValidators(nil, parent, []common.Address{randomAddress()})
+	assert.NoError(t, err)
+	assert.Equal(t, common.Address{}, schedule.Validator)
+	assert.False(t, schedule.Slots[0].Authorized)
+}
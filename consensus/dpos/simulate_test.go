@@ -0,0 +1,110 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"PureChain/accounts/abi/bind/backends"
+	"PureChain/common"
+	"PureChain/consensus"
+	dpossys "PureChain/consensus/dpos/systemcontract"
+	"PureChain/consensus/parlia/systemcontract"
+	"PureChain/core"
+	"PureChain/core/state"
+	"PureChain/core/types"
+	"PureChain/crypto"
+	"PureChain/params"
+)
+
+// claimingChain is a chain reader of sealed blocks claiming every header to be
+// simulated, with the governance fork enabled.
+type claimingChain struct {
+	consensus.ChainHeaderReader
+	config *params.ChainConfig
+}
+
+func (c *claimingChain) Config() *params.ChainConfig                        { return c.config }
+func (c *claimingChain) Simulated(header *types.Header) bool                { return true }
+func (c *claimingChain) SimulatedState(header *types.Header) *state.StateDB { return nil }
+
+// Tests that the checks relaxed for simulated blocks are only relaxed through
+// the simulation entry points, not for chain readers merely claiming to simulate
+// the blocks they serve.
+func TestFinalizeSimulatedOnly(t *testing.T) {
+	// Stand in for the governance contract, whose interface the engine doesn't
+	// load before the fork, no proposal ever passes
+	abis := dpossys.GetInteractiveABI()
+	abis[systemcontract.SysGovContractName] = systemcontract.GetInteractiveABI()[systemcontract.SysGovContractName]
+	defer delete(abis, systemcontract.SysGovContractName)
+
+	var (
+		valKey, _  = crypto.GenerateKey()
+		userKey, _ = crypto.GenerateKey()
+		alloc      = core.GenesisAlloc{
+			crypto.PubkeyToAddress(userKey.PublicKey): {Balance: big.NewInt(params.Ether)},
+			systemcontract.SysGovContractAddr:         {Code: common.FromHex("60206000f3"), Balance: new(big.Int)},
+		}
+	)
+	sim, err := backends.NewSimulatedDposBackend(alloc, 10000000, 0, valKey)
+	if err != nil {
+		t.Fatalf("could not create dpos backend: %v", err)
+	}
+	defer sim.Close()
+
+	// Seal a transfer in the first block, initializing the system contracts
+	head, _ := sim.HeaderByNumber(context.Background(), nil)
+	tx, _ := types.SignTx(types.NewTransaction(0, common.Address{0x1}, big.NewInt(1), params.TxGas, new(big.Int).Mul(head.BaseFee, big.NewInt(2)), nil), types.NewEIP155Signer(big.NewInt(1337)), userKey)
+	if err := sim.SendTransaction(context.Background(), tx); err != nil {
+		t.Fatalf("could not send transaction: %v", err)
+	}
+	sim.Commit()
+	sim.Commit()
+	var (
+		bc     = sim.Blockchain()
+		header = types.CopyHeader(bc.GetHeaderByNumber(2))
+		config = *bc.Config()
+		bogus  = types.NewTransaction(0, common.Address{}, new(big.Int), 0, new(big.Int), nil)
+	)
+	config.RedCoastBlock = big.NewInt(0)
+	chain := &claimingChain{ChainHeaderReader: bc, config: &config}
+
+	finalize := func(simulated bool) error {
+		statedb, err := bc.StateAt(bc.GetHeaderByNumber(1).Root)
+		if err != nil {
+			t.Fatalf("could not retrieve parent state: %v", err)
+		}
+		var (
+			txs       []*types.Transaction
+			receipts  []*types.Receipt
+			systemTxs = []*types.Transaction{bogus}
+			usedGas   uint64
+		)
+		if simulated {
+			return sim.Dpos().FinalizeSimulated(chain, header, statedb, &txs, &receipts, &usedGas)
+		}
+		return sim.Dpos().Finalize(chain, header, statedb, &txs, nil, &receipts, &systemTxs, &usedGas, true)
+	}
+	if err := finalize(false); err == nil || err.Error() != "invalid system governance tx count" {
+		t.Fatalf("governance tx count check skipped on import: have %v", err)
+	}
+	if err := finalize(true); err != nil {
+		t.Fatalf("failed to finalize simulated block: %v", err)
+	}
+}
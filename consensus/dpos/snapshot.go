@@ -21,7 +21,6 @@ import (
 	//"encoding/hex"
 	"encoding/json"
 	"errors"
-	"math/big"
 	"sort"
 
 	"PureChain/common"
@@ -37,13 +36,19 @@ import (
 type Snapshot struct {
 	config   *params.DposConfig // Consensus engine parameters to fine tune behavior
 	ethAPI   *ethapi.PublicBlockChainAPI
-	sigCache *lru.ARCCache // Cache of recent block signatures to speed up ecrecover
+	sigCache *lru.ARCCache    // Cache of recent block signatures to speed up ecrecover
+	liveness *livenessTracker // Tracker of the sealing outcome of recent slots
 
 	Number           uint64                      `json:"number"`             // Block number where the snapshot was created
 	Hash             common.Hash                 `json:"hash"`               // Block hash where the snapshot was created
 	Validators       map[common.Address]struct{} `json:"validators"`         // Set of authorized validators at this moment
 	Recents          map[uint64]common.Address   `json:"recents"`            // Set of recent validators for spam protections
 	RecentForkHashes map[uint64]string           `json:"recent_fork_hashes"` // Set of recent forkHash
+
+	VoteKeys        map[common.Address]types.BLSPublicKey `json:"vote_keys,omitempty"`        // BLS vote keys registered by the validators
+	Attestation     *types.VoteData                       `json:"attestation,omitempty"`      // Latest attested justification link
+	FinalizedNumber uint64                                `json:"finalized_number,omitempty"` // Number of the latest finalized block
+	FinalizedHash   common.Hash                           `json:"finalized_hash,omitempty"`   // Hash of the latest finalized block
 }
 
 // newSnapshot creates a new snapshot with the specified startup parameters. This
@@ -52,6 +57,7 @@ type Snapshot struct {
 func newSnapshot(
 	config *params.DposConfig,
 	sigCache *lru.ARCCache,
+	liveness *livenessTracker,
 	number uint64,
 	hash common.Hash,
 	validators []common.Address,
@@ -61,11 +67,13 @@ func newSnapshot(
 		config:           config,
 		ethAPI:           ethAPI,
 		sigCache:         sigCache,
+		liveness:         liveness,
 		Number:           number,
 		Hash:             hash,
 		Recents:          make(map[uint64]common.Address),
 		RecentForkHashes: make(map[uint64]string),
 		Validators:       make(map[common.Address]struct{}),
+		VoteKeys:         make(map[common.Address]types.BLSPublicKey),
 	}
 	for _, v := range validators {
 		snap.Validators[v] = struct{}{}
@@ -81,7 +89,7 @@ func (s validatorsAscending) Less(i, j int) bool { return bytes.Compare(s[i][:],
 func (s validatorsAscending) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 
 // loadSnapshot loads an existing snapshot from the database.
-func loadSnapshot(config *params.DposConfig, sigCache *lru.ARCCache, db ethdb.Database, hash common.Hash, ethAPI *ethapi.PublicBlockChainAPI) (*Snapshot, error) {
+func loadSnapshot(config *params.DposConfig, sigCache *lru.ARCCache, liveness *livenessTracker, db ethdb.Database, hash common.Hash, ethAPI *ethapi.PublicBlockChainAPI) (*Snapshot, error) {
 	blob, err := db.Get(append([]byte("dpos-"), hash[:]...))
 	if err != nil {
 		return nil, err
@@ -92,7 +100,11 @@ func loadSnapshot(config *params.DposConfig, sigCache *lru.ARCCache, db ethdb.Da
 	}
 	snap.config = config
 	snap.sigCache = sigCache
+	snap.liveness = liveness
 	snap.ethAPI = ethAPI
+	if snap.VoteKeys == nil {
+		snap.VoteKeys = make(map[common.Address]types.BLSPublicKey)
+	}
 
 	return snap, nil
 }
@@ -112,11 +124,15 @@ func (s *Snapshot) copy() *Snapshot {
 		config:           s.config,
 		ethAPI:           s.ethAPI,
 		sigCache:         s.sigCache,
+		liveness:         s.liveness,
 		Number:           s.Number,
 		Hash:             s.Hash,
 		Validators:       make(map[common.Address]struct{}),
 		Recents:          make(map[uint64]common.Address),
 		RecentForkHashes: make(map[uint64]string),
+		VoteKeys:         make(map[common.Address]types.BLSPublicKey),
+		FinalizedNumber:  s.FinalizedNumber,
+		FinalizedHash:    s.FinalizedHash,
 	}
 
 	for v := range s.Validators {
@@ -128,6 +144,13 @@ func (s *Snapshot) copy() *Snapshot {
 	for block, id := range s.RecentForkHashes {
 		cpy.RecentForkHashes[block] = id
 	}
+	for v, key := range s.VoteKeys {
+		cpy.VoteKeys[v] = key
+	}
+	if s.Attestation != nil {
+		data := *s.Attestation
+		cpy.Attestation = &data
+	}
 	return cpy
 }
 
@@ -141,7 +164,7 @@ func (s *Snapshot) isMajorityFork(forkHash string) bool {
 	return ally > len(s.RecentForkHashes)/2
 }
 
-func (s *Snapshot) apply(headers []*types.Header, chain consensus.ChainHeaderReader, parents []*types.Header, chainId *big.Int) (*Snapshot, error) {
+func (s *Snapshot) apply(headers []*types.Header, chain consensus.ChainHeaderReader, parents []*types.Header, chainConfig *params.ChainConfig) (*Snapshot, error) {
 	// Allow passing in no headers for cleaner code
 	if len(headers) == 0 {
 		return s, nil
@@ -166,6 +189,11 @@ func (s *Snapshot) apply(headers []*types.Header, chain consensus.ChainHeaderRea
 
 	for _, header := range headers {
 		number := header.Number.Uint64()
+		// Resolve the authorization key
+		validator, err := ecrecover(header, s.sigCache, chainConfig.ChainID)
+		if err != nil {
+			return nil, err
+		}
 		// Delete the oldest validator from the recent list to allow it signing again
 		if limit := uint64(len(snap.Validators)/2 + 1); number >= limit {
 			delete(snap.Recents, number-limit)
@@ -173,11 +201,7 @@ func (s *Snapshot) apply(headers []*types.Header, chain consensus.ChainHeaderRea
 		if limit := uint64(len(snap.Validators)); number >= limit {
 			delete(snap.RecentForkHashes, number-limit)
 		}
-		// Resolve the authorization key and check against signers
-		validator, err := ecrecover(header, s.sigCache, chainId)
-		if err != nil {
-			return nil, err
-		}
+		// Check the authorization key against signers
 		if _, ok := snap.Validators[validator]; !ok {
 			return nil, errUnauthorizedValidator
 		}
@@ -186,14 +210,23 @@ func (s *Snapshot) apply(headers []*types.Header, chain consensus.ChainHeaderRea
 				return nil, errRecentlySigned
 			}
 		}
+		// Record the sealing outcome of the slot now the header is known valid
+		snap.liveness.observe(header, validator, snap.validators(), snap.Recents)
 		snap.Recents[number] = validator
+		// Track the vote keys and the justified blocks before rotating the
+		// validator set, attestations being signed by the parent's validators
+		if err := snap.applyFinalityExtra(chainConfig, header, validator); err != nil {
+			return nil, err
+		}
 		if number > 0 && number%s.config.Epoch == 0 {
-			checkpointHeader := header
-
 			// get validators from headers and use that for new validator set
-			validators := make([]common.Address, (len(checkpointHeader.Extra)-extraVanity-extraSeal)/common.AddressLength)
-			for i := 0; i < len(validators); i++ {
-				copy(validators[i][:], checkpointHeader.Extra[extraVanity+i*common.AddressLength:])
+			checkpointBytes, err := validatorBytes(chainConfig, header)
+			if err != nil {
+				return nil, err
+			}
+			validators, err := ParseValidators(checkpointBytes)
+			if err != nil {
+				return nil, err
 			}
 
 			newValidators := make(map[common.Address]struct{})
@@ -256,6 +289,55 @@ func (s *Snapshot) apply(headers []*types.Header, chain consensus.ChainHeaderRea
 	//	return snap, nil
 }
 
+// applySimulated creates a new snapshot by applying the headers of simulated
+// blocks. These are unsigned, so their coinbase stands for the validator and is
+// checked neither against the validator set nor the recent signers. Liveness
+// and fast finality are not tracked.
+func (s *Snapshot) applySimulated(headers []*types.Header, chainConfig *params.ChainConfig) (*Snapshot, error) {
+	if len(headers) == 0 {
+		return s, nil
+	}
+	if headers[0].Number.Uint64() != s.Number+1 {
+		return nil, errOutOfRangeChain
+	}
+	snap := s.copy()
+
+	for _, header := range headers {
+		number := header.Number.Uint64()
+		if limit := uint64(len(snap.Validators)/2 + 1); number >= limit {
+			delete(snap.Recents, number-limit)
+		}
+		if limit := uint64(len(snap.Validators)); number >= limit {
+			delete(snap.RecentForkHashes, number-limit)
+		}
+		snap.Recents[number] = header.Coinbase
+
+		if number%s.config.Epoch == 0 {
+			checkpointBytes, err := validatorBytes(chainConfig, header)
+			if err != nil {
+				return nil, err
+			}
+			validators, err := ParseValidators(checkpointBytes)
+			if err != nil {
+				return nil, err
+			}
+			newValidators := make(map[common.Address]struct{})
+			for _, validator := range validators {
+				newValidators[validator] = struct{}{}
+			}
+			limit := uint64(len(newValidators)/2 + 1)
+			for i := 0; i < len(snap.Validators)/2-len(newValidators)/2; i++ {
+				delete(snap.Recents, number-limit-uint64(i))
+			}
+			snap.Validators = newValidators
+		}
+	}
+	snap.Number += uint64(len(headers))
+	snap.Hash = headers[len(headers)-1].Hash()
+
+	return snap, nil
+}
+
 // validators retrieves the list of validators in ascending order.
 func (s *Snapshot) validators() []common.Address {
 	validators := make([]common.Address, 0, len(s.Validators))
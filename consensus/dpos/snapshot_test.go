@@ -2,12 +2,16 @@ package dpos
 
 import (
 	"bytes"
+	"math/big"
 	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"PureChain/common"
+	"PureChain/core/rawdb"
+	"PureChain/core/types"
+	"PureChain/params"
 )
 
 func TestValidatorSetSort(t *testing.T) {
@@ -21,3 +25,66 @@ func TestValidatorSetSort(t *testing.T) {
 		assert.True(t, bytes.Compare(validators[i][:], validators[i+1][:]) < 0)
 	}
 }
+
+// headerOnlyChain is a header reader knowing only a fixed set of headers, like a
+// light client which started syncing from a trusted checkpoint.
+type headerOnlyChain struct {
+	config  *params.ChainConfig
+	headers map[uint64]*types.Header
+}
+
+func (c *headerOnlyChain) Config() *params.ChainConfig  { return c.config }
+func (c *headerOnlyChain) CurrentHeader() *types.Header { return nil }
+func (c *headerOnlyChain) GetHeaderByNumber(number uint64) *types.Header {
+	return c.headers[number]
+}
+func (c *headerOnlyChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.headers[number]; header != nil && header.Hash() == hash {
+		return header
+	}
+	return nil
+}
+func (c *headerOnlyChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	for _, header := range c.headers {
+		if header.Hash() == hash {
+			return header
+		}
+	}
+	return nil
+}
+
+func TestSnapshotFromTrustedCheckpoint(t *testing.T) {
+	config := &params.ChainConfig{ChainID: big.NewInt(1), Dpos: &params.DposConfig{Period: 3, Epoch: 100}}
+	engine := New(config, rawdb.NewMemoryDatabase(), nil, common.Hash{})
+
+	validators := []common.Address{randomAddress(), randomAddress(), randomAddress()}
+	sort.Sort(validatorsAscending(validators))
+
+	extra := make([]byte, extraVanity)
+	for _, val := range validators {
+		extra = append(extra, val.Bytes()...)
+	}
+	extra = append(extra, make([]byte, extraSeal)...)
+	checkpoint := &types.Header{Number: big.NewInt(300), Extra: extra, Difficulty: DiffInTurn}
+
+	// Epoch headers without ancestry are rejected unless trusted
+	chain := &headerOnlyChain{config: config, headers: map[uint64]*types.Header{300: checkpoint}}
+	_, err := engine.snapshot(chain, 300, checkpoint.Hash(), nil)
+	assert.Error(t, err)
+
+	// Trusting a different header at the same height must not help either
+	engine.TrustCheckpoint(&params.EngineCheckpoint{Number: 300, Hash: common.Hash{0x01}})
+	_, err = engine.snapshot(chain, 300, checkpoint.Hash(), nil)
+	assert.Error(t, err)
+
+	engine.TrustCheckpoint(&params.EngineCheckpoint{Number: 300, Hash: checkpoint.Hash()})
+	snap, err := engine.snapshot(chain, 300, checkpoint.Hash(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, validators, snap.validators())
+
+	// Non-epoch headers without ancestry must still be rejected
+	header := &types.Header{Number: big.NewInt(301), Extra: make([]byte, extraVanity+extraSeal), Difficulty: DiffInTurn}
+	chain.headers[301] = header
+	_, err = engine.snapshot(chain, 301, header.Hash(), nil)
+	assert.Error(t, err)
+}
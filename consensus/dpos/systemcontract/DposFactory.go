@@ -78,7 +78,7 @@ func (s *hardForkValidatorsV1) Execute(state *state.StateDB, header *types.Heade
 			return err
 		}
 
-		msg := types.NewMessage(header.Coinbase, &DposFactoryContractAddr, 0, new(big.Int), math.MaxUint64, new(big.Int), data, nil, false)
+		msg := types.NewMessage(header.Coinbase, &DposFactoryContractAddr, 0, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, false)
 		_, err = vmcaller.ExecuteMsg(msg, state, header, chainContext, config)
 
 		return*/
@@ -42,7 +42,7 @@ func (s *hardForkPunishV1) Update(config *params.ChainConfig, height *big.Int, s
 				return err
 			}
 
-			msg := types.NewMessage(header.Coinbase, &PunishV1ContractAddr, 0, new(big.Int), math.MaxUint64, new(big.Int), data, nil, false)
+			msg := types.NewMessage(header.Coinbase, &PunishV1ContractAddr, 0, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, false)
 			_, err = vmcaller.ExecuteMsg(msg, state, header, chainContext, config)
 
 			return
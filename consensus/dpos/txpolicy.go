@@ -0,0 +1,152 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"sync"
+
+	"PureChain/common"
+	"PureChain/consensus"
+	"PureChain/core"
+	"PureChain/core/state"
+	"PureChain/core/types"
+	"PureChain/log"
+)
+
+// TxPoolPolicy is a dpos aware transaction pool policy. On top of the default
+// policy it applies dedicated quotas to the accounts of the running PoR
+// providers, which are refreshed from the provider factory contract in the
+// background on every new chain head.
+type TxPoolPolicy struct {
+	*core.DefaultTxPoolPolicy
+
+	engine *Dpos
+	chain  consensus.ChainHeaderReader
+	config core.TxPoolPolicyConfig
+
+	providers  map[common.Address]struct{} // Owners of the running providers as of the last refreshed head
+	refresh    *providersRefresh           // Latest head waiting for the providers to be refreshed
+	refreshing bool                        // Whether a background refresh is running
+	lock       sync.RWMutex                // Protects the providers and the refresh state
+}
+
+// providersRefresh is a chain head to refresh the provider accounts at.
+type providersRefresh struct {
+	head    *types.Header
+	statedb *state.StateDB
+}
+
+// NewTxPoolPolicy creates a dpos aware transaction pool policy.
+func NewTxPoolPolicy(engine *Dpos, chain consensus.ChainHeaderReader, pool core.TxPoolConfig, config core.TxPoolPolicyConfig) *TxPoolPolicy {
+	return &TxPoolPolicy{
+		DefaultTxPoolPolicy: core.NewDefaultTxPoolPolicy(pool, config),
+		engine:              engine,
+		chain:               chain,
+		config:              config,
+		providers:           make(map[common.Address]struct{}),
+	}
+}
+
+// Reset implements core.TxPoolPolicy, scheduling the refresh of the provider
+// accounts. The providers are read from the chain outside of the pool lock, the
+// previously known ones being used until then or if the refresh fails. Heads
+// superseded before their refresh started are skipped.
+func (p *TxPoolPolicy) Reset(head *types.Header, statedb *state.StateDB) {
+	if p.config.ProviderSlots == 0 && p.config.ProviderQueue == 0 {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.refresh = &providersRefresh{head: head, statedb: statedb.Copy()}
+	if !p.refreshing {
+		p.refreshing = true
+		go p.refreshLoop()
+	}
+}
+
+// refreshLoop refreshes the provider accounts at the latest scheduled head until
+// no more refreshes are waiting.
+func (p *TxPoolPolicy) refreshLoop() {
+	for {
+		p.lock.Lock()
+		req := p.refresh
+		p.refresh = nil
+		if req == nil {
+			p.refreshing = false
+			p.lock.Unlock()
+			return
+		}
+		p.lock.Unlock()
+
+		infos, err := p.engine.getProviderInfoAt(p.chain, req.head, req.statedb, req.head.Coinbase)
+		if err != nil {
+			log.Debug("Failed to refresh txpool provider accounts", "number", req.head.Number, "err", err)
+			continue
+		}
+		providers := make(map[common.Address]struct{}, len(infos))
+		for _, info := range infos {
+			providers[info.ProviderAddress] = struct{}{}
+		}
+		p.lock.Lock()
+		p.providers = providers
+		p.lock.Unlock()
+	}
+}
+
+// ValidateTx implements core.TxPoolPolicy, enforcing the provider quotas on top
+// of the default rules. Like the default quotas, they don't apply to local
+// transactions.
+func (p *TxPoolPolicy) ValidateTx(tx *types.Transaction, from common.Address, local bool, known int) error {
+	if err := p.DefaultTxPoolPolicy.ValidateTx(tx, from, local, known); err != nil {
+		return err
+	}
+	if local || !p.isProvider(from) {
+		return nil
+	}
+	if uint64(known) >= p.AccountSlots(from)+p.AccountQueue(from) {
+		return core.ErrAccountQuota
+	}
+	return nil
+}
+
+// AccountSlots implements core.TxPoolPolicy, returning the provider quota for
+// provider accounts.
+func (p *TxPoolPolicy) AccountSlots(addr common.Address) uint64 {
+	if p.config.ProviderSlots != 0 && p.isProvider(addr) {
+		return p.config.ProviderSlots
+	}
+	return p.DefaultTxPoolPolicy.AccountSlots(addr)
+}
+
+// AccountQueue implements core.TxPoolPolicy, returning the provider quota for
+// provider accounts.
+func (p *TxPoolPolicy) AccountQueue(addr common.Address) uint64 {
+	if p.config.ProviderQueue != 0 && p.isProvider(addr) {
+		return p.config.ProviderQueue
+	}
+	return p.DefaultTxPoolPolicy.AccountQueue(addr)
+}
+
+// isProvider reports whether the account belongs to a running provider.
+func (p *TxPoolPolicy) isProvider(addr common.Address) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	_, ok := p.providers[addr]
+	return ok
+}
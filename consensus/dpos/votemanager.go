@@ -0,0 +1,180 @@
+package dpos
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"PureChain/common"
+	"PureChain/core"
+	"PureChain/core/types"
+	"PureChain/crypto/bls12381"
+	"PureChain/ethdb"
+	"PureChain/event"
+	"PureChain/log"
+)
+
+// voteStaleThreshold is the age in seconds above which a new head is not voted
+// for, as the node is most likely still syncing.
+const voteStaleThreshold = 30
+
+// voteRecord is the latest vote cast by a local validator, persisted so that a
+// restarted node never casts a conflicting vote.
+type voteRecord struct {
+	SourceNumber uint64 `json:"sourceNumber"`
+	TargetNumber uint64 `json:"targetNumber"`
+}
+
+// VoteManager casts the fast finality votes of a local validator for every new
+// chain head and puts them into the vote pool.
+type VoteManager struct {
+	chain  voteChain
+	engine *Dpos
+	pool   *VotePool
+	db     ethdb.Database
+
+	val common.Address
+	key *bls12381.SecretKey
+	pub types.BLSPublicKey
+
+	headCh  chan core.ChainHeadEvent
+	headSub event.Subscription
+	quit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewVoteManager creates a vote manager casting votes for validator val with
+// the BLS key, and registers the key with the engine.
+func NewVoteManager(chain voteChain, engine *Dpos, pool *VotePool, db ethdb.Database, val common.Address, key *bls12381.SecretKey) *VoteManager {
+	m := &VoteManager{
+		chain:  chain,
+		engine: engine,
+		pool:   pool,
+		db:     db,
+		val:    val,
+		key:    key,
+		headCh: make(chan core.ChainHeadEvent, chainHeadChanSize),
+		quit:   make(chan struct{}),
+	}
+	copy(m.pub[:], key.PublicKey())
+	engine.SetVoteKey(val, key)
+	m.headSub = chain.SubscribeChainHeadEvent(m.headCh)
+
+	m.wg.Add(1)
+	go m.loop()
+	return m
+}
+
+// Stop terminates the vote manager.
+func (m *VoteManager) Stop() {
+	close(m.quit)
+	m.wg.Wait()
+}
+
+func (m *VoteManager) loop() {
+	defer m.wg.Done()
+	defer m.headSub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-m.headCh:
+			m.vote(ev.Block.Header())
+		case <-m.headSub.Err():
+			return
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// vote casts a vote for the given head if the local validator is allowed to
+// attest it and the vote cannot conflict with a previous one.
+func (m *VoteManager) vote(head *types.Header) {
+	if !m.chain.Config().IsFastFinality(head.Number) {
+		return
+	}
+	if head.Time+voteStaleThreshold < uint64(time.Now().Unix()) {
+		return
+	}
+	snap, err := m.engine.snapshot(m.chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		log.Debug("Failed to retrieve snapshot for voting", "number", head.Number, "err", err)
+		return
+	}
+	if _, ok := snap.Validators[m.val]; !ok {
+		return
+	}
+	if key, ok := snap.VoteKeys[m.val]; !ok || key != m.pub {
+		return
+	}
+	source, sourceHash := m.engine.justified(snap)
+	data := &types.VoteData{
+		SourceNumber: source,
+		SourceHash:   sourceHash,
+		TargetNumber: head.Number.Uint64(),
+		TargetHash:   head.Hash(),
+	}
+	// Never vote twice for the same height nor surround a previous vote: both
+	// the source and the target of consecutive votes must be monotonic.
+	last := m.lastVote()
+	if last != nil && (data.TargetNumber <= last.TargetNumber || data.SourceNumber < last.SourceNumber) {
+		return
+	}
+	if err := m.storeVote(&voteRecord{SourceNumber: data.SourceNumber, TargetNumber: data.TargetNumber}); err != nil {
+		log.Warn("Failed to persist vote", "number", data.TargetNumber, "err", err)
+		return
+	}
+	hash := data.Hash()
+	vote := &types.VoteEnvelope{VoteAddress: m.pub, Data: data}
+	copy(vote.Signature[:], m.key.Sign(hash[:]))
+
+	if err := m.pool.PutVote(vote); err != nil {
+		log.Warn("Failed to add local vote", "number", data.TargetNumber, "err", err)
+		return
+	}
+	log.Debug("Voted for block", "number", data.TargetNumber, "hash", data.TargetHash, "source", data.SourceNumber)
+}
+
+// voteRecordKey returns the database key of the latest vote of the validator.
+func voteRecordKey(val common.Address) []byte {
+	return append([]byte("dpos-vote-"), val[:]...)
+}
+
+// lastVote loads the latest vote cast by the local validator, if any.
+func (m *VoteManager) lastVote() *voteRecord {
+	blob, err := m.db.Get(voteRecordKey(m.val))
+	if err != nil {
+		return nil
+	}
+	rec := new(voteRecord)
+	if err := json.Unmarshal(blob, rec); err != nil {
+		return nil
+	}
+	return rec
+}
+
+// storeVote persists the latest vote cast by the local validator.
+func (m *VoteManager) storeVote(rec *voteRecord) error {
+	blob, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return m.db.Put(voteRecordKey(m.val), blob)
+}
+
+// LoadVoteKey loads a BLS vote key from the given file, holding the secret key
+// as 64 hex characters.
+func LoadVoteKey(file string) (*bls12381.SecretKey, error) {
+	blob, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := hex.DecodeString(strings.TrimSpace(string(blob)))
+	if err != nil {
+		return nil, err
+	}
+	return bls12381.SecretKeyFromBytes(raw)
+}
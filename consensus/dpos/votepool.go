@@ -0,0 +1,279 @@
+package dpos
+
+import (
+	"errors"
+	"sync"
+
+	"PureChain/common"
+	"PureChain/consensus"
+	"PureChain/core"
+	"PureChain/core/types"
+	"PureChain/event"
+	"PureChain/log"
+)
+
+const (
+	voteKeepBlocks    = 256  // Number of blocks below the head whose votes are kept in the pool
+	voteFutureRange   = 16   // Number of blocks above the head votes are accepted for
+	voteFutureLimit   = 1024 // Maximum number of votes waiting for their target block
+	chainHeadChanSize = 10
+)
+
+var (
+	// ErrInvalidVote is returned if a vote is malformed or incorrectly signed.
+	// Peers relaying such votes are misbehaving.
+	ErrInvalidVote = errors.New("invalid vote")
+
+	// errVoteOutOfRange is returned if a vote targets a block too far from the
+	// current head.
+	errVoteOutOfRange = errors.New("vote target out of range")
+
+	// errUnknownVoteKey is returned if a vote is signed by a key not registered
+	// by any validator.
+	errUnknownVoteKey = errors.New("unknown vote key")
+
+	// errFutureVotesFull is returned if a vote targets an unknown block while
+	// the buffer of votes waiting for their target is full.
+	errFutureVotesFull = errors.New("future vote buffer full")
+)
+
+// voteChain is the subset of the blockchain the vote pool and manager need.
+type voteChain interface {
+	consensus.ChainHeaderReader
+
+	// SubscribeChainHeadEvent registers a subscription of ChainHeadEvent.
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// VotePool collects the fast finality votes of the validators, broadcast over
+// the network or cast locally, until they are aggregated into an attestation.
+type VotePool struct {
+	chain  voteChain
+	engine *Dpos
+
+	votes   map[common.Hash]map[common.Hash]*types.VoteEnvelope // Votes by target block hash and vote hash
+	targets map[common.Hash]uint64                              // Number of the voted target blocks
+
+	future      map[uint64]map[common.Hash]*types.VoteEnvelope // Votes waiting for their target block, by target number and vote hash
+	futureCount int                                            // Number of votes in the future buffer
+
+	voteFeed event.Feed
+	scope    event.SubscriptionScope
+
+	headCh  chan core.ChainHeadEvent
+	headSub event.Subscription
+	quit    chan struct{}
+	wg      sync.WaitGroup
+	lock    sync.RWMutex
+}
+
+// NewVotePool creates a vote pool on top of the given chain, pruning the votes
+// of old blocks as the chain progresses.
+func NewVotePool(chain voteChain, engine *Dpos) *VotePool {
+	pool := &VotePool{
+		chain:   chain,
+		engine:  engine,
+		votes:   make(map[common.Hash]map[common.Hash]*types.VoteEnvelope),
+		targets: make(map[common.Hash]uint64),
+		future:  make(map[uint64]map[common.Hash]*types.VoteEnvelope),
+		headCh:  make(chan core.ChainHeadEvent, chainHeadChanSize),
+		quit:    make(chan struct{}),
+	}
+	pool.headSub = chain.SubscribeChainHeadEvent(pool.headCh)
+
+	pool.wg.Add(1)
+	go pool.loop()
+	return pool
+}
+
+// loop prunes the votes falling out of the kept range and promotes the votes
+// whose target block arrived on every new head.
+func (pool *VotePool) loop() {
+	defer pool.wg.Done()
+	defer pool.headSub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-pool.headCh:
+			pool.prune(ev.Block.NumberU64())
+			pool.promote(ev.Block.NumberU64())
+		case <-pool.headSub.Err():
+			return
+		case <-pool.quit:
+			return
+		}
+	}
+}
+
+// Stop terminates the vote pool.
+func (pool *VotePool) Stop() {
+	pool.scope.Close()
+	close(pool.quit)
+	pool.wg.Wait()
+}
+
+// prune drops the votes targeting blocks too far below head.
+func (pool *VotePool) prune(head uint64) {
+	if head < voteKeepBlocks {
+		return
+	}
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	for hash, number := range pool.targets {
+		if number < head-voteKeepBlocks {
+			delete(pool.votes, hash)
+			delete(pool.targets, hash)
+		}
+	}
+}
+
+// promote moves the buffered votes whose target block became known into the
+// pool, dropping the ones whose target did not show up within the future range.
+func (pool *VotePool) promote(head uint64) {
+	pool.lock.Lock()
+	var ready []*types.VoteEnvelope
+	for number, votes := range pool.future {
+		if number > head {
+			continue
+		}
+		for hash, vote := range votes {
+			if pool.chain.GetHeader(vote.Data.TargetHash, number) == nil && number+voteFutureRange > head {
+				continue
+			}
+			ready = append(ready, vote)
+			delete(votes, hash)
+			pool.futureCount--
+		}
+		if len(votes) == 0 {
+			delete(pool.future, number)
+		}
+	}
+	pool.lock.Unlock()
+
+	for _, vote := range ready {
+		if err := pool.PutVote(vote); err != nil {
+			log.Trace("Discarded future vote", "target", vote.Data.TargetNumber, "hash", vote.Data.TargetHash, "err", err)
+		}
+	}
+}
+
+// PutVote validates a vote and adds it to the pool, announcing it to the
+// subscribers if it was not known yet. Votes arriving ahead of their target
+// block are buffered until the block is known.
+func (pool *VotePool) PutVote(vote *types.VoteEnvelope) error {
+	if vote.Data == nil {
+		return ErrInvalidVote
+	}
+	head := pool.chain.CurrentHeader()
+	target := vote.Data.TargetNumber
+	if target+voteKeepBlocks < head.Number.Uint64() || target > head.Number.Uint64()+voteFutureRange {
+		return errVoteOutOfRange
+	}
+	hash := vote.Hash()
+
+	pool.lock.RLock()
+	_, known := pool.votes[vote.Data.TargetHash][hash]
+	pool.lock.RUnlock()
+	if known {
+		return nil
+	}
+	// Check the key against the validators attesting the target, buffering the
+	// votes arriving ahead of their block. Their signature is verified upfront
+	// so that invalid votes cannot crowd out the buffer.
+	header := pool.chain.GetHeader(vote.Data.TargetHash, target)
+	if header == nil {
+		if target+voteFutureRange <= head.Number.Uint64() {
+			return errVoteOutOfRange
+		}
+		if err := vote.Verify(); err != nil {
+			return ErrInvalidVote
+		}
+		return pool.putFuture(vote)
+	}
+	snap, err := pool.engine.snapshot(pool.chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return err
+	}
+	registered := false
+	for val, key := range snap.VoteKeys {
+		if _, ok := snap.Validators[val]; ok && key == vote.VoteAddress {
+			registered = true
+			break
+		}
+	}
+	if !registered {
+		return errUnknownVoteKey
+	}
+	if err := vote.Verify(); err != nil {
+		return ErrInvalidVote
+	}
+	pool.lock.Lock()
+	if pool.votes[vote.Data.TargetHash] == nil {
+		pool.votes[vote.Data.TargetHash] = make(map[common.Hash]*types.VoteEnvelope)
+		pool.targets[vote.Data.TargetHash] = target
+	}
+	if _, known = pool.votes[vote.Data.TargetHash][hash]; !known {
+		pool.votes[vote.Data.TargetHash][hash] = vote
+	}
+	pool.lock.Unlock()
+
+	if !known {
+		log.Trace("Added vote to pool", "target", target, "hash", vote.Data.TargetHash, "voter", vote.VoteAddress)
+		pool.voteFeed.Send(core.NewVoteEvent{Vote: vote})
+	}
+	return nil
+}
+
+// putFuture buffers a vote until its target block is known.
+func (pool *VotePool) putFuture(vote *types.VoteEnvelope) error {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	number, hash := vote.Data.TargetNumber, vote.Hash()
+	if _, known := pool.future[number][hash]; known {
+		return nil
+	}
+	if pool.futureCount >= voteFutureLimit {
+		return errFutureVotesFull
+	}
+	if pool.future[number] == nil {
+		pool.future[number] = make(map[common.Hash]*types.VoteEnvelope)
+	}
+	pool.future[number][hash] = vote
+	pool.futureCount++
+
+	log.Trace("Buffered future vote", "target", number, "hash", vote.Data.TargetHash, "voter", vote.VoteAddress)
+	return nil
+}
+
+// FetchVotes retrieves the votes targeting the given block.
+func (pool *VotePool) FetchVotes(hash common.Hash) []*types.VoteEnvelope {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	votes := make([]*types.VoteEnvelope, 0, len(pool.votes[hash]))
+	for _, vote := range pool.votes[hash] {
+		votes = append(votes, vote)
+	}
+	return votes
+}
+
+// GetVotes retrieves all the votes in the pool.
+func (pool *VotePool) GetVotes() []*types.VoteEnvelope {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	var votes []*types.VoteEnvelope
+	for _, byHash := range pool.votes {
+		for _, vote := range byHash {
+			votes = append(votes, vote)
+		}
+	}
+	return votes
+}
+
+// SubscribeNewVoteEvent registers a subscription of NewVoteEvent.
+func (pool *VotePool) SubscribeNewVoteEvent(ch chan<- core.NewVoteEvent) event.Subscription {
+	return pool.scope.Track(pool.voteFeed.Subscribe(ch))
+}
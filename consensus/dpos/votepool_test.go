@@ -0,0 +1,133 @@
+package dpos
+
+import (
+	"crypto/rand"
+	"math/big"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"PureChain/common"
+	"PureChain/core"
+	"PureChain/core/rawdb"
+	"PureChain/core/types"
+	"PureChain/crypto/bls12381"
+	"PureChain/event"
+	"PureChain/params"
+)
+
+// testVoteChain is a header-only chain announcing its head to the vote pool.
+type testVoteChain struct {
+	*headerOnlyChain
+	head *types.Header
+	feed event.Feed
+}
+
+func (c *testVoteChain) CurrentHeader() *types.Header { return c.head }
+func (c *testVoteChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return c.feed.Subscribe(ch)
+}
+
+// setHead adds a header to the chain, with the validators of the snapshot
+// known to the engine, and announces it as the new head.
+func (c *testVoteChain) setHead(engine *Dpos, header *types.Header, snap *Snapshot) {
+	c.headers[header.Number.Uint64()] = header
+	c.head = header
+
+	snap = snap.copy()
+	snap.Number, snap.Hash = header.Number.Uint64(), header.Hash()
+	engine.recentSnaps.Add(snap.Hash, snap)
+
+	c.feed.Send(core.ChainHeadEvent{Block: types.NewBlockWithHeader(header)})
+}
+
+func TestVotePoolFutureVotes(t *testing.T) {
+	config := &params.ChainConfig{ChainID: big.NewInt(1), Dpos: &params.DposConfig{Period: 3, Epoch: 200}}
+	engine := New(config, rawdb.NewMemoryDatabase(), nil, common.Hash{})
+
+	validators := []common.Address{randomAddress(), randomAddress(), randomAddress()}
+	sort.Sort(validatorsAscending(validators))
+
+	snap := newSnapshot(config.Dpos, engine.signatures, nil, 0, common.Hash{}, validators, nil)
+	keys := make(map[common.Address]*bls12381.SecretKey)
+	for _, val := range validators {
+		key, err := bls12381.GenerateKey(rand.Reader)
+		assert.NoError(t, err)
+		keys[val] = key
+
+		var pub types.BLSPublicKey
+		copy(pub[:], key.PublicKey())
+		snap.VoteKeys[val] = pub
+	}
+	sign := func(val common.Address, target *types.Header) *types.VoteEnvelope {
+		vote := &types.VoteEnvelope{VoteAddress: snap.VoteKeys[val], Data: &types.VoteData{
+			TargetNumber: target.Number.Uint64(),
+			TargetHash:   target.Hash(),
+		}}
+		hash := vote.Data.Hash()
+		copy(vote.Signature[:], keys[val].Sign(hash[:]))
+		return vote
+	}
+	chain := &testVoteChain{headerOnlyChain: &headerOnlyChain{config: config, headers: make(map[uint64]*types.Header)}}
+	chain.setHead(engine, &types.Header{Number: big.NewInt(100)}, snap)
+
+	pool := NewVotePool(chain, engine)
+	defer pool.Stop()
+
+	// Votes for the head are pooled right away
+	vote := sign(validators[0], chain.head)
+	assert.NoError(t, pool.PutVote(vote))
+	assert.Len(t, pool.FetchVotes(chain.head.Hash()), 1)
+
+	// Badly signed votes are rejected as invalid, known or not
+	bad := sign(validators[1], chain.head)
+	bad.Signature[0] ^= 0xff
+	assert.Equal(t, ErrInvalidVote, pool.PutVote(bad))
+
+	next := &types.Header{Number: big.NewInt(101), ParentHash: chain.head.Hash()}
+	bad = sign(validators[1], next)
+	bad.Signature[0] ^= 0xff
+	assert.Equal(t, ErrInvalidVote, pool.PutVote(bad))
+
+	// Votes ahead of their block wait for it in the future buffer
+	future := sign(validators[1], next)
+	assert.NoError(t, pool.PutVote(future))
+	assert.Empty(t, pool.FetchVotes(next.Hash()))
+
+	chain.setHead(engine, next, snap)
+	for start := time.Now(); len(pool.FetchVotes(next.Hash())) == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("future vote not promoted")
+		}
+	}
+	assert.Equal(t, future.Hash(), pool.FetchVotes(next.Hash())[0].Hash())
+
+	// The future buffer is bounded
+	pool.lock.Lock()
+	pool.futureCount = voteFutureLimit
+	pool.lock.Unlock()
+	assert.Equal(t, errFutureVotesFull, pool.PutVote(sign(validators[2], &types.Header{Number: big.NewInt(102), ParentHash: next.Hash()})))
+}
+
+func TestAttestationValidatorLimit(t *testing.T) {
+	validators := make([]common.Address, types.MaxAttestedValidators+1)
+	for i := range validators {
+		validators[i] = randomAddress()
+	}
+	sort.Sort(validatorsAscending(validators))
+
+	snap := newSnapshot(&params.DposConfig{Epoch: 200}, nil, nil, 10, common.Hash{0xa}, validators, nil)
+	engine := &Dpos{chainConfig: &params.ChainConfig{FastFinalityBlock: big.NewInt(0)}}
+	parent := &types.Header{Number: big.NewInt(10)}
+
+	number, source := engine.justified(snap)
+	att := &types.VoteAttestation{
+		VoteAddressSet: ^types.ValidatorsBitSet(0),
+		Data:           &types.VoteData{SourceNumber: number, SourceHash: source, TargetNumber: 10, TargetHash: parent.Hash()},
+	}
+	assert.Equal(t, errTooManyAttesters, engine.verifyAttestation(att, parent, snap))
+	assert.Nil(t, engine.aggregateVotes(snap, parent.Hash(), nil))
+	assert.Panics(t, func() { att.VoteAddressSet.Set(types.MaxAttestedValidators) })
+}
@@ -29,6 +29,7 @@ import (
 	"PureChain/common/math"
 	"PureChain/consensus"
 	"PureChain/consensus/misc"
+	"PureChain/core/rawdb"
 	"PureChain/core/state"
 	"PureChain/core/types"
 	"PureChain/params"
@@ -291,6 +292,10 @@ func (ethash *Ethash) verifyHeader(chain consensus.ChainHeaderReader, header, pa
 	if uint64(diff) >= limit || header.GasLimit < params.MinGasLimit {
 		return fmt.Errorf("invalid gas limit: have %d, want %d += %d", header.GasLimit, parent.GasLimit, limit)
 	}
+	// Verify the base fee against the parent
+	if err := misc.VerifyEip1559Header(chain.Config(), parent, header); err != nil {
+		return err
+	}
 	// Verify that the block number is parent's +1
 	if diff := new(big.Int).Sub(header.Number, parent.Number); diff.Cmp(big.NewInt(1)) != 0 {
 		return consensus.ErrInvalidNumber
@@ -577,12 +582,23 @@ func (ethash *Ethash) Prepare(chain consensus.ChainHeaderReader, header *types.H
 	return nil
 }
 
+// BlockRewards implements consensus.RewardReporter, reporting the block and
+// uncle rewards credited to the miners.
+func (ethash *Ethash) BlockRewards(chain consensus.ChainHeaderReader, header *types.Header, uncles []*types.Header, tracer consensus.BalanceTracer) error {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	if err != nil {
+		return err
+	}
+	accumulateRewards(chain.Config(), statedb, header, uncles, tracer)
+	return nil
+}
+
 // Finalize implements consensus.Engine, accumulating the block and uncle rewards,
 // setting the final state on the header
 func (ethash *Ethash) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs *[]*types.Transaction, uncles []*types.Header,
 	receipts *[]*types.Receipt, _ *[]*types.Transaction, _ *uint64, _ bool) (err error) {
 	// Accumulate any block and uncle rewards and commit the final state root
-	accumulateRewards(chain.Config(), state, header, uncles)
+	accumulateRewards(chain.Config(), state, header, uncles, consensus.BalanceTracerOf(chain))
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
 	return
 }
@@ -606,7 +622,7 @@ func (ethash *Ethash) Delay(_ consensus.ChainReader, _ *types.Header) *time.Dura
 func (ethash *Ethash) SealHash(header *types.Header) (hash common.Hash) {
 	hasher := sha3.NewLegacyKeccak256()
 
-	rlp.Encode(hasher, []interface{}{
+	enc := []interface{}{
 		header.ParentHash,
 		header.UncleHash,
 		header.Coinbase,
@@ -620,7 +636,11 @@ func (ethash *Ethash) SealHash(header *types.Header) (hash common.Hash) {
 		header.GasUsed,
 		header.Time,
 		header.Extra,
-	})
+	}
+	if header.BaseFee != nil {
+		enc = append(enc, header.BaseFee)
+	}
+	rlp.Encode(hasher, enc)
 	hasher.Sum(hash[:0])
 	return hash
 }
@@ -633,8 +653,9 @@ var (
 
 // AccumulateRewards credits the coinbase of the given block with the mining
 // reward. The total reward consists of the static block reward and rewards for
-// included uncles. The coinbase of each uncle block is also rewarded.
-func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header, uncles []*types.Header) {
+// included uncles. The coinbase of each uncle block is also rewarded. The
+// rewards are reported to the tracer if it is non-nil.
+func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header, uncles []*types.Header, tracer consensus.BalanceTracer) {
 	// Skip block reward in catalyst mode
 	if config.IsCatalyst(header.Number) {
 		return
@@ -656,9 +677,15 @@ func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header
 		r.Mul(r, blockReward)
 		r.Div(r, big8)
 		state.AddBalance(uncle.Coinbase, r)
+		if tracer != nil {
+			tracer.CaptureBalanceChange(consensus.BalanceChangeUncleReward, common.Address{}, uncle.Coinbase, new(big.Int).Set(r))
+		}
 
 		r.Div(blockReward, big32)
 		reward.Add(reward, r)
 	}
 	state.AddBalance(header.Coinbase, reward)
+	if tracer != nil {
+		tracer.CaptureBalanceChange(consensus.BalanceChangeReward, common.Address{}, header.Coinbase, new(big.Int).Set(reward))
+	}
 }
@@ -18,6 +18,7 @@ package inihash
 
 import (
 	"PureChain/crypto/versaHash"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"github.com/shopspring/decimal"
@@ -30,6 +31,7 @@ import (
 	"PureChain/common/gopool"
 	"PureChain/consensus"
 	"PureChain/consensus/misc"
+	"PureChain/core/rawdb"
 	"PureChain/core/state"
 	"PureChain/core/types"
 	"PureChain/params"
@@ -93,12 +95,67 @@ func (inihash *Inihash) VerifyHeader(chain consensus.ChainHeaderReader, header *
 	}
 	parent := chain.GetHeader(header.ParentHash, number-1)
 	if parent == nil {
+		// Trusted checkpoints are verified without their ancestry, checking the
+		// VersaHash seal alone
+		if inihash.trustedCheckpoint(number, header.Hash()) {
+			return inihash.verifySeal(chain, header, false)
+		}
 		return consensus.ErrUnknownAncestor
 	}
 	// Sanity checks passed, do a proper verification
 	return inihash.verifyHeader(chain, header, parent, false, seal, time.Now().Unix())
 }
 
+// TrustCheckpoint implements consensus.CheckpointTruster, accepting the given
+// header without its parent, e.g. as the first header of a light client. The
+// checkpoint is persisted, so it's still trusted after a restart.
+func (inihash *Inihash) TrustCheckpoint(checkpoint *params.EngineCheckpoint) {
+	inihash.checkpointsLock.Lock()
+	defer inihash.checkpointsLock.Unlock()
+
+	if inihash.checkpoints == nil {
+		inihash.checkpoints = make(map[common.Hash]uint64)
+	}
+	inihash.checkpoints[checkpoint.Hash] = checkpoint.Number
+
+	if inihash.db != nil {
+		enc := make([]byte, 8)
+		binary.BigEndian.PutUint64(enc, checkpoint.Number)
+		if err := inihash.db.Put(append(checkpointPrefix, checkpoint.Hash[:]...), enc); err != nil {
+			inihash.config.Log.Error("Failed to store trusted checkpoint", "number", checkpoint.Number, "hash", checkpoint.Hash, "err", err)
+		}
+	}
+}
+
+// loadCheckpoints loads the trusted checkpoints persisted in the database.
+func (inihash *Inihash) loadCheckpoints() {
+	inihash.checkpointsLock.Lock()
+	defer inihash.checkpointsLock.Unlock()
+
+	it := inihash.db.NewIterator(checkpointPrefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		key, val := it.Key(), it.Value()
+		if len(key) != len(checkpointPrefix)+common.HashLength || len(val) != 8 {
+			continue
+		}
+		if inihash.checkpoints == nil {
+			inihash.checkpoints = make(map[common.Hash]uint64)
+		}
+		inihash.checkpoints[common.BytesToHash(key[len(checkpointPrefix):])] = binary.BigEndian.Uint64(val)
+	}
+}
+
+// trustedCheckpoint reports whether the given header is a trusted checkpoint.
+func (inihash *Inihash) trustedCheckpoint(number uint64, hash common.Hash) bool {
+	inihash.checkpointsLock.RLock()
+	defer inihash.checkpointsLock.RUnlock()
+
+	trusted, ok := inihash.checkpoints[hash]
+	return ok && trusted == number
+}
+
 // VerifyHeaders is similar to VerifyHeader, but verifies a batch of headers
 // concurrently. The method returns a quit channel to abort the operations and
 // a results channel to retrieve the async verifications.
@@ -171,6 +228,9 @@ func (inihash *Inihash) verifyHeaderWorker(chain consensus.ChainHeaderReader, he
 		parent = headers[index-1]
 	}
 	if parent == nil {
+		if index == 0 && inihash.trustedCheckpoint(headers[0].Number.Uint64(), headers[0].Hash()) {
+			return inihash.verifySeal(chain, headers[0], false)
+		}
 		return consensus.ErrUnknownAncestor
 	}
 	return inihash.verifyHeader(chain, headers[index], parent, false, seals[index], unixNow)
@@ -282,6 +342,10 @@ func (inihash *Inihash) verifyHeader(chain consensus.ChainHeaderReader, header,
 	if uint64(diff) >= limit || header.GasLimit < params.MinGasLimit {
 		return fmt.Errorf("invalid gas limit: have %d, want %d += %d", header.GasLimit, parent.GasLimit, limit)
 	}
+	// Verify the base fee against the parent
+	if err := misc.VerifyEip1559Header(chain.Config(), parent, header); err != nil {
+		return err
+	}
 	// Verify that the block number is parent's +1
 	if diff := new(big.Int).Sub(header.Number, parent.Number); diff.Cmp(big.NewInt(1)) != 0 {
 		return consensus.ErrInvalidNumber
@@ -421,12 +485,23 @@ func (inihash *Inihash) Prepare(chain consensus.ChainHeaderReader, header *types
 	return nil
 }
 
+// BlockRewards implements consensus.RewardReporter, reporting the block and
+// uncle rewards credited to the miners.
+func (inihash *Inihash) BlockRewards(chain consensus.ChainHeaderReader, header *types.Header, uncles []*types.Header, tracer consensus.BalanceTracer) error {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	if err != nil {
+		return err
+	}
+	accumulateRewards(chain.Config(), statedb, header, uncles, tracer)
+	return nil
+}
+
 // Finalize implements consensus.Engine, accumulating the block and uncle rewards,
 // setting the final state on the header
 func (inihash *Inihash) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs *[]*types.Transaction, uncles []*types.Header,
 	receipts *[]*types.Receipt, _ *[]*types.Transaction, _ *uint64, _ bool) (err error) {
 	// Accumulate any block and uncle rewards and commit the final state root
-	accumulateRewards(chain.Config(), state, header, uncles)
+	accumulateRewards(chain.Config(), state, header, uncles, consensus.BalanceTracerOf(chain))
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
 	return
 }
@@ -450,7 +525,7 @@ func (inihash *Inihash) Delay(_ consensus.ChainReader, _ *types.Header) *time.Du
 func (inihash *Inihash) SealHash(header *types.Header) (hash common.Hash) {
 	hasher := sha3.NewLegacyKeccak256()
 
-	rlp.Encode(hasher, []interface{}{
+	enc := []interface{}{
 		header.ParentHash,
 		header.UncleHash,
 		header.Coinbase,
@@ -468,7 +543,11 @@ func (inihash *Inihash) SealHash(header *types.Header) (hash common.Hash) {
 		header.TeamAddress,
 		header.ValidatorRate,
 		header.TeamRate,
-	})
+	}
+	if header.BaseFee != nil {
+		enc = append(enc, header.BaseFee)
+	}
+	rlp.Encode(hasher, enc)
 
 	hasher.Sum(hash[:0])
 	return hash
@@ -494,8 +573,9 @@ var (
 
 // AccumulateRewards credits the coinbase of the given block with the mining
 // reward. The total reward consists of the static block reward and rewards for
-// included uncles. The coinbase of each uncle block is also rewarded.
-func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header, uncles []*types.Header) {
+// included uncles. The coinbase of each uncle block is also rewarded. The
+// rewards are reported to the tracer if it is non-nil.
+func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header, uncles []*types.Header, tracer consensus.BalanceTracer) {
 	// Skip block reward in catalyst mode
 	if config.IsCatalyst(header.Number) {
 		return
@@ -518,6 +598,9 @@ func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header
 		r.Mul(r, blockReward)
 		r.Div(r, big8)
 		state.AddBalance(uncle.Coinbase, r)
+		if tracer != nil {
+			tracer.CaptureBalanceChange(consensus.BalanceChangeUncleReward, common.Address{}, uncle.Coinbase, new(big.Int).Set(r))
+		}
 
 		r.Div(blockReward, big32)
 		reward.Add(reward, r)
@@ -526,5 +609,8 @@ func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header
 	//TeamReward := big.NewInt(0).Div(big.NewInt(0).Mul(reward, big1), big10)
 
 	state.AddBalance(header.Coinbase, PersonalReward)
+	if tracer != nil {
+		tracer.CaptureBalanceChange(consensus.BalanceChangeReward, common.Address{}, header.Coinbase, new(big.Int).Set(PersonalReward))
+	}
 	//state.AddBalance(header.TeamAddress, TeamReward)
 }
@@ -25,7 +25,9 @@ import (
 	"path/filepath"
 	"testing"
 
+	"PureChain/common"
 	"PureChain/common/math"
+	"PureChain/core/rawdb"
 	"PureChain/core/types"
 	"PureChain/params"
 )
@@ -87,6 +89,29 @@ func TestCalcDifficulty(t *testing.T) {
 	}
 }
 
+// Tests that the trusted checkpoints are persisted and reloaded on restart.
+func TestTrustedCheckpointPersistence(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	checkpoint := &params.EngineCheckpoint{Number: 300, Hash: common.Hash{0x01}}
+
+	engine := New(Config{PowMode: ModeTest}, nil, false, nil, db)
+	if engine.trustedCheckpoint(checkpoint.Number, checkpoint.Hash) {
+		t.Fatalf("checkpoint trusted before being added")
+	}
+	engine.TrustCheckpoint(checkpoint)
+	engine.Close()
+
+	engine = New(Config{PowMode: ModeTest}, nil, false, nil, db)
+	defer engine.Close()
+
+	if !engine.trustedCheckpoint(checkpoint.Number, checkpoint.Hash) {
+		t.Errorf("checkpoint not trusted after restart")
+	}
+	if engine.trustedCheckpoint(checkpoint.Number+1, checkpoint.Hash) {
+		t.Errorf("checkpoint trusted at a different number")
+	}
+}
+
 func randSlice(min, max uint32) []byte {
 	var b = make([]byte, 4)
 	rand.Read(b)
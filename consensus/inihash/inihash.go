@@ -25,7 +25,9 @@ import (
 	"time"
 	"unsafe"
 
+	"PureChain/common"
 	"PureChain/consensus"
+	"PureChain/ethdb"
 	"PureChain/log"
 	"PureChain/metrics"
 	"PureChain/rpc"
@@ -46,6 +48,9 @@ var (
 
 	// dumpMagic is a dataset dump header to sanity check a data dump.
 	dumpMagic = []uint32{0xbaddcafe, 0xfee1dead}
+
+	// checkpointPrefix + hash -> number (uint64 big endian) of a trusted checkpoint.
+	checkpointPrefix = []byte("inihash-checkpoint-")
 )
 
 func init() {
@@ -54,7 +59,7 @@ func init() {
 		CachesInMem:   3,
 		DatasetsInMem: 1,
 	}
-	sharedEthash = New(sharedConfig, nil, false, nil)
+	sharedEthash = New(sharedConfig, nil, false, nil, nil)
 }
 
 // isLittleEndian returns whether the local system is running in little or big
@@ -158,6 +163,10 @@ type Inihash struct {
 	hashrate metrics.Meter // Meter tracking the average hashrate
 	remote   *remoteSealer
 
+	db              ethdb.Database         // Database to persist the trusted checkpoints into
+	checkpoints     map[common.Hash]uint64 // Trusted headers verified without their ancestry
+	checkpointsLock sync.RWMutex           // Protects the trusted checkpoints
+
 	// The fields below are hooks for testing
 	shared    *Inihash      // Shared PoW verifier to avoid cache regeneration
 	fakeFail  uint64        // Block number which fails PoW check even in fake mode
@@ -169,8 +178,8 @@ type Inihash struct {
 
 // New creates a full sized inihash PoW scheme and starts a background thread for
 // remote mining, also optionally notifying a batch of remote services of new work
-// packages.
-func New(config Config, notify []string, noverify bool, chainId *big.Int) *Inihash {
+// packages. The trusted checkpoints are persisted into the database, if any.
+func New(config Config, notify []string, noverify bool, chainId *big.Int, db ethdb.Database) *Inihash {
 	if config.Log == nil {
 		config.Log = log.Root()
 	}
@@ -189,6 +198,10 @@ func New(config Config, notify []string, noverify bool, chainId *big.Int) *Iniha
 		update:   make(chan struct{}),
 		hashrate: metrics.NewMeterForced(),
 		chainId:  chainId,
+		db:       db,
+	}
+	if db != nil {
+		ethash.loadCheckpoints()
 	}
 	if config.PowMode == ModeShared {
 		ethash.shared = sharedEthash
@@ -200,7 +213,7 @@ func New(config Config, notify []string, noverify bool, chainId *big.Int) *Iniha
 // NewTester creates a small sized inihash PoW scheme useful only for testing
 // purposes.
 func NewTester(notify []string, noverify bool) *Inihash {
-	return New(Config{PowMode: ModeTest}, notify, noverify, nil)
+	return New(Config{PowMode: ModeTest}, notify, noverify, nil, nil)
 }
 
 // NewFaker creates a inihash consensus engine with a fake PoW scheme that accepts
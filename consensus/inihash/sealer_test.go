@@ -98,7 +98,7 @@ func TestRemoteNotifyFull(t *testing.T) {
 		NotifyFull: true,
 		Log:        testlog.Logger(t, log.LvlWarn),
 	}
-	ethash := New(config, []string{server.URL}, false, nil)
+	ethash := New(config, []string{server.URL}, false, nil, nil)
 	defer ethash.Close()
 
 	// Stream a work task and ensure the notification bubbles out.
@@ -188,7 +188,7 @@ func TestRemoteMultiNotifyFull(t *testing.T) {
 		NotifyFull: true,
 		Log:        testlog.Logger(t, log.LvlWarn),
 	}
-	ethash := New(config, []string{server.URL}, false, nil)
+	ethash := New(config, []string{server.URL}, false, nil, nil)
 	defer ethash.Close()
 
 	// Provide a results reader.
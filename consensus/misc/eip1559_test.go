@@ -0,0 +1,82 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package misc
+
+import (
+	"math/big"
+	"testing"
+
+	"PureChain/core/types"
+	"PureChain/params"
+)
+
+// londonConfig returns a chain config with London active from genesis.
+func londonConfig() *params.ChainConfig {
+	config := *params.TestChainConfig
+	config.LondonBlock = big.NewInt(0)
+	return &config
+}
+
+// TestBlockElasticity tests that the base fee is only verified after London.
+func TestBlockElasticity(t *testing.T) {
+	initial := new(big.Int).SetUint64(params.InitialBaseFee)
+	parent := &types.Header{Number: big.NewInt(1), GasLimit: 20000000, GasUsed: 10000000, BaseFee: initial}
+
+	// Headers before London must not carry a base fee
+	if err := VerifyEip1559Header(params.TestChainConfig, parent, &types.Header{Number: big.NewInt(2), BaseFee: initial}); err == nil {
+		t.Errorf("pre-London header with base fee accepted")
+	}
+	if err := VerifyEip1559Header(params.TestChainConfig, parent, &types.Header{Number: big.NewInt(2)}); err != nil {
+		t.Errorf("pre-London header rejected: %v", err)
+	}
+	// Headers after London must carry the expected base fee
+	config := londonConfig()
+	if err := VerifyEip1559Header(config, parent, &types.Header{Number: big.NewInt(2)}); err == nil {
+		t.Errorf("London header without base fee accepted")
+	}
+	if err := VerifyEip1559Header(config, parent, &types.Header{Number: big.NewInt(2), BaseFee: big.NewInt(1)}); err == nil {
+		t.Errorf("London header with invalid base fee accepted")
+	}
+	if err := VerifyEip1559Header(config, parent, &types.Header{Number: big.NewInt(2), BaseFee: initial}); err != nil {
+		t.Errorf("London header rejected: %v", err)
+	}
+}
+
+// TestCalcBaseFee assumes all blocks are 1559-blocks
+func TestCalcBaseFee(t *testing.T) {
+	tests := []struct {
+		parentBaseFee   int64
+		parentGasLimit  uint64
+		parentGasUsed   uint64
+		expectedBaseFee int64
+	}{
+		{params.InitialBaseFee, 20000000, 10000000, params.InitialBaseFee}, // usage == target
+		{params.InitialBaseFee, 20000000, 9000000, 987500000},              // usage below target
+		{params.InitialBaseFee, 20000000, 11000000, 1012500000},            // usage above target
+	}
+	for i, test := range tests {
+		parent := &types.Header{
+			Number:   big.NewInt(32),
+			GasLimit: test.parentGasLimit,
+			GasUsed:  test.parentGasUsed,
+			BaseFee:  big.NewInt(test.parentBaseFee),
+		}
+		if have, want := CalcBaseFee(londonConfig(), parent), big.NewInt(test.expectedBaseFee); have.Cmp(want) != 0 {
+			t.Errorf("test %d: have %d, want %d", i, have, want)
+		}
+	}
+}
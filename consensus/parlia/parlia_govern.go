@@ -37,7 +37,7 @@ func (c *Parlia) getPassedProposalCount(chain consensus.ChainHeaderReader, heade
 		return 0, err
 	}
 
-	msg := types.NewMessage(header.Coinbase, &systemcontract.SysGovContractAddr, 0, new(big.Int), math.MaxUint64, new(big.Int), data, nil, false)
+	msg := types.NewMessage(header.Coinbase, &systemcontract.SysGovContractAddr, 0, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, false)
 
 	// use parent
 	result, err := vmcaller.ExecuteMsg(msg, state, header, newChainContext(chain, c), c.chainConfig)
@@ -70,7 +70,7 @@ func (c *Parlia) getPassedProposalByIndex(chain consensus.ChainHeaderReader, hea
 		return nil, err
 	}
 
-	msg := types.NewMessage(header.Coinbase, &systemcontract.SysGovContractAddr, 0, new(big.Int), math.MaxUint64, new(big.Int), data, nil, false)
+	msg := types.NewMessage(header.Coinbase, &systemcontract.SysGovContractAddr, 0, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, false)
 
 	// use parent
 	result, err := vmcaller.ExecuteMsg(msg, state, header, newChainContext(chain, c), c.chainConfig)
@@ -97,7 +97,7 @@ func (c *Parlia) finishProposalById(chain consensus.ChainHeaderReader, header *t
 		return err
 	}
 
-	msg := types.NewMessage(header.Coinbase, &systemcontract.SysGovContractAddr, 0, new(big.Int), math.MaxUint64, new(big.Int), data, nil, false)
+	msg := types.NewMessage(header.Coinbase, &systemcontract.SysGovContractAddr, 0, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, false)
 
 	// execute message without a transaction
 	state.Prepare(common.Hash{}, header.Hash(), 0)
@@ -187,7 +187,7 @@ func (c *Parlia) executeProposalMsg(chain consensus.ChainHeaderReader, header *t
 // the returned value should not nil.
 func (c *Parlia) executeEvmCallProposal(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, prop *Proposal, totalTxIndex int, txHash, bHash common.Hash) *types.Receipt {
 	// actually run the governance message
-	msg := types.NewMessage(prop.From, &prop.To, 0, prop.Value, header.GasLimit, new(big.Int), prop.Data, nil, false)
+	msg := types.NewMessage(prop.From, &prop.To, 0, prop.Value, header.GasLimit, new(big.Int), new(big.Int), new(big.Int), prop.Data, nil, false)
 	state.Prepare(txHash, bHash, totalTxIndex)
 	_, err := vmcaller.ExecuteMsg(msg, state, header, newChainContext(chain, c), c.chainConfig)
 	state.Finalise(true)
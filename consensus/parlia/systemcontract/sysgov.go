@@ -55,7 +55,7 @@ func (s *hardForkSysGov) Execute(state *state.StateDB, header *types.Header, cha
 		return err
 	}
 
-	msg := types.NewMessage(header.Coinbase, &SysGovContractAddr, 0, new(big.Int), math.MaxUint64, new(big.Int), data, nil, false)
+	msg := types.NewMessage(header.Coinbase, &SysGovContractAddr, 0, new(big.Int), math.MaxUint64, new(big.Int), new(big.Int), new(big.Int), data, nil, false)
 	vmcaller.ExecuteMsg(msg, state, header, chainContext, config)
 	//context := core.NewEVMContext(msg, header, chainContext, nil)
 	//evm := vm.NewEVM(context, state, config, vm.Config{})
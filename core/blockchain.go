@@ -34,6 +34,8 @@ import (
 	"PureChain/consensus"
 	"PureChain/core/rawdb"
 	"PureChain/core/state"
+	"PureChain/core/state/archive"
+	"PureChain/core/state/pruner"
 	"PureChain/core/state/snapshot"
 	"PureChain/core/types"
 	"PureChain/core/vm"
@@ -77,6 +79,7 @@ var (
 	blockReorgInvalidatedTx = metrics.NewRegisteredMeter("chain/reorg/invalidTx", nil)
 
 	errInsertionInterrupted = errors.New("insertion is interrupted")
+	errStateHistoryDisabled = errors.New("state history disabled")
 )
 
 const (
@@ -128,6 +131,10 @@ type CacheConfig struct {
 	Preimages          bool          // Whether to store preimage of trie key to the disk
 	TriesInMemory      uint64        // How many tries keeps in memory
 
+	HistoryTransactions uint64 // Number of recent blocks to retain the bodies of (0 = entire chain)
+	HistoryReceipts     uint64 // Number of recent blocks to retain the receipts of (0 = entire chain)
+	HistoryState        bool   // Whether to archive the state diffs of all blocks (requires snapshots)
+
 	SnapshotWait bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
 }
 
@@ -208,6 +215,11 @@ type BlockChain struct {
 
 	shouldPreserve  func(*types.Block) bool        // Function used to determine whether should preserve the given block.
 	terminateInsert func(common.Hash, uint64) bool // Testing hook used to terminate ancient receipt chain insertion.
+
+	pruner     *pruner.OnlinePruner // Online state pruner, nil if never started
+	prunerLock sync.Mutex           // Protects the online state pruner
+
+	archive *archive.Archive // State history serving old states, nil if disabled
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -375,14 +387,40 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		}
 		bc.snaps, _ = snapshot.New(bc.db, bc.stateCache.TrieDB(), bc.cacheConfig.SnapshotLimit, int(bc.cacheConfig.TriesInMemory), head.Root(), !bc.cacheConfig.SnapshotWait, true, recover)
 	}
+	// Start archiving the state diffs of the flattened snapshot layers
+	if bc.cacheConfig.HistoryState {
+		if bc.snaps == nil {
+			log.Warn("State history requires snapshots, disabling")
+		} else if bc.archive, err = archive.New(bc.db, bc, bc.snaps); err != nil {
+			log.Warn("Failed to open state history, disabling", "err", err)
+			bc.archive = nil
+		}
+	}
 	// Take ownership of this particular state
 	go bc.update()
+	// Receipts can't be derived and transactions can't be indexed without the
+	// bodies, so limit those to the retained transaction history.
+	if limit := bc.cacheConfig.HistoryTransactions; limit != 0 {
+		if bc.cacheConfig.HistoryReceipts == 0 || bc.cacheConfig.HistoryReceipts > limit {
+			log.Warn("Limiting receipt history to the transaction history", "provided", bc.cacheConfig.HistoryReceipts, "updated", limit)
+			bc.cacheConfig.HistoryReceipts = limit
+		}
+		if txLookupLimit != nil && (*txLookupLimit == 0 || *txLookupLimit > limit) {
+			log.Warn("Limiting transaction index to the transaction history", "provided", *txLookupLimit, "updated", limit)
+			txLookupLimit = &limit
+		}
+	}
 	if txLookupLimit != nil {
 		bc.txLookupLimit = *txLookupLimit
 
 		bc.wg.Add(1)
 		go bc.maintainTxIndex(txIndexBlock)
 	}
+	// Start pruning the chain history outside of the retention windows, if any
+	if bc.cacheConfig.HistoryTransactions != 0 || bc.cacheConfig.HistoryReceipts != 0 {
+		bc.wg.Add(1)
+		go bc.maintainHistory()
+	}
 	// If periodic cache journal is required, spin it up.
 	if bc.cacheConfig.TrieCleanRejournal > 0 {
 		if bc.cacheConfig.TrieCleanRejournal < time.Minute {
@@ -649,7 +687,16 @@ func (bc *BlockChain) SetHeadBeyondRoot(head uint64, root common.Hash) (uint64,
 	bc.txLookupCache.Purge()
 	bc.futureBlocks.Purge()
 
-	return rootNumber, bc.loadLastState()
+	if err := bc.loadLastState(); err != nil {
+		return rootNumber, err
+	}
+	// Discard the state history beyond the new head
+	if bc.archive != nil {
+		if err := bc.archive.Rewind(bc.CurrentBlock().NumberU64()); err != nil {
+			log.Error("Failed to rewind state history", "err", err)
+		}
+	}
+	return rootNumber, nil
 }
 
 // FastSyncCommitHead sets the current head block to the one defined by the hash
@@ -720,6 +767,33 @@ func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
 	return state.New(root, bc.stateCache, bc.snaps)
 }
 
+// HistoricState returns a read-only state of a canonical block, served from the
+// state history. Modifications of the state can't be committed and don't change
+// its root, so it serves state reads and calls but must not be used to execute
+// blocks or trace them.
+func (bc *BlockChain) HistoricState(header *types.Header) (*state.StateDB, error) {
+	if bc.archive == nil {
+		return nil, errStateHistoryDisabled
+	}
+	number := header.Number.Uint64()
+	if bc.GetCanonicalHash(number) != header.Hash() {
+		return nil, archive.ErrStateNotArchived
+	}
+	if first, last, ok := bc.archive.Range(); !ok || number < first || number > last {
+		return nil, archive.ErrStateNotArchived
+	}
+	return state.New(header.Root, archive.NewDatabase(bc.archive, number, bc.stateCache), nil)
+}
+
+// StateHistory returns the range of blocks whose state is served from the state
+// history.
+func (bc *BlockChain) StateHistory() (uint64, uint64, bool) {
+	if bc.archive == nil {
+		return 0, 0, false
+	}
+	return bc.archive.Range()
+}
+
 // StateCache returns the caching database underpinning the blockchain instance.
 func (bc *BlockChain) StateCache() state.Database {
 	return bc.stateCache
@@ -1027,6 +1101,17 @@ func (bc *BlockChain) Stop() {
 	bc.StopInsert()
 	bc.wg.Wait()
 
+	// Abort the online state pruning before persisting the recent states.
+	bc.prunerLock.Lock()
+	if bc.pruner != nil {
+		bc.pruner.Stop()
+	}
+	bc.prunerLock.Unlock()
+
+	// Stop archiving the state diffs before the snapshot is persisted.
+	if bc.archive != nil {
+		bc.archive.Close()
+	}
 	// Ensure that the entirety of the state snapshot is journalled to disk.
 	var snapBase common.Hash
 	if bc.snaps != nil {
@@ -1578,7 +1663,10 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	// Please refer to http://www.cs.cornell.edu/~ie53/publications/btcProcFC.pdf
 	reorg := externTd.Cmp(localTd) > 0
 	currentBlock = bc.CurrentBlock()
-	if !reorg && externTd.Cmp(localTd) == 0 {
+	if justified := bc.compareJustified(currentBlock.Header(), block.Header()); justified != 0 {
+		// A higher justified block takes precedence over the total difficulty
+		reorg = justified > 0
+	} else if !reorg && externTd.Cmp(localTd) == 0 {
 		// Split same-difficulty blocks by number, then preferentially select
 		// the block generated by the local miner as the canonical block.
 		if block.NumberU64() < currentBlock.NumberU64() || block.Time() < currentBlock.Time() {
@@ -1991,6 +2079,31 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, er
 	return it.index, err
 }
 
+// compareJustified compares the latest justified blocks as of the current and
+// the external header with a fast finality engine, returning 1 if the external
+// one is higher, -1 if it is lower and 0 if they are equal or unknown.
+func (bc *BlockChain) compareJustified(current, extern *types.Header) int {
+	p, ok := bc.engine.(consensus.FastFinality)
+	if !ok || current == nil || extern == nil {
+		return 0
+	}
+	localJustified, _, err := p.GetJustifiedNumberAndHash(bc, current)
+	if err != nil {
+		return 0
+	}
+	externJustified, _, err := p.GetJustifiedNumberAndHash(bc, extern)
+	if err != nil {
+		return 0
+	}
+	switch {
+	case externJustified > localJustified:
+		return 1
+	case externJustified < localJustified:
+		return -1
+	}
+	return 0
+}
+
 // insertSideChain is called when an import batch hits upon a pruned ancestor
 // error, which happens when a sidechain with a sufficiently old fork-block is
 // found.
@@ -2059,7 +2172,7 @@ func (bc *BlockChain) insertSideChain(block *types.Block, it *insertIterator) (i
 	// If the externTd was larger than our local TD, we now need to reimport the previous
 	// blocks to regenerate the required state
 	localTd := bc.GetTd(current.Hash(), current.NumberU64())
-	if localTd.Cmp(externTd) > 0 {
+	if justified := bc.compareJustified(current.Header(), it.previous()); justified < 0 || (justified == 0 && localTd.Cmp(externTd) > 0) {
 		log.Info("Sidechain written to disk", "start", it.first().NumberU64(), "end", it.previous().Number, "sidetd", externTd, "localtd", localTd)
 		return it.index, err
 	}
@@ -2380,6 +2493,99 @@ func (bc *BlockChain) maintainTxIndex(ancients uint64) {
 	}
 }
 
+// maintainHistory is responsible for the deletion of the block bodies and
+// receipts outside of the configured history retention windows.
+//
+// Block bodies are only pruned after the transaction indexer has unindexed them,
+// as the lookup entries can't be removed without the bodies.
+func (bc *BlockChain) maintainHistory() {
+	defer bc.wg.Done()
+
+	pruneBlocks := func(head uint64, done chan struct{}) {
+		defer func() { done <- struct{}{} }()
+
+		if limit := bc.cacheConfig.HistoryReceipts; limit != 0 && head >= limit {
+			rawdb.PruneBlockReceipts(bc.db, rawdb.ReadReceiptHistoryTail(bc.db), head-limit+1, bc.quit)
+		}
+		if limit := bc.cacheConfig.HistoryTransactions; limit != 0 && head >= limit {
+			to := head - limit + 1
+			if tail := rawdb.ReadTxIndexTail(bc.db); tail != nil && *tail < to {
+				to = *tail
+			}
+			rawdb.PruneBlockBodies(bc.db, rawdb.ReadBodyHistoryTail(bc.db), to, bc.quit)
+		}
+	}
+	var (
+		done   chan struct{}                  // Non-nil if background pruning routine is active.
+		headCh = make(chan ChainHeadEvent, 1) // Buffered to avoid locking up the event feed
+	)
+	sub := bc.SubscribeChainHeadEvent(headCh)
+	if sub == nil {
+		return
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case head := <-headCh:
+			if done == nil {
+				done = make(chan struct{})
+				go pruneBlocks(head.Block.NumberU64(), done)
+			}
+		case <-done:
+			done = nil
+		case <-bc.quit:
+			if done != nil {
+				log.Info("Waiting background history pruner to exit")
+				<-done
+			}
+			return
+		}
+	}
+}
+
+// PruneState starts deleting the stale state from the database in the background,
+// while the chain keeps running. The database IO of the pruning is limited to the
+// given budget in bytes per second, zero meaning unlimited.
+func (bc *BlockChain) PruneState(datadir string, bloomSize, budget uint64) error {
+	if bc.cacheConfig.TrieDirtyDisabled {
+		return errors.New("archive node doesn't prune state")
+	}
+	bc.prunerLock.Lock()
+	defer bc.prunerLock.Unlock()
+
+	if atomic.LoadInt32(&bc.running) == 1 {
+		return errors.New("blockchain is stopped")
+	}
+	if bc.pruner != nil && bc.pruner.Running() {
+		return errors.New("state pruning already running")
+	}
+	p, err := pruner.NewOnlinePruner(bc.db, bc, bc.stateCache.TrieDB(), bc.snaps, &bc.chainmu, pruner.OnlineConfig{
+		Datadir:       datadir,
+		BloomSize:     bloomSize,
+		TriesInMemory: bc.triesInMemory,
+		Budget:        budget,
+	})
+	if err != nil {
+		return err
+	}
+	p.Start()
+	bc.pruner = p
+	return nil
+}
+
+// PruneStatus returns the progress of the latest online state pruning, or nil
+// if none was started.
+func (bc *BlockChain) PruneStatus() *pruner.Status {
+	bc.prunerLock.Lock()
+	defer bc.prunerLock.Unlock()
+
+	if bc.pruner == nil {
+		return nil
+	}
+	return bc.pruner.Status()
+}
+
 // reportBlock logs a bad block error.
 func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, err error) {
 	rawdb.WriteBadBlock(bc.db, block)
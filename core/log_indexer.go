@@ -0,0 +1,181 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"PureChain/common"
+	"PureChain/core/rawdb"
+	"PureChain/core/types"
+	"PureChain/ethdb"
+)
+
+const (
+	// logIndexThrottling is the time to wait between processing two consecutive
+	// log index sections, preventing the initial indexing from hogging the disk.
+	logIndexThrottling = 100 * time.Millisecond
+)
+
+var (
+	// errLogIndexCorrupted is returned if an index entry cannot be decoded.
+	errLogIndexCorrupted = errors.New("corrupted log index entry")
+)
+
+// LogIndexer implements a core.ChainIndexer, building up an inverted index from
+// the addresses and topics of the logs to the blocks containing them, permitting
+// log filtering without testing the bloom of every block in a range.
+type LogIndexer struct {
+	size    uint64              // section size to generate the index for
+	db      ethdb.Database      // database instance to write index data and metadata into
+	section uint64              // Section is the section number being processed currently
+	head    common.Hash         // Head is the hash of the last header processed
+	entries map[string][]uint64 // Ascending block offsets within the section per index entry
+}
+
+// NewLogIndexer returns a chain indexer that generates the address and topic
+// log index for the canonical chain.
+func NewLogIndexer(db ethdb.Database, size, confirms uint64) *ChainIndexer {
+	backend := &LogIndexer{
+		db:   db,
+		size: size,
+	}
+	table := rawdb.NewTable(db, string(rawdb.LogIndexTablePrefix))
+
+	return NewChainIndexer(db, table, backend, size, confirms, logIndexThrottling, "logindex")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new log index section.
+func (l *LogIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	l.section, l.head, l.entries = section, common.Hash{}, make(map[string][]uint64)
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the logs of a new header
+// into the index.
+func (l *LogIndexer) Process(ctx context.Context, header *types.Header) error {
+	l.head = header.Hash()
+	if header.Bloom == (types.Bloom{}) {
+		return nil // No logs in the block
+	}
+	number := header.Number.Uint64()
+	receipts := rawdb.ReadRawReceipts(l.db, l.head, number)
+	if receipts == nil {
+		// Pruned receipts are never filtered, don't stall the index on them
+		if number < rawdb.ReadReceiptHistoryTail(l.db) {
+			return nil
+		}
+		return fmt.Errorf("receipts of block #%d [%x..] not found", number, l.head[:4])
+	}
+	offset := number - l.section*l.size
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			l.add(LogIndexAddressEntry(log.Address), offset)
+			for i, topic := range log.Topics {
+				l.add(LogIndexTopicEntry(i, topic), offset)
+			}
+		}
+	}
+	return nil
+}
+
+// add records that the block at the given offset contains a log matching the entry.
+func (l *LogIndexer) add(entry []byte, offset uint64) {
+	offsets := l.entries[string(entry)]
+	if n := len(offsets); n > 0 && offsets[n-1] == offset {
+		return
+	}
+	l.entries[string(entry)] = append(offsets, offset)
+}
+
+// Commit implements core.ChainIndexerBackend, finalizing the log index section
+// and writing it out into the database.
+func (l *LogIndexer) Commit() error {
+	batch := l.db.NewBatch()
+	for entry, offsets := range l.entries {
+		var (
+			blob = make([]byte, 0, len(offsets)*binary.MaxVarintLen16)
+			last uint64
+		)
+		for _, offset := range offsets {
+			blob = appendUvarint(blob, offset-last)
+			last = offset
+		}
+		rawdb.WriteLogIndex(batch, l.section, l.head, []byte(entry), blob)
+
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	// Mark the section complete only after all its entries are stored
+	rawdb.WriteLogIndexSection(batch, l.section, l.head)
+	return batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (l *LogIndexer) Prune(threshold uint64) error {
+	return nil
+}
+
+// LogIndexAddressEntry returns the log index entry of the logs emitted by the
+// given address.
+func LogIndexAddressEntry(address common.Address) []byte {
+	return append([]byte{0}, address.Bytes()...)
+}
+
+// LogIndexTopicEntry returns the log index entry of the logs carrying the given
+// topic at the given position.
+func LogIndexTopicEntry(position int, topic common.Hash) []byte {
+	return append([]byte{byte(position + 1)}, topic.Bytes()...)
+}
+
+// ReadLogIndexBlocks retrieves the ascending numbers of the blocks within the
+// given log index section containing logs matching the entry. An error is
+// returned if the section ending with the given head was not indexed.
+func ReadLogIndexBlocks(db ethdb.KeyValueReader, size, section uint64, head common.Hash, entry []byte) ([]uint64, error) {
+	if !rawdb.HasLogIndexSection(db, section, head) {
+		return nil, fmt.Errorf("log index section %d [%x..] not found", section, head[:4])
+	}
+	var (
+		blob   = rawdb.ReadLogIndex(db, section, head, entry)
+		blocks []uint64
+		number = section * size
+	)
+	for len(blob) > 0 {
+		delta, n := binary.Uvarint(blob)
+		if n <= 0 {
+			return nil, errLogIndexCorrupted
+		}
+		number += delta
+		blocks = append(blocks, number)
+		blob = blob[n:]
+	}
+	return blocks, nil
+}
+
+// appendUvarint appends the varint encoding of x to the buffer.
+func appendUvarint(buf []byte, x uint64) []byte {
+	var enc [binary.MaxVarintLen64]byte
+	return append(buf, enc[:binary.PutUvarint(enc[:], x)]...)
+}
@@ -252,6 +252,42 @@ func WriteTxIndexTail(db ethdb.KeyValueWriter, number uint64) {
 	}
 }
 
+// ReadBodyHistoryTail retrieves the number of the oldest block whose body has
+// not been pruned. Zero is returned if no body was ever pruned.
+func ReadBodyHistoryTail(db ethdb.KeyValueReader) uint64 {
+	data, _ := db.Get(bodyHistoryTailKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WriteBodyHistoryTail stores the number of the oldest block whose body has not
+// been pruned into database.
+func WriteBodyHistoryTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(bodyHistoryTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the body history tail", "err", err)
+	}
+}
+
+// ReadReceiptHistoryTail retrieves the number of the oldest block whose receipts
+// have not been pruned. Zero is returned if no receipt was ever pruned.
+func ReadReceiptHistoryTail(db ethdb.KeyValueReader) uint64 {
+	data, _ := db.Get(receiptHistoryTailKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WriteReceiptHistoryTail stores the number of the oldest block whose receipts
+// have not been pruned into database.
+func WriteReceiptHistoryTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(receiptHistoryTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the receipt history tail", "err", err)
+	}
+}
+
 // ReadFastTxLookupLimit retrieves the tx lookup limit used in fast sync.
 func ReadFastTxLookupLimit(db ethdb.KeyValueReader) *uint64 {
 	data, _ := db.Get(fastTxLookupLimitKey)
@@ -0,0 +1,170 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"PureChain/common"
+	"PureChain/ethdb"
+	"PureChain/log"
+)
+
+// ReadStateHistoryTail retrieves the number of the oldest block whose state diff
+// has been archived, or nil if the state history was never initialized.
+func ReadStateHistoryTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(stateHistoryTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteStateHistoryTail stores the number of the oldest block whose state diff
+// has been archived.
+func WriteStateHistoryTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(stateHistoryTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store state history tail", "err", err)
+	}
+}
+
+// ReadStateHistoryHead retrieves the number of the next block whose state diff
+// is to be archived, or nil if the state history was never initialized.
+func ReadStateHistoryHead(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(stateHistoryHeadKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteStateHistoryHead stores the number of the next block whose state diff
+// is to be archived.
+func WriteStateHistoryHead(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(stateHistoryHeadKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store state history head", "err", err)
+	}
+}
+
+// ReadStateHistory retrieves the state diff stored as the given item of the
+// ancient state history table.
+func ReadStateHistory(db ethdb.AncientReader, item uint64) []byte {
+	data, _ := db.Ancient(freezerStateHistoryTable, item)
+	return data
+}
+
+// HasStateHistory verifies the existence of the given item in the ancient state
+// history table.
+func HasStateHistory(db ethdb.AncientReader, item uint64) bool {
+	has, _ := db.HasAncient(freezerStateHistoryTable, item)
+	return has
+}
+
+// AppendStateHistory appends a state diff to the ancient state history table.
+func AppendStateHistory(db ethdb.AncientWriter, item uint64, blob []byte) error {
+	return db.AppendAncientItem(freezerStateHistoryTable, item, blob)
+}
+
+// TruncateStateHistory discards all but the first n items of the ancient state
+// history table.
+func TruncateStateHistory(db ethdb.AncientWriter, items uint64) error {
+	return db.TruncateAncientItems(freezerStateHistoryTable, items)
+}
+
+// WriteStateHistoryAccountIndex marks the account as modified by the block with
+// the given number.
+func WriteStateHistoryAccountIndex(db ethdb.KeyValueWriter, accountHash common.Hash, number uint64) {
+	if err := db.Put(stateHistoryAccountKey(accountHash, number), []byte{}); err != nil {
+		log.Crit("Failed to store account history index", "err", err)
+	}
+}
+
+// DeleteStateHistoryAccountIndex removes the account modification marker of the
+// block with the given number.
+func DeleteStateHistoryAccountIndex(db ethdb.KeyValueWriter, accountHash common.Hash, number uint64) {
+	if err := db.Delete(stateHistoryAccountKey(accountHash, number)); err != nil {
+		log.Crit("Failed to delete account history index", "err", err)
+	}
+}
+
+// WriteStateHistoryStorageIndex marks the storage slot as modified by the block
+// with the given number.
+func WriteStateHistoryStorageIndex(db ethdb.KeyValueWriter, accountHash, storageHash common.Hash, number uint64) {
+	if err := db.Put(stateHistoryStorageKey(accountHash, storageHash, number), []byte{}); err != nil {
+		log.Crit("Failed to store storage history index", "err", err)
+	}
+}
+
+// DeleteStateHistoryStorageIndex removes the storage slot modification marker
+// of the block with the given number.
+func DeleteStateHistoryStorageIndex(db ethdb.KeyValueWriter, accountHash, storageHash common.Hash, number uint64) {
+	if err := db.Delete(stateHistoryStorageKey(accountHash, storageHash, number)); err != nil {
+		log.Crit("Failed to delete storage history index", "err", err)
+	}
+}
+
+// FindStateHistoryAccount returns the number of the first block at or after the
+// given one that modified the account.
+func FindStateHistoryAccount(db ethdb.Iteratee, accountHash common.Hash, from uint64) (uint64, bool) {
+	return findStateHistory(db, stateHistoryAccountKey(accountHash, from))
+}
+
+// FindStateHistoryStorage returns the number of the first block at or after the
+// given one that modified the storage slot.
+func FindStateHistoryStorage(db ethdb.Iteratee, accountHash, storageHash common.Hash, from uint64) (uint64, bool) {
+	return findStateHistory(db, stateHistoryStorageKey(accountHash, storageHash, from))
+}
+
+// findStateHistory seeks to the given history index key, returning the block
+// number of the first index entry of the same item.
+func findStateHistory(db ethdb.Iteratee, key []byte) (uint64, bool) {
+	prefix, start := key[:len(key)-8], key[len(key)-8:]
+
+	it := db.NewIterator(prefix, start)
+	defer it.Release()
+
+	if !it.Next() || len(it.Key()) != len(key) {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(it.Key()[len(prefix):]), true
+}
+
+// DeleteStateHistoryIndex removes all the account and storage history index
+// entries from the database.
+func DeleteStateHistoryIndex(db ethdb.KeyValueStore) error {
+	for _, prefix := range [][]byte{stateHistoryAccountPrefix, stateHistoryStoragePrefix} {
+		it := db.NewIterator(prefix, nil)
+		batch := db.NewBatch()
+		for it.Next() {
+			batch.Delete(it.Key())
+			if batch.ValueSize() >= ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					it.Release()
+					return err
+				}
+				batch.Reset()
+			}
+		}
+		it.Release()
+		if err := batch.Write(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
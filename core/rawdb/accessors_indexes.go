@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"math/big"
 
 	"PureChain/common"
@@ -175,3 +176,105 @@ func DeleteBloombits(db ethdb.Database, bit uint, from uint64, to uint64) {
 		log.Crit("Failed to delete bloom bits", "err", it.Error())
 	}
 }
+
+// ReadLogIndex retrieves the encoded offsets of the blocks within the given log
+// index section containing logs matching the entry.
+func ReadLogIndex(db ethdb.KeyValueReader, section uint64, head common.Hash, entry []byte) []byte {
+	data, _ := db.Get(logIndexKey(section, head, entry))
+	return data
+}
+
+// WriteLogIndex stores the encoded offsets of the blocks within the given log
+// index section containing logs matching the entry.
+func WriteLogIndex(db ethdb.KeyValueWriter, section uint64, head common.Hash, entry []byte, offsets []byte) {
+	if err := db.Put(logIndexKey(section, head, entry), offsets); err != nil {
+		log.Crit("Failed to store log index", "err", err)
+	}
+}
+
+// HasLogIndexSection checks whether the log index section ending with the given
+// head was completely written.
+func HasLogIndexSection(db ethdb.KeyValueReader, section uint64, head common.Hash) bool {
+	ok, _ := db.Has(logIndexKey(section, head, nil))
+	return ok
+}
+
+// WriteLogIndexSection marks the log index section ending with the given head
+// as completely written.
+func WriteLogIndexSection(db ethdb.KeyValueWriter, section uint64, head common.Hash) {
+	if err := db.Put(logIndexKey(section, head, nil), []byte{}); err != nil {
+		log.Crit("Failed to store log index section", "err", err)
+	}
+}
+
+// DeleteLogIndex removes the entire log index along with the progress of its
+// indexer.
+func DeleteLogIndex(db ethdb.Database) {
+	for _, prefix := range [][]byte{logIndexPrefix, LogIndexTablePrefix} {
+		it := db.NewIterator(prefix, nil)
+		batch := db.NewBatch()
+		for it.Next() {
+			batch.Delete(it.Key())
+			if batch.ValueSize() > ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					log.Crit("Failed to delete log index", "err", err)
+				}
+				batch.Reset()
+			}
+		}
+		if it.Error() != nil {
+			log.Crit("Failed to delete log index", "err", it.Error())
+		}
+		it.Release()
+		if err := batch.Write(); err != nil {
+			log.Crit("Failed to delete log index", "err", err)
+		}
+	}
+}
+
+// TxHistoryEntry is a transaction an account took part in, or the reward credits
+// of a block if the transaction hash is zero.
+type TxHistoryEntry struct {
+	BlockNumber uint64
+	BlockHash   common.Hash
+	TxIndex     uint32
+	TxHash      common.Hash
+	Role        uint8 // Bit set of the roles of the account in the transaction
+}
+
+// WriteTxHistoryEntry stores an entry of the transaction history of an account.
+func WriteTxHistoryEntry(db ethdb.KeyValueWriter, address common.Address, entry *TxHistoryEntry) {
+	blob := make([]byte, 0, 2*common.HashLength+1)
+	blob = append(append(append(blob, entry.BlockHash.Bytes()...), entry.TxHash.Bytes()...), entry.Role)
+
+	if err := db.Put(txHistoryKey(address, entry.BlockNumber, entry.TxIndex), blob); err != nil {
+		log.Crit("Failed to store transaction history entry", "err", err)
+	}
+}
+
+// ReadTxHistory retrieves up to limit entries of the transaction history of an
+// account, newest first, starting with the entry at the given position. Entries
+// of blocks no longer canonical are not filtered out.
+func ReadTxHistory(db ethdb.Iteratee, address common.Address, number uint64, index uint32, limit int) []*TxHistoryEntry {
+	prefix := append(append([]byte{}, txHistoryPrefix...), address.Bytes()...)
+	start := txHistoryKey(address, number, index)[len(prefix):]
+
+	it := db.NewIterator(prefix, start)
+	defer it.Release()
+
+	var entries []*TxHistoryEntry
+	for len(entries) < limit && it.Next() {
+		key, blob := it.Key(), it.Value()
+		if len(key) != len(prefix)+12 || len(blob) != 2*common.HashLength+1 {
+			continue
+		}
+		entries = append(entries, &TxHistoryEntry{
+			BlockNumber: ^binary.BigEndian.Uint64(key[len(prefix):]),
+			BlockHash:   common.BytesToHash(blob[:common.HashLength]),
+			TxIndex:     ^binary.BigEndian.Uint32(key[len(prefix)+8:]),
+			TxHash:      common.BytesToHash(blob[common.HashLength : 2*common.HashLength]),
+			Role:        blob[2*common.HashLength],
+		})
+	}
+	return entries
+}
@@ -348,3 +348,100 @@ func UnindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt ch
 func unindexTransactionsForTesting(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
 	unindexTransactions(db, from, to, interrupt, hook)
 }
+
+// pruneHistory deletes the bodies or receipts of the canonical blocks in the
+// specified range from both the key-value store and the freezer, advancing the
+// matching history tail. The genesis block is always retained.
+func pruneHistory(db ethdb.Database, kind string, from uint64, to uint64, interrupt chan struct{}) {
+	if from == 0 {
+		from = 1
+	}
+	// short circuit for invalid range
+	if from >= to {
+		return
+	}
+	var (
+		writeTail = WriteBodyHistoryTail
+		deleteFn  = DeleteBody
+		start     = time.Now()
+		logged    = start.Add(-7 * time.Second)
+		batch     = db.NewBatch()
+		next      = from
+	)
+	if kind == freezerReceiptTable {
+		writeTail, deleteFn = WriteReceiptHistoryTail, DeleteReceipts
+	}
+	// Frozen data can only be deleted from the tail of the freezer
+	frozen, _ := db.Ancients()
+	if frozen > from {
+		limit := to
+		if frozen < limit {
+			limit = frozen
+		}
+		// Truncate before advancing the tail, so that a failure never marks data
+		// as pruned that is still served
+		retained, err := db.TruncateAncientTail(kind, limit)
+		if err != nil {
+			log.Error("Failed to truncate ancient tail", "kind", kind, "tail", limit, "err", err)
+			return
+		}
+		if limit == to {
+			// Only whole freezer files are deleted, the items retained below the
+			// requested tail remain available
+			if retained > from {
+				writeTail(db, retained)
+			}
+			log.Debug("Pruned chain history", "kind", kind, "tail", retained, "elapsed", common.PrettyDuration(time.Since(start)))
+			return
+		}
+		next = limit
+	}
+	// Delete the rest from the key-value store, together with the new tail
+	for ; next < to; next++ {
+		select {
+		case <-interrupt:
+			to = next
+		default:
+		}
+		if next == to {
+			break
+		}
+		if hash := ReadCanonicalHash(db, next); hash != (common.Hash{}) {
+			deleteFn(batch, hash, next)
+		}
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			writeTail(batch, next+1)
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed writing batch to db", "error", err)
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Pruning chain history", "kind", kind, "blocks", next-from, "total", to-from, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	writeTail(batch, next)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed writing batch to db", "error", err)
+	}
+	log.Debug("Pruned chain history", "kind", kind, "blocks", next-from, "tail", next, "elapsed", common.PrettyDuration(time.Since(start)))
+}
+
+// PruneBlockBodies deletes the canonical block bodies of the specified range and
+// advances the body history tail.
+//
+// There is a passed channel, the whole procedure will be interrupted if any
+// signal received.
+func PruneBlockBodies(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}) {
+	pruneHistory(db, freezerBodiesTable, from, to, interrupt)
+}
+
+// PruneBlockReceipts deletes the canonical block receipts of the specified range
+// and advances the receipt history tail.
+//
+// There is a passed channel, the whole procedure will be interrupted if any
+// signal received.
+func PruneBlockReceipts(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}) {
+	pruneHistory(db, freezerReceiptTable, from, to, interrupt)
+}
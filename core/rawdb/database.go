@@ -21,11 +21,13 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sync/atomic"
 	"time"
 
 	"PureChain/common"
 	"PureChain/ethdb"
+	"PureChain/ethdb/boltdb"
 	"PureChain/ethdb/leveldb"
 	"PureChain/ethdb/memorydb"
 	"PureChain/log"
@@ -99,6 +101,11 @@ func (db *nofreezedb) AncientSize(kind string) (uint64, error) {
 	return 0, errNotSupported
 }
 
+// AncientTail returns an error as we don't have a backing chain freezer.
+func (db *nofreezedb) AncientTail(kind string) (uint64, error) {
+	return 0, errNotSupported
+}
+
 // AppendAncient returns an error as we don't have a backing chain freezer.
 func (db *nofreezedb) AppendAncient(number uint64, hash, header, body, receipts, td []byte) error {
 	return errNotSupported
@@ -109,6 +116,21 @@ func (db *nofreezedb) TruncateAncients(items uint64) error {
 	return errNotSupported
 }
 
+// TruncateAncientTail returns an error as we don't have a backing chain freezer.
+func (db *nofreezedb) TruncateAncientTail(kind string, tail uint64) (uint64, error) {
+	return 0, errNotSupported
+}
+
+// AppendAncientItem returns an error as we don't have a backing chain freezer.
+func (db *nofreezedb) AppendAncientItem(kind string, number uint64, blob []byte) error {
+	return errNotSupported
+}
+
+// TruncateAncientItems returns an error as we don't have a backing chain freezer.
+func (db *nofreezedb) TruncateAncientItems(kind string, items uint64) error {
+	return errNotSupported
+}
+
 // Sync returns an error as we don't have a backing chain freezer.
 func (db *nofreezedb) Sync() error {
 	return errNotSupported
@@ -243,6 +265,77 @@ func NewLevelDBDatabaseWithFreezer(file string, cache int, handles int, freezer
 	return frdb, nil
 }
 
+const (
+	// DBLeveldb is the LevelDB key-value database engine.
+	DBLeveldb = "leveldb"
+
+	// DBBolt is the bbolt key-value database engine.
+	DBBolt = "bolt"
+)
+
+// PreexistingDatabase checks the given directory whether a key-value database is
+// already instantiated at that location, and if so, returns its engine (or the
+// empty string).
+func PreexistingDatabase(path string) string {
+	if common.FileExist(filepath.Join(path, boltdb.DatabaseFile)) {
+		return DBBolt
+	}
+	if common.FileExist(filepath.Join(path, "CURRENT")) {
+		return DBLeveldb
+	}
+	return ""
+}
+
+// NewKeyValueStore opens a persistent key-value store with the given engine. If
+// no engine is requested, the one of the preexisting database is used, falling
+// back to LevelDB for new databases. Opening an existing database with another
+// engine is refused.
+func NewKeyValueStore(engine string, file string, cache int, handles int, namespace string, readonly bool) (ethdb.KeyValueStore, error) {
+	existing := PreexistingDatabase(file)
+	if engine == "" {
+		engine = existing
+	}
+	if engine == "" {
+		engine = DBLeveldb
+	}
+	if existing != "" && existing != engine {
+		return nil, fmt.Errorf("database engine mismatch: %s requested, %s found at %s", engine, existing, file)
+	}
+	switch engine {
+	case DBLeveldb:
+		return leveldb.New(file, cache, handles, namespace, readonly)
+	case DBBolt:
+		return boltdb.New(file, namespace, readonly)
+	default:
+		return nil, fmt.Errorf("unknown database engine %q", engine)
+	}
+}
+
+// OpenDatabase creates a persistent key-value database with the given engine
+// without a freezer moving immutable chain segments into cold storage.
+func OpenDatabase(engine string, file string, cache int, handles int, namespace string, readonly bool) (ethdb.Database, error) {
+	kvdb, err := NewKeyValueStore(engine, file, cache, handles, namespace, readonly)
+	if err != nil {
+		return nil, err
+	}
+	return NewDatabase(kvdb), nil
+}
+
+// OpenDatabaseWithFreezer creates a persistent key-value database with the given
+// engine and a freezer moving immutable chain segments into cold storage.
+func OpenDatabaseWithFreezer(engine string, file string, cache int, handles int, freezer string, namespace string, readonly bool) (ethdb.Database, error) {
+	kvdb, err := NewKeyValueStore(engine, file, cache, handles, namespace, readonly)
+	if err != nil {
+		return nil, err
+	}
+	frdb, err := NewDatabaseWithFreezer(kvdb, freezer, namespace, readonly)
+	if err != nil {
+		kvdb.Close()
+		return nil, err
+	}
+	return frdb, nil
+}
+
 type counter uint64
 
 func (c counter) String() string {
@@ -298,9 +391,12 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		storageSnaps    stat
 		preimages       stat
 		bloomBits       stat
+		logIndex        stat
+		txHistory       stat
 		cliqueSnaps     stat
 		parliaSnaps     stat
 		dposSnaps       stat
+		iniCheckpoints  stat
 
 		// Ancient store statistics
 		ancientHeadersSize  common.StorageSize
@@ -357,12 +453,22 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			bloomBits.Add(size)
 		case bytes.HasPrefix(key, BloomBitsIndexPrefix):
 			bloomBits.Add(size)
+		case bytes.HasPrefix(key, logIndexPrefix) && len(key) >= len(logIndexPrefix)+8+common.HashLength:
+			logIndex.Add(size)
+		case bytes.HasPrefix(key, LogIndexTablePrefix):
+			logIndex.Add(size)
+		case bytes.HasPrefix(key, txHistoryPrefix) && len(key) == len(txHistoryPrefix)+common.AddressLength+12:
+			txHistory.Add(size)
+		case bytes.HasPrefix(key, TxHistoryTablePrefix):
+			txHistory.Add(size)
 		case bytes.HasPrefix(key, []byte("clique-")) && len(key) == 7+common.HashLength:
 			cliqueSnaps.Add(size)
 		case bytes.HasPrefix(key, []byte("parlia-")) && len(key) == 7+common.HashLength:
 			parliaSnaps.Add(size)
 		case bytes.HasPrefix(key, []byte("dpos-")) && len(key) == 7+common.HashLength:
 			dposSnaps.Add(size)
+		case bytes.HasPrefix(key, []byte("inihash-checkpoint-")) && len(key) == 19+common.HashLength:
+			iniCheckpoints.Add(size)
 
 		case bytes.HasPrefix(key, []byte("cht-")) ||
 			bytes.HasPrefix(key, []byte("chtIndexV2-")) ||
@@ -421,6 +527,8 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Key-Value store", "Block hash->number", hashNumPairings.Size(), hashNumPairings.Count()},
 		{"Key-Value store", "Transaction index", txLookups.Size(), txLookups.Count()},
 		{"Key-Value store", "Bloombit index", bloomBits.Size(), bloomBits.Count()},
+		{"Key-Value store", "Log index", logIndex.Size(), logIndex.Count()},
+		{"Key-Value store", "Transaction history", txHistory.Size(), txHistory.Count()},
 		{"Key-Value store", "Contract codes", codes.Size(), codes.Count()},
 		{"Key-Value store", "Trie nodes", tries.Size(), tries.Count()},
 		{"Key-Value store", "Trie preimages", preimages.Size(), preimages.Count()},
@@ -429,6 +537,7 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Key-Value store", "Clique snapshots", cliqueSnaps.Size(), cliqueSnaps.Count()},
 		{"Key-Value store", "Parlia snapshots", parliaSnaps.Size(), parliaSnaps.Count()},
 		{"Key-Value store", "Dpos snapshots", dposSnaps.Size(), dposSnaps.Count()},
+		{"Key-Value store", "Inihash checkpoints", iniCheckpoints.Size(), iniCheckpoints.Count()},
 		{"Key-Value store", "Singleton metadata", metadata.Size(), metadata.Count()},
 		{"Key-Value store", "Shutdown metadata", shutdownInfo.Size(), shutdownInfo.Count()},
 		{"Ancient store", "Headers", ancientHeadersSize.String(), ancients.String()},
@@ -15,3 +15,69 @@
 // along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
 
 package rawdb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"PureChain/ethdb/boltdb"
+	"PureChain/ethdb/leveldb"
+)
+
+// Tests that the engine of a preexisting database is detected and reused, and
+// that it cannot be opened with another engine.
+func TestDatabaseEngineSelection(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rawdb-engine-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, engine := range []string{DBLeveldb, DBBolt} {
+		path := filepath.Join(dir, engine)
+		if have := PreexistingDatabase(path); have != "" {
+			t.Fatalf("%s: preexisting engine mismatch: have %q, want none", engine, have)
+		}
+		db, err := NewKeyValueStore(engine, path, 0, 0, "", false)
+		if err != nil {
+			t.Fatalf("%s: failed to create database: %v", engine, err)
+		}
+		db.Put([]byte("key"), []byte("value"))
+		db.Close()
+
+		if have := PreexistingDatabase(path); have != engine {
+			t.Fatalf("%s: preexisting engine mismatch: have %q, want %q", engine, have, engine)
+		}
+		// Reopening without an explicit engine should pick the existing one
+		db, err = NewKeyValueStore("", path, 0, 0, "", true)
+		if err != nil {
+			t.Fatalf("%s: failed to reopen database: %v", engine, err)
+		}
+		switch db.(type) {
+		case *leveldb.Database:
+			if engine != DBLeveldb {
+				t.Fatalf("%s: reopened as leveldb", engine)
+			}
+		case *boltdb.Database:
+			if engine != DBBolt {
+				t.Fatalf("%s: reopened as bolt", engine)
+			}
+		}
+		if val, err := db.Get([]byte("key")); err != nil || string(val) != "value" {
+			t.Fatalf("%s: value mismatch: have %q, %v", engine, val, err)
+		}
+		db.Close()
+
+		// Opening with another engine should be refused
+		other := DBBolt
+		if engine == DBBolt {
+			other = DBLeveldb
+		}
+		if db, err := NewKeyValueStore(other, path, 0, 0, "", false); err == nil {
+			db.Close()
+			t.Fatalf("%s: opened as %s", engine, other)
+		}
+	}
+}
@@ -79,6 +79,7 @@ type freezer struct {
 
 	readonly     bool
 	tables       map[string]*freezerTable // Data tables for storing everything
+	auxiliary    map[string]*freezerTable // Data tables appended independently of the chain data
 	instanceLock fileutil.Releaser        // File-system lock to prevent double opens
 
 	trigger chan chan struct{} // Manual blocking freeze trigger, test determinism
@@ -114,6 +115,7 @@ func newFreezer(datadir string, namespace string, readonly bool) (*freezer, erro
 		readonly:     readonly,
 		threshold:    params.FullImmutabilityThreshold,
 		tables:       make(map[string]*freezerTable),
+		auxiliary:    make(map[string]*freezerTable),
 		instanceLock: lock,
 		trigger:      make(chan chan struct{}),
 		quit:         make(chan struct{}),
@@ -121,18 +123,23 @@ func newFreezer(datadir string, namespace string, readonly bool) (*freezer, erro
 	for name, disableSnappy := range FreezerNoSnappy {
 		table, err := newTable(datadir, name, readMeter, writeMeter, sizeGauge, disableSnappy)
 		if err != nil {
-			for _, table := range freezer.tables {
-				table.Close()
-			}
+			freezer.closeTables()
 			lock.Release()
 			return nil, err
 		}
 		freezer.tables[name] = table
 	}
-	if err := freezer.repair(); err != nil {
-		for _, table := range freezer.tables {
-			table.Close()
+	for name, disableSnappy := range freezerAuxiliaryTables {
+		table, err := newTable(datadir, name, readMeter, writeMeter, sizeGauge, disableSnappy)
+		if err != nil {
+			freezer.closeTables()
+			lock.Release()
+			return nil, err
 		}
+		freezer.auxiliary[name] = table
+	}
+	if err := freezer.repair(); err != nil {
+		freezer.closeTables()
 		lock.Release()
 		return nil, err
 	}
@@ -145,11 +152,7 @@ func (f *freezer) Close() error {
 	var errs []error
 	f.closeOnce.Do(func() {
 		close(f.quit)
-		for _, table := range f.tables {
-			if err := table.Close(); err != nil {
-				errs = append(errs, err)
-			}
-		}
+		errs = f.closeTables()
 		if err := f.instanceLock.Release(); err != nil {
 			errs = append(errs, err)
 		}
@@ -160,10 +163,31 @@ func (f *freezer) Close() error {
 	return nil
 }
 
+// closeTables closes all the chain and auxiliary data tables.
+func (f *freezer) closeTables() []error {
+	var errs []error
+	for _, tables := range []map[string]*freezerTable{f.tables, f.auxiliary} {
+		for _, table := range tables {
+			if err := table.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+// table returns the chain or auxiliary data table of the specified category.
+func (f *freezer) table(kind string) *freezerTable {
+	if table := f.tables[kind]; table != nil {
+		return table
+	}
+	return f.auxiliary[kind]
+}
+
 // HasAncient returns an indicator whether the specified ancient data exists
 // in the freezer.
 func (f *freezer) HasAncient(kind string, number uint64) (bool, error) {
-	if table := f.tables[kind]; table != nil {
+	if table := f.table(kind); table != nil {
 		return table.has(number), nil
 	}
 	return false, nil
@@ -171,7 +195,7 @@ func (f *freezer) HasAncient(kind string, number uint64) (bool, error) {
 
 // Ancient retrieves an ancient binary blob from the append-only immutable files.
 func (f *freezer) Ancient(kind string, number uint64) ([]byte, error) {
-	if table := f.tables[kind]; table != nil {
+	if table := f.table(kind); table != nil {
 		return table.Retrieve(number)
 	}
 	return nil, errUnknownTable
@@ -184,12 +208,21 @@ func (f *freezer) Ancients() (uint64, error) {
 
 // AncientSize returns the ancient size of the specified category.
 func (f *freezer) AncientSize(kind string) (uint64, error) {
-	if table := f.tables[kind]; table != nil {
+	if table := f.table(kind); table != nil {
 		return table.size()
 	}
 	return 0, errUnknownTable
 }
 
+// AncientTail returns the number of the first item retained in the specified
+// category.
+func (f *freezer) AncientTail(kind string) (uint64, error) {
+	if table := f.table(kind); table != nil {
+		return table.tail(), nil
+	}
+	return 0, errUnknownTable
+}
+
 // AppendAncient injects all binary blobs belong to block at the end of the
 // append-only immutable table files.
 //
@@ -257,12 +290,61 @@ func (f *freezer) TruncateAncients(items uint64) error {
 	return nil
 }
 
+// TruncateAncientTail discards the data of the specified category below the given
+// item number. Only whole data files are deleted, so the returned number of the
+// first retained item may be lower than the requested tail. The headers, hashes
+// and difficulties are needed to link the chain and cannot be truncated.
+func (f *freezer) TruncateAncientTail(kind string, tail uint64) (uint64, error) {
+	if f.readonly {
+		return 0, errReadOnly
+	}
+	if kind != freezerBodiesTable && kind != freezerReceiptTable {
+		return 0, errNotSupported
+	}
+	table := f.tables[kind]
+	if table == nil {
+		return 0, errUnknownTable
+	}
+	return table.truncateTail(tail)
+}
+
+// AppendAncientItem injects a binary blob at the end of an auxiliary table. The
+// auxiliary tables are not kept in lockstep with the chain data, so their items
+// are neither repaired nor truncated along with the chain.
+func (f *freezer) AppendAncientItem(kind string, number uint64, blob []byte) error {
+	if f.readonly {
+		return errReadOnly
+	}
+	table := f.auxiliary[kind]
+	if table == nil {
+		return errUnknownTable
+	}
+	if atomic.LoadUint64(&table.items) != number {
+		return errOutOrderInsertion
+	}
+	return table.Append(number, blob)
+}
+
+// TruncateAncientItems discards all but the first n items of an auxiliary table.
+func (f *freezer) TruncateAncientItems(kind string, items uint64) error {
+	if f.readonly {
+		return errReadOnly
+	}
+	table := f.auxiliary[kind]
+	if table == nil {
+		return errUnknownTable
+	}
+	return table.truncate(items)
+}
+
 // Sync flushes all data tables to disk.
 func (f *freezer) Sync() error {
 	var errs []error
-	for _, table := range f.tables {
-		if err := table.Sync(); err != nil {
-			errs = append(errs, err)
+	for _, tables := range []map[string]*freezerTable{f.tables, f.auxiliary} {
+		for _, table := range tables {
+			if err := table.Sync(); err != nil {
+				errs = append(errs, err)
+			}
 		}
 	}
 	if errs != nil {
@@ -359,13 +441,15 @@ func (f *freezer) freeze(db ethdb.KeyValueStore) {
 				log.Error("Block header missing, can't freeze", "number", f.frozen, "hash", hash)
 				break
 			}
+			// Bodies and receipts below the history tails were pruned already,
+			// freeze empty placeholders for them to keep the tables in sync
 			body := ReadBodyRLP(nfdb, hash, f.frozen)
-			if len(body) == 0 {
+			if len(body) == 0 && f.frozen >= ReadBodyHistoryTail(nfdb) {
 				log.Error("Block body missing, can't freeze", "number", f.frozen, "hash", hash)
 				break
 			}
 			receipts := ReadReceiptsRLP(nfdb, hash, f.frozen)
-			if len(receipts) == 0 {
+			if len(receipts) == 0 && f.frozen >= ReadReceiptHistoryTail(nfdb) {
 				log.Error("Block receipts missing, can't freeze", "number", f.frozen, "hash", hash)
 				break
 			}
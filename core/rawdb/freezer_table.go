@@ -350,12 +350,16 @@ func (t *freezerTable) truncate(items uint64) error {
 		log = t.logger.Warn // Only loud warn if we delete multiple items
 	}
 	log("Truncating freezer table", "items", existing, "limit", items)
-	if err := truncateFreezerFile(t.index, int64(items+1)*indexEntrySize); err != nil {
+	if items < uint64(t.itemOffset) {
+		return fmt.Errorf("truncating below the tail: tail %d, limit %d", t.itemOffset, items)
+	}
+	kept := items - uint64(t.itemOffset)
+	if err := truncateFreezerFile(t.index, int64(kept+1)*indexEntrySize); err != nil {
 		return err
 	}
 	// Calculate the new expected size of the data file and truncate it
 	buffer := make([]byte, indexEntrySize)
-	if _, err := t.index.ReadAt(buffer, int64(items*indexEntrySize)); err != nil {
+	if _, err := t.index.ReadAt(buffer, int64(kept*indexEntrySize)); err != nil {
 		return err
 	}
 	var expected indexEntry
@@ -393,6 +397,129 @@ func (t *freezerTable) truncate(items uint64) error {
 	return nil
 }
 
+// truncateTail discards the data files holding only items below the provided
+// tail number. Deletion happens at data file granularity, so some items below
+// the tail may be retained. The number of the first retained item is returned.
+func (t *freezerTable) truncateTail(tail uint64) (uint64, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// Ensure the table is still accessible
+	if t.index == nil || t.head == nil {
+		return 0, errClosed
+	}
+	// Never delete the head file, it's the one being appended to
+	items := atomic.LoadUint64(&t.items)
+	if tail > items {
+		tail = items
+	}
+	if tail <= uint64(t.itemOffset) {
+		return uint64(t.itemOffset), nil
+	}
+	// Find the first item stored in the same data file as the new tail. The
+	// index entries are ordered by file number, so search for the first item
+	// whose end entry points into the data file.
+	buffer := make([]byte, indexEntrySize)
+	readEntry := func(i uint64) (indexEntry, error) {
+		var entry indexEntry
+		if _, err := t.index.ReadAt(buffer, int64(i*indexEntrySize)); err != nil {
+			return entry, err
+		}
+		entry.unmarshalBinary(buffer)
+		return entry, nil
+	}
+	var (
+		filenum = t.headId
+		first   = items - uint64(t.itemOffset) // Relative number of the first item in filenum
+	)
+	if tail < items {
+		entry, err := readEntry(tail - uint64(t.itemOffset) + 1)
+		if err != nil {
+			return 0, err
+		}
+		filenum = entry.filenum
+		first = tail - uint64(t.itemOffset)
+	}
+	if filenum == t.tailId {
+		return uint64(t.itemOffset), nil
+	}
+	for first > 0 {
+		entry, err := readEntry(first)
+		if err != nil {
+			return 0, err
+		}
+		if entry.filenum != filenum {
+			break
+		}
+		first--
+	}
+	oldSize, err := t.sizeNolock()
+	if err != nil {
+		return 0, err
+	}
+	// Rewrite the index without the deleted entries, carrying the new item offset
+	// in the first entry
+	stat, err := t.index.Stat()
+	if err != nil {
+		return 0, err
+	}
+	rest := make([]byte, stat.Size()-int64(first+1)*indexEntrySize)
+	if _, err := t.index.ReadAt(rest, int64(first+1)*indexEntrySize); err != nil {
+		return 0, err
+	}
+	offset := t.itemOffset + uint32(first)
+	head := indexEntry{filenum: filenum, offset: offset}
+
+	name := t.index.Name()
+	tmp, err := openFreezerFileTruncated(name + ".tmp")
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tmp.Write(append(head.marshallBinary(), rest...)); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	tmp.Close()
+	t.index.Close()
+	if err := os.Rename(name+".tmp", name); err != nil {
+		return 0, err
+	}
+	if t.index, err = openFreezerFileForAppend(name); err != nil {
+		return 0, err
+	}
+	// Delete the data files no longer referenced
+	for num := t.tailId; num < filenum; num++ {
+		if f, err := t.openFile(num, openFreezerFileForReadOnly); err == nil {
+			t.releaseFile(num)
+			os.Remove(f.Name())
+		}
+	}
+	t.logger.Debug("Truncated freezer table tail", "items", items, "tail", offset)
+	t.tailId = filenum
+	t.itemOffset = offset
+
+	// Retrieve the new size and update the total size counter
+	newSize, err := t.sizeNolock()
+	if err != nil {
+		return 0, err
+	}
+	t.sizeGauge.Dec(int64(oldSize - newSize))
+
+	return uint64(offset), nil
+}
+
+// tail returns the number of the first item retained in the freezer table.
+func (t *freezerTable) tail() uint64 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return uint64(t.itemOffset)
+}
+
 // Close closes all opened files.
 func (t *freezerTable) Close() error {
 	t.lock.Lock()
@@ -629,7 +756,13 @@ func (t *freezerTable) retrieve(item uint64) ([]byte, error) {
 // has returns an indicator whether the specified number data
 // exists in the freezer table.
 func (t *freezerTable) has(number uint64) bool {
-	return atomic.LoadUint64(&t.items) > number
+	if atomic.LoadUint64(&t.items) <= number {
+		return false
+	}
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return uint64(t.itemOffset) <= number
 }
 
 // size returns the total data size in the freezer table.
@@ -649,6 +649,82 @@ func TestOffset(t *testing.T) {
 //
 // The reason why it's not a regular fuzzer, within tests/fuzzers, is that it is dependent
 // on timing rather than 'clever' input -- there's no determinism.
+// TestFreezerTruncateTail tests that data files below the tail are deleted and
+// that the remaining items are retrievable, also after reopening the table.
+func TestFreezerTruncateTail(t *testing.T) {
+	t.Parallel()
+	var (
+		fname      = fmt.Sprintf("truncate-tail-%d", rand.Uint64())
+		rm, wm, sg = metrics.NewMeter(), metrics.NewMeter(), metrics.NewGauge()
+	)
+	// Write 15 bytes 30 times, three items per file
+	f, err := newCustomTable(os.TempDir(), fname, rm, wm, sg, 50, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for x := 0; x < 30; x++ {
+		f.Append(uint64(x), getChunk(15, x))
+	}
+	// Truncating in the middle of a file retains the whole file
+	tail, err := f.truncateTail(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tail != 9 {
+		t.Fatalf("tail mismatch: have %d, want %d", tail, 9)
+	}
+	if _, err := os.Stat(filepath.Join(os.TempDir(), fmt.Sprintf("%s.0002.rdat", fname))); !os.IsNotExist(err) {
+		t.Fatalf("data file below the tail not deleted: %v", err)
+	}
+	check := func(f *freezerTable) {
+		for y := 0; y < 30; y++ {
+			got, err := f.Retrieve(uint64(y))
+			if y < 9 {
+				if err != errOutOfBounds {
+					t.Fatalf("item %d: expected out of bounds, got %v", y, err)
+				}
+				if f.has(uint64(y)) {
+					t.Fatalf("item %d: reported present below the tail", y)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("item %d: %v", y, err)
+			}
+			if exp := getChunk(15, y); !bytes.Equal(got, exp) {
+				t.Fatalf("item %d: got %x, want %x", y, got, exp)
+			}
+		}
+	}
+	check(f)
+
+	// The tail should survive a restart and new items should be appendable
+	f.Close()
+	f, err = newCustomTable(os.TempDir(), fname, rm, wm, sg, 50, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if have := f.tail(); have != 9 {
+		t.Fatalf("reopened tail mismatch: have %d, want %d", have, 9)
+	}
+	check(f)
+
+	if err := f.Append(30, getChunk(15, 30)); err != nil {
+		t.Fatal(err)
+	}
+	// Truncating the recent items should respect the tail offset
+	if err := f.truncate(20); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := f.Retrieve(19); err != nil || !bytes.Equal(got, getChunk(15, 19)) {
+		t.Fatalf("item 19: got %x, %v", got, err)
+	}
+	if _, err := f.Retrieve(20); err != errOutOfBounds {
+		t.Fatalf("item 20: expected out of bounds, got %v", err)
+	}
+}
+
 func TestAppendTruncateParallel(t *testing.T) {
 	dir, err := ioutil.TempDir("", "freezer")
 	if err != nil {
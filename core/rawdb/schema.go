@@ -66,6 +66,18 @@ var (
 	// txIndexTailKey tracks the oldest block whose transactions have been indexed.
 	txIndexTailKey = []byte("TransactionIndexTail")
 
+	// bodyHistoryTailKey tracks the oldest block whose body has not been pruned.
+	bodyHistoryTailKey = []byte("BodyHistoryTail")
+
+	// receiptHistoryTailKey tracks the oldest block whose receipts have not been pruned.
+	receiptHistoryTailKey = []byte("ReceiptHistoryTail")
+
+	// stateHistoryTailKey tracks the oldest block whose state diff has been archived.
+	stateHistoryTailKey = []byte("StateHistoryTail")
+
+	// stateHistoryHeadKey tracks the next block whose state diff is to be archived.
+	stateHistoryHeadKey = []byte("StateHistoryHead")
+
 	// fastTxLookupLimitKey tracks the transaction lookup limit during fast sync.
 	fastTxLookupLimitKey = []byte("FastTransactionLookupLimit")
 
@@ -95,6 +107,15 @@ var (
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	LogIndexTablePrefix  = []byte("iL") // LogIndexTablePrefix is the data table of the log indexer to track its progress
+
+	TxHistoryTablePrefix = []byte("iT") // TxHistoryTablePrefix is the data table of the transaction history indexer to track its progress
+
+	logIndexPrefix  = []byte("il") // logIndexPrefix + section (uint64 big endian) + hash + entry -> block offsets
+	txHistoryPrefix = []byte("it") // txHistoryPrefix + address + ^num (uint64 big endian) + ^tx index (uint32 big endian) -> history entry
+
+	stateHistoryAccountPrefix = []byte("ia") // stateHistoryAccountPrefix + account hash + num (uint64 big endian) -> nil
+	stateHistoryStoragePrefix = []byte("is") // stateHistoryStoragePrefix + account hash + storage hash + num (uint64 big endian) -> nil
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -115,6 +136,9 @@ const (
 
 	// freezerDifficultyTable indicates the name of the freezer total difficulty table.
 	freezerDifficultyTable = "diffs"
+
+	// freezerStateHistoryTable indicates the name of the freezer state history table.
+	freezerStateHistoryTable = "statehistory"
 )
 
 // FreezerNoSnappy configures whether compression is disabled for the ancient-tables.
@@ -127,6 +151,12 @@ var FreezerNoSnappy = map[string]bool{
 	freezerDifficultyTable: true,
 }
 
+// freezerAuxiliaryTables configures whether compression is disabled for the
+// ancient-tables which are appended independently of the chain data.
+var freezerAuxiliaryTables = map[string]bool{
+	freezerStateHistoryTable: false,
+}
+
 // LegacyTxLookupEntry is the legacy TxLookupEntry definition with some unnecessary
 // fields.
 type LegacyTxLookupEntry struct {
@@ -197,6 +227,16 @@ func storageSnapshotsKey(accountHash common.Hash) []byte {
 	return append(SnapshotStoragePrefix, accountHash.Bytes()...)
 }
 
+// stateHistoryAccountKey = stateHistoryAccountPrefix + account hash + num (uint64 big endian)
+func stateHistoryAccountKey(accountHash common.Hash, number uint64) []byte {
+	return append(append(stateHistoryAccountPrefix, accountHash.Bytes()...), encodeBlockNumber(number)...)
+}
+
+// stateHistoryStorageKey = stateHistoryStoragePrefix + account hash + storage hash + num (uint64 big endian)
+func stateHistoryStorageKey(accountHash, storageHash common.Hash, number uint64) []byte {
+	return append(append(append(stateHistoryStoragePrefix, accountHash.Bytes()...), storageHash.Bytes()...), encodeBlockNumber(number)...)
+}
+
 // bloomBitsKey = bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash
 func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	key := append(append(bloomBitsPrefix, make([]byte, 10)...), hash.Bytes()...)
@@ -207,6 +247,27 @@ func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	return key
 }
 
+// logIndexKey = logIndexPrefix + section (uint64 big endian) + hash + entry
+func logIndexKey(section uint64, hash common.Hash, entry []byte) []byte {
+	key := append(append(append(logIndexPrefix, make([]byte, 8)...), hash.Bytes()...), entry...)
+
+	binary.BigEndian.PutUint64(key[len(logIndexPrefix):], section)
+
+	return key
+}
+
+// txHistoryKey = txHistoryPrefix + address + ^num (uint64 big endian) + ^tx index (uint32 big endian)
+//
+// The position is inverted to iterate the history of an account newest first.
+func txHistoryKey(address common.Address, number uint64, index uint32) []byte {
+	key := append(append(txHistoryPrefix, address.Bytes()...), make([]byte, 12)...)
+
+	binary.BigEndian.PutUint64(key[len(txHistoryPrefix)+common.AddressLength:], ^number)
+	binary.BigEndian.PutUint32(key[len(txHistoryPrefix)+common.AddressLength+8:], ^index)
+
+	return key
+}
+
 // preimageKey = preimagePrefix + hash
 func preimageKey(hash common.Hash) []byte {
 	return append(preimagePrefix, hash.Bytes()...)
@@ -74,6 +74,12 @@ func (t *table) AncientSize(kind string) (uint64, error) {
 	return t.db.AncientSize(kind)
 }
 
+// AncientTail is a noop passthrough that just forwards the request to the underlying
+// database.
+func (t *table) AncientTail(kind string) (uint64, error) {
+	return t.db.AncientTail(kind)
+}
+
 // AppendAncient is a noop passthrough that just forwards the request to the underlying
 // database.
 func (t *table) AppendAncient(number uint64, hash, header, body, receipts, td []byte) error {
@@ -86,6 +92,24 @@ func (t *table) TruncateAncients(items uint64) error {
 	return t.db.TruncateAncients(items)
 }
 
+// TruncateAncientTail is a noop passthrough that just forwards the request to the
+// underlying database.
+func (t *table) TruncateAncientTail(kind string, tail uint64) (uint64, error) {
+	return t.db.TruncateAncientTail(kind, tail)
+}
+
+// AppendAncientItem is a noop passthrough that just forwards the request to the
+// underlying database.
+func (t *table) AppendAncientItem(kind string, number uint64, blob []byte) error {
+	return t.db.AppendAncientItem(kind, number, blob)
+}
+
+// TruncateAncientItems is a noop passthrough that just forwards the request to the
+// underlying database.
+func (t *table) TruncateAncientItems(kind string, items uint64) error {
+	return t.db.TruncateAncientItems(kind, items)
+}
+
 // Sync is a noop passthrough that just forwards the request to the underlying
 // database.
 func (t *table) Sync() error {
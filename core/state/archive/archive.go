@@ -0,0 +1,406 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package archive implements a state history built from the diff layers of the
+// snapshot tree, serving historical state without retaining archive tries.
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"PureChain/common"
+	"PureChain/core/rawdb"
+	"PureChain/core/state/snapshot"
+	"PureChain/core/types"
+	"PureChain/ethdb"
+	"PureChain/log"
+	"PureChain/rlp"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	// searchLimit is the number of canonical headers searched backwards from the
+	// chain head for the block of a flattened state.
+	searchLimit = 4096
+
+	// diffCacheSize is the number of decoded state diffs kept in memory.
+	diffCacheSize = 256
+)
+
+var (
+	// ErrStateNotArchived is returned if the requested state is outside of the
+	// range of blocks covered by the state history.
+	ErrStateNotArchived = errors.New("state not archived")
+
+	// errStateUnavailable is returned if the live snapshot layer at the head of
+	// the state history is not accessible.
+	errStateUnavailable = errors.New("archived state unavailable")
+)
+
+// Chain defines the canonical chain the state history is built for.
+type Chain interface {
+	// CurrentHeader retrieves the head header of the canonical chain.
+	CurrentHeader() *types.Header
+
+	// GetHeaderByNumber retrieves a canonical header by number.
+	GetHeaderByNumber(number uint64) *types.Header
+}
+
+// Archive maintains the history of the state, storing for every block the
+// previous values of the accounts and storage slots it modified.
+//
+// The state diffs are collected from the snapshot tree whenever a block diff
+// layer is flattened, and appended to the ancient store. Each modification is
+// also indexed by account or storage slot and block number in the key-value
+// store. The value of an item after block N is the previous value stored in
+// the first block after N modifying it, or the value in the live snapshot layer
+// of the last archived block if it was not modified since.
+//
+// The history covers a continuous range of blocks. If a block can't be archived,
+// e.g. because the snapshot is being generated, the history is restarted from
+// the next flattened block.
+type Archive struct {
+	db    ethdb.Database
+	chain Chain
+	snaps *snapshot.Tree
+
+	tail uint64      // First block whose state diff is archived
+	head uint64      // Next block whose state diff is to be archived, zero if none
+	root common.Hash // State root of the last archived block
+	lock sync.RWMutex
+
+	diffs *lru.Cache // Recently accessed state diffs by block number
+}
+
+// New opens the state history stored in the database and starts extending it
+// with the blocks flattened by the snapshot tree. An ancient store is required
+// to hold the state diffs.
+func New(db ethdb.Database, chain Chain, snaps *snapshot.Tree) (*Archive, error) {
+	diffs, _ := lru.New(diffCacheSize)
+	a := &Archive{
+		db:    db,
+		chain: chain,
+		snaps: snaps,
+		diffs: diffs,
+	}
+	if tail, head := rawdb.ReadStateHistoryTail(db), rawdb.ReadStateHistoryHead(db); tail != nil && head != nil {
+		a.tail, a.head = *tail, *head
+	}
+	// Drop any state diff appended but not indexed before a crash
+	if err := rawdb.TruncateStateHistory(db, a.head-a.tail); err != nil {
+		return nil, err
+	}
+	if a.head > a.tail && !rawdb.HasStateHistory(db, a.head-a.tail-1) {
+		log.Warn("State history incomplete, discarding", "tail", a.tail, "head", a.head)
+		if err := a.reset(0); err != nil {
+			return nil, err
+		}
+	}
+	// Discard the archived blocks beyond the chain head
+	if number := chain.CurrentHeader().Number.Uint64(); a.head > number+1 {
+		if err := a.rewind(number); err != nil {
+			return nil, err
+		}
+	}
+	if a.head > a.tail {
+		header := chain.GetHeaderByNumber(a.head - 1)
+		if header == nil {
+			return nil, fmt.Errorf("missing header #%d", a.head-1)
+		}
+		a.root = header.Root
+		log.Info("Loaded state history", "tail", a.tail, "head", a.head-1)
+	}
+	snaps.SetFlattenHook(a.index)
+	return a, nil
+}
+
+// Close stops extending the state history.
+func (a *Archive) Close() {
+	a.snaps.SetFlattenHook(nil)
+}
+
+// Range returns the numbers of the oldest and newest blocks whose state can be
+// served from the state history.
+func (a *Archive) Range() (uint64, uint64, bool) {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	if a.head <= a.tail {
+		return 0, 0, false
+	}
+	return a.tail - 1, a.head - 1, true
+}
+
+// Rewind discards the state history of all the blocks above the given number.
+func (a *Archive) Rewind(number uint64) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.rewind(number)
+}
+
+// Account retrieves the account with the given hash in the slim snapshot format
+// as it was after the given block.
+func (a *Archive) Account(hash common.Hash, number uint64) ([]byte, error) {
+	return a.lookup(number, func(from uint64) (uint64, bool) {
+		return rawdb.FindStateHistoryAccount(a.db, hash, from)
+	}, func(diff *snapshot.StateDiff) []byte {
+		blob, _ := diff.LookupAccount(hash)
+		return blob
+	}, func(snap snapshot.Snapshot) ([]byte, error) {
+		return snap.AccountRLP(hash)
+	})
+}
+
+// Storage retrieves the storage slot with the given hash of the account as it
+// was after the given block.
+func (a *Archive) Storage(accountHash, storageHash common.Hash, number uint64) ([]byte, error) {
+	return a.lookup(number, func(from uint64) (uint64, bool) {
+		return rawdb.FindStateHistoryStorage(a.db, accountHash, storageHash, from)
+	}, func(diff *snapshot.StateDiff) []byte {
+		blob, _ := diff.LookupStorage(accountHash, storageHash)
+		return blob
+	}, func(snap snapshot.Snapshot) ([]byte, error) {
+		return snap.Storage(accountHash, storageHash)
+	})
+}
+
+// lookup retrieves an item as it was after the given block, either from the
+// state diff of the first block modifying it afterwards, or from the live
+// snapshot layer if it was not modified since.
+func (a *Archive) lookup(number uint64, find func(uint64) (uint64, bool), previous func(*snapshot.StateDiff) []byte, current func(snapshot.Snapshot) ([]byte, error)) ([]byte, error) {
+	for {
+		a.lock.RLock()
+		if a.head <= a.tail || number+1 < a.tail || number >= a.head {
+			a.lock.RUnlock()
+			return nil, ErrStateNotArchived
+		}
+		if modified, ok := find(number + 1); ok && modified < a.head {
+			diff, err := a.diff(modified)
+			a.lock.RUnlock()
+			if err != nil {
+				return nil, err
+			}
+			return previous(diff), nil
+		}
+		head, root := a.head, a.root
+		a.lock.RUnlock()
+
+		// Not modified since, read the live state without holding the lock as the
+		// snapshot tree notifies the flattened layers with its own lock held. If
+		// the layer gets flattened meanwhile, retry with the extended history.
+		var (
+			blob []byte
+			err  = snapshot.ErrSnapshotStale
+		)
+		if snap := a.snaps.Snapshot(root); snap != nil {
+			blob, err = current(snap)
+		}
+		if err == nil {
+			return blob, nil
+		}
+		a.lock.RLock()
+		extended := a.head != head
+		a.lock.RUnlock()
+
+		if !extended {
+			if err == snapshot.ErrSnapshotStale {
+				err = errStateUnavailable
+			}
+			return nil, err
+		}
+	}
+}
+
+// diff retrieves the state diff of the given archived block. The lock must be
+// held by the caller.
+func (a *Archive) diff(number uint64) (*snapshot.StateDiff, error) {
+	if diff, ok := a.diffs.Get(number); ok {
+		return diff.(*snapshot.StateDiff), nil
+	}
+	blob := rawdb.ReadStateHistory(a.db, number-a.tail)
+	if len(blob) == 0 {
+		return nil, fmt.Errorf("missing state diff #%d", number)
+	}
+	diff := new(snapshot.StateDiff)
+	if err := rlp.DecodeBytes(blob, diff); err != nil {
+		return nil, fmt.Errorf("invalid state diff #%d: %v", number, err)
+	}
+	a.diffs.Add(number, diff)
+	return diff, nil
+}
+
+// index is the flatten hook of the snapshot tree, archiving the state diff of
+// the block with the given state root.
+func (a *Archive) index(root common.Hash, diff *snapshot.StateDiff) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	// Archive the diff if it belongs to the next block, filling in the blocks
+	// which didn't modify the state (and thus had no diff layer) beforehand
+	for a.head != 0 {
+		header := a.chain.GetHeaderByNumber(a.head)
+		if header == nil {
+			break
+		}
+		if header.Root == root {
+			if err := a.append(root, diff); err != nil {
+				log.Error("Failed to archive state diff", "number", a.head, "err", err)
+			}
+			return
+		}
+		if header.Root != a.root {
+			break
+		}
+		if err := a.append(header.Root, new(snapshot.StateDiff)); err != nil {
+			log.Error("Failed to archive state diff", "number", a.head, "err", err)
+			return
+		}
+	}
+	// Not the next block, locate it on the canonical chain. Diff layers loaded
+	// from the journal might get flattened again after a restart, skip those.
+	number, ok := a.locate(root)
+	if !ok {
+		log.Debug("Flattened state not canonical", "root", root)
+		return
+	}
+	if number < a.head {
+		return
+	}
+	if a.head > a.tail {
+		log.Warn("Gap in state history, restarting", "head", a.head, "number", number)
+	} else {
+		log.Info("Starting state history", "number", number)
+	}
+	if err := a.reset(number); err != nil {
+		log.Error("Failed to reset state history", "err", err)
+		return
+	}
+	if err := a.append(root, diff); err != nil {
+		log.Error("Failed to archive state diff", "number", a.head, "err", err)
+	}
+}
+
+// locate searches the recent canonical chain for the block with the given state
+// root.
+func (a *Archive) locate(root common.Hash) (uint64, bool) {
+	number := a.chain.CurrentHeader().Number.Uint64()
+	for i := 0; i < searchLimit; i++ {
+		header := a.chain.GetHeaderByNumber(number)
+		if header == nil {
+			return 0, false
+		}
+		if header.Root == root {
+			return number, true
+		}
+		if number == 0 {
+			return 0, false
+		}
+		number--
+	}
+	return 0, false
+}
+
+// append archives the state diff of the next block, with the given state root.
+// The lock must be held by the caller.
+func (a *Archive) append(root common.Hash, diff *snapshot.StateDiff) error {
+	blob, err := rlp.EncodeToBytes(diff)
+	if err != nil {
+		return err
+	}
+	if err := rawdb.AppendStateHistory(a.db, a.head-a.tail, blob); err != nil {
+		return err
+	}
+	batch := a.db.NewBatch()
+	for _, account := range diff.Accounts {
+		rawdb.WriteStateHistoryAccountIndex(batch, account.Hash, a.head)
+	}
+	for _, storage := range diff.Storage {
+		for _, slot := range storage.Slots {
+			rawdb.WriteStateHistoryStorageIndex(batch, storage.Hash, slot.Hash, a.head)
+		}
+	}
+	rawdb.WriteStateHistoryHead(batch, a.head+1)
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	a.diffs.Add(a.head, diff)
+	a.head, a.root = a.head+1, root
+	return nil
+}
+
+// rewind discards the state history of all the blocks above the given number.
+// The lock must be held by the caller.
+func (a *Archive) rewind(number uint64) error {
+	if a.head <= number+1 {
+		return nil
+	}
+	if number < a.tail {
+		return a.reset(0)
+	}
+	batch := a.db.NewBatch()
+	for n := number + 1; n < a.head; n++ {
+		diff, err := a.diff(n)
+		if err != nil {
+			return err
+		}
+		for _, account := range diff.Accounts {
+			rawdb.DeleteStateHistoryAccountIndex(batch, account.Hash, n)
+		}
+		for _, storage := range diff.Storage {
+			for _, slot := range storage.Slots {
+				rawdb.DeleteStateHistoryStorageIndex(batch, storage.Hash, slot.Hash, n)
+			}
+		}
+	}
+	rawdb.WriteStateHistoryHead(batch, number+1)
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	if err := rawdb.TruncateStateHistory(a.db, number+1-a.tail); err != nil {
+		return err
+	}
+	if header := a.chain.GetHeaderByNumber(number); header != nil {
+		a.root = header.Root
+	}
+	a.head = number + 1
+	a.diffs.Purge()
+
+	log.Info("Rewound state history", "head", number)
+	return nil
+}
+
+// reset discards the entire state history, restarting it from the given block.
+// The lock must be held by the caller.
+func (a *Archive) reset(number uint64) error {
+	if err := rawdb.DeleteStateHistoryIndex(a.db); err != nil {
+		return err
+	}
+	if err := rawdb.TruncateStateHistory(a.db, 0); err != nil {
+		return err
+	}
+	batch := a.db.NewBatch()
+	rawdb.WriteStateHistoryTail(batch, number)
+	rawdb.WriteStateHistoryHead(batch, number)
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	a.tail, a.head, a.root = number, number, common.Hash{}
+	a.diffs.Purge()
+	return nil
+}
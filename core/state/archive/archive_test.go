@@ -0,0 +1,184 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package archive
+
+import (
+	"math/big"
+	"testing"
+
+	"PureChain/common"
+	"PureChain/core/rawdb"
+	"PureChain/core/state"
+	"PureChain/core/state/snapshot"
+	"PureChain/core/types"
+)
+
+var (
+	testAccounts = 8
+	testSlots    = 4
+	testContract = common.HexToAddress("0xc0de")
+)
+
+// testState is the expected content of a state after a block.
+type testState struct {
+	balances     []*big.Int
+	lockBalances []*big.Int
+	slots        []common.Hash
+}
+
+// testChain is a minimal chain importing blocks which modify a few accounts and
+// storage slots each, keeping the recent states in the snapshot tree.
+type testChain struct {
+	headers []*types.Header
+	states  []*testState
+}
+
+func (c *testChain) CurrentHeader() *types.Header {
+	return c.headers[len(c.headers)-1]
+}
+
+func (c *testChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number >= uint64(len(c.headers)) {
+		return nil
+	}
+	return c.headers[number]
+}
+
+// collect reads the expected content of the test state.
+func collect(statedb *state.StateDB) *testState {
+	s := new(testState)
+	for i := 0; i < testAccounts; i++ {
+		addr := common.BigToAddress(big.NewInt(int64(i + 1)))
+		s.balances = append(s.balances, statedb.GetBalance(addr))
+		s.lockBalances = append(s.lockBalances, statedb.GetLockBalance(addr))
+	}
+	for i := 0; i < testSlots; i++ {
+		s.slots = append(s.slots, statedb.GetState(testContract, common.BigToHash(big.NewInt(int64(i)))))
+	}
+	return s
+}
+
+func TestStateHistory(t *testing.T) {
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	sdb := state.NewDatabase(db)
+	statedb, _ := state.New(common.Hash{}, sdb, nil)
+	statedb.SetState(testContract, common.Hash{}, common.HexToHash("0x01"))
+	root, _ := statedb.Commit(true)
+	if err := sdb.TrieDB().Commit(root, false, nil); err != nil {
+		t.Fatalf("failed to commit genesis state: %v", err)
+	}
+	snaps, err := snapshot.New(db, sdb.TrieDB(), 16, 4, root, false, true, false)
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	chain := &testChain{headers: []*types.Header{{Number: new(big.Int), Root: root}}}
+	statedb, _ = state.New(root, sdb, nil)
+	chain.states = append(chain.states, collect(statedb))
+
+	archive, err := New(db, chain, snaps)
+	if err != nil {
+		t.Fatalf("failed to open state history: %v", err)
+	}
+	// Import blocks modifying balances, lock balances and storage, with the
+	// contract destructed midway and a block leaving the state untouched
+	for number := uint64(1); number <= 48; number++ {
+		parent := chain.CurrentHeader()
+		statedb, err := state.New(parent.Root, sdb, snaps)
+		if err != nil {
+			t.Fatalf("failed to open state %d: %v", number-1, err)
+		}
+		switch {
+		case number == 20:
+			statedb.Suicide(testContract)
+		case number == 30:
+		default:
+			addr := common.BigToAddress(new(big.Int).SetUint64(number%uint64(testAccounts) + 1))
+			statedb.SetBalance(addr, new(big.Int).SetUint64(number))
+			statedb.SetLockBalance(addr, new(big.Int).SetUint64(2*number))
+			statedb.SetState(testContract, common.BigToHash(new(big.Int).SetUint64(number%uint64(testSlots))), common.BigToHash(new(big.Int).SetUint64(number)))
+		}
+		root, err := statedb.Commit(true)
+		if err != nil {
+			t.Fatalf("failed to commit state %d: %v", number, err)
+		}
+		chain.headers = append(chain.headers, &types.Header{Number: new(big.Int).SetUint64(number), ParentHash: parent.Hash(), Root: root})
+		statedb, _ = state.New(root, sdb, nil)
+		chain.states = append(chain.states, collect(statedb))
+	}
+	check := func(first, last uint64) {
+		t.Helper()
+		if have, want := first, uint64(0); have != want {
+			t.Fatalf("first archived state mismatch: have %d, want %d", have, want)
+		}
+		for number := first; number <= last; number++ {
+			statedb, err := state.New(chain.headers[number].Root, NewDatabase(archive, number, sdb), nil)
+			if err != nil {
+				t.Fatalf("failed to open archived state %d: %v", number, err)
+			}
+			have, want := collect(statedb), chain.states[number]
+			for i := 0; i < testAccounts; i++ {
+				if have.balances[i].Cmp(want.balances[i]) != 0 {
+					t.Errorf("state %d: balance %d mismatch: have %v, want %v", number, i, have.balances[i], want.balances[i])
+				}
+				if have.lockBalances[i].Cmp(want.lockBalances[i]) != 0 {
+					t.Errorf("state %d: lock balance %d mismatch: have %v, want %v", number, i, have.lockBalances[i], want.lockBalances[i])
+				}
+			}
+			for i := 0; i < testSlots; i++ {
+				if have.slots[i] != want.slots[i] {
+					t.Errorf("state %d: slot %d mismatch: have %x, want %x", number, i, have.slots[i], want.slots[i])
+				}
+			}
+		}
+		if _, err := archive.Account(common.Hash{}, last+1); err != ErrStateNotArchived {
+			t.Errorf("state beyond history served: %v", err)
+		}
+	}
+	first, last, ok := archive.Range()
+	if !ok {
+		t.Fatalf("state history empty")
+	}
+	if last < 40 {
+		t.Fatalf("last archived state too old: %d", last)
+	}
+	check(first, last)
+
+	// Reopen the history and ensure it's reloaded from the database
+	archive.Close()
+
+	archive, err = New(db, chain, snaps)
+	if err != nil {
+		t.Fatalf("failed to reopen state history: %v", err)
+	}
+	check(first, last)
+
+	// Rewind the history and ensure the discarded states are not served
+	if err := archive.Rewind(last - 10); err != nil {
+		t.Fatalf("failed to rewind state history: %v", err)
+	}
+	if _, have, _ := archive.Range(); have != last-10 {
+		t.Fatalf("rewound state history mismatch: have %d, want %d", have, last-10)
+	}
+	if _, err := archive.Account(common.Hash{}, last-9); err != ErrStateNotArchived {
+		t.Errorf("rewound state served: %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package archive
+
+import (
+	"errors"
+
+	"PureChain/common"
+	"PureChain/core/state"
+	"PureChain/core/state/snapshot"
+	"PureChain/crypto"
+	"PureChain/ethdb"
+	"PureChain/trie"
+)
+
+var (
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+	// errNoTrieNodes is returned for operations requiring the trie nodes of an
+	// archived state, which are not retained.
+	errNoTrieNodes = errors.New("archived state has no trie nodes")
+)
+
+// database is a state database serving the state after a particular block
+// from the state history. Contract codes are read from the wrapped database.
+type database struct {
+	state.Database
+	archive *Archive
+	number  uint64
+}
+
+// NewDatabase creates a state database serving the state after the given block
+// from the state history, with the contract codes of the given database.
+func NewDatabase(archive *Archive, number uint64, db state.Database) state.Database {
+	return &database{Database: db, archive: archive, number: number}
+}
+
+// OpenTrie opens the account trie of the archived state.
+func (db *database) OpenTrie(root common.Hash) (state.Trie, error) {
+	return newArchiveTrie(db.archive, db.number, root, nil), nil
+}
+
+// OpenStorageTrie opens the storage trie of an account of the archived state.
+func (db *database) OpenStorageTrie(addrHash, root common.Hash) (state.Trie, error) {
+	return newArchiveTrie(db.archive, db.number, root, &addrHash), nil
+}
+
+// CopyTrie returns an independent copy of the given archived trie.
+func (db *database) CopyTrie(t state.Trie) state.Trie {
+	return t.(*archiveTrie).copy()
+}
+
+// CacheAccount is a noop, archived tries are not cached.
+func (db *database) CacheAccount(root common.Hash, t state.Trie) {}
+
+// CacheStorage is a noop, archived tries are not cached.
+func (db *database) CacheStorage(addrHash common.Hash, root common.Hash, t state.Trie) {}
+
+// Purge is a noop, archived tries are not cached.
+func (db *database) Purge() {}
+
+// archiveTrie is an account or storage trie of an archived state. The leaves
+// are retrieved from the state history, modifications are only kept in memory
+// and don't change the root hash.
+type archiveTrie struct {
+	archive *Archive
+	number  uint64
+	root    common.Hash
+	owner   *common.Hash           // Hash of the account owning the storage trie, nil for the account trie
+	dirty   map[common.Hash][]byte // Modified leaves, empty if deleted
+}
+
+func newArchiveTrie(archive *Archive, number uint64, root common.Hash, owner *common.Hash) *archiveTrie {
+	return &archiveTrie{
+		archive: archive,
+		number:  number,
+		root:    root,
+		owner:   owner,
+		dirty:   make(map[common.Hash][]byte),
+	}
+}
+
+// GetKey returns nil, the preimages of archived tries are not tracked.
+func (t *archiveTrie) GetKey([]byte) []byte {
+	return nil
+}
+
+// TryGet returns the archived value for the key, in the trie leaf format.
+func (t *archiveTrie) TryGet(key []byte) ([]byte, error) {
+	hash := crypto.Keccak256Hash(key)
+	if blob, ok := t.dirty[hash]; ok {
+		return blob, nil
+	}
+	if t.owner != nil {
+		if t.root == emptyRoot {
+			return nil, nil
+		}
+		return t.archive.Storage(*t.owner, hash, t.number)
+	}
+	blob, err := t.archive.Account(hash, t.number)
+	if err != nil || len(blob) == 0 {
+		return nil, err
+	}
+	return snapshot.FullAccountRLP(blob)
+}
+
+// TryUpdate associates the key with the value in memory.
+func (t *archiveTrie) TryUpdate(key, value []byte) error {
+	t.dirty[crypto.Keccak256Hash(key)] = common.CopyBytes(value)
+	return nil
+}
+
+// TryDelete removes the value of the key in memory.
+func (t *archiveTrie) TryDelete(key []byte) error {
+	t.dirty[crypto.Keccak256Hash(key)] = nil
+	return nil
+}
+
+// Hash returns the root hash of the archived trie, regardless of any in-memory
+// modifications.
+func (t *archiveTrie) Hash() common.Hash {
+	return t.root
+}
+
+// Commit returns an error, archived tries can't be written.
+func (t *archiveTrie) Commit(onleaf trie.LeafCallback) (common.Hash, error) {
+	return common.Hash{}, errNoTrieNodes
+}
+
+// NodeIterator returns an exhausted iterator, archived tries have no nodes.
+func (t *archiveTrie) NodeIterator(startKey []byte) trie.NodeIterator {
+	return new(emptyIterator)
+}
+
+// Prove returns an error, archived tries have no nodes to prove with.
+func (t *archiveTrie) Prove(key []byte, fromLevel uint, proofDb ethdb.KeyValueWriter) error {
+	return errNoTrieNodes
+}
+
+// copy returns an independent copy of the trie.
+func (t *archiveTrie) copy() *archiveTrie {
+	cpy := newArchiveTrie(t.archive, t.number, t.root, t.owner)
+	for hash, blob := range t.dirty {
+		cpy.dirty[hash] = blob
+	}
+	return cpy
+}
+
+// emptyIterator is the node iterator of archived tries, failing right away.
+type emptyIterator struct{}
+
+func (it *emptyIterator) Next(bool) bool                  { return false }
+func (it *emptyIterator) Error() error                    { return errNoTrieNodes }
+func (it *emptyIterator) Hash() common.Hash               { return common.Hash{} }
+func (it *emptyIterator) Parent() common.Hash             { return common.Hash{} }
+func (it *emptyIterator) Path() []byte                    { return nil }
+func (it *emptyIterator) Leaf() bool                      { return false }
+func (it *emptyIterator) LeafKey() []byte                 { return nil }
+func (it *emptyIterator) LeafBlob() []byte                { return nil }
+func (it *emptyIterator) LeafProof() [][]byte             { return nil }
+func (it *emptyIterator) AddResolver(ethdb.KeyValueStore) {}
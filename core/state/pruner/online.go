@@ -0,0 +1,497 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"PureChain/common"
+	"PureChain/core/rawdb"
+	"PureChain/core/state/snapshot"
+	"PureChain/core/types"
+	"PureChain/ethdb"
+	"PureChain/log"
+	"PureChain/trie"
+)
+
+const (
+	// onlineRecheckInterval is the time between two checks of the chain head
+	// while the online pruner waits for the pruning target to mature.
+	onlineRecheckInterval = 3 * time.Second
+
+	// onlineSweepBatch is the number of stale entries deleted at once while
+	// holding the chain lock.
+	onlineSweepBatch = 1024
+
+	// onlineNodeCost is the approximate size of a trie node read while marking
+	// the target state, charged against the IO budget.
+	onlineNodeCost = 128
+)
+
+// Phases of an online pruning run, as reported in the status.
+const (
+	phaseWaiting  = "waiting"
+	phaseMarking  = "marking"
+	phaseSweeping = "sweeping"
+	phaseDone     = "done"
+	phaseAborted  = "aborted"
+	phaseFailed   = "failed"
+)
+
+// errPruningAborted is returned if the online pruning is stopped midway.
+var errPruningAborted = errors.New("pruning aborted")
+
+// Chain defines the live blockchain the online pruner runs against.
+type Chain interface {
+	// CurrentHeader retrieves the head header of the canonical chain.
+	CurrentHeader() *types.Header
+
+	// GetHeaderByNumber retrieves a canonical header by number.
+	GetHeaderByNumber(number uint64) *types.Header
+}
+
+// OnlineConfig contains the settings of an online pruning run.
+type OnlineConfig struct {
+	Datadir       string // Directory to persist the state bloom into
+	BloomSize     uint64 // Megabytes of memory allocated to the state bloom
+	TriesInMemory uint64 // Number of recent states kept in memory by the chain
+	Budget        uint64 // Database IO allowed in bytes per second, zero for unlimited
+}
+
+// Status describes the progress of an online pruning run.
+type Status struct {
+	Phase    string             `json:"phase"`
+	Target   common.Hash        `json:"target"`
+	Number   uint64             `json:"number"`
+	Marked   uint64             `json:"marked"`
+	Scanned  uint64             `json:"scanned"`
+	Deleted  uint64             `json:"deleted"`
+	Size     common.StorageSize `json:"size"`
+	Progress float64            `json:"progress"`
+	Budget   uint64             `json:"budget"`
+	Elapsed  string             `json:"elapsed"`
+	Error    string             `json:"error,omitempty"`
+}
+
+// OnlinePruner deletes the stale state from the database of a running node.
+//
+// Contrary to the offline Pruner, the chain keeps importing blocks meanwhile, so
+// the pruner has to keep every node written after it started. It does so by
+// hooking into the trie database and marking each flushed node in the state
+// bloom. Once the bottom-most diff layer of the live snapshot tree was created
+// after the hook got installed, it is picked as the target: every live state is
+// then made of the nodes of the target and of nodes written since. The target
+// is persisted and marked, after which all unmarked trie nodes are deleted in
+// batches, holding the chain lock to exclude concurrent flushes.
+//
+// The state bloom is persisted under the same name as the offline pruner does,
+// so that RecoverPruning resumes the deletion if the node crashes midway. It's
+// removed if the pruning finishes or is stopped, as the database is consistent
+// at any point of the run. Contract codes are written outside of the trie
+// database and are rarely stale, they are left untouched.
+//
+// The bloom is only persisted once the target is marked, the nodes flushed
+// afterwards are marked in memory alone. Recovering from a crash thus deletes
+// them along with the states above the target, like after an offline pruning:
+// the chain rewinds to the target on restart and re-executes the blocks above.
+type OnlinePruner struct {
+	db         ethdb.Database
+	chain      Chain
+	triedb     *trie.Database
+	snaptree   *snapshot.Tree
+	chainLock  sync.Locker // Lock excluding the trie flushes of the chain
+	config     OnlineConfig
+	stateBloom *stateBloom
+
+	// Progress counters, accessed atomically
+	marked   uint64
+	scanned  uint64
+	deleted  uint64
+	size     uint64
+	position uint64 // Leading 8 bytes of the last swept trie node key
+
+	status     Status       // Phase, target and error of the run
+	start      time.Time    // Time the run was started
+	end        time.Time    // Time the run finished, zero if still running
+	statusLock sync.RWMutex // Protects the status fields
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+// NewOnlinePruner creates an online pruner for the given live chain. The chain
+// lock must be held by the chain whenever trie nodes are flushed to disk.
+func NewOnlinePruner(db ethdb.Database, chain Chain, triedb *trie.Database, snaptree *snapshot.Tree, chainLock sync.Locker, config OnlineConfig) (*OnlinePruner, error) {
+	if snaptree == nil {
+		return nil, errors.New("snapshot not available")
+	}
+	if generating, err := snaptree.Generating(); err != nil {
+		return nil, err
+	} else if generating {
+		return nil, errors.New("snapshot not fully generated yet")
+	}
+	// Refuse to start on top of an interrupted pruning, it is resumed by
+	// RecoverPruning on the next restart.
+	path, _, err := findBloomFilter(config.Datadir)
+	if err != nil {
+		return nil, err
+	}
+	if path != "" {
+		return nil, fmt.Errorf("unfinished pruning found: %s", path)
+	}
+	// Sanitize the bloom filter size if it's too small.
+	if config.BloomSize < 256 {
+		log.Warn("Sanitizing bloomfilter size", "provided(MB)", config.BloomSize, "updated(MB)", 256)
+		config.BloomSize = 256
+	}
+	stateBloom, err := newStateBloomWithSize(config.BloomSize)
+	if err != nil {
+		return nil, err
+	}
+	return &OnlinePruner{
+		db:         db,
+		chain:      chain,
+		triedb:     triedb,
+		snaptree:   snaptree,
+		chainLock:  chainLock,
+		config:     config,
+		stateBloom: stateBloom,
+		status:     Status{Phase: phaseWaiting, Budget: config.Budget},
+		quit:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}, nil
+}
+
+// Start runs the pruning in the background.
+func (p *OnlinePruner) Start() {
+	p.statusLock.Lock()
+	p.start = time.Now()
+	p.statusLock.Unlock()
+
+	go p.loop()
+}
+
+// Stop aborts the pruning and waits for it to terminate.
+func (p *OnlinePruner) Stop() {
+	select {
+	case <-p.quit:
+	default:
+		close(p.quit)
+	}
+	<-p.done
+}
+
+// Running reports whether the pruning is still in progress.
+func (p *OnlinePruner) Running() bool {
+	select {
+	case <-p.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Done returns a channel which is closed when the pruning terminates.
+func (p *OnlinePruner) Done() <-chan struct{} {
+	return p.done
+}
+
+// Status returns the current progress of the pruning.
+func (p *OnlinePruner) Status() *Status {
+	p.statusLock.RLock()
+	status := p.status
+	end := p.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+	status.Elapsed = common.PrettyDuration(end.Sub(p.start)).String()
+	p.statusLock.RUnlock()
+
+	status.Marked = atomic.LoadUint64(&p.marked)
+	status.Scanned = atomic.LoadUint64(&p.scanned)
+	status.Deleted = atomic.LoadUint64(&p.deleted)
+	status.Size = common.StorageSize(atomic.LoadUint64(&p.size))
+	switch status.Phase {
+	case phaseSweeping:
+		status.Progress = float64(atomic.LoadUint64(&p.position)) / math.MaxUint64
+	case phaseDone:
+		status.Progress = 1
+	}
+	return &status
+}
+
+// setPhase moves the pruning into the given phase.
+func (p *OnlinePruner) setPhase(phase string) {
+	p.statusLock.Lock()
+	p.status.Phase = phase
+	p.statusLock.Unlock()
+}
+
+// mark commits a flushed trie node into the state bloom.
+func (p *OnlinePruner) mark(hash common.Hash) {
+	p.stateBloom.Put(hash.Bytes(), nil)
+	atomic.AddUint64(&p.marked, 1)
+}
+
+// loop runs the pruning and cleans up after it terminates.
+func (p *OnlinePruner) loop() {
+	defer close(p.done)
+
+	var (
+		root, err = p.prune()
+		phase     = phaseDone
+	)
+	switch {
+	case err == errPruningAborted:
+		phase = phaseAborted
+		log.Warn("State pruning aborted", "elapsed", common.PrettyDuration(time.Since(p.start)))
+	case err != nil:
+		phase = phaseFailed
+		log.Error("State pruning failed", "err", err)
+	default:
+		log.Info("State pruning successful", "pruned", common.StorageSize(atomic.LoadUint64(&p.size)), "elapsed", common.PrettyDuration(time.Since(p.start)))
+	}
+	p.chainLock.Lock()
+	p.triedb.SetFlushHook(nil)
+	p.chainLock.Unlock()
+
+	// The database is consistent whenever the deletion stops, there is
+	// nothing left to recover.
+	if root != (common.Hash{}) {
+		os.RemoveAll(bloomFilterName(p.config.Datadir, root))
+	}
+	p.statusLock.Lock()
+	p.status.Phase = phase
+	if err != nil && err != errPruningAborted {
+		p.status.Error = err.Error()
+	}
+	p.end = time.Now()
+	p.statusLock.Unlock()
+}
+
+// prune executes the pruning run, returning the selected target root.
+func (p *OnlinePruner) prune() (common.Hash, error) {
+	// Start marking all flushed nodes, the live states created from now on
+	// are made of these and of the nodes of their ancestors.
+	p.chainLock.Lock()
+	p.triedb.SetFlushHook(p.mark)
+	start := p.chain.CurrentHeader().Number.Uint64()
+	p.chainLock.Unlock()
+
+	log.Info("Started online state pruning", "number", start, "budget", p.config.Budget)
+
+	// Wait until all the live states were created after the hook, then pick
+	// the bottom-most one as the target. Similarly to the offline pruning, a
+	// reorg below the target is not expected.
+	var (
+		header *types.Header
+		err    error
+	)
+	for {
+		p.chainLock.Lock()
+		header, err = p.selectTarget(start)
+		p.chainLock.Unlock()
+
+		if err != nil {
+			return common.Hash{}, err
+		}
+		if header != nil {
+			break
+		}
+		select {
+		case <-p.quit:
+			return common.Hash{}, errPruningAborted
+		case <-time.After(onlineRecheckInterval):
+		}
+	}
+	root := header.Root
+
+	p.statusLock.Lock()
+	p.status.Phase, p.status.Target, p.status.Number = phaseMarking, root, header.Number.Uint64()
+	p.statusLock.Unlock()
+
+	log.Info("Selected online pruning target", "number", header.Number, "hash", header.Hash(), "root", root)
+
+	// Traverse the target state and the genesis, committing all the entries
+	// into the bloom filter.
+	throttle := newThrottle(p.config.Budget, p.quit)
+	if err := commitState(p.db, root, p.stateBloom, func() error {
+		atomic.AddUint64(&p.marked, 1)
+		return throttle.consume(onlineNodeCost)
+	}); err != nil {
+		return common.Hash{}, err
+	}
+	if err := extractGenesis(p.db, p.stateBloom); err != nil {
+		return common.Hash{}, err
+	}
+	filterName := bloomFilterName(p.config.Datadir, root)
+
+	// Nodes flushed from now on are not persisted in the bloom, a recovery
+	// rewinds the chain to the target.
+	log.Info("Writing state bloom to disk", "name", filterName)
+	if err := p.stateBloom.Commit(filterName, filterName+stateBloomFileTempSuffix); err != nil {
+		return common.Hash{}, err
+	}
+	p.setPhase(phaseSweeping)
+	return root, p.sweep(throttle)
+}
+
+// selectTarget returns the header of the pruning target, or nil if the state
+// is not old enough yet. The chain lock is assumed to be held.
+func (p *OnlinePruner) selectTarget(start uint64) (*types.Header, error) {
+	head := p.chain.CurrentHeader()
+	if number := head.Number.Uint64(); number < start+p.config.TriesInMemory {
+		return nil, nil
+	}
+	header := p.chain.GetHeaderByNumber(head.Number.Uint64() - (p.config.TriesInMemory - 1))
+	if header == nil || p.snaptree.Snapshot(header.Root) == nil {
+		return nil, nil
+	}
+	// Persist the target state, so that it can be traversed from disk. All the
+	// dirty nodes are marked by the hook on the way.
+	if err := p.triedb.Commit(header.Root, false, nil); err != nil {
+		return nil, err
+	}
+	if blob := rawdb.ReadTrieNode(p.db, header.Root); len(blob) == 0 {
+		return nil, fmt.Errorf("associated state[%x] is not present", header.Root)
+	}
+	return header, nil
+}
+
+// sweep deletes all the trie nodes from the database which are not marked in
+// the state bloom.
+func (p *OnlinePruner) sweep(throttle *throttle) error {
+	var (
+		stale []common.Hash
+		iter  = p.db.NewIterator(nil, nil)
+	)
+	defer func() { iter.Release() }()
+
+	for iter.Next() {
+		key := iter.Key()
+
+		atomic.AddUint64(&p.scanned, 1)
+		if err := throttle.consume(len(key) + len(iter.Value())); err != nil {
+			return err
+		}
+		if len(key) != common.HashLength {
+			continue
+		}
+		atomic.StoreUint64(&p.position, binary.BigEndian.Uint64(key[:8]))
+		if ok, _ := p.stateBloom.Contain(key); ok {
+			continue
+		}
+		stale = append(stale, common.BytesToHash(key))
+		if len(stale) >= onlineSweepBatch {
+			if err := p.deleteStale(stale); err != nil {
+				return err
+			}
+			stale = stale[:0]
+
+			// Recreate the iterator after every batch commit in order
+			// to allow the underlying compactor to delete the entries.
+			next := common.CopyBytes(key)
+			iter.Release()
+			iter = p.db.NewIterator(nil, next)
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	return p.deleteStale(stale)
+}
+
+// deleteStale removes the given trie nodes from the database, excluding the
+// concurrent trie flushes of the chain.
+func (p *OnlinePruner) deleteStale(stale []common.Hash) error {
+	p.chainLock.Lock()
+	defer p.chainLock.Unlock()
+
+	var (
+		batch   = p.db.NewBatch()
+		deleted []common.Hash
+		size    int
+	)
+	for _, hash := range stale {
+		// The node might have been flushed again by the chain since it was
+		// picked, check it again now that flushes are excluded.
+		if ok, _ := p.stateBloom.Contain(hash.Bytes()); ok {
+			continue
+		}
+		blob := rawdb.ReadTrieNode(p.db, hash)
+		if len(blob) == 0 {
+			continue
+		}
+		rawdb.DeleteTrieNode(batch, hash)
+		deleted = append(deleted, hash)
+		size += common.HashLength + len(blob)
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	for _, hash := range deleted {
+		p.triedb.Evict(hash)
+	}
+	atomic.AddUint64(&p.deleted, uint64(len(deleted)))
+	atomic.AddUint64(&p.size, uint64(size))
+	return nil
+}
+
+// throttle limits the database IO of the pruning to the configured budget and
+// terminates it if the pruning is stopped.
+type throttle struct {
+	budget uint64 // Bytes per second, zero for unlimited
+	start  time.Time
+	used   uint64
+	quit   chan struct{}
+}
+
+func newThrottle(budget uint64, quit chan struct{}) *throttle {
+	return &throttle{budget: budget, start: time.Now(), quit: quit}
+}
+
+// consume charges the given amount of bytes against the budget, sleeping if
+// the budget is exhausted.
+func (t *throttle) consume(n int) error {
+	select {
+	case <-t.quit:
+		return errPruningAborted
+	default:
+	}
+	if t.budget == 0 {
+		return nil
+	}
+	t.used += uint64(n)
+
+	wait := time.Duration(float64(t.used)/float64(t.budget)*float64(time.Second)) - time.Since(t.start)
+	if wait < 10*time.Millisecond {
+		return nil
+	}
+	select {
+	case <-t.quit:
+		return errPruningAborted
+	case <-time.After(wait):
+		return nil
+	}
+}
@@ -0,0 +1,300 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"PureChain/common"
+	"PureChain/core/rawdb"
+	"PureChain/core/state"
+	"PureChain/core/state/snapshot"
+	"PureChain/core/types"
+	"PureChain/ethdb"
+	"PureChain/rlp"
+	"PureChain/trie"
+)
+
+// testChain is a minimal live chain, importing blocks which modify a few
+// accounts and storage slots each.
+type testChain struct {
+	db      ethdb.Database
+	sdb     state.Database
+	snaps   *snapshot.Tree
+	lock    sync.Mutex
+	headers []*types.Header
+	roots   []common.Hash // Roots referenced in the trie database
+	tries   uint64
+}
+
+func newTestChain(t *testing.T, tries uint64) *testChain {
+	db := rawdb.NewMemoryDatabase()
+	sdb := state.NewDatabase(db)
+
+	statedb, _ := state.New(common.Hash{}, sdb, nil)
+	for i := 0; i < 64; i++ {
+		statedb.SetBalance(common.BigToAddress(big.NewInt(int64(i))), big.NewInt(1))
+	}
+	statedb.SetCode(common.HexToAddress("0xc0de"), []byte{0x1})
+	root, _ := statedb.Commit(true)
+	if err := sdb.TrieDB().Commit(root, false, nil); err != nil {
+		t.Fatalf("failed to commit genesis state: %v", err)
+	}
+	genesis := &types.Header{Number: new(big.Int), Root: root}
+	rawdb.WriteBlock(db, types.NewBlockWithHeader(genesis))
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+
+	snaps, err := snapshot.New(db, sdb.TrieDB(), 16, int(tries), root, false, true, false)
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	return &testChain{db: db, sdb: sdb, snaps: snaps, headers: []*types.Header{genesis}, tries: tries}
+}
+
+func (c *testChain) CurrentHeader() *types.Header {
+	return c.headers[len(c.headers)-1]
+}
+
+func (c *testChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number >= uint64(len(c.headers)) {
+		return nil
+	}
+	return c.headers[number]
+}
+
+// insert imports a new block the way the blockchain does, keeping the recent
+// states in memory and flushing the trie database every other block.
+func (c *testChain) insert(t *testing.T) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	parent := c.CurrentHeader()
+	number := parent.Number.Uint64() + 1
+
+	statedb, err := state.New(parent.Root, c.sdb, c.snaps)
+	if err != nil {
+		t.Fatalf("failed to open state %d: %v", number-1, err)
+	}
+	for i := uint64(0); i < 4; i++ {
+		statedb.SetBalance(common.BigToAddress(new(big.Int).SetUint64((number*4+i)%64)), new(big.Int).SetUint64(number+1))
+	}
+	statedb.SetState(common.HexToAddress("0xc0de"), common.BigToHash(new(big.Int).SetUint64(number%8)), common.BigToHash(new(big.Int).SetUint64(number)))
+	root, err := statedb.Commit(true)
+	if err != nil {
+		t.Fatalf("failed to commit state %d: %v", number, err)
+	}
+	triedb := c.sdb.TrieDB()
+	triedb.Reference(root, common.Hash{})
+	c.roots = append(c.roots, root)
+	if number%2 == 0 {
+		triedb.Cap(0)
+	}
+	if len(c.roots) > int(c.tries) {
+		triedb.Dereference(c.roots[0])
+		c.roots = c.roots[1:]
+	}
+	header := &types.Header{Number: new(big.Int).SetUint64(number), ParentHash: parent.Hash(), Root: root}
+	rawdb.WriteHeader(c.db, header)
+	rawdb.WriteCanonicalHash(c.db, header.Hash(), number)
+	c.headers = append(c.headers, header)
+}
+
+// checkState traverses the entire state with the given root.
+func checkState(triedb *trie.Database, root common.Hash) error {
+	accTrie, err := trie.NewSecure(root, triedb)
+	if err != nil {
+		return err
+	}
+	accIter := accTrie.NodeIterator(nil)
+	for accIter.Next(true) {
+		if !accIter.Leaf() {
+			continue
+		}
+		var acc state.Account
+		if err := rlp.DecodeBytes(accIter.LeafBlob(), &acc); err != nil {
+			return err
+		}
+		if acc.Root == emptyRoot {
+			continue
+		}
+		storageTrie, err := trie.NewSecure(acc.Root, triedb)
+		if err != nil {
+			return err
+		}
+		storageIter := storageTrie.NodeIterator(nil)
+		for storageIter.Next(true) {
+		}
+		if err := storageIter.Error(); err != nil {
+			return err
+		}
+	}
+	return accIter.Error()
+}
+
+func TestOnlinePruning(t *testing.T) {
+	var (
+		tries   = uint64(4)
+		chain   = newTestChain(t, tries)
+		datadir = t.TempDir()
+	)
+	for i := 0; i < 16; i++ {
+		chain.insert(t)
+	}
+	stale := chain.headers[2].Root
+	if blob := rawdb.ReadTrieNode(chain.db, stale); len(blob) == 0 {
+		t.Fatalf("stale state not flushed")
+	}
+	p, err := NewOnlinePruner(chain.db, chain, chain.sdb.TrieDB(), chain.snaps, &chain.lock, OnlineConfig{
+		Datadir:       datadir,
+		TriesInMemory: tries,
+	})
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	p.Start()
+
+	// Keep importing blocks until the pruning terminates
+	timeout := time.After(time.Minute)
+	for p.Running() {
+		select {
+		case <-timeout:
+			p.Stop()
+			t.Fatalf("pruning timed out: %+v", p.Status())
+		case <-time.After(100 * time.Millisecond):
+			chain.insert(t)
+		}
+	}
+	status := p.Status()
+	if status.Phase != phaseDone {
+		t.Fatalf("pruning phase mismatch: have %s, want %s (error %q)", status.Phase, phaseDone, status.Error)
+	}
+	if status.Deleted == 0 {
+		t.Fatalf("no stale nodes deleted")
+	}
+	if blob := rawdb.ReadTrieNode(chain.db, stale); len(blob) != 0 {
+		t.Errorf("stale state root not deleted")
+	}
+	if path, _, _ := findBloomFilter(datadir); path != "" {
+		t.Errorf("state bloom left behind: %s", path)
+	}
+	// All the states from the target onwards must be intact, even with more
+	// blocks imported and flushed after the pruning.
+	for i := 0; i < 8; i++ {
+		chain.insert(t)
+	}
+	chain.sdb.TrieDB().Cap(0)
+	for number := status.Number; number < uint64(len(chain.headers)); number++ {
+		root := chain.headers[number].Root
+		if err := checkState(chain.sdb.TrieDB(), root); err != nil {
+			t.Fatalf("state %d corrupted: %v", number, err)
+		}
+	}
+	if err := checkState(trie.NewDatabase(chain.db), chain.headers[0].Root); err != nil {
+		t.Fatalf("genesis state corrupted: %v", err)
+	}
+}
+
+// Tests that a crash while sweeping is recovered from by resuming the deletion
+// with the persisted state bloom, rewinding the chain to the pruning target as
+// the nodes flushed after the bloom was persisted are deleted too.
+func TestOnlinePruningRecovery(t *testing.T) {
+	var (
+		tries   = uint64(4)
+		chain   = newTestChain(t, tries)
+		datadir = t.TempDir()
+		crashed = t.TempDir()
+	)
+	for i := 0; i < 16; i++ {
+		chain.insert(t)
+	}
+	p, err := NewOnlinePruner(chain.db, chain, chain.sdb.TrieDB(), chain.snaps, &chain.lock, OnlineConfig{
+		Datadir:       datadir,
+		TriesInMemory: tries,
+		Budget:        64 * 1024,
+	})
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	p.Start()
+
+	// Keep importing blocks until the state bloom is persisted
+	var bloomPath string
+	timeout := time.After(time.Minute)
+	for bloomPath == "" {
+		select {
+		case <-timeout:
+			p.Stop()
+			t.Fatalf("state bloom not persisted: %+v", p.Status())
+		case <-time.After(100 * time.Millisecond):
+			chain.insert(t)
+		}
+		if bloomPath, _, err = findBloomFilter(datadir); err != nil {
+			t.Fatalf("failed to find state bloom: %v", err)
+		}
+	}
+	// Flush a few more states, then crash leaving the state bloom behind
+	for i := 0; i < 4; i++ {
+		chain.insert(t)
+	}
+	chain.lock.Lock()
+	chain.sdb.TrieDB().Cap(0)
+	chain.lock.Unlock()
+
+	blob, err := ioutil.ReadFile(bloomPath)
+	if err != nil {
+		t.Fatalf("failed to read state bloom: %v", err)
+	}
+	p.Stop()
+	if status := p.Status(); status.Phase != phaseAborted {
+		t.Fatalf("pruning phase mismatch: have %s, want %s", status.Phase, phaseAborted)
+	}
+	if err := ioutil.WriteFile(filepath.Join(crashed, filepath.Base(bloomPath)), blob, 0644); err != nil {
+		t.Fatalf("failed to write state bloom: %v", err)
+	}
+	head := chain.CurrentHeader()
+	rawdb.WriteBlock(chain.db, types.NewBlockWithHeader(head))
+	rawdb.WriteHeadBlockHash(chain.db, head.Hash())
+	if _, err := chain.snaps.Journal(head.Root); err != nil {
+		t.Fatalf("failed to journal snapshot: %v", err)
+	}
+	// Recover the pruning, the states above the target are deleted and the
+	// chain has to rewind to the target
+	if err := RecoverPruning(crashed, chain.db, "", tries); err != nil {
+		t.Fatalf("failed to recover pruning: %v", err)
+	}
+	if path, _, _ := findBloomFilter(crashed); path != "" {
+		t.Errorf("state bloom left behind: %s", path)
+	}
+	target := p.Status().Number
+	triedb := trie.NewDatabase(chain.db)
+	for number := target + 1; number < uint64(len(chain.headers)); number++ {
+		if blob := rawdb.ReadTrieNode(chain.db, chain.headers[number].Root); len(blob) != 0 {
+			t.Errorf("state %d above the target not deleted", number)
+		}
+	}
+	if err := checkState(triedb, chain.headers[target].Root); err != nil {
+		t.Fatalf("target state corrupted: %v", err)
+	}
+	if err := checkState(triedb, chain.headers[0].Root); err != nil {
+		t.Fatalf("genesis state corrupted: %v", err)
+	}
+}
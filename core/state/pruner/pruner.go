@@ -191,18 +191,22 @@ func prune(snaptree *snapshot.Tree, root common.Hash, maindb ethdb.Database, sta
 	// Pruning is done, now drop the "useless" layers from the snapshot.
 	// Firstly, flushing the target layer into the disk. After that all
 	// diff layers below the target will all be merged into the disk.
-	if root != snaptree.DiskRoot() {
-		if err := snaptree.Cap(root, 0); err != nil {
+	// Without a snapshot paired with the target, the snapshot is left
+	// to be rebuilt on the next start.
+	if snaptree != nil {
+		if root != snaptree.DiskRoot() {
+			if err := snaptree.Cap(root, 0); err != nil {
+				return err
+			}
+		}
+		// Secondly, flushing the snapshot journal into the disk. All diff
+		// layers upon are dropped silently. Eventually the entire snapshot
+		// tree is converted into a single disk layer with the pruning target
+		// as the root.
+		if _, err := snaptree.Journal(root); err != nil {
 			return err
 		}
 	}
-	// Secondly, flushing the snapshot journal into the disk. All diff
-	// layers upon are dropped silently. Eventually the entire snapshot
-	// tree is converted into a single disk layer with the pruning target
-	// as the root.
-	if _, err := snaptree.Journal(root); err != nil {
-		return err
-	}
 	// Delete the state bloom, it marks the entire pruning procedure is
 	// finished. If any crashes or manual exit happens before this,
 	// `RecoverPruning` will pick it up in the next restarts to redo all
@@ -405,12 +409,37 @@ func RecoverPruning(datadir string, db ethdb.Database, trieCachePath string, tri
 		middleRoots[layer.Root()] = struct{}{}
 	}
 	if !found {
-		log.Error("Pruning target state is not existent")
-		return errors.New("non-existent target state")
+		// An interrupted online pruning may have picked its target long before
+		// the current snapshot layers. Walk the canonical chain back to it and
+		// forcibly prune all the state roots above instead.
+		middleRoots, found = canonicalMiddleRoots(db, headBlock.Header(), stateBloomRoot)
+		if !found || len(rawdb.ReadTrieNode(db, stateBloomRoot)) == 0 {
+			log.Error("Pruning target state is not existent")
+			return errors.New("non-existent target state")
+		}
+		log.Info("Resuming pruning below the snapshot layers", "root", stateBloomRoot, "middle", len(middleRoots))
+		snaptree = nil
 	}
 	return prune(snaptree, stateBloomRoot, db, stateBloom, stateBloomPath, middleRoots, time.Now())
 }
 
+// canonicalMiddleRoots walks the canonical chain back from the given head until
+// the block with the target state root, collecting the state roots above it.
+func canonicalMiddleRoots(db ethdb.Database, head *types.Header, target common.Hash) (map[common.Hash]struct{}, bool) {
+	roots := make(map[common.Hash]struct{})
+	for header := head; header != nil; {
+		if header.Root == target {
+			return roots, true
+		}
+		roots[header.Root] = struct{}{}
+		if header.Number.Uint64() == 0 {
+			break
+		}
+		header = rawdb.ReadHeader(db, header.ParentHash, header.Number.Uint64()-1)
+	}
+	return nil, false
+}
+
 // extractGenesis loads the genesis state and commits all the state entries
 // into the given bloomfilter.
 func extractGenesis(db ethdb.Database, stateBloom *stateBloom) error {
@@ -422,7 +451,15 @@ func extractGenesis(db ethdb.Database, stateBloom *stateBloom) error {
 	if genesis == nil {
 		return errors.New("missing genesis block")
 	}
-	t, err := trie.NewSecure(genesis.Root(), trie.NewDatabase(db))
+	return commitState(db, genesis.Root(), stateBloom, nil)
+}
+
+// commitState traverses the persisted state with the given root and commits
+// all the trie nodes and contract codes into the given bloomfilter. The optional
+// tick is invoked for every visited trie node, aborting the traversal if it
+// returns an error.
+func commitState(db ethdb.Database, root common.Hash, stateBloom *stateBloom, tick func() error) error {
+	t, err := trie.NewSecure(root, trie.NewDatabase(db))
 	if err != nil {
 		return err
 	}
@@ -434,6 +471,11 @@ func extractGenesis(db ethdb.Database, stateBloom *stateBloom) error {
 		if hash != (common.Hash{}) {
 			stateBloom.Put(hash.Bytes(), nil)
 		}
+		if tick != nil {
+			if err := tick(); err != nil {
+				return err
+			}
+		}
 		// If it's a leaf node, yes we are touching an account,
 		// dig into the storage trie further.
 		if accIter.Leaf() {
@@ -452,6 +494,11 @@ func extractGenesis(db ethdb.Database, stateBloom *stateBloom) error {
 					if hash != (common.Hash{}) {
 						stateBloom.Put(hash.Bytes(), nil)
 					}
+					if tick != nil {
+						if err := tick(); err != nil {
+							return err
+						}
+					}
 				}
 				if storageIter.Error() != nil {
 					return storageIter.Error()
@@ -102,8 +102,9 @@ type diffLayer struct {
 	parent snapshot   // Parent snapshot modified by this one, never nil
 	memory uint64     // Approximate guess as to how much memory we use
 
-	root  common.Hash // Root hash to which this snapshot diff belongs to
-	stale uint32      // Signals that the layer became stale (state progressed)
+	root     common.Hash // Root hash to which this snapshot diff belongs to
+	stale    uint32      // Signals that the layer became stale (state progressed)
+	recorded bool        // Whether the layer was already reported to the flatten hook
 
 	// destructSet is a very special helper marker. If an account is marked as
 	// deleted, then it's recorded in this set. However it's allowed that an account
@@ -479,6 +480,7 @@ func (dl *diffLayer) flatten() snapshot {
 		parent:      parent.parent,
 		origin:      parent.origin,
 		root:        dl.root,
+		recorded:    dl.recorded,
 		destructSet: parent.destructSet,
 		accountData: parent.accountData,
 		storageData: parent.storageData,
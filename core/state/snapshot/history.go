@@ -0,0 +1,205 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"sort"
+
+	"PureChain/common"
+	"PureChain/log"
+)
+
+// StateDiff is the state of all the accounts and storage slots modified by a
+// single block, as it was before the block was applied.
+type StateDiff struct {
+	Accounts []DiffAccount // Modified accounts sorted by hash
+	Storage  []DiffStorage // Modified storage tries sorted by account hash
+}
+
+// DiffAccount is the previous value of a modified account in the slim snapshot
+// format, empty if the account did not exist.
+type DiffAccount struct {
+	Hash common.Hash
+	Blob []byte
+}
+
+// DiffStorage is the list of modified storage slots of an account.
+type DiffStorage struct {
+	Hash  common.Hash
+	Slots []DiffSlot // Modified slots sorted by hash
+}
+
+// DiffSlot is the previous value of a modified storage slot, empty if the slot
+// did not exist.
+type DiffSlot struct {
+	Hash common.Hash
+	Blob []byte
+}
+
+// LookupAccount returns the previous value of the given account, and whether it
+// was modified at all.
+func (diff *StateDiff) LookupAccount(hash common.Hash) ([]byte, bool) {
+	i := sort.Search(len(diff.Accounts), func(i int) bool {
+		return bytes.Compare(diff.Accounts[i].Hash[:], hash[:]) >= 0
+	})
+	if i < len(diff.Accounts) && diff.Accounts[i].Hash == hash {
+		return diff.Accounts[i].Blob, true
+	}
+	return nil, false
+}
+
+// LookupStorage returns the previous value of the given storage slot, and
+// whether it was modified at all.
+func (diff *StateDiff) LookupStorage(accountHash, storageHash common.Hash) ([]byte, bool) {
+	i := sort.Search(len(diff.Storage), func(i int) bool {
+		return bytes.Compare(diff.Storage[i].Hash[:], accountHash[:]) >= 0
+	})
+	if i == len(diff.Storage) || diff.Storage[i].Hash != accountHash {
+		return nil, false
+	}
+	slots := diff.Storage[i].Slots
+	j := sort.Search(len(slots), func(j int) bool {
+		return bytes.Compare(slots[j].Hash[:], storageHash[:]) >= 0
+	})
+	if j < len(slots) && slots[j].Hash == storageHash {
+		return slots[j].Blob, true
+	}
+	return nil, false
+}
+
+// FlattenHook is called with the root and the previous state of every block
+// diff layer right before it is flattened into the layers below. Blocks are
+// reported in the order they were applied.
+type FlattenHook func(root common.Hash, diff *StateDiff)
+
+// SetFlattenHook installs a callback to be notified of every block diff layer
+// before it's flattened, or removes it if nil. The hook is invoked with the
+// tree lock held, so it must not access the tree itself.
+func (t *Tree) SetFlattenHook(hook FlattenHook) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.hook = hook
+}
+
+// record reports all the not yet flattened block diff layers from the given one
+// downwards to the flatten hook, oldest first.
+func (t *Tree) record(layer *diffLayer) {
+	var layers []*diffLayer
+	for layer != nil && !layer.recorded {
+		layers = append(layers, layer)
+		layer, _ = layer.parent.(*diffLayer)
+	}
+	for i := len(layers) - 1; i >= 0; i-- {
+		layers[i].recorded = true
+		if t.hook == nil {
+			continue
+		}
+		diff, err := layers[i].previous()
+		if err != nil {
+			log.Warn("Failed to collect state diff", "root", layers[i].root, "err", err)
+			continue
+		}
+		t.hook(layers[i].root, diff)
+	}
+}
+
+// previous collects the state of all the items modified by the diff layer from
+// the layers below it.
+func (dl *diffLayer) previous() (*StateDiff, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	var (
+		diff     = new(StateDiff)
+		accounts = make(map[common.Hash]struct{})
+		storage  = make(map[common.Hash]struct{})
+	)
+	for hash := range dl.destructSet {
+		accounts[hash] = struct{}{}
+		storage[hash] = struct{}{}
+	}
+	for hash := range dl.accountData {
+		accounts[hash] = struct{}{}
+	}
+	for hash := range dl.storageData {
+		storage[hash] = struct{}{}
+	}
+	for _, hash := range sortHashes(accounts) {
+		blob, err := dl.parent.AccountRLP(hash)
+		if err != nil {
+			return nil, err
+		}
+		diff.Accounts = append(diff.Accounts, DiffAccount{Hash: hash, Blob: common.CopyBytes(blob)})
+	}
+	for _, hash := range sortHashes(storage) {
+		slots := make(map[common.Hash][]byte)
+
+		// The entire storage of destructed accounts is gone, collect all of it
+		if _, destructed := dl.destructSet[hash]; destructed {
+			var it StorageIterator
+			switch parent := dl.parent.(type) {
+			case *diffLayer:
+				it = parent.newBinaryStorageIterator(hash)
+			case *diskLayer:
+				it, _ = parent.StorageIterator(hash, common.Hash{})
+			}
+			for it.Next() {
+				if slot := it.Slot(); len(slot) > 0 {
+					slots[it.Hash()] = common.CopyBytes(slot)
+				}
+			}
+			it.Release()
+			if err := it.Error(); err != nil {
+				return nil, err
+			}
+		}
+		for slot := range dl.storageData[hash] {
+			if _, ok := slots[slot]; ok {
+				continue
+			}
+			blob, err := dl.parent.Storage(hash, slot)
+			if err != nil {
+				return nil, err
+			}
+			slots[slot] = common.CopyBytes(blob)
+		}
+		if len(slots) == 0 {
+			continue
+		}
+		entry := DiffStorage{Hash: hash, Slots: make([]DiffSlot, 0, len(slots))}
+		for slot, blob := range slots {
+			entry.Slots = append(entry.Slots, DiffSlot{Hash: slot, Blob: blob})
+		}
+		sort.Slice(entry.Slots, func(i, j int) bool {
+			return bytes.Compare(entry.Slots[i].Hash[:], entry.Slots[j].Hash[:]) < 0
+		})
+		diff.Storage = append(diff.Storage, entry)
+	}
+	return diff, nil
+}
+
+// sortHashes returns the hashes in the given set in ascending order.
+func sortHashes(set map[common.Hash]struct{}) []common.Hash {
+	list := make([]common.Hash, 0, len(set))
+	for hash := range set {
+		list = append(list, hash)
+	}
+	sort.Sort(hashes(list))
+	return list
+}
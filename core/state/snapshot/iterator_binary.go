@@ -195,7 +195,9 @@ func (it *binaryIterator) Slot() []byte {
 // Release recursively releases all the iterators in the stack.
 func (it *binaryIterator) Release() {
 	it.a.Release()
-	it.b.Release()
+	if it.b != nil {
+		it.b.Release() // Missing if the storage is destructed
+	}
 }
 
 // newBinaryAccountIterator creates a simplistic account iterator to step over
@@ -164,6 +164,7 @@ type Tree struct {
 	layers   map[common.Hash]snapshot // Collection of all known layers
 	lock     sync.RWMutex
 	capLimit int
+	hook     FlattenHook // Optional callback notified of every flattened block diff
 }
 
 // New attempts to load an already existing snapshot from a persistent key-value
@@ -389,6 +390,8 @@ func (t *Tree) Cap(root common.Hash, layers int) error {
 	// child for the capping and then remove it.
 	if layers == 0 {
 		// If full commit was requested, flatten the diffs and merge onto disk
+		t.record(diff)
+
 		diff.lock.RLock()
 		base := diffToDisk(diff.flatten().(*diffLayer))
 		diff.lock.RUnlock()
@@ -468,6 +471,8 @@ func (t *Tree) cap(diff *diffLayer, layers int) *diskLayer {
 	case *diffLayer:
 		// Flatten the parent into the grandparent. The flattening internally obtains a
 		// write lock on grandparent.
+		t.record(parent)
+
 		diff.lock.Lock()
 		defer diff.lock.Unlock()
 		flattened := parent.flatten().(*diffLayer)
@@ -828,6 +833,11 @@ func (t *Tree) generating() (bool, error) {
 	return layer.genMarker != nil, nil
 }
 
+// Generating reports whether the snapshot is still under the construction.
+func (t *Tree) Generating() (bool, error) {
+	return t.generating()
+}
+
 // diskRoot is a external helper function to return the disk layer root.
 func (t *Tree) DiskRoot() common.Hash {
 	t.lock.Lock()
@@ -73,6 +73,16 @@ func (journal *txJournal) load(add func([]*types.Transaction) []error) error {
 	defer func() { journal.writer = nil }()
 
 	// Inject all transactions from the journal into the pool
+	total, dropped, err := loadTransactions(input, add)
+	log.Info("Loaded transaction journal", "path", journal.path, "transactions", total, "dropped", dropped)
+
+	return err
+}
+
+// loadTransactions parses a stream of RLP encoded transactions, loading them
+// into the specified pool in small-ish batches. The number of parsed and the
+// number of rejected transactions are returned.
+func loadTransactions(input io.Reader, add func([]*types.Transaction) []error) (int, int, error) {
 	stream := rlp.NewStream(input, 0)
 	total, dropped := 0, 0
 
@@ -94,7 +104,7 @@ func (journal *txJournal) load(add func([]*types.Transaction) []error) error {
 	for {
 		// Parse the next transaction and terminate on error
 		tx := new(types.Transaction)
-		if err = stream.Decode(tx); err != nil {
+		if err := stream.Decode(tx); err != nil {
 			if err != io.EOF {
 				failure = err
 			}
@@ -111,9 +121,7 @@ func (journal *txJournal) load(add func([]*types.Transaction) []error) error {
 			batch = batch[:0]
 		}
 	}
-	log.Info("Loaded local transaction journal", "transactions", total, "dropped", dropped)
-
-	return failure
+	return total, dropped, failure
 }
 
 // insert adds the specified transaction to the local disk journal.
@@ -163,7 +171,7 @@ func (journal *txJournal) rotate(all map[common.Address]types.Transactions) erro
 		return err
 	}
 	journal.writer = sink
-	log.Info("Regenerated local transaction journal", "transactions", journaled, "accounts", len(all))
+	log.Info("Regenerated transaction journal", "path", journal.path, "transactions", journaled, "accounts", len(all))
 
 	return nil
 }
@@ -0,0 +1,136 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+
+	"PureChain/common"
+	"PureChain/core/state"
+	"PureChain/core/types"
+)
+
+// ErrAccountQuota is returned if a transaction is rejected by the pool policy
+// because its sender already holds its quota of pooled transactions.
+var ErrAccountQuota = errors.New("account transaction quota exceeded")
+
+// TxPoolPolicy is a pluggable set of admission and eviction rules consulted by
+// the transaction pool on top of its consensus level validation.
+//
+// All methods are called with the pool lock held, implementations must not call
+// back into the pool.
+type TxPoolPolicy interface {
+	// Reset is called every time the pool is moved to a new chain head, allowing
+	// the policy to refresh any chain derived data.
+	Reset(head *types.Header, statedb *state.StateDB)
+
+	// ValidateTx is consulted when a transaction is added to the pool, after it
+	// passed the pool's own validation. The known count is the number of pending
+	// and queued transactions the sender already has in the pool. Local accounts
+	// are exempt from the account quotas, as the pool never evicts them either.
+	ValidateTx(tx *types.Transaction, from common.Address, local bool, known int) error
+
+	// AccountSlots returns the number of executable transaction slots guaranteed
+	// to the account when evicting over the global pending limit.
+	AccountSlots(addr common.Address) uint64
+
+	// AccountQueue returns the maximum number of non-executable transaction slots
+	// permitted to the account when promoting queued transactions.
+	AccountQueue(addr common.Address) uint64
+}
+
+// TxPoolPolicyConfig are the overrides applied by the shipped pool policies.
+type TxPoolPolicyConfig struct {
+	MinGasPrices map[common.Address]*big.Int `toml:",omitempty"` // Minimum gas price (tip) for calls into specific contracts
+	AccountSlots map[common.Address]uint64   `toml:",omitempty"` // Per account executable slot overrides
+	AccountQueue map[common.Address]uint64   `toml:",omitempty"` // Per account non-executable slot overrides
+
+	ProviderSlots uint64 `toml:",omitempty"` // Executable slots of PoR provider accounts (dpos policy, 0 = pool default)
+	ProviderQueue uint64 `toml:",omitempty"` // Non-executable slots of PoR provider accounts (dpos policy, 0 = pool default)
+}
+
+// DefaultTxPoolPolicy is the policy used by the pool if none is configured. It
+// applies the limits of the pool configuration together with the per account
+// and per contract overrides of the policy configuration.
+type DefaultTxPoolPolicy struct {
+	pool   TxPoolConfig
+	config TxPoolPolicyConfig
+}
+
+// NewDefaultTxPoolPolicy creates the default pool policy.
+func NewDefaultTxPoolPolicy(pool TxPoolConfig, config TxPoolPolicyConfig) *DefaultTxPoolPolicy {
+	if pool.AccountSlots < 1 {
+		pool.AccountSlots = DefaultTxPoolConfig.AccountSlots
+	}
+	if pool.AccountQueue < 1 {
+		pool.AccountQueue = DefaultTxPoolConfig.AccountQueue
+	}
+	return &DefaultTxPoolPolicy{
+		pool:   pool,
+		config: config,
+	}
+}
+
+// Reset implements TxPoolPolicy, the default policy has no chain derived data.
+func (p *DefaultTxPoolPolicy) Reset(head *types.Header, statedb *state.StateDB) {}
+
+// ValidateTx implements TxPoolPolicy, enforcing the contract specific minimum
+// gas prices and the per account quotas on remote transactions.
+func (p *DefaultTxPoolPolicy) ValidateTx(tx *types.Transaction, from common.Address, local bool, known int) error {
+	if local {
+		return nil
+	}
+	if to := tx.To(); to != nil {
+		if min := p.config.MinGasPrices[*to]; min != nil && tx.GasTipCapIntCmp(min) < 0 {
+			return ErrUnderpriced
+		}
+	}
+	slots, overridden := p.config.AccountSlots[from]
+	queue, queueOverridden := p.config.AccountQueue[from]
+	if !overridden && !queueOverridden {
+		return nil
+	}
+	if !overridden {
+		slots = p.pool.AccountSlots
+	}
+	if !queueOverridden {
+		queue = p.pool.AccountQueue
+	}
+	if uint64(known) >= slots+queue {
+		return ErrAccountQuota
+	}
+	return nil
+}
+
+// AccountSlots implements TxPoolPolicy, returning the account override if any
+// or the pool wide limit otherwise.
+func (p *DefaultTxPoolPolicy) AccountSlots(addr common.Address) uint64 {
+	if slots, ok := p.config.AccountSlots[addr]; ok {
+		return slots
+	}
+	return p.pool.AccountSlots
+}
+
+// AccountQueue implements TxPoolPolicy, returning the account override if any
+// or the pool wide limit otherwise.
+func (p *DefaultTxPoolPolicy) AccountQueue(addr common.Address) uint64 {
+	if queue, ok := p.config.AccountQueue[addr]; ok {
+		return queue
+	}
+	return p.pool.AccountQueue
+}
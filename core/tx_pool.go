@@ -18,6 +18,7 @@ package core
 
 import (
 	"errors"
+	"io"
 	"math"
 	"math/big"
 	"sort"
@@ -32,6 +33,7 @@ import (
 	"PureChain/log"
 	"PureChain/metrics"
 	"PureChain/params"
+	"PureChain/rlp"
 )
 
 const (
@@ -83,6 +85,15 @@ var (
 	// than some meaningful limit a user might use. This is not a consensus error
 	// making the transaction invalid, rather a DOS protection.
 	ErrOversizedData = errors.New("oversized data")
+
+	// ErrSystemTxPriced is returned if a transaction submitted to the system lane
+	// is not a zero gas price transaction.
+	ErrSystemTxPriced = errors.New("system transaction with non-zero gas price")
+
+	// ErrSystemNonceConflict is returned if a transaction takes a nonce already
+	// used by a transaction of the same account in the other lane of the pool,
+	// i.e. a system transaction conflicting with an ordinary one or vice versa.
+	ErrSystemNonceConflict = errors.New("nonce conflicts with the system lane")
 )
 
 var (
@@ -144,6 +155,9 @@ type TxPoolConfig struct {
 	Journal   string           // Journal of local transactions to survive node restarts
 	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
 
+	RemoteJournal   string        // Journal of the whole pool snapshotted to survive node restarts (empty disables)
+	RemoteRejournal time.Duration // Time interval to snapshot the whole pool into the remote journal
+
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 
@@ -153,6 +167,8 @@ type TxPoolConfig struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	Policy TxPoolPolicy `toml:"-"` // Admission and eviction policy (nil = default policy)
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
@@ -161,6 +177,8 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	Journal:   "transactions.rlp",
 	Rejournal: time.Hour,
 
+	RemoteRejournal: 10 * time.Minute,
+
 	PriceLimit: 1,
 	PriceBump:  10,
 
@@ -180,6 +198,10 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
 		conf.Rejournal = time.Second
 	}
+	if conf.RemoteRejournal < time.Second {
+		log.Warn("Sanitizing invalid txpool remote journal time", "provided", conf.RemoteRejournal, "updated", time.Second)
+		conf.RemoteRejournal = time.Second
+	}
 	if conf.PriceLimit < 1 {
 		log.Warn("Sanitizing invalid txpool price limit", "provided", conf.PriceLimit, "updated", DefaultTxPoolConfig.PriceLimit)
 		conf.PriceLimit = DefaultTxPoolConfig.PriceLimit
@@ -230,19 +252,23 @@ type TxPool struct {
 
 	istanbul bool // Fork indicator whether we are in the istanbul stage.
 	eip2718  bool // Fork indicator whether we are using EIP-2718 type transactions.
+	eip1559  bool // Fork indicator whether we are using EIP-1559 type transactions.
 
 	currentState  *state.StateDB // Current state in the blockchain head
 	pendingNonces *txNoncer      // Pending state tracking virtual nonces
 	currentMaxGas uint64         // Current gas limit for transaction caps
 
-	locals  *accountSet // Set of local transaction to exempt from eviction rules
-	journal *txJournal  // Journal of local transaction to back up to disk
+	locals        *accountSet // Set of local transaction to exempt from eviction rules
+	journal       *txJournal  // Journal of local transaction to back up to disk
+	remoteJournal *txJournal  // Journal of the whole pool periodically snapshotted to disk
 
 	pending map[common.Address]*txList   // All currently processable transactions
 	queue   map[common.Address]*txList   // Queued but non-processable transactions
 	beats   map[common.Address]time.Time // Last heartbeat from each known account
 	all     *txLookup                    // All transactions to allow lookups
 	priced  *txPricedList                // All transactions sorted by price
+	policy  TxPoolPolicy                 // Pluggable admission and eviction rules
+	system  *txSystemLane                // Engine generated system transactions
 
 	chainHeadCh     chan ChainHeadEvent
 	chainHeadSub    event.Subscription
@@ -274,6 +300,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		queue:           make(map[common.Address]*txList),
 		beats:           make(map[common.Address]time.Time),
 		all:             newTxLookup(),
+		system:          newTxSystemLane(),
 		chainHeadCh:     make(chan ChainHeadEvent, chainHeadChanSize),
 		reqResetCh:      make(chan *txpoolResetRequest),
 		reqPromoteCh:    make(chan *accountSet),
@@ -281,6 +308,10 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		reorgDoneCh:     make(chan chan struct{}),
 		reorgShutdownCh: make(chan struct{}),
 		gasPrice:        new(big.Int).SetUint64(config.PriceLimit),
+		policy:          config.Policy,
+	}
+	if pool.policy == nil {
+		pool.policy = NewDefaultTxPoolPolicy(config, TxPoolPolicyConfig{})
 	}
 	pool.locals = newAccountSet(pool.signer)
 	for _, addr := range config.Locals {
@@ -305,6 +336,14 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If remote journaling is enabled, warm the pool up from the last snapshot
+	if config.RemoteJournal != "" {
+		pool.remoteJournal = newTxJournal(config.RemoteJournal)
+
+		if err := pool.remoteJournal.load(pool.AddRemotes); err != nil {
+			log.Warn("Failed to load remote transaction journal", "err", err)
+		}
+	}
 
 	// Subscribe events from blockchain and start the main event loop.
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
@@ -323,15 +362,17 @@ func (pool *TxPool) loop() {
 	var (
 		prevPending, prevQueued, prevStales int
 		// Start the stats reporting and transaction eviction tickers
-		report  = time.NewTicker(statsReportInterval)
-		evict   = time.NewTicker(evictionInterval)
-		journal = time.NewTicker(pool.config.Rejournal)
+		report   = time.NewTicker(statsReportInterval)
+		evict    = time.NewTicker(evictionInterval)
+		journal  = time.NewTicker(pool.config.Rejournal)
+		snapshot = time.NewTicker(pool.config.RemoteRejournal)
 		// Track the previous head headers for transaction reorgs
 		head = pool.chain.CurrentBlock()
 	)
 	defer report.Stop()
 	defer evict.Stop()
 	defer journal.Stop()
+	defer snapshot.Stop()
 
 	for {
 		select {
@@ -387,6 +428,16 @@ func (pool *TxPool) loop() {
 				}
 				pool.mu.Unlock()
 			}
+
+		// Handle remote transaction journal snapshots
+		case <-snapshot.C:
+			if pool.remoteJournal != nil {
+				pool.mu.Lock()
+				if err := pool.remoteJournal.rotate(pool.flatten()); err != nil {
+					log.Warn("Failed to snapshot remote tx journal", "err", err)
+				}
+				pool.mu.Unlock()
+			}
 		}
 	}
 }
@@ -403,6 +454,14 @@ func (pool *TxPool) Stop() {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	if pool.remoteJournal != nil {
+		pool.mu.Lock()
+		if err := pool.remoteJournal.rotate(pool.flatten()); err != nil {
+			log.Warn("Failed to snapshot remote tx journal", "err", err)
+		}
+		pool.mu.Unlock()
+		pool.remoteJournal.close()
+	}
 	log.Info("Transaction pool stopped")
 }
 
@@ -439,7 +498,7 @@ func (pool *TxPool) Nonce(addr common.Address) uint64 {
 	pool.mu.RLock()
 	defer pool.mu.RUnlock()
 
-	return pool.pendingNonces.get(addr)
+	return pool.system.nonce(addr, pool.pendingNonces.get(addr))
 }
 
 // Stats retrieves the current pool stats, namely the number of pending and the
@@ -496,6 +555,87 @@ func (pool *TxPool) Pending() (map[common.Address]types.Transactions, error) {
 	return pending, nil
 }
 
+// SystemContent retrieves all the system transactions waiting in the reserved
+// system lane, grouped by validator and sorted by nonce.
+func (pool *TxPool) SystemContent() map[common.Address]types.Transactions {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	return pool.system.content()
+}
+
+// SystemPending retrieves the system transactions of every validator that are
+// processable on top of the given state, grouped by validator and sorted by
+// nonce. The returned transaction set is a copy and can be freely modified by
+// calling code.
+func (pool *TxPool) SystemPending(statedb *state.StateDB) map[common.Address]types.Transactions {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	return pool.system.executable(statedb)
+}
+
+// SystemNonce returns the next nonce a new system transaction of the validator
+// should use on top of the given state. Both lanes share the nonces of the
+// account, so the ordinary pending transactions of the validator are skipped
+// as well as the system ones.
+func (pool *TxPool) SystemNonce(addr common.Address, statedb *state.StateDB) uint64 {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	next := statedb.GetNonce(addr)
+	if pending := pool.pendingNonces.get(addr); pending > next {
+		next = pending
+	}
+	return pool.system.nonce(addr, next)
+}
+
+// AddSystem enqueues a batch of engine generated system transactions into the
+// reserved system lane. System transactions must carry a zero gas price, they
+// are exempt from pricing and eviction rules and are not announced to peers.
+func (pool *TxPool) AddSystem(txs []*types.Transaction) []error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	errs := make([]error, len(txs))
+	for i, tx := range txs {
+		errs[i] = pool.addSystem(tx)
+	}
+	return errs
+}
+
+// addSystem validates a single system transaction and inserts it into the
+// system lane. The caller must hold the pool lock.
+func (pool *TxPool) addSystem(tx *types.Transaction) error {
+	if pool.system.get(tx.Hash()) != nil {
+		return ErrAlreadyKnown
+	}
+	if tx.GasFeeCap().Sign() != 0 || tx.GasTipCap().Sign() != 0 {
+		return ErrSystemTxPriced
+	}
+	if pool.currentMaxGas < tx.Gas() {
+		return ErrGasLimit
+	}
+	from, err := types.Sender(pool.signer, tx)
+	if err != nil {
+		return ErrInvalidSender
+	}
+	if pool.currentState.GetNonce(from) > tx.Nonce() {
+		return ErrNonceTooLow
+	}
+	if list := pool.pending[from]; list != nil && list.Overlaps(tx) {
+		return ErrSystemNonceConflict
+	}
+	if list := pool.queue[from]; list != nil && list.Overlaps(tx) {
+		return ErrSystemNonceConflict
+	}
+	if err := pool.system.add(from, tx); err != nil {
+		return err
+	}
+	log.Trace("Pooled new system transaction", "hash", tx.Hash(), "from", from, "nonce", tx.Nonce())
+	return nil
+}
+
 // Locals retrieves the accounts currently considered local by the pool.
 func (pool *TxPool) Locals() []common.Address {
 	pool.mu.Lock()
@@ -520,6 +660,47 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 	return txs
 }
 
+// flatten retrieves all the pending and queued transactions of the pool, grouped
+// by account and sorted by nonce. The returned transaction set is a copy and can
+// be freely modified by calling code.
+func (pool *TxPool) flatten() map[common.Address]types.Transactions {
+	txs := make(map[common.Address]types.Transactions)
+	for addr, list := range pool.pending {
+		txs[addr] = append(txs[addr], list.Flatten()...)
+	}
+	for addr, list := range pool.queue {
+		txs[addr] = append(txs[addr], list.Flatten()...)
+	}
+	return txs
+}
+
+// Export writes all the pending and queued transactions of the pool into the
+// given writer as a stream of RLP encoded transactions, returning the number of
+// transactions exported.
+func (pool *TxPool) Export(w io.Writer) (int, error) {
+	pool.mu.Lock()
+	all := pool.flatten()
+	pool.mu.Unlock()
+
+	exported := 0
+	for _, txs := range all {
+		for _, tx := range txs {
+			if err := rlp.Encode(w, tx); err != nil {
+				return exported, err
+			}
+			exported++
+		}
+	}
+	return exported, nil
+}
+
+// Import parses a stream of RLP encoded transactions from the given reader and
+// adds them to the pool as remote ones, returning the number of transactions
+// parsed and the number of transactions rejected by the pool.
+func (pool *TxPool) Import(r io.Reader) (int, int, error) {
+	return loadTransactions(r, pool.AddRemotesSync)
+}
+
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
@@ -527,6 +708,10 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if !pool.eip2718 && tx.Type() != types.LegacyTxType {
 		return ErrTxTypeNotSupported
 	}
+	// Reject dynamic fee transactions until EIP-1559 activates.
+	if !pool.eip1559 && tx.Type() == types.DynamicFeeTxType {
+		return ErrTxTypeNotSupported
+	}
 	// Reject transactions over defined size to prevent DOS attacks
 	if uint64(tx.Size()) > txMaxSize {
 		return ErrOversizedData
@@ -536,6 +721,17 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if tx.Value().Sign() < 0 {
 		return ErrNegativeValue
 	}
+	// Sanity check for extremely large numbers
+	if tx.GasFeeCap().BitLen() > 256 {
+		return ErrFeeCapVeryHigh
+	}
+	if tx.GasTipCap().BitLen() > 256 {
+		return ErrTipVeryHigh
+	}
+	// Ensure gasFeeCap is greater than or equal to gasTipCap.
+	if tx.GasFeeCapIntCmp(tx.GasTipCap()) < 0 {
+		return ErrTipAboveFeeCap
+	}
 	// Ensure the transaction doesn't exceed the current block limit gas.
 	if pool.currentMaxGas < tx.Gas() {
 		return ErrGasLimit
@@ -545,8 +741,8 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if err != nil {
 		return ErrInvalidSender
 	}
-	// Drop non-local transactions under our own minimal accepted gas price
-	if !local && tx.GasPriceIntCmp(pool.gasPrice) < 0 {
+	// Drop non-local transactions under our own minimal accepted gas price or tip
+	if !local && tx.GasTipCapIntCmp(pool.gasPrice) < 0 {
 		return ErrUnderpriced
 	}
 	// Ensure the transaction adheres to nonce ordering
@@ -566,7 +762,21 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if tx.Gas() < intrGas {
 		return ErrIntrinsicGas
 	}
-	return nil
+	// Consult the pool policy with the number of other transactions of the sender
+	known := 0
+	if list := pool.pending[from]; list != nil {
+		known += list.Len()
+		if list.txs.Get(tx.Nonce()) != nil {
+			known--
+		}
+	}
+	if list := pool.queue[from]; list != nil {
+		known += list.Len()
+		if list.txs.Get(tx.Nonce()) != nil {
+			known--
+		}
+	}
+	return pool.policy.ValidateTx(tx, from, local, known)
 }
 
 // add validates a transaction and inserts it into the non-executable queue for later
@@ -594,6 +804,11 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		invalidTxMeter.Mark(1)
 		return false, err
 	}
+	// The nonces taken by the system lane are reserved for the engine
+	if from, _ := types.Sender(pool.signer, tx); pool.system.has(from, tx.Nonce()) {
+		invalidTxMeter.Mark(1)
+		return false, ErrSystemNonceConflict
+	}
 	// If the transaction pool is full, discard underpriced transactions
 	if uint64(pool.all.Count()+numSlots(tx)) > pool.config.GlobalSlots+pool.config.GlobalQueue {
 		// If the new transaction is underpriced, don't accept it
@@ -893,6 +1108,15 @@ func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
 	return pool.all.Get(hash)
 }
 
+// GetSystem returns a system transaction if it is contained in the system lane
+// and nil otherwise.
+func (pool *TxPool) GetSystem(hash common.Hash) *types.Transaction {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.system.get(hash)
+}
+
 // Has returns an indicator whether txpool has a transaction cached with the
 // given hash.
 func (pool *TxPool) Has(hash common.Hash) bool {
@@ -1196,6 +1420,12 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.pendingNonces = newTxNoncer(statedb)
 	pool.currentMaxGas = newHead.GasLimit
 
+	// Drop any system transactions included in or invalidated by the new head
+	pool.system.forward(statedb)
+
+	// Let the pool policy refresh any chain derived data
+	pool.policy.Reset(newHead, statedb)
+
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
 	senderCacher.recover(pool.signer, reinject)
@@ -1205,6 +1435,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	next := new(big.Int).Add(newHead.Number, big.NewInt(1))
 	pool.istanbul = pool.chainconfig.IsIstanbul(next)
 	pool.eip2718 = pool.chainconfig.IsBerlin(next)
+	pool.eip1559 = pool.chainconfig.IsLondon(next)
 }
 
 // promoteExecutables moves transactions that have become processable from the
@@ -1250,7 +1481,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 		// Drop all transactions over the allowed limit
 		var caps types.Transactions
 		if !pool.locals.contains(addr) {
-			caps = list.Cap(int(pool.config.AccountQueue))
+			caps = list.Cap(int(pool.policy.AccountQueue(addr)))
 			for _, tx := range caps {
 				hash := tx.Hash()
 				pool.all.Remove(hash)
@@ -1290,7 +1521,7 @@ func (pool *TxPool) truncatePending() {
 	spammers := prque.New(nil)
 	for addr, list := range pool.pending {
 		// Only evict transactions from high rollers
-		if !pool.locals.contains(addr) && uint64(len(list.txs.items)) > pool.config.AccountSlots {
+		if !pool.locals.contains(addr) && uint64(len(list.txs.items)) > pool.policy.AccountSlots(addr) {
 			spammers.Push(addr, int64(len(list.txs.items)))
 		}
 	}
@@ -1334,7 +1565,7 @@ func (pool *TxPool) truncatePending() {
 
 	// If still above threshold, reduce to limit or min allowance
 	if pending > pool.config.GlobalSlots && len(offenders) > 0 {
-		for pending > pool.config.GlobalSlots && uint64(len(pool.pending[offenders[len(offenders)-1]].txs.items)) > pool.config.AccountSlots {
+		for pending > pool.config.GlobalSlots && uint64(len(pool.pending[offenders[len(offenders)-1]].txs.items)) > pool.policy.AccountSlots(offenders[len(offenders)-1]) {
 			for _, addr := range offenders {
 				list := pool.pending[addr]
 
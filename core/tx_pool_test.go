@@ -17,6 +17,7 @@
 package core
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"errors"
 	"fmt"
@@ -1933,6 +1934,250 @@ func TestTransactionSlotCount(t *testing.T) {
 	}
 }
 
+// Tests that the pool consults its policy for contract specific minimum gas
+// prices and per account quotas.
+func TestTransactionPolicy(t *testing.T) {
+	t.Parallel()
+
+	quota, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	contract := common.Address{0xc0}
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.Policy = NewDefaultTxPoolPolicy(config, TxPoolPolicyConfig{
+		MinGasPrices: map[common.Address]*big.Int{contract: big.NewInt(10)},
+		AccountSlots: map[common.Address]uint64{crypto.PubkeyToAddress(quota.PublicKey): 1},
+		AccountQueue: map[common.Address]uint64{crypto.PubkeyToAddress(quota.PublicKey): 1},
+	})
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(quota.PublicKey), big.NewInt(1000000000))
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(other.PublicKey), big.NewInt(1000000000))
+
+	// Calls into the contract must pay at least its minimum gas price
+	call := func(nonce uint64, gasprice int64) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, contract, big.NewInt(0), 100000, big.NewInt(gasprice), nil), types.HomesteadSigner{}, other)
+		return tx
+	}
+	if err := pool.addRemoteSync(call(0, 9)); err != ErrUnderpriced {
+		t.Fatalf("cheap contract call error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	if err := pool.addRemoteSync(call(0, 10)); err != nil {
+		t.Fatalf("failed to add priced contract call: %v", err)
+	}
+	// The account with a quota may only hold two transactions, replacements allowed
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(1), quota)); err != nil {
+		t.Fatalf("failed to add first quota transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(2, 100000, big.NewInt(1), quota)); err != nil {
+		t.Fatalf("failed to add second quota transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(3, 100000, big.NewInt(1), quota)); err != ErrAccountQuota {
+		t.Fatalf("quota exceeding transaction error mismatch: have %v, want %v", err, ErrAccountQuota)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(2, 100000, big.NewInt(2), quota)); err != nil {
+		t.Fatalf("failed to replace quota transaction: %v", err)
+	}
+	// Local transactions are exempt from the quota, as they are from eviction
+	if err := pool.AddLocal(pricedTransaction(1, 100000, big.NewInt(1), quota)); err != nil {
+		t.Fatalf("failed to add local quota transaction: %v", err)
+	}
+	if err := pool.AddLocal(pricedTransaction(3, 100000, big.NewInt(1), quota)); err != nil {
+		t.Fatalf("failed to add local quota exceeding transaction: %v", err)
+	}
+	if pending, _ := pool.Stats(); pending != 5 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 5)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the whole pool, remote transactions included, survives a restart
+// when remote journaling is enabled, and that it can be moved between pools via
+// an export and import.
+func TestTransactionRemoteJournaling(t *testing.T) {
+	t.Parallel()
+
+	// Create a temporary file for the journal
+	file, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary journal: %v", err)
+	}
+	journal := file.Name()
+	defer os.Remove(journal)
+
+	// Clean up the temporary file, we only need the path for now
+	file.Close()
+	os.Remove(journal)
+
+	// Create the original pool to inject transaction into the journal
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.RemoteJournal = journal
+	config.RemoteRejournal = time.Second
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	key, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	// Add two executable and a gapped remote transactions
+	txs := []*types.Transaction{
+		pricedTransaction(0, 100000, big.NewInt(1), key),
+		pricedTransaction(1, 100000, big.NewInt(1), key),
+		pricedTransaction(3, 100000, big.NewInt(1), key),
+	}
+	for i, err := range pool.AddRemotesSync(txs) {
+		if err != nil {
+			t.Fatalf("transaction %d: failed to add remote: %v", i, err)
+		}
+	}
+	pending, queued := pool.Stats()
+	if pending != 2 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 2)
+	}
+	if queued != 1 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 1)
+	}
+	// Export the pool content before shutting it down
+	var dump bytes.Buffer
+	if exported, err := pool.Export(&dump); err != nil {
+		t.Fatalf("failed to export pool: %v", err)
+	} else if exported != 3 {
+		t.Fatalf("exported transactions mismatched: have %d, want %d", exported, 3)
+	}
+	// Terminate the old pool and ensure the remote transactions are reloaded
+	pool.Stop()
+
+	blockchain = &testBlockChain{statedb, 1000000, new(event.Feed)}
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	<-pool.requestReset(nil, nil)
+
+	pending, queued = pool.Stats()
+	if pending != 2 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 2)
+	}
+	if queued != 1 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 1)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	pool.Stop()
+
+	// Import the exported dump into a fresh pool without journaling
+	blockchain = &testBlockChain{statedb, 1000000, new(event.Feed)}
+	pool = NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	total, dropped, err := pool.Import(&dump)
+	if err != nil {
+		t.Fatalf("failed to import pool: %v", err)
+	}
+	if total != 3 || dropped != 0 {
+		t.Fatalf("imported transactions mismatched: have %d/%d, want %d/%d", total, dropped, 3, 0)
+	}
+	pending, queued = pool.Stats()
+	if pending != 2 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 2)
+	}
+	if queued != 1 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 1)
+	}
+}
+
+// Tests that engine generated system transactions are kept in their own lane,
+// sharing the nonces of the account with the ordinary pending transactions
+// without conflicting with them and not being subject to the pool limits.
+func TestSystemTransactionLane(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(from, big.NewInt(1000000))
+
+	// Priced transactions are rejected from the lane
+	if err := pool.AddSystem([]*types.Transaction{pricedTransaction(0, 100000, big.NewInt(1), key)})[0]; err != ErrSystemTxPriced {
+		t.Fatalf("priced system transaction error mismatch: have %v, want %v", err, ErrSystemTxPriced)
+	}
+	// System transactions follow the ordinary pending ones, and cannot take
+	// their nonces
+	if err := pool.addRemoteSync(transaction(0, 100000, key)); err != nil {
+		t.Fatalf("failed to add ordinary transaction: %v", err)
+	}
+	if nonce := pool.SystemNonce(from, pool.currentState); nonce != 1 {
+		t.Fatalf("system nonce mismatch: have %d, want %d", nonce, 1)
+	}
+	if err := pool.AddSystem([]*types.Transaction{pricedTransaction(0, 100000, big.NewInt(0), key)})[0]; err != ErrSystemNonceConflict {
+		t.Fatalf("conflicting system transaction error mismatch: have %v, want %v", err, ErrSystemNonceConflict)
+	}
+	// Add a few system transactions with a nonce gap, ordinary ones cannot take
+	// their nonces either
+	sys := []*types.Transaction{
+		pricedTransaction(1, 100000, big.NewInt(0), key),
+		pricedTransaction(2, 100000, big.NewInt(0), key),
+		pricedTransaction(4, 100000, big.NewInt(0), key),
+	}
+	for i, err := range pool.AddSystem(sys) {
+		if err != nil {
+			t.Fatalf("system transaction %d: failed to add: %v", i, err)
+		}
+	}
+	if err := pool.addRemoteSync(transaction(1, 100000, key)); err != ErrSystemNonceConflict {
+		t.Fatalf("conflicting ordinary transaction error mismatch: have %v, want %v", err, ErrSystemNonceConflict)
+	}
+	if nonce := pool.Nonce(from); nonce != 3 {
+		t.Fatalf("pool nonce mismatch: have %d, want %d", nonce, 3)
+	}
+	if nonce := pool.SystemNonce(from, pool.currentState); nonce != 3 {
+		t.Fatalf("system nonce mismatch: have %d, want %d", nonce, 3)
+	}
+	pending, queued := pool.Stats()
+	if pending != 1 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 1)
+	}
+	if queued != 0 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 0)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Only the gapless system transactions after the included ordinary one are
+	// executable
+	if txs := pool.SystemPending(pool.currentState)[from]; len(txs) != 0 {
+		t.Fatalf("executable system transactions mismatched: have %d, want %d", len(txs), 0)
+	}
+	statedb := pool.currentState.Copy()
+	statedb.SetNonce(from, 1)
+	if txs := pool.SystemPending(statedb)[from]; len(txs) != 2 {
+		t.Fatalf("executable system transactions mismatched: have %d, want %d", len(txs), 2)
+	}
+	if tx := pool.GetSystem(sys[2].Hash()); tx == nil {
+		t.Fatalf("system transaction not retrievable")
+	}
+	// Include the first system transaction and check that it's dropped
+	pool.mu.Lock()
+	pool.currentState.SetNonce(from, 2)
+	pool.mu.Unlock()
+	<-pool.requestReset(nil, nil)
+
+	if txs := pool.SystemContent()[from]; len(txs) != 2 {
+		t.Fatalf("system transactions mismatched: have %d, want %d", len(txs), 2)
+	}
+	if tx := pool.GetSystem(sys[0].Hash()); tx != nil {
+		t.Fatalf("included system transaction not dropped")
+	}
+}
+
 // Benchmarks the speed of validating the contents of the pending queue of the
 // transaction pool.
 func BenchmarkPendingDemotion100(b *testing.B)   { benchmarkPendingDemotion(b, 100) }
@@ -0,0 +1,139 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"PureChain/common"
+	"PureChain/core/state"
+	"PureChain/core/types"
+)
+
+// maxSystemSlots is the maximum number of system transactions a single
+// validator may have waiting in the system lane.
+const maxSystemSlots = 64
+
+// txSystemLane is the reserved lane of the pool holding zero gas price system
+// transactions generated by the consensus engine (e.g. PoR challenges and
+// challenge results). The lane is keyed by validator and tracks its own nonces,
+// it is never subject to price based eviction nor to the global pool limits and
+// its transactions are not announced to the network.
+//
+// The lane is not thread safe, it is guarded by the pool's lock.
+type txSystemLane struct {
+	txs map[common.Address]*txSortedMap // Per validator nonce sorted system transactions
+	all map[common.Hash]*types.Transaction
+}
+
+// newTxSystemLane creates an empty system transaction lane.
+func newTxSystemLane() *txSystemLane {
+	return &txSystemLane{
+		txs: make(map[common.Address]*txSortedMap),
+		all: make(map[common.Hash]*types.Transaction),
+	}
+}
+
+// add inserts a system transaction of the given validator into the lane,
+// overwriting any previous one with the same nonce.
+func (l *txSystemLane) add(from common.Address, tx *types.Transaction) error {
+	list := l.txs[from]
+	if list == nil {
+		list = newTxSortedMap()
+		l.txs[from] = list
+	}
+	old := list.Get(tx.Nonce())
+	if old == nil && list.Len() >= maxSystemSlots {
+		return ErrTxPoolOverflow
+	}
+	if old != nil {
+		delete(l.all, old.Hash())
+	}
+	list.Put(tx)
+	l.all[tx.Hash()] = tx
+	return nil
+}
+
+// get returns a system transaction if it is contained in the lane.
+func (l *txSystemLane) get(hash common.Hash) *types.Transaction {
+	return l.all[hash]
+}
+
+// has reports whether the lane holds a system transaction of the validator with
+// the given nonce.
+func (l *txSystemLane) has(from common.Address, nonce uint64) bool {
+	if list := l.txs[from]; list != nil {
+		return list.Get(nonce) != nil
+	}
+	return false
+}
+
+// nonce returns the next nonce a new transaction of the validator should use,
+// skipping the system transactions already waiting from the given one on.
+func (l *txSystemLane) nonce(from common.Address, next uint64) uint64 {
+	if list := l.txs[from]; list != nil {
+		for _, tx := range list.Flatten() {
+			if tx.Nonce() == next {
+				next++
+			}
+		}
+	}
+	return next
+}
+
+// executable retrieves the system transactions of every validator that are
+// processable on top of the given state, grouped by validator and sorted by
+// nonce. Transactions after a nonce gap are withheld.
+func (l *txSystemLane) executable(statedb *state.StateDB) map[common.Address]types.Transactions {
+	ready := make(map[common.Address]types.Transactions)
+	for addr, list := range l.txs {
+		next := statedb.GetNonce(addr)
+		for _, tx := range list.Flatten() {
+			if tx.Nonce() < next {
+				continue
+			}
+			if tx.Nonce() != next {
+				break
+			}
+			ready[addr] = append(ready[addr], tx)
+			next++
+		}
+	}
+	return ready
+}
+
+// content retrieves every system transaction in the lane, grouped by validator
+// and sorted by nonce.
+func (l *txSystemLane) content() map[common.Address]types.Transactions {
+	content := make(map[common.Address]types.Transactions)
+	for addr, list := range l.txs {
+		content[addr] = list.Flatten()
+	}
+	return content
+}
+
+// forward drops every system transaction that has been made stale by the given
+// state, i.e. the ones included in a block or overridden by another transaction
+// of the validator.
+func (l *txSystemLane) forward(statedb *state.StateDB) {
+	for addr, list := range l.txs {
+		for _, tx := range list.Forward(statedb.GetNonce(addr)) {
+			delete(l.all, tx.Hash())
+		}
+		if list.Len() == 0 {
+			delete(l.txs, addr)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"PureChain/common"
+	"PureChain/consensus"
+	"PureChain/core/rawdb"
+	"PureChain/core/types"
+	"PureChain/crypto"
+	"PureChain/ethdb"
+)
+
+const (
+	// txHistoryThrottling is the time to wait between processing two consecutive
+	// transaction history sections, preventing the initial indexing from hogging
+	// the disk.
+	txHistoryThrottling = 100 * time.Millisecond
+
+	// TxHistoryRewardIndex is the transaction index of the history entries of the
+	// reward credits, which are applied after all transactions of a block.
+	TxHistoryRewardIndex = math.MaxUint32
+)
+
+// TxRole is the set of roles an account plays in an entry of its transaction
+// history.
+type TxRole uint8
+
+const (
+	TxRoleSender    TxRole = 1 << iota // Account sent the transaction
+	TxRoleRecipient                    // Account received the transaction
+	TxRoleCreated                      // Account was deployed by the transaction
+	TxRoleSystem                       // Transaction is a system transaction of the consensus engine
+	TxRoleReward                       // Account was credited a minted reward by the consensus engine, fee shares excluded
+)
+
+// TxHistoryIndexer implements a core.ChainIndexer, building up the history of
+// the transactions each account sent or received, along with the rewards the
+// consensus engine credited it with.
+type TxHistoryIndexer struct {
+	db     ethdb.Database              // database instance to write index data and metadata into
+	chain  consensus.ChainHeaderReader // chain to retrieve the headers the engine rewards depend on
+	engine consensus.Engine            // consensus engine telling system transactions and rewards
+	batch  ethdb.Batch                 // batch collecting the entries of the current section
+}
+
+// NewTxHistoryIndexer returns a chain indexer that generates the account
+// transaction history for the canonical chain.
+func NewTxHistoryIndexer(db ethdb.Database, chain consensus.ChainHeaderReader, engine consensus.Engine, size, confirms uint64) *ChainIndexer {
+	backend := &TxHistoryIndexer{
+		db:     db,
+		chain:  chain,
+		engine: engine,
+	}
+	table := rawdb.NewTable(db, string(rawdb.TxHistoryTablePrefix))
+
+	return NewChainIndexer(db, table, backend, size, confirms, txHistoryThrottling, "txhistory")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new transaction history
+// section. Entries of reorged blocks are left behind and filtered out by the
+// readers, as their block hashes are no longer canonical.
+func (t *TxHistoryIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	t.batch = t.db.NewBatch()
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the transactions and the
+// reward credits of a new header into the history.
+func (t *TxHistoryIndexer) Process(ctx context.Context, header *types.Header) error {
+	var (
+		hash   = header.Hash()
+		number = header.Number.Uint64()
+	)
+	body := rawdb.ReadBody(t.db, hash, number)
+	if body == nil {
+		// Pruned bodies are never served, don't stall the index on them
+		if number < rawdb.ReadBodyHistoryTail(t.db) {
+			return nil
+		}
+		return fmt.Errorf("body of block #%d [%x..] not found", number, hash[:4])
+	}
+	history, err := TxHistoryOf(t.chain, t.engine, header, body, rawdb.ReadRawReceipts(t.db, hash, number))
+	if err != nil {
+		return err
+	}
+	for address, entries := range history {
+		for _, entry := range entries {
+			rawdb.WriteTxHistoryEntry(t.batch, address, entry)
+		}
+	}
+	if t.batch.ValueSize() > ethdb.IdealBatchSize {
+		if err := t.batch.Write(); err != nil {
+			return err
+		}
+		t.batch.Reset()
+	}
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, writing out the remaining entries
+// of the section.
+func (t *TxHistoryIndexer) Commit() error {
+	return t.batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (t *TxHistoryIndexer) Prune(threshold uint64) error {
+	return nil
+}
+
+// rewardCollector gathers the accounts a consensus engine credits rewards to.
+type rewardCollector struct {
+	credited []common.Address
+}
+
+// CaptureBalanceChange implements consensus.BalanceTracer.
+func (c *rewardCollector) CaptureBalanceChange(kind consensus.BalanceChangeKind, from, to common.Address, value *big.Int) {
+	if kind == consensus.BalanceChangeReward || kind == consensus.BalanceChangeUncleReward {
+		c.credited = append(c.credited, to)
+	}
+}
+
+// TxHistoryOf returns the transaction history entries of a block per account,
+// in the order of the transactions. Receipts are optional; without them failed
+// contract deployments are reported as successful.
+func TxHistoryOf(chain consensus.ChainHeaderReader, engine consensus.Engine, header *types.Header, body *types.Body, receipts types.Receipts) (map[common.Address][]*rawdb.TxHistoryEntry, error) {
+	var (
+		hash    = header.Hash()
+		number  = header.Number.Uint64()
+		signer  = types.MakeSigner(chain.Config(), header.Number)
+		history = make(map[common.Address][]*rawdb.TxHistoryEntry)
+	)
+	add := func(address common.Address, index uint32, tx common.Hash, role TxRole) {
+		entries := history[address]
+		if n := len(entries); n > 0 && entries[n-1].TxIndex == index {
+			entries[n-1].Role |= uint8(role)
+			return
+		}
+		history[address] = append(entries, &rawdb.TxHistoryEntry{
+			BlockNumber: number,
+			BlockHash:   hash,
+			TxIndex:     index,
+			TxHash:      tx,
+			Role:        uint8(role),
+		})
+	}
+	posa, isPoSA := engine.(consensus.PoSA)
+	for i, tx := range body.Transactions {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transaction %#x: %v", tx.Hash(), err)
+		}
+		var system TxRole
+		if isPoSA {
+			if ok, _ := posa.IsSystemTransaction(tx, header); ok {
+				system = TxRoleSystem
+			}
+		}
+		add(from, uint32(i), tx.Hash(), TxRoleSender|system)
+
+		if to := tx.To(); to != nil {
+			add(*to, uint32(i), tx.Hash(), TxRoleRecipient|system)
+		} else if i >= len(receipts) || receipts[i].Status == types.ReceiptStatusSuccessful || len(receipts[i].PostState) > 0 {
+			add(crypto.CreateAddress(from, tx.Nonce()), uint32(i), tx.Hash(), TxRoleCreated)
+		}
+	}
+	if reporter, ok := engine.(consensus.RewardReporter); ok {
+		collector := new(rewardCollector)
+		if err := reporter.BlockRewards(chain, header, body.Uncles, collector); err != nil {
+			return nil, err
+		}
+		for _, address := range collector.credited {
+			add(address, TxHistoryRewardIndex, common.Hash{}, TxRoleReward)
+		}
+	}
+	return history, nil
+}
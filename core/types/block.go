@@ -87,6 +87,9 @@ type Header struct {
 	TeamRate      uint64         `json:"team_rate"          gencodec:"required"`
 	Nonce         BlockNonce     `json:"nonce"`
 	ExtraNonce    BlockNonce     `json:"extra_nonce"`
+
+	// BaseFee was added by EIP-1559 and is ignored in legacy headers.
+	BaseFee *big.Int `json:"baseFeePerGas" rlp:"optional"`
 }
 
 // field type overrides for gencodec
@@ -100,6 +103,7 @@ type headerMarshaling struct {
 	Hash          common.Hash `json:"hash"` // adds call to Hash() in MarshalJSON
 	ValidatorRate hexutil.Uint64
 	TeamRate      hexutil.Uint64
+	BaseFee       *hexutil.Big
 }
 
 // Hash returns the block hash of the header, which is simply the keccak256 hash of its
@@ -132,6 +136,11 @@ func (h *Header) SanityCheck() error {
 	if eLen := len(h.Extra); eLen > 100*1024 {
 		return fmt.Errorf("too large block extradata: size %d", eLen)
 	}
+	if h.BaseFee != nil {
+		if bfLen := h.BaseFee.BitLen(); bfLen > 256 {
+			return fmt.Errorf("too large base fee: bitlen %d", bfLen)
+		}
+	}
 	return nil
 }
 
@@ -236,6 +245,9 @@ func CopyHeader(h *Header) *Header {
 	if cpy.Number = new(big.Int); h.Number != nil {
 		cpy.Number.Set(h.Number)
 	}
+	if h.BaseFee != nil {
+		cpy.BaseFee = new(big.Int).Set(h.BaseFee)
+	}
 	if len(h.Extra) > 0 {
 		cpy.Extra = make([]byte, len(h.Extra))
 		copy(cpy.Extra, h.Extra)
@@ -301,6 +313,14 @@ func (b *Block) TeamRate() uint64            { return b.header.TeamRate }
 func (b *Block) ValidatorRate() uint64       { return b.header.ValidatorRate }
 func (b *Block) Extra() []byte               { return common.CopyBytes(b.header.Extra) }
 
+// BaseFee returns the base fee of the block, nil before London.
+func (b *Block) BaseFee() *big.Int {
+	if b.header.BaseFee == nil {
+		return nil
+	}
+	return new(big.Int).Set(b.header.BaseFee)
+}
+
 func (b *Block) Header() *Header { return CopyHeader(b.header) }
 
 // Body returns the non-header content of the block.
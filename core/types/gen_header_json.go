@@ -35,6 +35,7 @@ func (h Header) MarshalJSON() ([]byte, error) {
 		TeamRate      hexutil.Uint64 `json:"team_rate"          gencodec:"required"`
 		Nonce         BlockNonce     `json:"nonce"`
 		ExtraNonce    BlockNonce     `json:"extra_nonce"`
+		BaseFee       *hexutil.Big   `json:"baseFeePerGas" rlp:"optional"`
 		Hash          common.Hash    `json:"hash"`
 	}
 	var enc Header
@@ -58,6 +59,7 @@ func (h Header) MarshalJSON() ([]byte, error) {
 	enc.TeamRate = hexutil.Uint64(h.TeamRate)
 	enc.Nonce = h.Nonce
 	enc.ExtraNonce = h.ExtraNonce
+	enc.BaseFee = (*hexutil.Big)(h.BaseFee)
 	enc.Hash = h.Hash()
 	return json.Marshal(&enc)
 }
@@ -85,6 +87,7 @@ func (h *Header) UnmarshalJSON(input []byte) error {
 		TeamRate      *hexutil.Uint64 `json:"team_rate"          gencodec:"required"`
 		Nonce         *BlockNonce     `json:"nonce"`
 		ExtraNonce    *BlockNonce     `json:"extra_nonce"`
+		BaseFee       *hexutil.Big    `json:"baseFeePerGas" rlp:"optional"`
 	}
 	var dec Header
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -167,5 +170,8 @@ func (h *Header) UnmarshalJSON(input []byte) error {
 	if dec.ExtraNonce != nil {
 		h.ExtraNonce = *dec.ExtraNonce
 	}
+	if dec.BaseFee != nil {
+		h.BaseFee = (*big.Int)(dec.BaseFee)
+	}
 	return nil
 }
@@ -0,0 +1,166 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"sync/atomic"
+
+	"PureChain/common"
+	"PureChain/common/hexutil"
+	"PureChain/crypto"
+	"PureChain/crypto/bls12381"
+)
+
+const (
+	BLSPublicKeyLength = bls12381.PublicKeyLength
+	BLSSignatureLength = bls12381.SignatureLength
+)
+
+var errInvalidVoteSignature = errors.New("invalid vote signature")
+
+// BLSPublicKey is a serialized BLS public key of a voting validator.
+type BLSPublicKey [BLSPublicKeyLength]byte
+
+// MarshalText encodes the public key as a hex string with 0x prefix.
+func (k BLSPublicKey) MarshalText() ([]byte, error) {
+	return hexutil.Bytes(k[:]).MarshalText()
+}
+
+// UnmarshalText parses a public key in hex syntax.
+func (k *BLSPublicKey) UnmarshalText(input []byte) error {
+	return hexutil.UnmarshalFixedText("BLSPublicKey", input, k[:])
+}
+
+// BLSSignature is a serialized BLS signature, possibly an aggregated one.
+type BLSSignature [BLSSignatureLength]byte
+
+// MarshalText encodes the signature as a hex string with 0x prefix.
+func (s BLSSignature) MarshalText() ([]byte, error) {
+	return hexutil.Bytes(s[:]).MarshalText()
+}
+
+// UnmarshalText parses a signature in hex syntax.
+func (s *BLSSignature) UnmarshalText(input []byte) error {
+	return hexutil.UnmarshalFixedText("BLSSignature", input, s[:])
+}
+
+// MaxAttestedValidators is the size of the largest validator set a bit set can
+// mark. Fast finality is suspended while the validator set is larger.
+const MaxAttestedValidators = 64
+
+// ValidatorsBitSet marks a subset of a validator set, bit i standing for the
+// i-th validator in ascending address order.
+type ValidatorsBitSet uint64
+
+// Count returns the number of validators in the set.
+func (b ValidatorsBitSet) Count() int {
+	return bits.OnesCount64(uint64(b))
+}
+
+// Has reports whether the i-th validator is in the set.
+func (b ValidatorsBitSet) Has(i int) bool {
+	return i >= 0 && i < MaxAttestedValidators && b&(1<<uint(i)) != 0
+}
+
+// Set adds the i-th validator to the set. It panics if i is out of range, as
+// the validator could not be attested.
+func (b *ValidatorsBitSet) Set(i int) {
+	if i < 0 || i >= MaxAttestedValidators {
+		panic(fmt.Sprintf("validator index %d out of bit set range", i))
+	}
+	*b |= 1 << uint(i)
+}
+
+// VoteData is the justification link a validator votes for: the source is the
+// latest justified block known by the voter, the target the block it attests.
+type VoteData struct {
+	SourceNumber uint64      `json:"sourceNumber"`
+	SourceHash   common.Hash `json:"sourceHash"`
+	TargetNumber uint64      `json:"targetNumber"`
+	TargetHash   common.Hash `json:"targetHash"`
+}
+
+// Hash returns the hash of the vote data, which is the message being signed.
+func (d *VoteData) Hash() common.Hash {
+	return rlpHash(d)
+}
+
+// VoteEnvelope is a single signed vote of a validator.
+type VoteEnvelope struct {
+	VoteAddress BLSPublicKey `json:"voteAddress"`
+	Signature   BLSSignature `json:"signature"`
+	Data        *VoteData    `json:"data"`
+
+	// caches
+	hash atomic.Value
+}
+
+// Hash returns the hash of the whole vote envelope.
+func (v *VoteEnvelope) Hash() common.Hash {
+	if hash := v.hash.Load(); hash != nil {
+		return hash.(common.Hash)
+	}
+	h := rlpHash(v)
+	v.hash.Store(h)
+	return h
+}
+
+// Verify checks the BLS signature of the vote against its vote address.
+func (v *VoteEnvelope) Verify() error {
+	if v.Data == nil {
+		return errInvalidVoteSignature
+	}
+	hash := v.Data.Hash()
+	if !bls12381.Verify(v.VoteAddress[:], hash[:], v.Signature[:]) {
+		return errInvalidVoteSignature
+	}
+	return nil
+}
+
+// VoteAttestation is the aggregation of the votes of a quorum of validators for
+// the same vote data, carried in the header extra-data of the next block.
+type VoteAttestation struct {
+	VoteAddressSet ValidatorsBitSet `json:"voteAddressSet"`
+	AggSignature   BLSSignature     `json:"aggSignature"`
+	Data           *VoteData        `json:"data"`
+}
+
+// VoteKeyRegistration binds a BLS vote key to the validator sealing the header
+// carrying it. The proof of possession rules out rogue key attacks on the
+// aggregated signatures.
+type VoteKeyRegistration struct {
+	VoteAddress BLSPublicKey `json:"voteAddress"`
+	Proof       BLSSignature `json:"proof"`
+}
+
+// VoteKeyProofHash returns the message a validator signs with its BLS key to
+// prove the possession of the key.
+func VoteKeyProofHash(validator common.Address, key BLSPublicKey) common.Hash {
+	return crypto.Keccak256Hash([]byte("dpos-vote-key"), validator[:], key[:])
+}
+
+// Verify checks the proof of possession of the registered key for validator.
+func (r *VoteKeyRegistration) Verify(validator common.Address) error {
+	hash := VoteKeyProofHash(validator, r.VoteAddress)
+	if !bls12381.Verify(r.VoteAddress[:], hash[:], r.Proof[:]) {
+		return errInvalidVoteSignature
+	}
+	return nil
+}
@@ -46,6 +46,7 @@ type Config struct {
 
 	ExtraEips      []int // Additional EIPS that are to be enabled
 	IsSkipProvider bool  // stateat skip provider check
+	NoBaseFee      bool  // Forces the EIP-1559 baseFee to 0 (needed for 0 price calls)
 }
 
 // Interpreter is used to run Ethereum based contracts and will utilise the
@@ -106,6 +107,8 @@ func NewEVMInterpreter(evm *EVM, cfg Config) *EVMInterpreter {
 	if cfg.JumpTable[STOP] == nil {
 		var jt JumpTable
 		switch {
+		case evm.chainRules.IsLondon:
+			jt = londonInstructionSet
 		case evm.chainRules.IsBerlin:
 			jt = berlinInstructionSet
 		case evm.chainRules.IsIstanbul:
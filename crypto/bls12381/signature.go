@@ -0,0 +1,234 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls12381
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math/big"
+)
+
+const (
+	// SecretKeyLength is the length of a serialized secret key.
+	SecretKeyLength = 32
+	// PublicKeyLength is the length of a serialized (uncompressed G1) public key.
+	PublicKeyLength = 96
+	// SignatureLength is the length of a serialized (uncompressed G2) signature.
+	SignatureLength = 192
+)
+
+// signatureDST is the domain separation tag used when hashing messages to G2.
+var signatureDST = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+
+// fieldModulus is the modulus of the base field as a big integer.
+var fieldModulus = bigFromHex("0x1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffaaab")
+
+var (
+	errInvalidSecretKey = errors.New("invalid bls secret key")
+	errInvalidPublicKey = errors.New("invalid bls public key")
+	errInvalidSignature = errors.New("invalid bls signature")
+	errNoSignatures     = errors.New("no signatures to aggregate")
+)
+
+// SecretKey is a BLS secret key, a scalar of the prime order subgroup. Public
+// keys live in G1 and signatures in G2 (the "minimal public key size" variant).
+type SecretKey struct {
+	k *big.Int
+}
+
+// GenerateKey creates a new random secret key.
+func GenerateKey(rand io.Reader) (*SecretKey, error) {
+	for {
+		var buf [SecretKeyLength]byte
+		if _, err := io.ReadFull(rand, buf[:]); err != nil {
+			return nil, err
+		}
+		k := new(big.Int).SetBytes(buf[:])
+		k.Mod(k, q)
+		if k.Sign() > 0 {
+			return &SecretKey{k: k}, nil
+		}
+	}
+}
+
+// SecretKeyFromBytes parses a 32 byte big endian secret key.
+func SecretKeyFromBytes(in []byte) (*SecretKey, error) {
+	if len(in) != SecretKeyLength {
+		return nil, errInvalidSecretKey
+	}
+	k := new(big.Int).SetBytes(in)
+	if k.Sign() == 0 || k.Cmp(q) >= 0 {
+		return nil, errInvalidSecretKey
+	}
+	return &SecretKey{k: k}, nil
+}
+
+// Bytes serializes the secret key into 32 big endian bytes.
+func (sk *SecretKey) Bytes() []byte {
+	out := make([]byte, SecretKeyLength)
+	return sk.k.FillBytes(out)
+}
+
+// PublicKey returns the serialized public key belonging to the secret key.
+func (sk *SecretKey) PublicKey() []byte {
+	g := NewG1()
+	return g.ToBytes(g.MulScalar(g.New(), g.One(), sk.k))
+}
+
+// Sign signs the message, returning the serialized signature.
+func (sk *SecretKey) Sign(msg []byte) []byte {
+	g := NewG2()
+	h, err := hashToG2(g, msg)
+	if err != nil {
+		// hashToG2 only fails on invalid field elements, which the reduction
+		// modulo the field prime rules out.
+		panic(err)
+	}
+	return g.ToBytes(g.MulScalar(g.New(), h, sk.k))
+}
+
+// Verify checks that sig is a valid signature of msg by the owner of pub.
+func Verify(pub, msg, sig []byte) bool {
+	return FastAggregateVerify([][]byte{pub}, msg, sig)
+}
+
+// AggregateSignatures combines the given signatures into a single one.
+func AggregateSignatures(sigs [][]byte) ([]byte, error) {
+	if len(sigs) == 0 {
+		return nil, errNoSignatures
+	}
+	g := NewG2()
+	agg := g.Zero()
+	for _, sig := range sigs {
+		p, err := decodeSignature(g, sig)
+		if err != nil {
+			return nil, err
+		}
+		g.Add(agg, agg, p)
+	}
+	return g.ToBytes(agg), nil
+}
+
+// FastAggregateVerify checks that sig is the aggregate of the signatures over
+// the same msg by the owners of all the given public keys. The public keys must
+// have had their possession proven beforehand to rule out rogue key attacks.
+func FastAggregateVerify(pubs [][]byte, msg, sig []byte) bool {
+	if len(pubs) == 0 {
+		return false
+	}
+	g1 := NewG1()
+	agg := g1.Zero()
+	for _, pub := range pubs {
+		p, err := decodePublicKey(g1, pub)
+		if err != nil {
+			return false
+		}
+		g1.Add(agg, agg, p)
+	}
+	g2 := NewG2()
+	s, err := decodeSignature(g2, sig)
+	if err != nil {
+		return false
+	}
+	h, err := hashToG2(g2, msg)
+	if err != nil {
+		return false
+	}
+	// e(pk, H(m)) == e(g1, sig)
+	return NewPairingEngine().AddPair(agg, h).AddPairInv(g1.One(), s).Check()
+}
+
+// decodePublicKey parses a serialized public key, rejecting the identity and
+// points outside of the prime order subgroup.
+func decodePublicKey(g *G1, in []byte) (*PointG1, error) {
+	p, err := g.FromBytes(in)
+	if err != nil || g.IsZero(p) || !g.InCorrectSubgroup(p) {
+		return nil, errInvalidPublicKey
+	}
+	return p, nil
+}
+
+// decodeSignature parses a serialized signature, rejecting points outside of
+// the prime order subgroup.
+func decodeSignature(g *G2, in []byte) (*PointG2, error) {
+	p, err := g.FromBytes(in)
+	if err != nil || !g.InCorrectSubgroup(p) {
+		return nil, errInvalidSignature
+	}
+	return p, nil
+}
+
+// hashToG2 hashes msg into two field elements of Fp2 with expand_message_xmd
+// and maps both onto the curve, returning their sum.
+func hashToG2(g *G2, msg []byte) (*PointG2, error) {
+	uniform := expandMessageXMD(msg, signatureDST, 4*64)
+
+	var elems [4][]byte
+	for i := range elems {
+		e := new(big.Int).SetBytes(uniform[i*64 : (i+1)*64])
+		elems[i] = e.Mod(e, fieldModulus).FillBytes(make([]byte, 48))
+	}
+	// Fp2 elements are serialized as c1 || c0
+	p0, err := g.MapToCurve(append(append([]byte{}, elems[1]...), elems[0]...))
+	if err != nil {
+		return nil, err
+	}
+	p1, err := g.MapToCurve(append(append([]byte{}, elems[3]...), elems[2]...))
+	if err != nil {
+		return nil, err
+	}
+	return g.Add(g.New(), p0, p1), nil
+}
+
+// expandMessageXMD implements expand_message_xmd with SHA-256 from the hash to
+// curve specification.
+func expandMessageXMD(msg, dst []byte, length int) []byte {
+	const blockSize, hashSize = 64, sha256.Size
+
+	dstPrime := append(append([]byte{}, dst...), byte(len(dst)))
+	ell := (length + hashSize - 1) / hashSize
+
+	h := sha256.New()
+	h.Write(make([]byte, blockSize))
+	h.Write(msg)
+	h.Write([]byte{byte(length >> 8), byte(length), 0})
+	h.Write(dstPrime)
+	b0 := h.Sum(nil)
+
+	h.Reset()
+	h.Write(b0)
+	h.Write([]byte{1})
+	h.Write(dstPrime)
+	bi := h.Sum(nil)
+
+	out := make([]byte, 0, ell*hashSize)
+	out = append(out, bi...)
+	for i := 2; i <= ell; i++ {
+		xored := make([]byte, hashSize)
+		for j := range xored {
+			xored[j] = b0[j] ^ bi[j]
+		}
+		h.Reset()
+		h.Write(xored)
+		h.Write([]byte{byte(i)})
+		h.Write(dstPrime)
+		bi = h.Sum(nil)
+		out = append(out, bi...)
+	}
+	return out[:length]
+}
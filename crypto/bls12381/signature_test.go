@@ -0,0 +1,59 @@
+package bls12381
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestSignatureVerify(t *testing.T) {
+	sk, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("attest")
+	sig := sk.Sign(msg)
+	if !Verify(sk.PublicKey(), msg, sig) {
+		t.Fatal("valid signature rejected")
+	}
+	if Verify(sk.PublicKey(), []byte("other"), sig) {
+		t.Fatal("signature accepted for wrong message")
+	}
+	other, _ := GenerateKey(rand.Reader)
+	if Verify(other.PublicKey(), msg, sig) {
+		t.Fatal("signature accepted for wrong key")
+	}
+	restored, err := SecretKeyFromBytes(sk.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(restored.PublicKey(), sk.PublicKey()) {
+		t.Fatal("secret key serialization mismatch")
+	}
+}
+
+func TestAggregateSignatureVerify(t *testing.T) {
+	var (
+		msg  = []byte("attest")
+		pubs [][]byte
+		sigs [][]byte
+	)
+	for i := 0; i < 4; i++ {
+		sk, err := GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubs = append(pubs, sk.PublicKey())
+		sigs = append(sigs, sk.Sign(msg))
+	}
+	agg, err := AggregateSignatures(sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !FastAggregateVerify(pubs, msg, agg) {
+		t.Fatal("valid aggregate signature rejected")
+	}
+	if FastAggregateVerify(pubs[:3], msg, agg) {
+		t.Fatal("aggregate signature accepted with missing signer")
+	}
+}
@@ -30,11 +30,14 @@ import (
 
 	"PureChain/common"
 	"PureChain/common/hexutil"
+	"PureChain/consensus/dpos"
 	"PureChain/core"
 	"PureChain/core/rawdb"
 	"PureChain/core/state"
+	"PureChain/core/state/pruner"
 	"PureChain/core/types"
 	"PureChain/internal/ethapi"
+	"PureChain/log"
 	"PureChain/rlp"
 	"PureChain/rpc"
 	"PureChain/trie"
@@ -144,6 +147,12 @@ func (api *PrivateMinerAPI) SetRecommitInterval(interval int) {
 	api.e.Miner().SetRecommitInterval(time.Duration(interval) * time.Millisecond)
 }
 
+// GetValidatorSchedule returns the sealing status of every local dpos validator
+// for the next block, along with the validator selected to seal it.
+func (api *PrivateMinerAPI) GetValidatorSchedule() (*dpos.ValidatorSchedule, error) {
+	return api.e.Miner().ValidatorSchedule()
+}
+
 // PrivateAdminAPI is the collection of Ethereum full node-related APIs
 // exposed over the private admin endpoint.
 type PrivateAdminAPI struct {
@@ -254,6 +263,71 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// PrivateTxPoolAPI is the collection of transaction pool related APIs exposed
+// over the private txpool endpoint. As they access the local file system, they
+// are only served over local or authenticated transports.
+type PrivateTxPoolAPI struct {
+	eth *Ethereum
+}
+
+// NewPrivateTxPoolAPI creates a new API definition for the full node private
+// transaction pool methods of the Ethereum service.
+func NewPrivateTxPoolAPI(eth *Ethereum) *PrivateTxPoolAPI {
+	return &PrivateTxPoolAPI{eth: eth}
+}
+
+// Export exports the pending and queued transactions of the pool into a local
+// file as a stream of RLP encoded transactions.
+func (api *PrivateTxPoolAPI) Export(file string) (bool, error) {
+	if _, err := os.Stat(file); err == nil {
+		// File already exists. Allowing overwrite could be a DoS vector,
+		// since the 'file' may point to arbitrary paths on the drive
+		return false, errors.New("location would overwrite an existing file")
+	}
+	// Make sure we can create the file to export into
+	out, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return false, err
+	}
+	defer out.Close()
+
+	var writer io.Writer = out
+	if strings.HasSuffix(file, ".gz") {
+		writer = gzip.NewWriter(writer)
+		defer writer.(*gzip.Writer).Close()
+	}
+	exported, err := api.eth.TxPool().Export(writer)
+	if err != nil {
+		return false, err
+	}
+	log.Info("Exported transaction pool", "file", file, "transactions", exported)
+	return true, nil
+}
+
+// Import imports the transactions of a local RLP file into the pool, treating
+// them as remote ones.
+func (api *PrivateTxPoolAPI) Import(file string) (bool, error) {
+	// Make sure the can access the file to import
+	in, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer in.Close()
+
+	var reader io.Reader = in
+	if strings.HasSuffix(file, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return false, err
+		}
+	}
+	total, dropped, err := api.eth.TxPool().Import(reader)
+	if err != nil {
+		return false, fmt.Errorf("transaction %d: failed to parse: %v", total, err)
+	}
+	log.Info("Imported transaction pool", "file", file, "transactions", total, "dropped", dropped)
+	return true, nil
+}
+
 // PublicDebugAPI is the collection of Ethereum full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
@@ -311,6 +385,24 @@ func (api *PrivateDebugAPI) Preimage(ctx context.Context, hash common.Hash) (hex
 	return nil, errors.New("unknown preimage")
 }
 
+// PruneState starts deleting the stale state from the database in the background,
+// limiting the database IO to the given budget in bytes per second. A zero budget
+// leaves the pruning unthrottled.
+func (api *PrivateDebugAPI) PruneState(budget hexutil.Uint64) error {
+	if !api.eth.Synced() {
+		return errors.New("chain is not synced yet")
+	}
+	return api.eth.blockchain.PruneState(api.eth.datadir, 0, uint64(budget))
+}
+
+// PruneStatus returns the progress of the latest online state pruning.
+func (api *PrivateDebugAPI) PruneStatus() (*pruner.Status, error) {
+	if status := api.eth.blockchain.PruneStatus(); status != nil {
+		return status, nil
+	}
+	return nil, errors.New("state pruning not started")
+}
+
 // BadBlockArgs represents the entries in the list returned when bad blocks are queried.
 type BadBlockArgs struct {
 	Hash  common.Hash            `json:"hash"`
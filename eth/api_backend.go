@@ -19,6 +19,7 @@ package eth
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math/big"
 
 	"PureChain/accounts"
@@ -71,9 +72,26 @@ func (b *EthAPIBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumb
 	if number == rpc.LatestBlockNumber {
 		return b.eth.blockchain.CurrentBlock().Header(), nil
 	}
+	if number == rpc.FinalizedBlockNumber {
+		return b.finalizedHeader()
+	}
 	return b.eth.blockchain.GetHeaderByNumber(uint64(number)), nil
 }
 
+// finalizedHeader returns the latest finalized header of the canonical chain,
+// which is only known by fast finality engines.
+func (b *EthAPIBackend) finalizedHeader() (*types.Header, error) {
+	engine, ok := b.eth.engine.(consensus.FastFinality)
+	if !ok {
+		return nil, errors.New("finalized block not supported by the consensus engine")
+	}
+	header := engine.GetFinalizedHeader(b.eth.blockchain, b.eth.blockchain.CurrentHeader())
+	if header == nil {
+		return nil, errors.New("finalized block not found")
+	}
+	return header, nil
+}
+
 func (b *EthAPIBackend) HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
 	if blockNr, ok := blockNrOrHash.Number(); ok {
 		return b.HeaderByNumber(ctx, blockNr)
@@ -105,6 +123,13 @@ func (b *EthAPIBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumbe
 	if number == rpc.LatestBlockNumber {
 		return b.eth.blockchain.CurrentBlock(), nil
 	}
+	if number == rpc.FinalizedBlockNumber {
+		header, err := b.finalizedHeader()
+		if err != nil {
+			return nil, err
+		}
+		return b.eth.blockchain.GetBlock(header.Hash(), header.Number.Uint64()), nil
+	}
 	return b.eth.blockchain.GetBlockByNumber(uint64(number)), nil
 }
 
@@ -147,10 +172,22 @@ func (b *EthAPIBackend) StateAndHeaderByNumber(ctx context.Context, number rpc.B
 	if header == nil {
 		return nil, nil, errors.New("header not found")
 	}
-	stateDb, err := b.eth.BlockChain().StateAt(header.Root)
+	stateDb, err := b.stateAt(header)
 	return stateDb, header, err
 }
 
+// stateAt returns the state of the given header, falling back to the state
+// history if the state was already pruned.
+func (b *EthAPIBackend) stateAt(header *types.Header) (*state.StateDB, error) {
+	stateDb, err := b.eth.BlockChain().StateAt(header.Root)
+	if err != nil {
+		if historic, herr := b.eth.BlockChain().HistoricState(header); herr == nil {
+			return historic, nil
+		}
+	}
+	return stateDb, err
+}
+
 func (b *EthAPIBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
 	if blockNr, ok := blockNrOrHash.Number(); ok {
 		return b.StateAndHeaderByNumber(ctx, blockNr)
@@ -166,20 +203,24 @@ func (b *EthAPIBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockN
 		if blockNrOrHash.RequireCanonical && b.eth.blockchain.GetCanonicalHash(header.Number.Uint64()) != hash {
 			return nil, nil, errors.New("hash is not currently canonical")
 		}
-		stateDb, err := b.eth.BlockChain().StateAt(header.Root)
+		stateDb, err := b.stateAt(header)
 		return stateDb, header, err
 	}
 	return nil, nil, errors.New("invalid arguments; neither block nor hash specified")
 }
 
 func (b *EthAPIBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
-	return b.eth.blockchain.GetReceiptsByHash(hash), nil
+	receipts := b.eth.blockchain.GetReceiptsByHash(hash)
+	if receipts == nil {
+		return nil, b.receiptsPruned(hash)
+	}
+	return receipts, nil
 }
 
 func (b *EthAPIBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*types.Log, error) {
 	receipts := b.eth.blockchain.GetReceiptsByHash(hash)
 	if receipts == nil {
-		return nil, nil
+		return nil, b.receiptsPruned(hash)
 	}
 	logs := make([][]*types.Log, len(receipts))
 	for i, receipt := range receipts {
@@ -188,6 +229,19 @@ func (b *EthAPIBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*typ
 	return logs, nil
 }
 
+// receiptsPruned returns an error if the receipts of the given block are missing
+// because they are older than the retained chain history.
+func (b *EthAPIBackend) receiptsPruned(hash common.Hash) error {
+	number := rawdb.ReadHeaderNumber(b.eth.ChainDb(), hash)
+	if number == nil || *number == 0 {
+		return nil
+	}
+	if tail := rawdb.ReadReceiptHistoryTail(b.eth.ChainDb()); *number < tail {
+		return fmt.Errorf("%w: receipts of block #%d are not retained, the oldest available is #%d", core.ErrHistoryPruned, *number, tail)
+	}
+	return nil
+}
+
 func (b *EthAPIBackend) GetTd(ctx context.Context, hash common.Hash) *big.Int {
 	return b.eth.blockchain.GetTdByHash(hash)
 }
@@ -246,11 +300,22 @@ func (b *EthAPIBackend) PosEtherbase() []common.Address {
 	return b.eth.posEtherbase
 }
 func (b *EthAPIBackend) GetPoolTransaction(hash common.Hash) *types.Transaction {
-	return b.eth.txPool.Get(hash)
+	if tx := b.eth.txPool.Get(hash); tx != nil {
+		return tx
+	}
+	return b.eth.txPool.GetSystem(hash)
 }
 
 func (b *EthAPIBackend) GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, common.Hash, uint64, uint64, error) {
 	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(b.eth.ChainDb(), txHash)
+	if tx == nil {
+		// Still indexed transactions of pruned bodies are gone for good
+		if number := rawdb.ReadTxLookupEntry(b.eth.ChainDb(), txHash); number != nil && *number > 0 {
+			if tail := rawdb.ReadBodyHistoryTail(b.eth.ChainDb()); *number < tail {
+				return nil, common.Hash{}, 0, 0, fmt.Errorf("%w: body of block #%d is not retained, the oldest available is #%d", core.ErrHistoryPruned, *number, tail)
+			}
+		}
+	}
 	return tx, blockHash, blockNumber, index, nil
 }
 
@@ -266,6 +331,10 @@ func (b *EthAPIBackend) TxPoolContent() (map[common.Address]types.Transactions,
 	return b.eth.TxPool().Content()
 }
 
+func (b *EthAPIBackend) TxPoolSystemContent() map[common.Address]types.Transactions {
+	return b.eth.TxPool().SystemContent()
+}
+
 func (b *EthAPIBackend) TxPool() *core.TxPool {
 	return b.eth.TxPool()
 }
@@ -286,6 +355,10 @@ func (b *EthAPIBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
 
+func (b *EthAPIBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return b.gpo.SuggestTipCap(ctx)
+}
+
 func (b *EthAPIBackend) ChainDb() ethdb.Database {
 	return b.eth.ChainDb()
 }
@@ -319,6 +392,16 @@ func (b *EthAPIBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, sections
 }
 
+// LogIndexStatus implements filters.LogIndexBackend, reporting no indexed
+// sections if the log index is disabled.
+func (b *EthAPIBackend) LogIndexStatus() (uint64, uint64) {
+	if b.eth.logIndexer == nil {
+		return params.LogIndexBlocks, 0
+	}
+	sections, _, _ := b.eth.logIndexer.Sections()
+	return params.LogIndexBlocks, sections
+}
+
 func (b *EthAPIBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	for i := 0; i < bloomFilterThreads; i++ {
 		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.eth.bloomRequests)
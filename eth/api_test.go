@@ -18,6 +18,8 @@ package eth
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	"reflect"
@@ -25,9 +27,15 @@ import (
 	"testing"
 
 	"PureChain/common"
+	"PureChain/consensus/ethash"
+	"PureChain/core"
 	"PureChain/core/rawdb"
 	"PureChain/core/state"
+	"PureChain/core/types"
+	"PureChain/core/vm"
 	"PureChain/crypto"
+	"PureChain/internal/ethapi"
+	"PureChain/params"
 	"github.com/davecgh/go-spew/spew"
 )
 
@@ -207,3 +215,56 @@ func TestStorageRangeAt(t *testing.T) {
 		}
 	}
 }
+
+// Tests that receipts of pruned blocks are reported as pruned instead of unknown.
+func TestGetTransactionReceiptPruned(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		engine = ethash.NewFaker()
+		signer = types.LatestSigner(params.TestChainConfig)
+		txs    []common.Hash
+	)
+	(&core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{testAddr: {Balance: big.NewInt(params.Ether)}},
+	}).MustCommit(db)
+
+	chain, _ := core.NewBlockChain(db, nil, params.TestChainConfig, engine, vm.Config{}, nil, nil)
+
+	blocks, _ := core.GenerateChain(params.TestChainConfig, chain.Genesis(), engine, db, 10, func(i int, gen *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(testAddr), common.Address{0x1}, big.NewInt(1), params.TxGas, big.NewInt(2*params.InitialBaseFee), nil), signer, testKey)
+		gen.AddTx(tx)
+		txs = append(txs, tx.Hash())
+	})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	rawdb.PruneBlockReceipts(db, 0, 6, nil)
+	rawdb.PruneBlockBodies(db, 0, 4, nil)
+
+	// Reopen the chain to drop the cached bodies and receipts
+	chain.Stop()
+	chain, _ = core.NewBlockChain(db, nil, params.TestChainConfig, engine, vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	api := ethapi.NewPublicTransactionPoolAPI(&EthAPIBackend{eth: &Ethereum{blockchain: chain, chainDb: db}}, nil)
+	tests := []struct {
+		hash   common.Hash
+		pruned bool
+		found  bool
+	}{
+		{txs[1], true, false}, // block #2, body pruned
+		{txs[4], true, false}, // block #5, receipts pruned
+		{txs[7], false, true}, // block #8, retained
+		{common.Hash{0xff}, false, false},
+	}
+	for i, tt := range tests {
+		receipt, err := api.GetTransactionReceipt(context.Background(), tt.hash)
+		if pruned := errors.Is(err, core.ErrHistoryPruned); pruned != tt.pruned {
+			t.Errorf("test %d: pruned mismatch: have %v, want %v", i, err, tt.pruned)
+		}
+		if found := receipt != nil; found != tt.found {
+			t.Errorf("test %d: receipt mismatch: have %v, want found %v", i, receipt, tt.found)
+		}
+	}
+}
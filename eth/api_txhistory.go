@@ -0,0 +1,205 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"PureChain/common"
+	"PureChain/common/hexutil"
+	"PureChain/core"
+	"PureChain/core/rawdb"
+	"PureChain/ethdb"
+	"PureChain/params"
+)
+
+const (
+	// txHistoryDefaultLimit is the number of history entries returned if the
+	// caller doesn't specify a limit.
+	txHistoryDefaultLimit = 100
+
+	// txHistoryMaxLimit is the maximum number of history entries returned by a
+	// single call.
+	txHistoryMaxLimit = 1000
+
+	// txHistoryMaxUnindexed is the maximum number of blocks not yet covered by the
+	// index that are scanned on demand. Beyond it the index is still being built.
+	txHistoryMaxUnindexed = 4 * (params.TxHistoryBlocks + params.TxHistoryConfirms)
+
+	// txHistoryCursorLength is the length of an encoded history cursor.
+	txHistoryCursorLength = 12
+)
+
+var (
+	errTxHistoryDisabled = errors.New("transaction history index not enabled (--txhistory)")
+	errTxHistoryCursor   = errors.New("invalid transaction history cursor")
+)
+
+// txRoleNames are the names of the transaction roles reported over RPC.
+var txRoleNames = []struct {
+	role core.TxRole
+	name string
+}{
+	{core.TxRoleSender, "sender"},
+	{core.TxRoleRecipient, "recipient"},
+	{core.TxRoleCreated, "created"},
+	{core.TxRoleSystem, "system"},
+	{core.TxRoleReward, "reward"},
+}
+
+// TxHistoryEntry is a transaction an account took part in, or the rewards the
+// consensus engine credited it with in a block.
+type TxHistoryEntry struct {
+	BlockNumber      hexutil.Uint64  `json:"blockNumber"`
+	BlockHash        common.Hash     `json:"blockHash"`
+	TransactionHash  *common.Hash    `json:"transactionHash"`  // nil for reward credits
+	TransactionIndex *hexutil.Uint64 `json:"transactionIndex"` // nil for reward credits
+	Roles            []string        `json:"roles"`
+}
+
+// TxHistoryPage is a page of the transaction history of an account.
+type TxHistoryPage struct {
+	Transactions []*TxHistoryEntry `json:"transactions"`
+	NextCursor   *hexutil.Bytes    `json:"nextCursor"` // nil on the last page
+}
+
+// GetTransactionsByAddress returns the transactions the given account sent,
+// received or deployed a contract with, and the blocks crediting it rewards,
+// newest first. Pass the nextCursor of a page to retrieve the following one.
+//
+// The reward role only covers the block subsidy the engine mints, which can be
+// derived from the headers alone. The collected fees swept out of the system
+// address and the shares of the base fee depend on the state of the block and
+// are not indexed, trace the block with the rewards option to retrieve them.
+func (api *PublicEthereumAPI) GetTransactionsByAddress(ctx context.Context, address common.Address, cursor *hexutil.Bytes, limit *hexutil.Uint64) (*TxHistoryPage, error) {
+	if api.e.txHistoryIndexer == nil {
+		return nil, errTxHistoryDisabled
+	}
+	n := uint64(txHistoryDefaultLimit)
+	if limit != nil {
+		n = uint64(*limit)
+	}
+	if n == 0 || n > txHistoryMaxLimit {
+		return nil, fmt.Errorf("limit must be between 1 and %d", txHistoryMaxLimit)
+	}
+	var (
+		number = api.e.blockchain.CurrentBlock().NumberU64()
+		index  = uint32(math.MaxUint32)
+	)
+	if cursor != nil {
+		if len(*cursor) != txHistoryCursorLength {
+			return nil, errTxHistoryCursor
+		}
+		number = binary.BigEndian.Uint64((*cursor)[:8])
+		index = binary.BigEndian.Uint32((*cursor)[8:])
+	}
+	sections, _, _ := api.e.txHistoryIndexer.Sections()
+	return txHistoryPage(ctx, api.e.chainDb, api.e.blockchain, sections*params.TxHistoryBlocks, address, number, index, int(n))
+}
+
+// txHistoryPage collects up to limit canonical history entries of the account,
+// starting with the given position. Blocks from the indexed number on are not
+// yet covered by the index and are scanned instead.
+func txHistoryPage(ctx context.Context, db ethdb.Database, chain *core.BlockChain, indexed uint64, address common.Address, number uint64, index uint32, limit int) (*TxHistoryPage, error) {
+	head := chain.CurrentBlock().NumberU64()
+	if head >= indexed && head-indexed >= txHistoryMaxUnindexed {
+		return nil, fmt.Errorf("transaction history index is still being built (%d blocks behind)", head-indexed)
+	}
+	if number > head {
+		number, index = head, math.MaxUint32
+	}
+	// Collect one extra entry to know where the next page starts
+	var entries []*rawdb.TxHistoryEntry
+	for ; number >= indexed && len(entries) <= limit; number, index = number-1, math.MaxUint32 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		history, err := core.TxHistoryOf(chain, chain.Engine(), block.Header(), block.Body(), chain.GetReceiptsByHash(block.Hash()))
+		if err != nil {
+			return nil, err
+		}
+		matches := history[address]
+		for i := len(matches) - 1; i >= 0; i-- {
+			if matches[i].TxIndex <= index {
+				entries = append(entries, matches[i])
+			}
+		}
+		if number == 0 {
+			break
+		}
+	}
+	if number >= indexed && indexed > 0 {
+		number, index = indexed-1, math.MaxUint32
+	}
+	if number < indexed {
+		for len(entries) <= limit {
+			want := limit + 1 - len(entries)
+			batch := rawdb.ReadTxHistory(db, address, number, index, want)
+			for _, entry := range batch {
+				// Entries of reorged blocks are overwritten or left behind, skip the latter
+				if rawdb.ReadCanonicalHash(db, entry.BlockNumber) == entry.BlockHash {
+					entries = append(entries, entry)
+				}
+			}
+			if len(batch) < want {
+				break
+			}
+			last := batch[len(batch)-1]
+			if last.TxIndex > 0 {
+				number, index = last.BlockNumber, last.TxIndex-1
+			} else if last.BlockNumber > 0 {
+				number, index = last.BlockNumber-1, math.MaxUint32
+			} else {
+				break
+			}
+		}
+	}
+	page := &TxHistoryPage{Transactions: make([]*TxHistoryEntry, 0, limit)}
+	for i, entry := range entries {
+		if i == limit {
+			next := make(hexutil.Bytes, txHistoryCursorLength)
+			binary.BigEndian.PutUint64(next[:8], entry.BlockNumber)
+			binary.BigEndian.PutUint32(next[8:], entry.TxIndex)
+			page.NextCursor = &next
+			break
+		}
+		result := &TxHistoryEntry{
+			BlockNumber: hexutil.Uint64(entry.BlockNumber),
+			BlockHash:   entry.BlockHash,
+			Roles:       make([]string, 0, 1),
+		}
+		if entry.TxIndex != core.TxHistoryRewardIndex {
+			hash, txIndex := entry.TxHash, hexutil.Uint64(entry.TxIndex)
+			result.TransactionHash, result.TransactionIndex = &hash, &txIndex
+		}
+		for _, role := range txRoleNames {
+			if core.TxRole(entry.Role)&role.role != 0 {
+				result.Roles = append(result.Roles, role.name)
+			}
+		}
+		page.Transactions = append(page.Transactions, result)
+	}
+	return page, nil
+}
@@ -0,0 +1,229 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"PureChain/accounts/abi/bind/backends"
+	"PureChain/common"
+	"PureChain/consensus"
+	"PureChain/consensus/dpos/systemcontract"
+	"PureChain/consensus/ethash"
+	"PureChain/core"
+	"PureChain/core/rawdb"
+	"PureChain/core/types"
+	"PureChain/core/vm"
+	"PureChain/crypto"
+	"PureChain/params"
+)
+
+// Tests that the account transaction history served from the index matches the
+// one derived from the blocks, across pages and the unindexed chain tail.
+func TestTxHistory(t *testing.T) {
+	var (
+		db        = rawdb.NewMemoryDatabase()
+		engine    = ethash.NewFaker()
+		signer    = types.LatestSigner(params.TestChainConfig)
+		recipient = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		coinbase  = common.HexToAddress("0x2222222222222222222222222222222222222222")
+		gasPrice  = big.NewInt(2 * params.InitialBaseFee)
+		created   common.Address
+	)
+	(&core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{testAddr: {Balance: big.NewInt(params.Ether)}},
+	}).MustCommit(db)
+
+	chain, _ := core.NewBlockChain(db, nil, params.TestChainConfig, engine, vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	blocks, _ := core.GenerateChain(params.TestChainConfig, chain.Genesis(), engine, db, 20, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(coinbase)
+
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(testAddr), recipient, big.NewInt(1), params.TxGas, gasPrice, nil), signer, testKey)
+		gen.AddTx(tx)
+		if i == 7 {
+			created = crypto.CreateAddress(testAddr, gen.TxNonce(testAddr))
+			tx, _ = types.SignTx(types.NewContractCreation(gen.TxNonce(testAddr), nil, 100000, gasPrice, nil), signer, testKey)
+			gen.AddTx(tx)
+		}
+	})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Index all complete sections of 4 blocks
+	indexer := core.NewTxHistoryIndexer(db, chain, engine, 4, 2)
+	defer indexer.Close()
+	indexer.Start(chain)
+
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if sections, _, _ := indexer.Sections(); sections == 4 {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("timeout waiting for the history index")
+		}
+	}
+	tests := []struct {
+		address common.Address
+		entries int
+		roles   []string
+	}{
+		{testAddr, 21, []string{"sender"}},
+		{recipient, 20, []string{"recipient"}},
+		{coinbase, 20, []string{"reward"}},
+		{created, 1, []string{"created"}},
+	}
+	for _, tt := range tests {
+		// Derive the full history from the blocks alone
+		want, err := txHistoryPage(context.Background(), db, chain, 0, tt.address, 20, math.MaxUint32, 100)
+		if err != nil {
+			t.Fatalf("%x: failed to scan history: %v", tt.address, err)
+		}
+		if len(want.Transactions) != tt.entries || want.NextCursor != nil {
+			t.Fatalf("%x: history length mismatch: have %d, want %d", tt.address, len(want.Transactions), tt.entries)
+		}
+		for _, entry := range want.Transactions {
+			if !reflect.DeepEqual(entry.Roles, tt.roles) {
+				t.Errorf("%x: block #%d roles mismatch: have %v, want %v", tt.address, entry.BlockNumber, entry.Roles, tt.roles)
+			}
+			if (entry.TransactionHash == nil) != (tt.roles[0] == "reward") {
+				t.Errorf("%x: block #%d transaction hash mismatch: %v", tt.address, entry.BlockNumber, entry.TransactionHash)
+			}
+		}
+		// Page through the indexed history, three entries at a time
+		var (
+			have   []*TxHistoryEntry
+			number = uint64(20)
+			index  = uint32(math.MaxUint32)
+		)
+		for {
+			page, err := txHistoryPage(context.Background(), db, chain, 16, tt.address, number, index, 3)
+			if err != nil {
+				t.Fatalf("%x: failed to read history: %v", tt.address, err)
+			}
+			have = append(have, page.Transactions...)
+			if page.NextCursor == nil {
+				break
+			}
+			number = binary.BigEndian.Uint64((*page.NextCursor)[:8])
+			index = binary.BigEndian.Uint32((*page.NextCursor)[8:])
+		}
+		if !reflect.DeepEqual(have, want.Transactions) {
+			t.Errorf("%x: indexed history mismatch: have %d entries, want %d", tt.address, len(have), len(want.Transactions))
+		}
+	}
+}
+
+// rewardRoundsChain serves the headers of the reward rounds paid out in a block
+// far ahead of the chain it wraps.
+type rewardRoundsChain struct {
+	consensus.ChainHeaderReader
+	headers map[uint64]*types.Header
+}
+
+func (c *rewardRoundsChain) GetHeaderByNumber(number uint64) *types.Header {
+	return c.headers[number]
+}
+
+// Tests that the history of a dpos chain flags the system transactions of the
+// engine and credits the accounts rewarded by the block reward distribution.
+func TestTxHistoryDpos(t *testing.T) {
+	var (
+		valKey, _ = crypto.GenerateKey()
+		validator = crypto.PubkeyToAddress(valKey.PublicKey)
+		signer    = types.NewEIP155Signer(big.NewInt(1337))
+	)
+	sim, err := backends.NewSimulatedDposBackend(core.GenesisAlloc{testAddr: {Balance: big.NewInt(params.Ether)}}, 10000000, 0, valKey)
+	if err != nil {
+		t.Fatalf("failed to create dpos backend: %v", err)
+	}
+	defer sim.Close()
+
+	head, _ := sim.HeaderByNumber(context.Background(), nil)
+	tx, _ := types.SignTx(types.NewTransaction(0, common.Address{0x1}, big.NewInt(1), params.TxGas, new(big.Int).Mul(head.BaseFee, big.NewInt(2)), nil), signer, testKey)
+	if err := sim.SendTransaction(context.Background(), tx); err != nil {
+		t.Fatalf("failed to send transaction: %v", err)
+	}
+	sim.Commit()
+
+	// A zero priced call of the validator into a system contract is a system
+	// transaction, unless it is one of the PoR challenges
+	var (
+		block     = sim.Blockchain().CurrentBlock()
+		factory   = systemcontract.ValidatorFactoryContractAddr
+		system, _ = types.SignTx(types.NewTransaction(0, factory, new(big.Int), 1000000, new(big.Int), crypto.Keccak256([]byte("tryPunish(address)"))[:4]), signer, valKey)
+		challenge = append(crypto.Keccak256([]byte("validatorNotSubmitResult(address)"))[:4], common.LeftPadBytes(validator.Bytes(), 32)...)
+	)
+	notSystem, _ := types.SignTx(types.NewTransaction(1, factory, new(big.Int), 1000000, new(big.Int), challenge), signer, valKey)
+
+	body := &types.Body{Transactions: append(block.Transactions(), system, notSystem)}
+	history, err := core.TxHistoryOf(sim.Blockchain(), sim.Dpos(), block.Header(), body, nil)
+	if err != nil {
+		t.Fatalf("failed to derive history: %v", err)
+	}
+	roles := func(address common.Address) []core.TxRole {
+		var roles []core.TxRole
+		for _, entry := range history[address] {
+			roles = append(roles, core.TxRole(entry.Role))
+		}
+		return roles
+	}
+	if have, want := roles(testAddr), []core.TxRole{core.TxRoleSender}; !reflect.DeepEqual(have, want) {
+		t.Errorf("sender roles mismatch: have %v, want %v", have, want)
+	}
+	if have, want := roles(validator), []core.TxRole{core.TxRoleSender | core.TxRoleSystem, core.TxRoleSender}; !reflect.DeepEqual(have, want) {
+		t.Errorf("validator roles mismatch: have %v, want %v", have, want)
+	}
+	if have, want := roles(factory), []core.TxRole{core.TxRoleRecipient | core.TxRoleSystem, core.TxRoleRecipient}; !reflect.DeepEqual(have, want) {
+		t.Errorf("system contract roles mismatch: have %v, want %v", have, want)
+	}
+	// Blocks a few days into the chain pay out the rewards of the blocks sealed a
+	// day before and of every day of the distribution before that
+	var (
+		number = 2*common.BigOneDayUint + 5
+		yest   = &types.Header{Number: new(big.Int).SetUint64(number - common.BigOneDayUint), Coinbase: validator, Provider: common.Address{0xa1}, TeamAddress: common.Address{0xb1}, ValidatorRate: 1000, TeamRate: 400}
+		round  = &types.Header{Number: new(big.Int).SetUint64(number - 2*common.BigOneDayUint), Coinbase: common.Address{0xc2}, Provider: common.Address{0xa2}, TeamAddress: common.Address{0xb2}, ValidatorRate: 1000, TeamRate: 400}
+		header = &types.Header{Number: new(big.Int).SetUint64(number), Coinbase: validator}
+	)
+	chain := &rewardRoundsChain{
+		ChainHeaderReader: sim.Blockchain(),
+		headers:           map[uint64]*types.Header{yest.Number.Uint64(): yest, round.Number.Uint64(): round},
+	}
+	history, err = core.TxHistoryOf(chain, sim.Dpos(), header, new(types.Body), nil)
+	if err != nil {
+		t.Fatalf("failed to derive reward history: %v", err)
+	}
+	// The team of the day old block is credited the team shares of every round
+	rewarded := []common.Address{validator, yest.Provider, yest.TeamAddress, round.Coinbase, round.Provider}
+	if len(history) != len(rewarded) {
+		t.Errorf("rewarded account count mismatch: have %d, want %d", len(history), len(rewarded))
+	}
+	for _, address := range rewarded {
+		entries := history[address]
+		if len(entries) != 1 || entries[0].TxIndex != core.TxHistoryRewardIndex || core.TxRole(entries[0].Role) != core.TxRoleReward || entries[0].BlockNumber != number {
+			t.Errorf("%x: reward entries mismatch: %+v", address, entries)
+		}
+	}
+}
@@ -45,6 +45,7 @@ import (
 	"PureChain/eth/gasprice"
 	"PureChain/eth/protocols/eth"
 	"PureChain/eth/protocols/snap"
+	"PureChain/eth/protocols/vote"
 	"PureChain/ethdb"
 	"PureChain/event"
 	"PureChain/internal/ethapi"
@@ -69,6 +70,8 @@ type Ethereum struct {
 
 	// Handlers
 	txPool             *core.TxPool
+	votePool           *dpos.VotePool
+	voteManager        *dpos.VoteManager
 	blockchain         *core.BlockChain
 	handler            *handler
 	ethDialCandidates  enode.Iterator
@@ -76,6 +79,7 @@ type Ethereum struct {
 
 	// DB interfaces
 	chainDb ethdb.Database // Block chain database
+	datadir string         // Data directory of the node, holding the state bloom of pruning
 
 	eventMux       *event.TypeMux
 	engine         consensus.Engine
@@ -84,6 +88,8 @@ type Ethereum struct {
 	bloomRequests     chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer      *core.ChainIndexer             // Bloom indexer operating during block imports
 	closeBloomHandler chan struct{}
+	logIndexer        *core.ChainIndexer // Address and topic log indexer, nil if disabled
+	txHistoryIndexer  *core.ChainIndexer // Account transaction history indexer, nil if disabled
 
 	APIBackend *EthAPIBackend
 
@@ -152,6 +158,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	eth := &Ethereum{
 		config:            config,
 		chainDb:           chainDb,
+		datadir:           stack.ResolvePath(""),
 		eventMux:          stack.EventMux(),
 		accountManager:    stack.AccountManager(),
 		closeBloomHandler: make(chan struct{}),
@@ -204,6 +211,10 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			SnapshotLimit:      config.SnapshotCache,
 			TriesInMemory:      config.TriesInMemory,
 			Preimages:          config.Preimages,
+
+			HistoryTransactions: config.HistoryTransactions,
+			HistoryReceipts:     config.HistoryReceipts,
+			HistoryState:        config.HistoryState,
 		}
 	)
 	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, chainConfig, eth.engine, vmConfig, eth.shouldPreserve, &config.TxLookupLimit)
@@ -217,10 +228,33 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 	eth.bloomIndexer.Start(eth.blockchain)
+	if config.LogIndex {
+		eth.logIndexer = core.NewLogIndexer(chainDb, params.LogIndexBlocks, params.LogIndexConfirms)
+		eth.logIndexer.Start(eth.blockchain)
+	}
+	if config.TxHistory {
+		eth.txHistoryIndexer = core.NewTxHistoryIndexer(chainDb, eth.blockchain, eth.engine, params.TxHistoryBlocks, params.TxHistoryConfirms)
+		eth.txHistoryIndexer.Start(eth.blockchain)
+	}
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
 	}
+	if config.TxPool.RemoteJournal != "" {
+		config.TxPool.RemoteJournal = stack.ResolvePath(config.TxPool.RemoteJournal)
+	}
+	switch config.TxPoolPolicy {
+	case "", "default":
+		config.TxPool.Policy = core.NewDefaultTxPoolPolicy(config.TxPool, config.TxPoolPolicyConfig)
+	case "dpos":
+		dposEngine, ok := eth.engine.(*dpos.Dpos)
+		if !ok {
+			return nil, errors.New("dpos txpool policy requires the dpos consensus engine")
+		}
+		config.TxPool.Policy = dpos.NewTxPoolPolicy(dposEngine, eth.blockchain, config.TxPool, config.TxPoolPolicyConfig)
+	default:
+		return nil, fmt.Errorf("unknown txpool policy %q", config.TxPoolPolicy)
+	}
 	eth.txPool = core.NewTxPool(config.TxPool, chainConfig, eth.blockchain)
 
 	if parliaEngine, ok := eth.engine.(*parlia.Parlia); ok {
@@ -234,6 +268,25 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		dposEngine.SetStateFn(eth.blockchain.StateAt)
 		// set consensus-related transaction validator
 
+		// export the validator liveness of the canonical chain
+		dposEngine.StartLivenessMetrics(eth.blockchain)
+
+		// collect and cast fast finality votes
+		if chainConfig.FastFinalityBlock != nil {
+			eth.votePool = dpos.NewVotePool(eth.blockchain, dposEngine)
+			dposEngine.SetVotePool(eth.votePool)
+
+			if config.DposVoteKey != "" {
+				if config.Miner.Etherbase == (common.Address{}) {
+					return nil, errors.New("dpos vote key requires an explicit etherbase")
+				}
+				key, err := dpos.LoadVoteKey(config.DposVoteKey)
+				if err != nil {
+					return nil, fmt.Errorf("failed to load dpos vote key: %v", err)
+				}
+				eth.voteManager = dpos.NewVoteManager(eth.blockchain, dposEngine, eth.votePool, chainDb, config.Miner.Etherbase, key)
+			}
+		}
 	}
 
 	// Permit the downloader to use the trie cache allowance during fast sync
@@ -254,6 +307,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		Checkpoint:      checkpoint,
 		Whitelist:       config.Whitelist,
 		DirectBroadcast: config.DirectBroadcast,
+		VotePool:        eth.votePool,
 	}); err != nil {
 		return nil, err
 	}
@@ -357,6 +411,11 @@ func (s *Ethereum) APIs() []rpc.API {
 			Namespace: "admin",
 			Version:   "1.0",
 			Service:   NewPrivateAdminAPI(s),
+		}, {
+			Namespace:     "txpool",
+			Version:       "1.0",
+			Service:       NewPrivateTxPoolAPI(s),
+			Authenticated: true,
 		}, {
 			Namespace: "debug",
 			Version:   "1.0",
@@ -606,6 +665,9 @@ func (s *Ethereum) Protocols() []p2p.Protocol {
 	if s.config.SnapshotCache > 0 {
 		protos = append(protos, snap.MakeProtocols((*snapHandler)(s.handler), s.snapDialCandidates)...)
 	}
+	if s.votePool != nil {
+		protos = append(protos, vote.MakeProtocols((*voteHandler)(s.handler))...)
+	}
 	return protos
 }
 
@@ -641,7 +703,19 @@ func (s *Ethereum) Stop() error {
 	// Then stop everything else.
 	s.bloomIndexer.Close()
 	close(s.closeBloomHandler)
+	if s.logIndexer != nil {
+		s.logIndexer.Close()
+	}
+	if s.txHistoryIndexer != nil {
+		s.txHistoryIndexer.Close()
+	}
 	s.txPool.Stop()
+	if s.voteManager != nil {
+		s.voteManager.Stop()
+	}
+	if s.votePool != nil {
+		s.votePool.Stop()
+	}
 	s.miner.Stop()
 	s.miner.Close()
 	// TODO this is a hotfix for https://github.com/Project-DeCloud/chain/issues/22892, need a better solution
@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"PureChain/common"
+	"PureChain/consensus/misc"
 	"PureChain/core"
 	"PureChain/core/state"
 	"PureChain/core/types"
@@ -143,6 +144,9 @@ func (api *consensusAPI) AssembleBlock(params assembleBlockParams) (*executableD
 		Extra:      []byte{},
 		Time:       params.Timestamp,
 	}
+	if config := bc.Config(); config.IsLondon(header.Number) {
+		header.BaseFee = misc.CalcBaseFee(config, parent.Header())
+	}
 	err = api.eth.Engine().Prepare(bc, header)
 	if err != nil {
 		return nil, err
@@ -155,7 +159,7 @@ func (api *consensusAPI) AssembleBlock(params assembleBlockParams) (*executableD
 
 	var (
 		signer       = types.MakeSigner(bc.Config(), header.Number)
-		txHeap       = types.NewTransactionsByPriceAndNonce(signer, pending)
+		txHeap       = types.NewTransactionsByPriceAndNonce(signer, pending, header.BaseFee)
 		transactions []*types.Transaction
 	)
 	for {
@@ -139,6 +139,13 @@ type Config struct {
 
 	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 
+	HistoryTransactions uint64 `toml:",omitempty"` // The maximum number of blocks from head whose bodies are retained.
+	HistoryReceipts     uint64 `toml:",omitempty"` // The maximum number of blocks from head whose receipts are retained.
+	HistoryState        bool   `toml:",omitempty"` // Whether to archive the state diffs of all blocks.
+
+	LogIndex  bool `toml:",omitempty"` // Whether to maintain the address and topic index of the logs.
+	TxHistory bool `toml:",omitempty"` // Whether to maintain the transaction history index of the accounts.
+
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
 
@@ -177,6 +184,9 @@ type Config struct {
 	// POR options
 	PorChallengeCommitUrl string
 	Por                   bool
+
+	// Dpos fast finality options
+	DposVoteKey string // File holding the hex encoded BLS key to vote with
 	// Ethash options
 	Ethash  ethash.Config  `toml:",omitempty"`
 	Inihash inihash.Config `toml:",omitempty"`
@@ -184,6 +194,10 @@ type Config struct {
 	// Transaction pool options
 	TxPool core.TxPoolConfig
 
+	// Transaction pool admission and eviction policy ("default" or "dpos")
+	TxPoolPolicy       string                  `toml:",omitempty"`
+	TxPoolPolicyConfig core.TxPoolPolicyConfig `toml:",omitempty"`
+
 	// Gas Price Oracle options
 	GPO gasprice.Config
 
@@ -249,7 +263,7 @@ func CreateConsensusEngine(stack *node.Node, chainConfig *params.ChainConfig, co
 			DatasetsOnDisk:   iniConfig.DatasetsOnDisk,
 			DatasetsLockMmap: iniConfig.DatasetsLockMmap,
 			NotifyFull:       iniConfig.NotifyFull,
-		}, notify, noverify, chainConfig.ChainID)
+		}, notify, noverify, chainConfig.ChainID, db)
 		engine.SetThreads(-1) // Disable CPU mining
 		return engine
 	}
@@ -25,6 +25,11 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		NoPruning               bool
 		NoPrefetch              bool
 		TxLookupLimit           uint64                 `toml:",omitempty"`
+		HistoryTransactions     uint64                 `toml:",omitempty"`
+		HistoryReceipts         uint64                 `toml:",omitempty"`
+		HistoryState            bool                   `toml:",omitempty"`
+		LogIndex                bool                   `toml:",omitempty"`
+		TxHistory               bool                   `toml:",omitempty"`
 		Whitelist               map[uint64]common.Hash `toml:"-"`
 		LightServ               int                    `toml:",omitempty"`
 		LightIngress            int                    `toml:",omitempty"`
@@ -51,6 +56,8 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		Miner                   miner.Config
 		Ethash                  ethash.Config
 		TxPool                  core.TxPoolConfig
+		TxPoolPolicy            string                  `toml:",omitempty"`
+		TxPoolPolicyConfig      core.TxPoolPolicyConfig `toml:",omitempty"`
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
 		DocRoot                 string `toml:"-"`
@@ -69,6 +76,11 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.SnapDiscoveryURLs = c.SnapDiscoveryURLs
 	enc.NoPruning = c.NoPruning
 	enc.TxLookupLimit = c.TxLookupLimit
+	enc.HistoryTransactions = c.HistoryTransactions
+	enc.HistoryReceipts = c.HistoryReceipts
+	enc.HistoryState = c.HistoryState
+	enc.LogIndex = c.LogIndex
+	enc.TxHistory = c.TxHistory
 	enc.Whitelist = c.Whitelist
 	enc.LightServ = c.LightServ
 	enc.LightIngress = c.LightIngress
@@ -95,6 +107,8 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.Miner = c.Miner
 	enc.Ethash = c.Ethash
 	enc.TxPool = c.TxPool
+	enc.TxPoolPolicy = c.TxPoolPolicy
+	enc.TxPoolPolicyConfig = c.TxPoolPolicyConfig
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
 	enc.DocRoot = c.DocRoot
@@ -118,6 +132,11 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		NoPruning               *bool
 		NoPrefetch              *bool
 		TxLookupLimit           *uint64                `toml:",omitempty"`
+		HistoryTransactions     *uint64                `toml:",omitempty"`
+		HistoryReceipts         *uint64                `toml:",omitempty"`
+		HistoryState            *bool                  `toml:",omitempty"`
+		LogIndex                *bool                  `toml:",omitempty"`
+		TxHistory               *bool                  `toml:",omitempty"`
 		Whitelist               map[uint64]common.Hash `toml:"-"`
 		LightServ               *int                   `toml:",omitempty"`
 		LightIngress            *int                   `toml:",omitempty"`
@@ -144,6 +163,8 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		Miner                   *miner.Config
 		Ethash                  *ethash.Config
 		TxPool                  *core.TxPoolConfig
+		TxPoolPolicy            *string                  `toml:",omitempty"`
+		TxPoolPolicyConfig      *core.TxPoolPolicyConfig `toml:",omitempty"`
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
 		DocRoot                 *string `toml:"-"`
@@ -179,6 +200,21 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TxLookupLimit != nil {
 		c.TxLookupLimit = *dec.TxLookupLimit
 	}
+	if dec.HistoryTransactions != nil {
+		c.HistoryTransactions = *dec.HistoryTransactions
+	}
+	if dec.HistoryReceipts != nil {
+		c.HistoryReceipts = *dec.HistoryReceipts
+	}
+	if dec.HistoryState != nil {
+		c.HistoryState = *dec.HistoryState
+	}
+	if dec.LogIndex != nil {
+		c.LogIndex = *dec.LogIndex
+	}
+	if dec.TxHistory != nil {
+		c.TxHistory = *dec.TxHistory
+	}
 	if dec.Whitelist != nil {
 		c.Whitelist = dec.Whitelist
 	}
@@ -257,6 +293,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TxPool != nil {
 		c.TxPool = *dec.TxPool
 	}
+	if dec.TxPoolPolicy != nil {
+		c.TxPoolPolicy = *dec.TxPoolPolicy
+	}
+	if dec.TxPoolPolicyConfig != nil {
+		c.TxPoolPolicyConfig = *dec.TxPoolPolicyConfig
+	}
 	if dec.GPO != nil {
 		c.GPO = *dec.GPO
 	}
@@ -243,7 +243,14 @@ func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, er
 }
 
 // Logs creates a subscription that fires for all new log that match the given filter criteria.
-func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc.Subscription, error) {
+//
+// If a cursor or a fromBlock number is given, the matching historical logs after
+// it are delivered first, and every notification carries the cursor to resume
+// the subscription after.
+func (api *PublicFilterAPI) Logs(ctx context.Context, crit LogsCriteria) (*rpc.Subscription, error) {
+	if crit.backfilling() {
+		return api.backfillLogs(ctx, crit)
+	}
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
@@ -254,7 +261,7 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 		matchedLogs = make(chan []*types.Log)
 	)
 
-	logsSub, err := api.events.SubscribeLogs(ethereum.FilterQuery(crit), matchedLogs)
+	logsSub, err := api.events.SubscribeLogs(ethereum.FilterQuery(crit.FilterCriteria), matchedLogs)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,298 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"PureChain"
+	"PureChain/common"
+	"PureChain/common/gopool"
+	"PureChain/common/hexutil"
+	"PureChain/core"
+	"PureChain/core/rawdb"
+	"PureChain/core/types"
+	"PureChain/log"
+	"PureChain/rpc"
+)
+
+const (
+	// maxBackfillBlocks is the maximum number of historical blocks a log
+	// subscription may backfill before switching to live delivery.
+	maxBackfillBlocks = 20 * maxFilterBlockRange
+
+	// maxBackfillPending is the maximum number of live logs buffered while a
+	// subscription is still backfilling.
+	maxBackfillPending = 10000
+)
+
+var (
+	errUnknownCursor      = errors.New("unknown cursor block")
+	errBackfillRangeLimit = errors.New("toBlock cannot be set on backfilling log subscriptions")
+	errBackfillTooLong    = fmt.Errorf("backfill range exceeds %d blocks", maxBackfillBlocks)
+	errBackfillOverflow   = fmt.Errorf("more than %d live logs arrived while backfilling", maxBackfillPending)
+)
+
+// LogCursor is the position of a delivered log in the chain. Log subscriptions
+// given a cursor resume with the logs following it.
+type LogCursor struct {
+	BlockHash common.Hash  `json:"blockHash"`
+	LogIndex  hexutil.Uint `json:"logIndex"`
+}
+
+// LogsCriteria is the criteria of a log subscription. On top of the filter
+// criteria it accepts a cursor to resume a previous subscription after.
+//
+// Subscriptions with a cursor or a fromBlock number backfill the historical logs
+// before switching to live delivery, and attach a cursor to every notification.
+type LogsCriteria struct {
+	FilterCriteria
+	Cursor *LogCursor
+}
+
+// UnmarshalJSON sets *args fields with given data.
+func (args *LogsCriteria) UnmarshalJSON(data []byte) error {
+	if err := args.FilterCriteria.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	var raw struct {
+		Cursor *LogCursor `json:"cursor"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	args.Cursor = raw.Cursor
+	return nil
+}
+
+// backfilling reports whether the subscription replays historical logs.
+func (args *LogsCriteria) backfilling() bool {
+	return args.Cursor != nil || (args.BlockHash == nil && args.FromBlock != nil && args.FromBlock.Sign() >= 0)
+}
+
+// cursorLog is a log notification carrying the cursor resuming after it.
+type cursorLog struct {
+	*types.Log
+	Cursor LogCursor
+}
+
+// MarshalJSON encodes the log with an additional cursor field.
+func (l *cursorLog) MarshalJSON() ([]byte, error) {
+	enc, err := json.Marshal(l.Log)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := json.Marshal(l.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	enc = append(enc[:len(enc)-1], `,"cursor":`...)
+	enc = append(enc, cursor...)
+	return append(enc, '}'), nil
+}
+
+// backfillLogs creates a subscription first delivering the matching historical
+// logs from the requested position, then the live ones. Live logs are buffered
+// while backfilling, so no logs are missed in between. The subscription ends
+// with an error if too many of them pile up.
+func (api *PublicFilterAPI) backfillLogs(ctx context.Context, crit LogsCriteria) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	if crit.ToBlock != nil && crit.ToBlock.Sign() >= 0 {
+		return nil, errBackfillRangeLimit
+	}
+	// Subscribe to live logs before resolving the backfill range to leave no gap
+	var (
+		rpcSub      = notifier.CreateSubscription()
+		matchedLogs = make(chan []*types.Log)
+		query       = ethereum.FilterQuery{Addresses: crit.Addresses, Topics: crit.Topics}
+	)
+	logsSub, err := api.events.SubscribeLogs(query, matchedLogs)
+	if err != nil {
+		return nil, err
+	}
+	begin, removed, skip, err := api.resolveBackfill(ctx, crit)
+	if err != nil {
+		logsSub.Unsubscribe()
+		return nil, err
+	}
+	header, err := api.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil || header == nil {
+		logsSub.Unsubscribe()
+		return nil, fmt.Errorf("failed to retrieve head: %v", err)
+	}
+	end := header.Number.Uint64()
+
+	if tail := rawdb.ReadReceiptHistoryTail(api.chainDb); tail > 1 && begin < tail && begin <= end {
+		logsSub.Unsubscribe()
+		return nil, fmt.Errorf("%w: logs are only available from block #%d", core.ErrHistoryPruned, tail)
+	}
+	if begin <= end && end-begin >= maxBackfillBlocks {
+		logsSub.Unsubscribe()
+		return nil, errBackfillTooLong
+	}
+	notify := func(logs []*types.Log) {
+		for _, log := range logs {
+			notifier.Notify(rpcSub.ID, &cursorLog{Log: log, Cursor: LogCursor{BlockHash: log.BlockHash, LogIndex: hexutil.Uint(log.Index)}})
+		}
+	}
+	backfillCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	gopool.Submit(func() {
+		notify(removed)
+		for from := begin; from <= end; from += maxFilterBlockRange {
+			to := from + maxFilterBlockRange - 1
+			if to > end {
+				to = end
+			}
+			logs, err := NewRangeFilter(api.backend, int64(from), int64(to), crit.Addresses, crit.Topics, false).Logs(backfillCtx)
+			if err != nil {
+				done <- err
+				return
+			}
+			if skip != nil && from == begin {
+				logs = skipLogs(logs, skip)
+			}
+			notify(logs)
+		}
+		done <- nil
+	})
+	gopool.Submit(func() {
+		defer cancel()
+		defer logsSub.Unsubscribe()
+
+		var (
+			pending   [][]*types.Log
+			buffered  int
+			live      bool
+			delivered = end // Highest block whose logs were delivered
+		)
+		forward := func(logs []*types.Log) {
+			// Skip the live logs already backfilled, unless a reorg replaced them
+			var fresh []*types.Log
+			for _, log := range logs {
+				if log.Removed || log.BlockNumber > delivered {
+					fresh = append(fresh, log)
+				}
+			}
+			for _, log := range logs {
+				if log.Removed && log.BlockNumber <= delivered {
+					delivered = log.BlockNumber - 1
+				} else if !log.Removed && log.BlockNumber > delivered {
+					delivered = log.BlockNumber
+				}
+			}
+			notify(fresh)
+		}
+		for {
+			select {
+			case logs := <-matchedLogs:
+				if !live {
+					if buffered += len(logs); buffered > maxBackfillPending {
+						log.Warn("Dropping backfilling log subscription", "id", rpcSub.ID, "err", errBackfillOverflow)
+						notifier.Fail(rpcSub.ID, errBackfillOverflow)
+						return
+					}
+					pending = append(pending, logs)
+					continue
+				}
+				forward(logs)
+			case err := <-done:
+				if err != nil {
+					log.Warn("Failed to backfill logs", "id", rpcSub.ID, "err", err)
+					notifier.Fail(rpcSub.ID, err)
+					return
+				}
+				for _, logs := range pending {
+					forward(logs)
+				}
+				live, pending = true, nil
+			case <-rpcSub.Err(): // client send an unsubscribe request
+				return
+			case <-notifier.Closed(): // connection dropped
+				return
+			}
+		}
+	})
+	return rpcSub, nil
+}
+
+// resolveBackfill determines the first block to backfill the logs from. If the
+// cursor block was reorged out of the canonical chain, the logs delivered from
+// the orphaned blocks are returned as removed, and the backfill restarts from the
+// fork point. Otherwise the cursor to skip the logs up to is returned.
+func (api *PublicFilterAPI) resolveBackfill(ctx context.Context, crit LogsCriteria) (uint64, []*types.Log, *LogCursor, error) {
+	if crit.Cursor == nil {
+		return crit.FromBlock.Uint64(), nil, nil, nil
+	}
+	header, err := api.backend.HeaderByHash(ctx, crit.Cursor.BlockHash)
+	if err != nil || header == nil {
+		return 0, nil, nil, errUnknownCursor
+	}
+	// Walk back to the canonical chain, collecting the orphaned blocks
+	var orphaned []*types.Header
+	for {
+		canon, _ := api.backend.HeaderByNumber(ctx, rpc.BlockNumber(header.Number.Int64()))
+		if canon != nil && canon.Hash() == header.Hash() {
+			break
+		}
+		orphaned = append(orphaned, header)
+		if header, _ = api.backend.HeaderByHash(ctx, header.ParentHash); header == nil {
+			return 0, nil, nil, errUnknownCursor
+		}
+	}
+	if len(orphaned) == 0 {
+		return header.Number.Uint64(), nil, crit.Cursor, nil
+	}
+	// Revert the delivered logs of the orphaned blocks, newest first
+	var removed []*types.Log
+	for i, orphan := range orphaned {
+		blockLogs, err := api.backend.GetLogs(ctx, orphan.Hash())
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		var unfiltered []*types.Log
+		for _, logs := range blockLogs {
+			for _, log := range logs {
+				if i == 0 && log.Index > uint(crit.Cursor.LogIndex) {
+					continue // Never delivered
+				}
+				cpy := *log
+				cpy.Removed = true
+				unfiltered = append(unfiltered, &cpy)
+			}
+		}
+		removed = append(removed, filterLogs(unfiltered, nil, nil, crit.Addresses, crit.Topics)...)
+	}
+	return header.Number.Uint64() + 1, removed, nil, nil
+}
+
+// skipLogs drops the logs up to and including the cursor position.
+func skipLogs(logs []*types.Log, cursor *LogCursor) []*types.Log {
+	for i, log := range logs {
+		if log.BlockHash != cursor.BlockHash || log.Index > uint(cursor.LogIndex) {
+			return logs[i:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"PureChain/common"
+	"PureChain/consensus/ethash"
+	"PureChain/core"
+	"PureChain/core/rawdb"
+	"PureChain/core/types"
+	"PureChain/params"
+	"PureChain/rpc"
+)
+
+// backfilledLog is a log notification of a backfilling subscription.
+type backfilledLog struct {
+	types.Log
+	Cursor LogCursor
+}
+
+func (l *backfilledLog) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &l.Log); err != nil {
+		return err
+	}
+	var raw struct {
+		Cursor LogCursor `json:"cursor"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	l.Cursor = raw.Cursor
+	return nil
+}
+
+// TestLogsBackfill tests that log subscriptions from a block or cursor deliver the
+// historical logs before the live ones, and revert the logs of orphaned cursors.
+func TestLogsBackfill(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		api     = NewPublicFilterAPI(backend, false, deadline, false)
+		addr    = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		genesis = core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+	)
+	generate := func(parent *types.Block, n int, topic string) ([]*types.Block, []types.Receipts) {
+		return core.GenerateChain(params.TestChainConfig, parent, ethash.NewFaker(), db, n, func(i int, gen *core.BlockGen) {
+			receipt := types.NewReceipt(nil, false, 0)
+			receipt.Logs = []*types.Log{
+				{Address: addr, Topics: []common.Hash{common.BytesToHash([]byte(topic))}},
+				{Address: addr, Topics: []common.Hash{common.BytesToHash([]byte(topic))}},
+			}
+			gen.AddUncheckedReceipt(receipt)
+			gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x1"), big.NewInt(1), 1, big.NewInt(1), nil))
+		})
+	}
+	chain, receipts := generate(genesis, 5, "canon")
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	// Store a non-canonical side block on top of block 3
+	side, sideReceipts := generate(chain[2], 1, "side")
+	rawdb.WriteBlock(db, side[0])
+	rawdb.WriteReceipts(db, side[0].Hash(), side[0].NumberU64(), sideReceipts[0])
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", api); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	subscribe := func(crit map[string]interface{}) (chan *backfilledLog, *rpc.ClientSubscription) {
+		ch := make(chan *backfilledLog, 64)
+		sub, err := client.EthSubscribe(context.Background(), ch, "logs", crit)
+		if err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+		return ch, sub
+	}
+	expect := func(ch chan *backfilledLog, number uint64, index uint, removed bool) *backfilledLog {
+		t.Helper()
+		select {
+		case log := <-ch:
+			if log.BlockNumber != number || log.Index != index || log.Removed != removed {
+				t.Fatalf("log mismatch: have #%d/%d removed %v, want #%d/%d removed %v", log.BlockNumber, log.Index, log.Removed, number, index, removed)
+			}
+			if log.Cursor.BlockHash != log.BlockHash || uint(log.Cursor.LogIndex) != log.Index {
+				t.Fatalf("cursor mismatch: have %x/%d, want %x/%d", log.Cursor.BlockHash, log.Cursor.LogIndex, log.BlockHash, log.Index)
+			}
+			return log
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for log #%d/%d", number, index)
+		}
+		return nil
+	}
+	// Backfill from the genesis, then switch to live logs skipping the delivered ones
+	ch, sub := subscribe(map[string]interface{}{"fromBlock": "0x0"})
+	var cursor *backfilledLog
+	for number := uint64(1); number <= 5; number++ {
+		expect(ch, number, 0, false)
+		if log := expect(ch, number, 1, false); number == 3 {
+			cursor = log
+		}
+	}
+	next := &types.Log{Address: addr, Topics: []common.Hash{{0x06}}, BlockNumber: 6, BlockHash: common.Hash{0x06}}
+	backend.logsFeed.Send([]*types.Log{receipts[4][0].Logs[1], next})
+	expect(ch, 6, 0, false)
+	sub.Unsubscribe()
+
+	// Resume after a canonical cursor in the middle of a block
+	ch, sub = subscribe(map[string]interface{}{"cursor": map[string]interface{}{"blockHash": cursor.BlockHash, "logIndex": "0x0"}})
+	expect(ch, 3, 1, false)
+	expect(ch, 4, 0, false)
+	expect(ch, 4, 1, false)
+	expect(ch, 5, 0, false)
+	expect(ch, 5, 1, false)
+	sub.Unsubscribe()
+
+	// Resume after an orphaned cursor, reverting the logs delivered from it
+	ch, sub = subscribe(map[string]interface{}{"cursor": map[string]interface{}{"blockHash": side[0].Hash(), "logIndex": "0x0"}})
+	if log := expect(ch, 4, 0, true); log.BlockHash != side[0].Hash() {
+		t.Fatalf("removed log from wrong block: have %x, want %x", log.BlockHash, side[0].Hash())
+	}
+	expect(ch, 4, 0, false)
+	expect(ch, 4, 1, false)
+	expect(ch, 5, 0, false)
+	expect(ch, 5, 1, false)
+	sub.Unsubscribe()
+
+	// Unknown cursors and bounded ranges are rejected
+	if _, err := client.EthSubscribe(context.Background(), make(chan *backfilledLog), "logs", map[string]interface{}{"cursor": map[string]interface{}{"blockHash": common.Hash{0xff}, "logIndex": "0x0"}}); err == nil {
+		t.Fatal("subscription with unknown cursor succeeded")
+	}
+	if _, err := client.EthSubscribe(context.Background(), make(chan *backfilledLog), "logs", map[string]interface{}{"fromBlock": "0x0", "toBlock": "0x2"}); err == nil {
+		t.Fatal("bounded backfilling subscription succeeded")
+	}
+	// Backfilling too many blocks is rejected
+	head := &types.Header{Number: big.NewInt(maxBackfillBlocks), ParentHash: chain[4].Hash()}
+	rawdb.WriteHeader(db, head)
+	rawdb.WriteCanonicalHash(db, head.Hash(), head.Number.Uint64())
+	rawdb.WriteHeadBlockHash(db, head.Hash())
+
+	if _, err := client.EthSubscribe(context.Background(), make(chan *backfilledLog), "logs", map[string]interface{}{"fromBlock": "0x0"}); err == nil || err.Error() != errBackfillTooLong.Error() {
+		t.Fatalf("overlong backfilling subscription error mismatch: have %v, want %v", err, errBackfillTooLong)
+	}
+	ch, sub = subscribe(map[string]interface{}{"fromBlock": "0x1"})
+	expect(ch, 1, 0, false)
+	sub.Unsubscribe()
+}
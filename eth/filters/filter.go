@@ -25,6 +25,7 @@ import (
 	"PureChain/common"
 	"PureChain/core"
 	"PureChain/core/bloombits"
+	"PureChain/core/rawdb"
 	"PureChain/core/types"
 	"PureChain/ethdb"
 	"PureChain/event"
@@ -50,6 +51,13 @@ type Backend interface {
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 }
 
+// LogIndexBackend is implemented by backends maintaining the address and topic
+// log index, which filters use in place of the bloom bits where available.
+type LogIndexBackend interface {
+	// LogIndexStatus returns the section size and the number of indexed sections.
+	LogIndexStatus() (uint64, uint64)
+}
+
 // Filter can be used to retrieve and filter logs.
 type Filter struct {
 	backend Backend
@@ -141,6 +149,18 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	}
 	head := header.Number.Uint64()
 
+	if f.begin == rpc.FinalizedBlockNumber.Int64() || f.end == rpc.FinalizedBlockNumber.Int64() {
+		final, err := f.backend.HeaderByNumber(ctx, rpc.FinalizedBlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		if f.begin == rpc.FinalizedBlockNumber.Int64() {
+			f.begin = final.Number.Int64()
+		}
+		if f.end == rpc.FinalizedBlockNumber.Int64() {
+			f.end = final.Number.Int64()
+		}
+	}
 	if f.begin == -1 {
 		f.begin = int64(head)
 	}
@@ -151,18 +171,39 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	if f.rangeLimit && (int64(end)-f.begin) > maxFilterBlockRange {
 		return nil, fmt.Errorf("exceed maximum block range: %d", maxFilterBlockRange)
 	}
+	// Refuse ranges reaching into pruned receipts instead of silently skipping them
+	if tail := rawdb.ReadReceiptHistoryTail(f.db); tail > 1 && uint64(f.begin) < tail {
+		return nil, fmt.Errorf("%w: logs are only available from block #%d", core.ErrHistoryPruned, tail)
+	}
 	// Gather all indexed logs, and finish with non indexed ones
 	var (
 		logs []*types.Log
 		err  error
 	)
+	if clauses := logIndexClauses(f.addresses, f.topics); len(clauses) > 0 {
+		if backend, ok := f.backend.(LogIndexBackend); ok {
+			size, sections := backend.LogIndexStatus()
+			if indexed := sections * size; indexed > uint64(f.begin) {
+				if indexed > end {
+					logs, err = f.logIndexedLogs(ctx, clauses, size, end)
+				} else {
+					logs, err = f.logIndexedLogs(ctx, clauses, size, indexed-1)
+				}
+				if err != nil {
+					return logs, err
+				}
+			}
+		}
+	}
 	size, sections := f.backend.BloomStatus()
-	if indexed := sections * size; indexed > uint64(f.begin) {
+	if indexed := sections * size; indexed > uint64(f.begin) && uint64(f.begin) <= end {
+		var found []*types.Log
 		if indexed > end {
-			logs, err = f.indexedLogs(ctx, end)
+			found, err = f.indexedLogs(ctx, end)
 		} else {
-			logs, err = f.indexedLogs(ctx, indexed-1)
+			found, err = f.indexedLogs(ctx, indexed-1)
 		}
+		logs = append(logs, found...)
 		if err != nil {
 			return logs, err
 		}
@@ -219,6 +260,43 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 	}
 }
 
+// logIndexedLogs returns the logs matching the filter criteria based on the
+// address and topic log index, whose clauses must all match a block.
+func (f *Filter) logIndexedLogs(ctx context.Context, clauses [][][]byte, size, end uint64) ([]*types.Log, error) {
+	var logs []*types.Log
+
+	for section := uint64(f.begin) / size; section*size <= end; section++ {
+		last := (section+1)*size - 1
+		blocks, err := logIndexMatches(f.db, clauses, size, section, rawdb.ReadCanonicalHash(f.db, last))
+		if err != nil {
+			return logs, err
+		}
+		if last > end {
+			last = end
+		}
+		for _, number := range blocks {
+			if number < uint64(f.begin) || number > last {
+				continue
+			}
+			header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+			if header == nil || err != nil {
+				return logs, err
+			}
+			found, err := f.checkMatches(ctx, header)
+			if err != nil {
+				return logs, err
+			}
+			logs = append(logs, found...)
+		}
+		f.begin = int64(last) + 1
+
+		if err := ctx.Err(); err != nil {
+			return logs, err
+		}
+	}
+	return logs, nil
+}
+
 // unindexedLogs returns the logs matching the filter criteria based on raw block
 // iteration and bloom matching.
 func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
@@ -327,6 +405,89 @@ Logs:
 	return ret
 }
 
+// logIndexClauses converts the address and topic criteria into the log index
+// entries to look up. A block matches if it contains any entry of every clause.
+// Wildcard criteria yield no clause.
+func logIndexClauses(addresses []common.Address, topics [][]common.Hash) [][][]byte {
+	var clauses [][][]byte
+	if len(addresses) > 0 {
+		clause := make([][]byte, len(addresses))
+		for i, address := range addresses {
+			clause[i] = core.LogIndexAddressEntry(address)
+		}
+		clauses = append(clauses, clause)
+	}
+	for i, sub := range topics {
+		if len(sub) == 0 {
+			continue
+		}
+		clause := make([][]byte, len(sub))
+		for j, topic := range sub {
+			clause[j] = core.LogIndexTopicEntry(i, topic)
+		}
+		clauses = append(clauses, clause)
+	}
+	return clauses
+}
+
+// logIndexMatches returns the ascending numbers of the blocks within a log index
+// section matching all the clauses.
+func logIndexMatches(db ethdb.KeyValueReader, clauses [][][]byte, size, section uint64, head common.Hash) ([]uint64, error) {
+	var matches []uint64
+	for i, clause := range clauses {
+		var union []uint64
+		for _, entry := range clause {
+			blocks, err := core.ReadLogIndexBlocks(db, size, section, head, entry)
+			if err != nil {
+				return nil, err
+			}
+			union = mergeBlocks(union, blocks)
+		}
+		if i == 0 {
+			matches = union
+		} else {
+			matches = intersectBlocks(matches, union)
+		}
+		if len(matches) == 0 {
+			return nil, nil
+		}
+	}
+	return matches, nil
+}
+
+// mergeBlocks returns the union of two ascending block number lists.
+func mergeBlocks(a, b []uint64) []uint64 {
+	merged := make([]uint64, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case a[0] < b[0]:
+			merged, a = append(merged, a[0]), a[1:]
+		case a[0] > b[0]:
+			merged, b = append(merged, b[0]), b[1:]
+		default:
+			merged, a, b = append(merged, a[0]), a[1:], b[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
+
+// intersectBlocks returns the intersection of two ascending block number lists.
+func intersectBlocks(a, b []uint64) []uint64 {
+	var shared []uint64
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case a[0] < b[0]:
+			a = a[1:]
+		case a[0] > b[0]:
+			b = b[1:]
+		default:
+			shared, a, b = append(shared, a[0]), a[1:], b[1:]
+		}
+	}
+	return shared
+}
+
 func bloomFilter(bloom types.Bloom, addresses []common.Address, topics [][]common.Hash) bool {
 	if len(addresses) > 0 {
 		var included bool
@@ -21,7 +21,9 @@ import (
 	"io/ioutil"
 	"math/big"
 	"os"
+	"reflect"
 	"testing"
+	"time"
 
 	"PureChain/common"
 	"PureChain/consensus/ethash"
@@ -29,6 +31,7 @@ import (
 	"PureChain/core/rawdb"
 	"PureChain/core/types"
 	"PureChain/crypto"
+	"PureChain/event"
 	"PureChain/params"
 )
 
@@ -223,3 +226,105 @@ func TestFilters(t *testing.T) {
 		t.Error("expected 0 log, got", len(logs))
 	}
 }
+
+// indexerChain feeds a fixed head into a chain indexer.
+type indexerChain struct {
+	head *types.Header
+}
+
+func (c *indexerChain) CurrentHeader() *types.Header { return c.head }
+
+func (c *indexerChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// TestLogIndexFilters tests that filters served from the address and topic log
+// index return the same logs as the ones iterating the blocks.
+func TestLogIndexFilters(t *testing.T) {
+	var (
+		db       = rawdb.NewMemoryDatabase()
+		plain    = &testBackend{db: db}
+		indexed  = &testBackend{db: db, logIndexSize: 16}
+		addr1    = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		addr2    = common.HexToAddress("0x2222222222222222222222222222222222222222")
+		hash1    = common.BytesToHash([]byte("topic1"))
+		hash2    = common.BytesToHash([]byte("topic2"))
+		genesis  = core.GenesisBlockForTesting(db, addr1, big.NewInt(1000000))
+		sections = uint64(4)
+	)
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 70, func(i int, gen *core.BlockGen) {
+		var logs []*types.Log
+		switch {
+		case i%7 == 0:
+			logs = append(logs, &types.Log{Address: addr1, Topics: []common.Hash{hash1, hash2}})
+		case i%5 == 0:
+			logs = append(logs, &types.Log{Address: addr2, Topics: []common.Hash{hash2, hash1}})
+		case i%3 == 0:
+			logs = append(logs, &types.Log{Address: addr1, Topics: []common.Hash{hash2}}, &types.Log{Address: addr2})
+		default:
+			return
+		}
+		receipt := types.NewReceipt(nil, false, 0)
+		receipt.Logs = logs
+		gen.AddUncheckedReceipt(receipt)
+		gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x1"), big.NewInt(1), 1, big.NewInt(1), nil))
+	})
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	indexer := core.NewLogIndexer(db, indexed.logIndexSize, 0)
+	defer indexer.Close()
+	indexer.Start(&indexerChain{head: chain[len(chain)-1].Header()})
+
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		if stored, _, _ := indexer.Sections(); stored >= sections {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for the log index")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	indexed.logSections = sections
+
+	tests := []struct {
+		begin, end int64
+		addresses  []common.Address
+		topics     [][]common.Hash
+	}{
+		{0, -1, []common.Address{addr1}, nil},
+		{0, -1, []common.Address{addr1, addr2}, nil},
+		{0, -1, nil, [][]common.Hash{{hash2}}},
+		{0, -1, nil, [][]common.Hash{nil, {hash1}}},
+		{0, -1, []common.Address{addr2}, [][]common.Hash{{hash2}, {hash1}}},
+		{0, -1, []common.Address{addr1}, [][]common.Hash{{hash1, hash2}}},
+		{5, 40, []common.Address{addr1}, [][]common.Hash{{hash2}}},
+		{20, 60, nil, [][]common.Hash{{hash1}, {hash2}}},
+		{50, -1, []common.Address{addr2}, nil},
+		{0, -1, []common.Address{common.HexToAddress("0xdead")}, nil},
+	}
+	for i, tt := range tests {
+		want, err := NewRangeFilter(plain, tt.begin, tt.end, tt.addresses, tt.topics, false).Logs(context.Background())
+		if err != nil {
+			t.Fatalf("test %d: unindexed filter failed: %v", i, err)
+		}
+		have, err := NewRangeFilter(indexed, tt.begin, tt.end, tt.addresses, tt.topics, false).Logs(context.Background())
+		if err != nil {
+			t.Fatalf("test %d: indexed filter failed: %v", i, err)
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("test %d: log mismatch: have %d logs, want %d", i, len(have), len(want))
+		}
+	}
+	// Filters must not silently skip sections missing from the index
+	indexed.logSections = sections + 1
+	if _, err := NewRangeFilter(indexed, 0, -1, []common.Address{addr1}, nil, false).Logs(context.Background()); err == nil {
+		t.Error("filter over missing index section succeeded")
+	}
+}
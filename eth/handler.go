@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"PureChain/common"
+	"PureChain/consensus/dpos"
 	"PureChain/core"
 	"PureChain/core/forkid"
 	"PureChain/core/types"
@@ -32,6 +33,7 @@ import (
 	"PureChain/eth/fetcher"
 	"PureChain/eth/protocols/eth"
 	"PureChain/eth/protocols/snap"
+	"PureChain/eth/protocols/vote"
 	"PureChain/ethdb"
 	"PureChain/event"
 	"PureChain/log"
@@ -86,6 +88,7 @@ type handlerConfig struct {
 	Checkpoint      *params.TrustedCheckpoint // Hard coded checkpoint for sync challenges
 	Whitelist       map[uint64]common.Hash    // Hard coded whitelist for sync challenged
 	DirectBroadcast bool
+	VotePool        *dpos.VotePool // Fast finality vote pool to propagate from (dpos only)
 }
 
 type handler struct {
@@ -116,6 +119,12 @@ type handler struct {
 	txsSub        event.Subscription
 	minedBlockSub *event.TypeMuxSubscription
 
+	votePool      *dpos.VotePool
+	votePeers     map[string]*vote.Peer
+	votePeersLock sync.RWMutex
+	votesCh       chan core.NewVoteEvent
+	votesSub      event.Subscription
+
 	whitelist map[uint64]common.Hash
 
 	// channels for fetcher, syncer, txsyncLoop
@@ -143,6 +152,8 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		peers:           newPeerSet(),
 		whitelist:       config.Whitelist,
 		directBroadcast: config.DirectBroadcast,
+		votePool:        config.VotePool,
+		votePeers:       make(map[string]*vote.Peer),
 		txsyncCh:        make(chan *txsync),
 		quitSync:        make(chan struct{}),
 	}
@@ -405,6 +416,14 @@ func (h *handler) Start(maxPeers int) {
 	h.minedBlockSub = h.eventMux.Subscribe(core.NewMinedBlockEvent{})
 	go h.minedBroadcastLoop()
 
+	// broadcast fast finality votes
+	if h.votePool != nil {
+		h.wg.Add(1)
+		h.votesCh = make(chan core.NewVoteEvent, voteChanSize)
+		h.votesSub = h.votePool.SubscribeNewVoteEvent(h.votesCh)
+		go h.voteBroadcastLoop()
+	}
+
 	// start sync handlers
 	h.wg.Add(2)
 	go h.chainSync.loop()
@@ -414,6 +433,9 @@ func (h *handler) Start(maxPeers int) {
 func (h *handler) Stop() {
 	h.txsSub.Unsubscribe()        // quits txBroadcastLoop
 	h.minedBlockSub.Unsubscribe() // quits blockBroadcastLoop
+	if h.votesSub != nil {
+		h.votesSub.Unsubscribe() // quits voteBroadcastLoop
+	}
 
 	// Quit chainSync and txsync64.
 	// After this is done, no new peers will be accepted.
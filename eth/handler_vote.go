@@ -0,0 +1,144 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+
+	"PureChain/consensus/dpos"
+	"PureChain/core"
+	"PureChain/core/types"
+	"PureChain/eth/protocols/vote"
+	"PureChain/p2p/enode"
+)
+
+// voteChanSize is the size of channel listening to NewVoteEvent.
+const voteChanSize = 256
+
+// errInvalidRemoteVote is returned if a peer relays a malformed or incorrectly
+// signed vote.
+var errInvalidRemoteVote = errors.New("invalid remote vote")
+
+// voteHandler implements the vote.Backend interface to handle the fast finality
+// votes broadcast by the remote peers.
+type voteHandler handler
+
+func (h *voteHandler) Chain() *core.BlockChain { return h.chain }
+
+// RunPeer is invoked when a peer joins on the `vote` protocol.
+func (h *voteHandler) RunPeer(peer *vote.Peer, hand vote.Handler) error {
+	return (*handler)(h).runVotePeer(peer, hand)
+}
+
+// PeerInfo retrieves all known `vote` information about a peer.
+func (h *voteHandler) PeerInfo(id enode.ID) interface{} {
+	h.votePeersLock.RLock()
+	defer h.votePeersLock.RUnlock()
+
+	if p := h.votePeers[id.String()]; p != nil {
+		return &struct {
+			Version uint `json:"version"`
+		}{p.Version()}
+	}
+	return nil
+}
+
+// Handle is invoked from a peer's message handler when it receives a new remote
+// message that the handler couldn't consume and serve itself.
+func (h *voteHandler) Handle(peer *vote.Peer, packet vote.Packet) error {
+	switch packet := packet.(type) {
+	case *vote.VotesPacket:
+		for _, v := range *packet {
+			// Votes of unregistered keys or for unknown forks are legit on a
+			// live network, only drop them without penalising the peer. Badly
+			// signed votes are never relayed by honest peers, drop the peer.
+			if err := h.votePool.PutVote(v); err != nil {
+				if errors.Is(err, dpos.ErrInvalidVote) {
+					return fmt.Errorf("%w: %v", errInvalidRemoteVote, err)
+				}
+				peer.Log().Trace("Discarded remote vote", "err", err)
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unexpected vote packet type: %T", packet)
+	}
+}
+
+// runVotePeer registers a `vote` peer for the lifetime of the connection and
+// sends it the votes currently pooled.
+func (h *handler) runVotePeer(peer *vote.Peer, hand vote.Handler) error {
+	h.peerWG.Add(1)
+	defer h.peerWG.Done()
+
+	if h.votePool == nil {
+		return errPeerNotRegistered
+	}
+	h.votePeersLock.Lock()
+	if _, ok := h.votePeers[peer.ID()]; ok {
+		h.votePeersLock.Unlock()
+		return errPeerAlreadyRegistered
+	}
+	h.votePeers[peer.ID()] = peer
+	h.votePeersLock.Unlock()
+
+	defer func() {
+		h.votePeersLock.Lock()
+		delete(h.votePeers, peer.ID())
+		h.votePeersLock.Unlock()
+	}()
+	if votes := h.votePool.GetVotes(); len(votes) > 0 {
+		if err := peer.SendVotes(votes); err != nil {
+			return err
+		}
+	}
+	return hand(peer)
+}
+
+// BroadcastVote propagates a vote to all the `vote` peers not knowing it yet.
+func (h *handler) BroadcastVote(v *types.VoteEnvelope) {
+	h.votePeersLock.RLock()
+	defer h.votePeersLock.RUnlock()
+
+	hash := v.Hash()
+	for _, peer := range h.votePeers {
+		if peer.KnownVote(hash) {
+			continue
+		}
+		peer := peer
+		go func() {
+			if err := peer.SendVotes([]*types.VoteEnvelope{v}); err != nil {
+				peer.Log().Debug("Failed to send vote", "err", err)
+			}
+		}()
+	}
+}
+
+// voteBroadcastLoop announces new votes to connected peers.
+func (h *handler) voteBroadcastLoop() {
+	defer h.wg.Done()
+	for {
+		select {
+		case event := <-h.votesCh:
+			h.BroadcastVote(event.Vote)
+		case <-h.votesSub.Err():
+			return
+		}
+	}
+}
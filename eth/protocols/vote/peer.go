@@ -0,0 +1,94 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vote
+
+import (
+	"PureChain/common"
+	"PureChain/core/types"
+	"PureChain/log"
+	"PureChain/p2p"
+	mapset "github.com/deckarep/golang-set"
+)
+
+// maxKnownVotes is the maximum vote hashes to keep in the known list before
+// starting to randomly evict them.
+const maxKnownVotes = 8192
+
+// Peer is a collection of relevant information we have about a `vote` peer.
+type Peer struct {
+	id string // Unique ID for the peer, cached
+
+	*p2p.Peer                   // The embedded P2P package peer
+	rw        p2p.MsgReadWriter // Input/output streams for vote
+	version   uint              // Protocol version negotiated
+
+	knownVotes mapset.Set // Set of vote hashes known to be known by this peer
+
+	logger log.Logger // Contextual logger with the peer id injected
+}
+
+// newPeer create a wrapper for a network connection and negotiated  protocol
+// version.
+func newPeer(version uint, p *p2p.Peer, rw p2p.MsgReadWriter) *Peer {
+	id := p.ID().String()
+	return &Peer{
+		id:         id,
+		Peer:       p,
+		rw:         rw,
+		version:    version,
+		knownVotes: mapset.NewSet(),
+		logger:     log.New("peer", id[:8]),
+	}
+}
+
+// ID retrieves the peer's unique identifier.
+func (p *Peer) ID() string {
+	return p.id
+}
+
+// Version retrieves the peer's negoatiated `vote` protocol version.
+func (p *Peer) Version() uint {
+	return p.version
+}
+
+// Log overrides the P2P logget with the higher level one containing only the id.
+func (p *Peer) Log() log.Logger {
+	return p.logger
+}
+
+// KnownVote returns whether peer is known to already have a vote.
+func (p *Peer) KnownVote(hash common.Hash) bool {
+	return p.knownVotes.Contains(hash)
+}
+
+// markVote marks a vote as known for the peer, ensuring that it will never be
+// propagated to this particular peer.
+func (p *Peer) markVote(hash common.Hash) {
+	for p.knownVotes.Cardinality() >= maxKnownVotes {
+		p.knownVotes.Pop()
+	}
+	p.knownVotes.Add(hash)
+}
+
+// SendVotes propagates a batch of votes to the remote peer, marking them as
+// known.
+func (p *Peer) SendVotes(votes []*types.VoteEnvelope) error {
+	for _, vote := range votes {
+		p.markVote(vote.Hash())
+	}
+	return p2p.Send(p.rw, VotesMsg, votes)
+}
@@ -162,7 +162,7 @@ func (b *testBackend) StateAtTransaction(ctx context.Context, block *types.Block
 	// Recompute transactions up to the target index.
 	signer := types.MakeSigner(b.chainConfig, block.Number())
 	for idx, tx := range block.Transactions() {
-		msg, _ := tx.AsMessage(signer)
+		msg, _ := tx.AsMessage(signer, block.BaseFee())
 		txContext := core.NewEVMTxContext(msg)
 		context := core.NewEVMBlockContext(block.Header(), b.chain, nil)
 		if idx == txIndex {
@@ -592,6 +592,56 @@ func TestTraceBlock(t *testing.T) {
 	}
 }
 
+func TestTraceBlockRewards(t *testing.T) {
+	t.Parallel()
+
+	accounts := newAccounts(2)
+	genesis := &core.Genesis{Alloc: core.GenesisAlloc{
+		accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+	}}
+	genBlocks := 3
+	signer := types.HomesteadSigner{}
+	api := NewAPI(newTestBackend(t, genBlocks, genesis, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(accounts[1].addr)
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), accounts[1].addr, big.NewInt(1000), params.TxGas, big.NewInt(0), nil), signer, accounts[0].key)
+		b.AddTx(tx)
+	}))
+	reward := &rewardFrame{
+		Type:  consensus.BalanceChangeReward,
+		To:    accounts[1].addr,
+		Value: (*hexutil.Big)(ethash.ConstantinopleBlockReward),
+	}
+	frames, err := api.TraceBlockRewards(context.Background(), rpc.BlockNumber(genBlocks), nil)
+	if err != nil {
+		t.Fatalf("failed to trace block rewards: %v", err)
+	}
+	if !reflect.DeepEqual(frames, []*rewardFrame{reward}) {
+		t.Errorf("reward frames mismatch: have %v, want %v", frames, reward)
+	}
+	if _, err := api.TraceBlockRewards(context.Background(), rpc.BlockNumber(0), nil); err == nil {
+		t.Errorf("expected error tracing the genesis block")
+	}
+	// The block trace keeps one result per transaction unless the rewards are
+	// requested as a trailing result
+	results, err := api.TraceBlockByNumber(context.Background(), rpc.BlockNumber(genBlocks), nil)
+	if err != nil {
+		t.Fatalf("failed to trace block: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("result count mismatch: have %d, want 1", len(results))
+	}
+	results, err = api.TraceBlockByNumber(context.Background(), rpc.BlockNumber(genBlocks), &TraceConfig{Rewards: true})
+	if err != nil {
+		t.Fatalf("failed to trace block with rewards: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("result count mismatch: have %d, want 2", len(results))
+	}
+	if !reflect.DeepEqual(results[1].Result, []*rewardFrame{reward}) {
+		t.Errorf("trailing result mismatch: have %v, want %v", results[1].Result, reward)
+	}
+}
+
 type Account struct {
 	key  *ecdsa.PrivateKey
 	addr common.Address
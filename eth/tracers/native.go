@@ -0,0 +1,112 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"sync/atomic"
+
+	"PureChain/common"
+	"PureChain/core/vm"
+	"PureChain/log"
+)
+
+// NativeTracer is a transaction tracer implemented in Go. Like the JavaScript
+// tracers it assembles its own result, but without the interpreter overhead.
+type NativeTracer interface {
+	vm.Tracer
+
+	// GetResult returns the json encoded result of the trace.
+	GetResult() (json.RawMessage, error)
+
+	// Stop terminates the trace at the first opportune moment.
+	Stop(err error)
+}
+
+// NativeTracerCtor creates a native tracer for the transaction with the given
+// context.
+type NativeTracerCtor func(txCtx vm.TxContext) NativeTracer
+
+// natives contains all the registered native tracers by name.
+var natives = make(map[string]NativeTracerCtor)
+
+// RegisterNativeTracer makes a native tracer available under the given name,
+// taking precedence over any JavaScript tracer of the same name. It is not
+// safe for concurrent use and is meant to be called from init functions.
+func RegisterNativeTracer(name string, ctor NativeTracerCtor) {
+	natives[name] = ctor
+}
+
+// newNativeTracer creates the native tracer registered under the given name.
+func newNativeTracer(name string, txCtx vm.TxContext) (NativeTracer, bool) {
+	ctor, ok := natives[name]
+	if !ok {
+		return nil, false
+	}
+	return ctor(txCtx), true
+}
+
+func init() {
+	RegisterNativeTracer("callTracer", newCallTracer)
+	RegisterNativeTracer("prestateTracer", newPrestateTracer)
+	RegisterNativeTracer("4byteTracer", newFourByteTracer)
+}
+
+// interrupter implements the asynchronous termination of a native tracer.
+type interrupter struct {
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+	err       error  // Error, if one has occurred
+}
+
+// Stop implements NativeTracer, terminating the trace at the next step.
+func (i *interrupter) Stop(err error) {
+	i.reason = err
+	atomic.StoreUint32(&i.interrupt, 1)
+}
+
+// stopped reports whether the trace was terminated, recording the reason.
+func (i *interrupter) stopped() bool {
+	if i.err != nil {
+		return true
+	}
+	if atomic.LoadUint32(&i.interrupt) > 0 {
+		i.err = i.reason
+		return true
+	}
+	return false
+}
+
+// isPrecompiled reports whether the address is one of the precompiled contracts
+// the JavaScript tracers skip.
+func isPrecompiled(addr common.Address) bool {
+	_, ok := vm.PrecompiledContractsIstanbul[addr]
+	return ok
+}
+
+// memorySlice returns a copy of the memory in [begin, end), or nil if the range
+// is out of bounds.
+func memorySlice(mem *vm.Memory, begin, end int64) []byte {
+	if end == begin {
+		return []byte{}
+	}
+	if end < begin || begin < 0 || int64(mem.Len()) < end {
+		log.Warn("Tracer accessed out of bound memory", "offset", begin, "end", end)
+		return nil
+	}
+	return mem.GetCopy(begin, end-begin)
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"time"
+
+	"PureChain/common"
+	"PureChain/common/hexutil"
+	"PureChain/core/vm"
+)
+
+// fourByteTracer is the native implementation of the JavaScript 4byteTracer,
+// counting the 4byte method identifiers called along with their data sizes.
+type fourByteTracer struct {
+	interrupter
+
+	ids   map[string]int
+	input []byte
+}
+
+func newFourByteTracer(vm.TxContext) NativeTracer {
+	return &fourByteTracer{ids: make(map[string]int)}
+}
+
+// store saves the given identifier and data size.
+func (t *fourByteTracer) store(id []byte, size int64) {
+	t.ids[hexutil.Encode(id)+"-"+strconv.FormatInt(size, 10)]++
+}
+
+// CaptureStart implements vm.Tracer.
+func (t *fourByteTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.input = input
+}
+
+// CaptureState implements vm.Tracer.
+func (t *fourByteTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if t.stopped() {
+		return
+	}
+	// Skip any opcodes that are not internal calls, the index points to the
+	// input offset on the stack
+	var in int
+	switch op {
+	case vm.CALL, vm.CALLCODE:
+		in = 3
+	case vm.DELEGATECALL, vm.STATICCALL:
+		in = 2
+	default:
+		return
+	}
+	stack := scope.Stack
+	// Skip any pre-compile invocations, those are just fancy opcodes
+	if isPrecompiled(common.Address(stack.Back(1).Bytes20())) {
+		return
+	}
+	// Gather internal call details
+	if size := int64(stack.Back(in + 1).Uint64()); size >= 4 {
+		offset := int64(stack.Back(in).Uint64())
+		t.store(memorySlice(scope.Memory, offset, offset+4), size-4)
+	}
+}
+
+// CaptureFault implements vm.Tracer.
+func (t *fourByteTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// CaptureEnd implements vm.Tracer.
+func (t *fourByteTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) {
+}
+
+// GetResult implements NativeTracer, returning the counters by identifier.
+func (t *fourByteTracer) GetResult() (json.RawMessage, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	// Save the outer calldata also
+	if len(t.input) >= 4 {
+		t.store(t.input[:4], int64(len(t.input)-4))
+	}
+	return json.Marshal(t.ids)
+}
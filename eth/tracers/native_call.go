@@ -0,0 +1,280 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"PureChain/common"
+	"PureChain/common/hexutil"
+	"PureChain/core/vm"
+)
+
+// callFrame is a single call reported by the call tracer, with the fields and
+// field order of the JavaScript callTracer output.
+type callFrame struct {
+	Type    string       `json:"type"`
+	From    string       `json:"from,omitempty"`
+	To      string       `json:"to,omitempty"`
+	Value   string       `json:"value,omitempty"`
+	Gas     string       `json:"gas,omitempty"`
+	GasUsed string       `json:"gasUsed,omitempty"`
+	Input   string       `json:"input,omitempty"`
+	Output  string       `json:"output,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Time    string       `json:"time,omitempty"`
+	Calls   []*callFrame `json:"calls,omitempty"`
+
+	gasIn   uint64 // Gas available to the caller when issuing the call
+	gasCost uint64 // Cost of the call opcode
+	gas     uint64 // Gas available to the callee, if known
+	hasGas  bool
+	outOff  int64 // Memory offset of the call output in the caller
+	outLen  int64 // Memory length of the call output in the caller
+}
+
+// callTracer is the native implementation of the JavaScript callTracer,
+// reporting all the internal calls made by a transaction.
+type callTracer struct {
+	interrupter
+
+	env       *vm.EVM
+	callstack []*callFrame
+	descended bool
+
+	// Transaction context gathered throughout execution
+	create  bool
+	from    common.Address
+	to      common.Address
+	input   []byte
+	txGas   uint64
+	value   *big.Int
+	output  []byte
+	gasUsed uint64
+	time    time.Duration
+	txErr   error
+}
+
+func newCallTracer(vm.TxContext) NativeTracer {
+	return &callTracer{callstack: []*callFrame{{}}}
+}
+
+// CaptureStart implements vm.Tracer.
+func (t *callTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+	t.create, t.from, t.to, t.input, t.txGas, t.value = create, from, to, input, gas, value
+}
+
+// CaptureState implements vm.Tracer.
+func (t *callTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if t.stopped() {
+		return
+	}
+	// Capture any errors immediately
+	if err != nil {
+		t.fault(err)
+		return
+	}
+	stack := scope.Stack
+	switch op {
+	case vm.CREATE, vm.CREATE2:
+		// If a new contract is being created, add to the call stack
+		inOff := int64(stack.Back(1).Uint64())
+		inEnd := inOff + int64(stack.Back(2).Uint64())
+
+		t.callstack = append(t.callstack, &callFrame{
+			Type:    op.String(),
+			From:    hexutil.Encode(scope.Contract.Address().Bytes()),
+			Input:   hexutil.Encode(memorySlice(scope.Memory, inOff, inEnd)),
+			Value:   hexutil.EncodeBig(stack.Back(0).ToBig()),
+			gasIn:   gas,
+			gasCost: cost,
+		})
+		t.descended = true
+		return
+
+	case vm.SELFDESTRUCT:
+		// If a contract is being self destructed, gather that as a subcall too
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, &callFrame{
+			Type:    op.String(),
+			From:    hexutil.Encode(scope.Contract.Address().Bytes()),
+			To:      hexutil.Encode(common.Address(stack.Back(0).Bytes20()).Bytes()),
+			Value:   hexutil.EncodeBig(env.StateDB.GetBalance(scope.Contract.Address())),
+			gasIn:   gas,
+			gasCost: cost,
+		})
+		return
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		// Skip any pre-compile invocations, those are just fancy opcodes
+		to := common.Address(stack.Back(1).Bytes20())
+		if isPrecompiled(to) {
+			return
+		}
+		off := 1
+		if op == vm.DELEGATECALL || op == vm.STATICCALL {
+			off = 0
+		}
+		inOff := int64(stack.Back(2 + off).Uint64())
+		inEnd := inOff + int64(stack.Back(3+off).Uint64())
+
+		call := &callFrame{
+			Type:    op.String(),
+			From:    hexutil.Encode(scope.Contract.Address().Bytes()),
+			To:      hexutil.Encode(to.Bytes()),
+			Input:   hexutil.Encode(memorySlice(scope.Memory, inOff, inEnd)),
+			gasIn:   gas,
+			gasCost: cost,
+			outOff:  int64(stack.Back(4 + off).Uint64()),
+			outLen:  int64(stack.Back(5 + off).Uint64()),
+		}
+		if off == 1 {
+			call.Value = hexutil.EncodeBig(stack.Back(2).ToBig())
+		}
+		t.callstack = append(t.callstack, call)
+		t.descended = true
+		return
+	}
+	// If we've just descended into an inner call, retrieve it's true allowance. We
+	// need to extract if from within the call as there may be funky gas dynamics
+	// with regard to requested and actually given gas (2300 stipend, 63/64 rule).
+	if t.descended {
+		if depth >= len(t.callstack) {
+			top := t.callstack[len(t.callstack)-1]
+			top.gas, top.hasGas = gas, true
+		}
+		t.descended = false
+	}
+	// If an existing call is returning, pop off the call stack
+	if op == vm.REVERT {
+		t.callstack[len(t.callstack)-1].Error = "execution reverted"
+		return
+	}
+	if depth == len(t.callstack)-1 {
+		// Pop off the last call and get the execution results
+		call := t.callstack[len(t.callstack)-1]
+		t.callstack = t.callstack[:len(t.callstack)-1]
+
+		ret := stack.Back(0)
+		if call.Type == vm.CREATE.String() || call.Type == vm.CREATE2.String() {
+			// If the call was a CREATE, retrieve the contract address and output code
+			call.GasUsed = hexutil.EncodeUint64(call.gasIn - call.gasCost - gas)
+			if !ret.IsZero() {
+				addr := common.Address(ret.Bytes20())
+				call.To = hexutil.Encode(addr.Bytes())
+				call.Output = hexutil.Encode(env.StateDB.GetCode(addr))
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		} else {
+			// If the call was a contract call, retrieve the gas usage and output
+			if call.hasGas {
+				call.GasUsed = hexutil.EncodeUint64(call.gasIn - call.gasCost + call.gas - gas)
+			}
+			if !ret.IsZero() {
+				call.Output = hexutil.Encode(memorySlice(scope.Memory, call.outOff, call.outOff+call.outLen))
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		}
+		if call.hasGas {
+			call.Gas = hexutil.EncodeUint64(call.gas)
+		}
+		// Inject the call into the previous one
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, call)
+	}
+}
+
+// CaptureFault implements vm.Tracer.
+func (t *callTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	if t.err != nil {
+		return
+	}
+	t.fault(err)
+}
+
+// fault handles the failure of the executing call.
+func (t *callTracer) fault(err error) {
+	// If the topmost call already reverted, don't handle the additional fault again
+	if t.callstack[len(t.callstack)-1].Error != "" {
+		return
+	}
+	// Pop off the just failed call
+	call := t.callstack[len(t.callstack)-1]
+	t.callstack = t.callstack[:len(t.callstack)-1]
+	call.Error = err.Error()
+
+	// Consume all available gas
+	if call.hasGas {
+		call.Gas = hexutil.EncodeUint64(call.gas)
+		call.GasUsed = call.Gas
+	}
+	// Flatten the failed call into its parent
+	if len(t.callstack) > 0 {
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, call)
+		return
+	}
+	// Last call failed too, leave it in the stack
+	t.callstack = append(t.callstack, call)
+}
+
+// CaptureEnd implements vm.Tracer.
+func (t *callTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) {
+	t.output, t.gasUsed, t.time, t.txErr = output, gasUsed, d, err
+}
+
+// GetResult implements NativeTracer, returning the top level call with all
+// the internal calls nested in it.
+func (t *callTracer) GetResult() (json.RawMessage, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	typ := "CALL"
+	if t.create {
+		typ = "CREATE"
+	}
+	value := t.value
+	if value == nil {
+		value = new(big.Int)
+	}
+	result := &callFrame{
+		Type:    typ,
+		From:    hexutil.Encode(t.from.Bytes()),
+		To:      hexutil.Encode(t.to.Bytes()),
+		Value:   hexutil.EncodeBig(value),
+		Gas:     hexutil.EncodeUint64(t.txGas),
+		GasUsed: hexutil.EncodeUint64(t.gasUsed),
+		Input:   hexutil.Encode(t.input),
+		Output:  hexutil.Encode(t.output),
+		Time:    t.time.String(),
+		Calls:   t.callstack[0].Calls,
+	}
+	if t.callstack[0].Error != "" {
+		result.Error = t.callstack[0].Error
+	} else if t.txErr != nil {
+		result.Error = t.txErr.Error()
+	}
+	if result.Error != "" && (result.Error != "execution reverted" || result.Output == "0x") {
+		result.Output = ""
+	}
+	return json.Marshal(result)
+}
@@ -0,0 +1,183 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"PureChain/common"
+	"PureChain/common/hexutil"
+	"PureChain/core"
+	"PureChain/core/vm"
+	"PureChain/crypto"
+)
+
+// prestateAccount is the state of an account prior to the traced transaction.
+type prestateAccount struct {
+	Balance *big.Int                    `json:"-"`
+	Nonce   uint64                      `json:"nonce"`
+	Code    hexutil.Bytes               `json:"code"`
+	Storage map[common.Hash]common.Hash `json:"storage"`
+}
+
+// MarshalJSON encodes the account the way the JavaScript prestateTracer does.
+func (a *prestateAccount) MarshalJSON() ([]byte, error) {
+	type account prestateAccount
+	return json.Marshal(&struct {
+		Balance string `json:"balance"`
+		*account
+	}{hexutil.EncodeBig(a.Balance), (*account)(a)})
+}
+
+// prestateTracer is the native implementation of the JavaScript prestateTracer,
+// collecting the state needed to replay the transaction on a custom genesis.
+type prestateTracer struct {
+	interrupter
+
+	env      *vm.EVM
+	prestate map[common.Address]*prestateAccount
+	gasPrice *big.Int
+
+	// Transaction context gathered throughout execution
+	create       bool
+	from         common.Address
+	to           common.Address
+	value        *big.Int
+	intrinsicGas uint64
+	gasUsed      uint64
+}
+
+func newPrestateTracer(txCtx vm.TxContext) NativeTracer {
+	return &prestateTracer{gasPrice: txCtx.GasPrice}
+}
+
+// lookupAccount injects the specified account into the prestate.
+func (t *prestateTracer) lookupAccount(addr common.Address) {
+	if _, ok := t.prestate[addr]; ok {
+		return
+	}
+	db := t.env.StateDB
+	t.prestate[addr] = &prestateAccount{
+		Balance: new(big.Int).Set(db.GetBalance(addr)),
+		Nonce:   db.GetNonce(addr),
+		Code:    common.CopyBytes(db.GetCode(addr)),
+		Storage: make(map[common.Hash]common.Hash),
+	}
+}
+
+// lookupStorage injects the specified storage entry of the given account into
+// the prestate.
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
+	t.lookupAccount(addr)
+	if _, ok := t.prestate[addr].Storage[key]; ok {
+		return
+	}
+	t.prestate[addr].Storage[key] = t.env.StateDB.GetState(addr, key)
+}
+
+// CaptureStart implements vm.Tracer.
+func (t *prestateTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+	t.create, t.from, t.to, t.value = create, from, to, value
+
+	isHomestead := env.ChainConfig().IsHomestead(env.Context.BlockNumber)
+	isIstanbul := env.ChainConfig().IsIstanbul(env.Context.BlockNumber)
+	t.intrinsicGas, _ = core.IntrinsicGas(input, nil, create, isHomestead, isIstanbul)
+}
+
+// CaptureState implements vm.Tracer.
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if t.stopped() {
+		return
+	}
+	// Add the current account if we just started tracing. Its balance will
+	// include the value sent along with the message, fixed in GetResult.
+	if t.prestate == nil {
+		t.prestate = make(map[common.Address]*prestateAccount)
+		t.lookupAccount(scope.Contract.Address())
+	}
+	// Whenever new state is accessed, add it to the prestate
+	stack := scope.Stack
+	switch op {
+	case vm.EXTCODECOPY, vm.EXTCODESIZE, vm.BALANCE:
+		t.lookupAccount(common.Address(stack.Back(0).Bytes20()))
+	case vm.CREATE:
+		from := scope.Contract.Address()
+		t.lookupAccount(crypto.CreateAddress(from, env.StateDB.GetNonce(from)))
+	case vm.CREATE2:
+		offset := int64(stack.Back(1).Uint64())
+		code := memorySlice(scope.Memory, offset, offset+int64(stack.Back(2).Uint64()))
+		t.lookupAccount(crypto.CreateAddress2(scope.Contract.Address(), stack.Back(3).Bytes32(), crypto.Keccak256(code)))
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		t.lookupAccount(common.Address(stack.Back(1).Bytes20()))
+	case vm.SSTORE, vm.SLOAD:
+		t.lookupStorage(scope.Contract.Address(), stack.Back(0).Bytes32())
+	}
+}
+
+// CaptureFault implements vm.Tracer.
+func (t *prestateTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// CaptureEnd implements vm.Tracer.
+func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) {
+	t.gasUsed = gasUsed
+}
+
+// GetResult implements NativeTracer, returning the assembled prestate.
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	// Without any executed opcode the recipient was never looked up
+	if t.prestate == nil {
+		t.prestate = make(map[common.Address]*prestateAccount)
+		t.lookupAccount(t.to)
+	}
+	// At this point, we need to deduct the value from the outer transaction,
+	// and move it back to the origin
+	t.lookupAccount(t.from)
+
+	value := t.value
+	if value == nil {
+		value = new(big.Int)
+	}
+	fee := new(big.Int).SetUint64(t.gasUsed + t.intrinsicGas)
+	if t.gasPrice != nil {
+		fee.Mul(fee, t.gasPrice)
+	} else {
+		fee.SetUint64(0)
+	}
+	to, from := t.prestate[t.to], t.prestate[t.from]
+	toBal, fromBal := to.Balance, from.Balance
+	to.Balance = new(big.Int).Sub(toBal, value)
+	from.Balance = new(big.Int).Add(fromBal, new(big.Int).Add(value, fee))
+
+	// Decrement the caller's nonce, and remove empty create targets
+	from.Nonce--
+	if t.create {
+		delete(t.prestate, t.to)
+	}
+	// Encode the addresses in lower case like the JavaScript tracer
+	prestate := make(map[string]*prestateAccount, len(t.prestate))
+	for addr, account := range t.prestate {
+		prestate[hexutil.Encode(addr.Bytes())] = account
+	}
+	return json.Marshal(prestate)
+}
@@ -0,0 +1,173 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"PureChain/common"
+	"PureChain/core"
+	"PureChain/core/rawdb"
+	"PureChain/core/state"
+	"PureChain/core/types"
+	"PureChain/core/vm"
+	"PureChain/rlp"
+)
+
+// traceResult is the result of a tracer able to return one.
+type traceResult interface {
+	vm.Tracer
+	GetResult() (json.RawMessage, error)
+}
+
+// runTracerTest executes the transaction of the test case with the tracer and
+// returns its decoded result.
+func runTracerTest(t *testing.T, test *callTracerTest, create func(vm.TxContext) traceResult) interface{} {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+		t.Fatalf("failed to parse testcase input: %v", err)
+	}
+	signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+	origin, _ := signer.Sender(tx)
+	txContext := vm.TxContext{
+		Origin:   origin,
+		GasPrice: tx.GasPrice(),
+	}
+	context := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Coinbase:    test.Context.Miner,
+		BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+		Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+		Difficulty:  (*big.Int)(test.Context.Difficulty),
+		GasLimit:    uint64(test.Context.GasLimit),
+	}
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	for addr, account := range test.Genesis.Alloc {
+		statedb.SetCode(addr, account.Code)
+		statedb.SetNonce(addr, account.Nonce)
+		statedb.SetBalance(addr, account.Balance)
+		for key, value := range account.Storage {
+			statedb.SetState(addr, key, value)
+		}
+	}
+	statedb.Finalise(true)
+
+	tracer := create(txContext)
+	evm := vm.NewEVM(context, txContext, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+
+	msg, err := tx.AsMessage(signer, nil)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if _, err = st.TransitionDb(); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	var ret interface{}
+	if err := json.Unmarshal(res, &ret); err != nil {
+		t.Fatalf("failed to unmarshal trace result: %v", err)
+	}
+	return ret
+}
+
+// dropTimes removes the execution times from a decoded call trace.
+func dropTimes(trace interface{}) {
+	if call, ok := trace.(map[string]interface{}); ok {
+		delete(call, "time")
+		if calls, ok := call["calls"].([]interface{}); ok {
+			for _, inner := range calls {
+				dropTimes(inner)
+			}
+		}
+	}
+}
+
+// Iterates over all the input-output datasets in the tracer test harness and
+// checks the native tracers produce the same output as the JavaScript ones.
+func TestNativeTracerParity(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, name := range []string{"callTracer", "prestateTracer", "4byteTracer"} {
+		for _, file := range files {
+			if !strings.HasPrefix(file.Name(), "call_tracer_") {
+				continue
+			}
+			name, file := name, file // capture range variables
+			t.Run(name+"/"+camel(strings.TrimSuffix(strings.TrimPrefix(file.Name(), "call_tracer_"), ".json")), func(t *testing.T) {
+				t.Parallel()
+
+				blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+				if err != nil {
+					t.Fatalf("failed to read testcase: %v", err)
+				}
+				test := new(callTracerTest)
+				if err := json.Unmarshal(blob, test); err != nil {
+					t.Fatalf("failed to parse testcase: %v", err)
+				}
+				code, _ := tracer(name)
+				want := runTracerTest(t, test, func(txCtx vm.TxContext) traceResult {
+					tracer, err := New(code, txCtx)
+					if err != nil {
+						t.Fatalf("failed to create js tracer: %v", err)
+					}
+					return tracer
+				})
+				have := runTracerTest(t, test, func(txCtx vm.TxContext) traceResult {
+					tracer, ok := newNativeTracer(name, txCtx)
+					if !ok {
+						t.Fatalf("native tracer %s not registered", name)
+					}
+					return tracer
+				})
+				dropTimes(want)
+				dropTimes(have)
+				if !reflect.DeepEqual(have, want) {
+					haveJSON, _ := json.MarshalIndent(have, "", " ")
+					wantJSON, _ := json.MarshalIndent(want, "", " ")
+					t.Fatalf("trace mismatch:\nhave %s\nwant %s", haveJSON, wantJSON)
+				}
+			})
+		}
+	}
+}
+
+func TestNativeTracerStop(t *testing.T) {
+	tracer, ok := newNativeTracer("callTracer", vm.TxContext{})
+	if !ok {
+		t.Fatal("native call tracer not registered")
+	}
+	stopErr := errors.New("stop")
+	tracer.Stop(stopErr)
+	tracer.CaptureState(nil, 0, vm.STOP, 0, 0, nil, nil, 1, nil)
+	if _, err := tracer.GetResult(); err != stopErr {
+		t.Fatalf("error mismatch: have %v, want %v", err, stopErr)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"PureChain/common"
+	"PureChain/common/hexutil"
+	"PureChain/consensus"
+	"PureChain/core"
+	"PureChain/core/types"
+	"PureChain/core/vm"
+	"PureChain/params"
+	"PureChain/rpc"
+)
+
+// rewardFrame is a synthetic call frame describing a balance change applied by
+// the consensus engine outside of any transaction.
+type rewardFrame struct {
+	Type  consensus.BalanceChangeKind `json:"type"`
+	From  common.Address              `json:"from"`
+	To    common.Address              `json:"to"`
+	Value *hexutil.Big                `json:"value"`
+}
+
+// rewardTracer collects the balance changes reported by the consensus engine.
+type rewardTracer struct {
+	frames []*rewardFrame
+}
+
+// CaptureBalanceChange implements consensus.BalanceTracer.
+func (t *rewardTracer) CaptureBalanceChange(kind consensus.BalanceChangeKind, from, to common.Address, value *big.Int) {
+	t.frames = append(t.frames, &rewardFrame{Type: kind, From: from, To: to, Value: (*hexutil.Big)(value)})
+}
+
+// tracedChain is the chain reader handed to the consensus engine while replaying
+// a block, carrying the tracer of the engine balance changes.
+type tracedChain struct {
+	*chainContext
+	tracer consensus.BalanceTracer
+}
+
+func (c *tracedChain) Config() *params.ChainConfig {
+	return c.api.backend.ChainConfig()
+}
+
+func (c *tracedChain) CurrentHeader() *types.Header {
+	header, _ := c.api.backend.HeaderByNumber(c.ctx, rpc.LatestBlockNumber)
+	return header
+}
+
+func (c *tracedChain) GetHeaderByNumber(number uint64) *types.Header {
+	header, _ := c.api.backend.HeaderByNumber(c.ctx, rpc.BlockNumber(number))
+	return header
+}
+
+func (c *tracedChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	header, _ := c.api.backend.HeaderByHash(c.ctx, hash)
+	return header
+}
+
+func (c *tracedChain) BalanceTracer() consensus.BalanceTracer {
+	return c.tracer
+}
+
+// TraceBlockRewards returns the balance changes applied by the consensus engine
+// while finalizing the block, such as block rewards, lock releases and fee
+// sweeps, as synthetic call frames.
+func (api *API) TraceBlockRewards(ctx context.Context, number rpc.BlockNumber, config *TraceConfig) ([]*rewardFrame, error) {
+	block, err := api.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	reexec := defaultTraceReexec
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	return api.traceBlockRewards(ctx, block, reexec)
+}
+
+// traceBlockRewards replays the block on top of its parent state through the
+// state processor and records the balance changes of the engine.
+func (api *API) traceBlockRewards(ctx context.Context, block *types.Block, reexec uint64) ([]*rewardFrame, error) {
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	parent, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(block.NumberU64()-1), block.ParentHash())
+	if err != nil {
+		return nil, err
+	}
+	statedb, err := api.backend.StateAtBlock(ctx, parent, reexec, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		tracer    = new(rewardTracer)
+		chain     = &tracedChain{chainContext: &chainContext{api: api, ctx: ctx}, tracer: tracer}
+		processor = core.NewStateProcessor(api.backend.ChainConfig(), chain, api.backend.Engine())
+	)
+	if _, _, _, err := processor.Process(block, statedb, vm.Config{IsSkipProvider: true}); err != nil {
+		return nil, err
+	}
+	if tracer.frames == nil {
+		return []*rewardFrame{}, nil
+	}
+	return tracer.frames, nil
+}
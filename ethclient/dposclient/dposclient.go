@@ -0,0 +1,215 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package dposclient provides a client for the dpos consensus RPC API.
+package dposclient
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"PureChain/common"
+	"PureChain/common/hexutil"
+	"PureChain/core/types"
+	"PureChain/rpc"
+)
+
+// Client defines typed wrappers for the dpos RPC API.
+type Client struct {
+	c *rpc.Client
+}
+
+// Dial connects a client to the given URL.
+func Dial(rawurl string) (*Client, error) {
+	return DialContext(context.Background(), rawurl)
+}
+
+// DialContext connects a client to the given URL with context.
+func DialContext(ctx context.Context, rawurl string) (*Client, error) {
+	c, err := rpc.DialContext(ctx, rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(c), nil
+}
+
+// NewClient creates a client that uses the given RPC client.
+func NewClient(c *rpc.Client) *Client {
+	return &Client{c}
+}
+
+// Close closes the underlying RPC connection.
+func (dc *Client) Close() {
+	dc.c.Close()
+}
+
+// Snapshot is the validator set snapshot of the dpos engine at a given block,
+// mirroring consensus/dpos.Snapshot.
+type Snapshot struct {
+	Number           uint64                      `json:"number"`             // Block number where the snapshot was created
+	Hash             common.Hash                 `json:"hash"`               // Block hash where the snapshot was created
+	Validators       map[common.Address]struct{} `json:"validators"`         // Set of authorized validators at this moment
+	Recents          map[uint64]common.Address   `json:"recents"`            // Set of recent validators for spam protections
+	RecentForkHashes map[uint64]string           `json:"recent_fork_hashes"` // Set of recent forkHash
+
+	VoteKeys        map[common.Address]types.BLSPublicKey `json:"vote_keys,omitempty"`        // BLS vote keys registered by the validators
+	Attestation     *types.VoteData                       `json:"attestation,omitempty"`      // Latest attested justification link
+	FinalizedNumber uint64                                `json:"finalized_number,omitempty"` // Number of the latest finalized block
+	FinalizedHash   common.Hash                           `json:"finalized_hash,omitempty"`   // Hash of the latest finalized block
+}
+
+// SortedValidators returns the authorized validators of the snapshot sorted by
+// address, in the order the engine assigns the in-turn slots.
+func (s *Snapshot) SortedValidators() []common.Address {
+	validators := make([]common.Address, 0, len(s.Validators))
+	for val := range s.Validators {
+		validators = append(validators, val)
+	}
+	sort.Slice(validators, func(i, j int) bool {
+		return validators[i].Hash().Big().Cmp(validators[j].Hash().Big()) < 0
+	})
+	return validators
+}
+
+// Status is the sealing status of the validator set over the recent blocks.
+type Status struct {
+	InturnPercent float64                `json:"inturnPercent"`
+	SigningStatus map[common.Address]int `json:"sealerActivity"` // Number of blocks sealed by each validator
+	NumBlocks     uint64                 `json:"numBlocks"`
+}
+
+// ValidatorLiveness is the sealing activity of a single validator.
+type ValidatorLiveness struct {
+	InTurn     uint64 `json:"inTurn"`     // Number of blocks sealed in-turn
+	OutOfTurn  uint64 `json:"outOfTurn"`  // Number of blocks sealed out-of-turn
+	Missed     uint64 `json:"missed"`     // Number of in-turn slots the validator did not seal
+	LastSealed uint64 `json:"lastSealed"` // Number of the last block sealed within the window
+}
+
+// LivenessReport is the sealing activity of the validator set over the block
+// range [From, To].
+type LivenessReport struct {
+	From          uint64                                `json:"from"`
+	To            uint64                                `json:"to"`
+	NumBlocks     uint64                                `json:"numBlocks"`
+	InturnPercent float64                               `json:"inturnPercent"`
+	Validators    map[common.Address]*ValidatorLiveness `json:"validators"`
+}
+
+// ProviderSelection is the selection frequency of a single provider.
+type ProviderSelection struct {
+	Provider common.Address `json:"provider"`
+	Observed uint64         `json:"observed"` // Number of blocks naming the provider in their header
+	Replayed uint64         `json:"replayed"` // Number of blocks the replayed selection picked the provider for
+	Expected float64        `json:"expected"` // Sum of the provider's share of the voting power over the range
+}
+
+// ProviderSelectionStats compares the observed provider selections over the
+// block range [From, To] with the frequencies expected from the voting power.
+type ProviderSelectionStats struct {
+	From             uint64               `json:"from"`
+	To               uint64               `json:"to"`
+	NumBlocks        uint64               `json:"numBlocks"`
+	Unassigned       uint64               `json:"unassigned"`       // Blocks without any eligible provider
+	Mismatches       uint64               `json:"mismatches"`       // Blocks whose provider differs from the replayed selection
+	ChiSquare        float64              `json:"chiSquare"`        // Pearson's statistic of observed vs expected selections
+	DegreesOfFreedom int                  `json:"degreesOfFreedom"` // Number of eligible providers minus one
+	PValue           float64              `json:"pValue"`           // Probability of a statistic at least as extreme under fair selection
+	Providers        []*ProviderSelection `json:"providers"`
+}
+
+// Snapshot retrieves the validator set snapshot at the given block. The latest
+// block is used if number is nil.
+func (dc *Client) Snapshot(ctx context.Context, number *big.Int) (*Snapshot, error) {
+	var snap *Snapshot
+	if err := dc.c.CallContext(ctx, &snap, "dpos_getSnapshot", toBlockNumArg(number)); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// SnapshotAtHash retrieves the validator set snapshot at the given block.
+func (dc *Client) SnapshotAtHash(ctx context.Context, hash common.Hash) (*Snapshot, error) {
+	var snap *Snapshot
+	if err := dc.c.CallContext(ctx, &snap, "dpos_getSnapshotAtHash", hash); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// Validators retrieves the sorted validators authorized at the given block. The
+// latest block is used if number is nil.
+func (dc *Client) Validators(ctx context.Context, number *big.Int) ([]common.Address, error) {
+	var validators []common.Address
+	err := dc.c.CallContext(ctx, &validators, "dpos_getValidators", toBlockNumArg(number))
+	return validators, err
+}
+
+// ValidatorsAtHash retrieves the sorted validators authorized at the given block.
+func (dc *Client) ValidatorsAtHash(ctx context.Context, hash common.Hash) ([]common.Address, error) {
+	var validators []common.Address
+	err := dc.c.CallContext(ctx, &validators, "dpos_getValidatorsAtHash", hash)
+	return validators, err
+}
+
+// Status retrieves the sealing status of the validator set over the recent blocks.
+func (dc *Client) Status(ctx context.Context) (*Status, error) {
+	var status *Status
+	if err := dc.c.CallContext(ctx, &status, "dpos_status"); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// ValidatorLiveness retrieves the sealing activity of every validator over the
+// last window blocks. The node's default window is used if window is zero.
+func (dc *Client) ValidatorLiveness(ctx context.Context, window uint64) (*LivenessReport, error) {
+	var (
+		report *LivenessReport
+		err    error
+	)
+	if window == 0 {
+		err = dc.c.CallContext(ctx, &report, "dpos_getValidatorLiveness")
+	} else {
+		err = dc.c.CallContext(ctx, &report, "dpos_getValidatorLiveness", window)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// ProviderSelectionStats replays the provider selection over the block range
+// [from, to]. Nil bounds select the node's default range.
+func (dc *Client) ProviderSelectionStats(ctx context.Context, from, to *big.Int) (*ProviderSelectionStats, error) {
+	var stats *ProviderSelectionStats
+	if err := dc.c.CallContext(ctx, &stats, "dpos_getProviderSelectionStats", toBlockNumArg(from), toBlockNumArg(to)); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func toBlockNumArg(number *big.Int) string {
+	if number == nil {
+		return "latest"
+	}
+	pending := big.NewInt(-1)
+	if number.Cmp(pending) == 0 {
+		return "pending"
+	}
+	return hexutil.EncodeBig(number)
+}
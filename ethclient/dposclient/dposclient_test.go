@@ -0,0 +1,155 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dposclient
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"PureChain/common"
+	"PureChain/core"
+	"PureChain/crypto"
+	"PureChain/eth"
+	"PureChain/eth/ethconfig"
+	"PureChain/node"
+	"PureChain/params"
+)
+
+var (
+	testKey, _      = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testValidator   = crypto.PubkeyToAddress(testKey.PublicKey)
+	testChainConfig = &params.ChainConfig{
+		ChainID:             big.NewInt(1337),
+		HomesteadBlock:      big.NewInt(0),
+		EIP150Block:         big.NewInt(0),
+		EIP155Block:         big.NewInt(0),
+		EIP158Block:         big.NewInt(0),
+		ByzantiumBlock:      big.NewInt(0),
+		ConstantinopleBlock: big.NewInt(0),
+		PetersburgBlock:     big.NewInt(0),
+		IstanbulBlock:       big.NewInt(0),
+		MuirGlacierBlock:    big.NewInt(0),
+		BerlinBlock:         big.NewInt(0),
+		RamanujanBlock:      big.NewInt(0),
+		NielsBlock:          big.NewInt(0),
+		MirrorSyncBlock:     big.NewInt(0),
+		Dpos:                &params.DposConfig{Period: 3, Epoch: 10},
+	}
+)
+
+func newTestBackend(t *testing.T) (*node.Node, *core.Genesis) {
+	// Deploy the system contracts and seal the validator into the genesis
+	alloc := make(core.GenesisAlloc)
+	for addr, account := range core.DefaultDevnetGenesisBlock().Alloc {
+		if len(account.Code) > 0 {
+			alloc[addr] = account
+		}
+	}
+	extra := make([]byte, 32)
+	extra = append(extra, testValidator.Bytes()...)
+	extra = append(extra, make([]byte, crypto.SignatureLength)...)
+
+	genesis := &core.Genesis{
+		Config:     testChainConfig,
+		Alloc:      alloc,
+		ExtraData:  extra,
+		GasLimit:   30_000_000,
+		Difficulty: big.NewInt(1),
+	}
+	// Create node
+	n, err := node.New(&node.Config{})
+	if err != nil {
+		t.Fatalf("can't create new node: %v", err)
+	}
+	// Create Ethereum Service
+	if _, err := eth.New(n, &ethconfig.Config{Genesis: genesis}); err != nil {
+		t.Fatalf("can't create new ethereum service: %v", err)
+	}
+	if err := n.Start(); err != nil {
+		t.Fatalf("can't start test node: %v", err)
+	}
+	return n, genesis
+}
+
+func TestDposClient(t *testing.T) {
+	backend, genesis := newTestBackend(t)
+	defer backend.Close()
+
+	rpcClient, err := backend.Attach()
+	if err != nil {
+		t.Fatalf("can't attach to test node: %v", err)
+	}
+	client := NewClient(rpcClient)
+	defer client.Close()
+
+	ctx := context.Background()
+	hash := genesis.ToBlock(nil).Hash()
+
+	// Both snapshot lookups must resolve the genesis validator set
+	snap, err := client.Snapshot(ctx, nil)
+	if err != nil {
+		t.Fatalf("can't retrieve snapshot: %v", err)
+	}
+	if snap.Number != 0 || snap.Hash != hash {
+		t.Fatalf("snapshot mismatch: have #%d [%x], want #0 [%x]", snap.Number, snap.Hash, hash)
+	}
+	if validators := snap.SortedValidators(); !reflect.DeepEqual(validators, []common.Address{testValidator}) {
+		t.Fatalf("snapshot validators mismatch: have %v, want %v", validators, []common.Address{testValidator})
+	}
+	byHash, err := client.SnapshotAtHash(ctx, hash)
+	if err != nil {
+		t.Fatalf("can't retrieve snapshot by hash: %v", err)
+	}
+	if !reflect.DeepEqual(snap, byHash) {
+		t.Fatalf("snapshot by hash mismatch: have %+v, want %+v", byHash, snap)
+	}
+	if _, err := client.SnapshotAtHash(ctx, common.Hash{0x01}); err == nil {
+		t.Fatalf("snapshot of unknown block retrieved")
+	}
+	// The validator lists must match the snapshot
+	validators, err := client.Validators(ctx, big.NewInt(0))
+	if err != nil {
+		t.Fatalf("can't retrieve validators: %v", err)
+	}
+	if !reflect.DeepEqual(validators, []common.Address{testValidator}) {
+		t.Fatalf("validators mismatch: have %v, want %v", validators, []common.Address{testValidator})
+	}
+	validators, err = client.ValidatorsAtHash(ctx, hash)
+	if err != nil {
+		t.Fatalf("can't retrieve validators by hash: %v", err)
+	}
+	if !reflect.DeepEqual(validators, []common.Address{testValidator}) {
+		t.Fatalf("validators by hash mismatch: have %v, want %v", validators, []common.Address{testValidator})
+	}
+	// No blocks were sealed yet, the activity reports must be empty
+	status, err := client.Status(ctx)
+	if err != nil {
+		t.Fatalf("can't retrieve status: %v", err)
+	}
+	if status.NumBlocks != 0 {
+		t.Fatalf("status block count mismatch: have %d, want 0", status.NumBlocks)
+	}
+	report, err := client.ValidatorLiveness(ctx, 16)
+	if err != nil {
+		t.Fatalf("can't retrieve liveness: %v", err)
+	}
+	if report.To != 0 || report.NumBlocks != 0 {
+		t.Fatalf("liveness range mismatch: have #%d, %d blocks, want #0, 0 blocks", report.To, report.NumBlocks)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package inihashclient provides a client for the inihash remote sealer RPC API.
+package inihashclient
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"PureChain/common"
+	"PureChain/common/hexutil"
+	"PureChain/core/types"
+	"PureChain/rpc"
+)
+
+// Client defines typed wrappers for the inihash RPC API.
+type Client struct {
+	c *rpc.Client
+}
+
+// Dial connects a client to the given URL.
+func Dial(rawurl string) (*Client, error) {
+	return DialContext(context.Background(), rawurl)
+}
+
+// DialContext connects a client to the given URL with context.
+func DialContext(ctx context.Context, rawurl string) (*Client, error) {
+	c, err := rpc.DialContext(ctx, rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(c), nil
+}
+
+// NewClient creates a client that uses the given RPC client.
+func NewClient(c *rpc.Client) *Client {
+	return &Client{c}
+}
+
+// Close closes the underlying RPC connection.
+func (ic *Client) Close() {
+	ic.c.Close()
+}
+
+// Work is a work package handed out to remote miners.
+type Work struct {
+	PowHash   common.Hash // Seal hash of the header to be mined
+	Target    *big.Int    // Boundary condition the result must meet, 2^256/difficulty
+	Number    uint64      // Number of the block to be mined
+	Timestamp uint64      // Timestamp of the block to be mined
+}
+
+// GetWork retrieves the current work package of the remote sealer.
+func (ic *Client) GetWork(ctx context.Context) (*Work, error) {
+	var result [4]string
+	if err := ic.c.CallContext(ctx, &result, "eth_getWork"); err != nil {
+		return nil, err
+	}
+	work := &Work{
+		PowHash: common.HexToHash(result[0]),
+		Target:  new(big.Int).SetBytes(common.HexToHash(result[1]).Bytes()),
+	}
+	var err error
+	if work.Number, err = hexutil.DecodeUint64(result[2]); err != nil {
+		return nil, fmt.Errorf("invalid work number %q: %v", result[2], err)
+	}
+	if work.Timestamp, err = hexutil.DecodeUint64(result[3]); err != nil {
+		return nil, fmt.Errorf("invalid work timestamp %q: %v", result[3], err)
+	}
+	return work, nil
+}
+
+// SubmitWork submits a proof-of-work solution for the work package identified
+// by its pow hash. It reports whether the solution was accepted; invalid, stale
+// or unknown work is rejected.
+func (ic *Client) SubmitWork(ctx context.Context, nonce, extraNonce types.BlockNonce, powHash common.Hash) (bool, error) {
+	var accepted bool
+	err := ic.c.CallContext(ctx, &accepted, "eth_submitWork", nonce, extraNonce, powHash)
+	return accepted, err
+}
+
+// SubmitHashrate reports the hash rate of the remote miner identified by id.
+func (ic *Client) SubmitHashrate(ctx context.Context, rate uint64, id common.Hash) (bool, error) {
+	var accepted bool
+	err := ic.c.CallContext(ctx, &accepted, "eth_submitHashrate", hexutil.Uint64(rate), id)
+	return accepted, err
+}
+
+// Hashrate retrieves the combined hash rate of the local and remote miners.
+func (ic *Client) Hashrate(ctx context.Context) (uint64, error) {
+	var rate uint64
+	err := ic.c.CallContext(ctx, &rate, "inihash_getHashrate")
+	return rate, err
+}
+
+// BlockReward retrieves the mining reward of the block with the given number.
+func (ic *Client) BlockReward(ctx context.Context, number uint64) (*big.Int, error) {
+	var result string
+	if err := ic.c.CallContext(ctx, &result, "inihash_getBlockReward", hexutil.Uint64(number)); err != nil {
+		return nil, err
+	}
+	return hexutil.DecodeBig(result)
+}
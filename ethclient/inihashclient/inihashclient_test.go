@@ -0,0 +1,132 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package inihashclient
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"PureChain/common"
+	"PureChain/consensus/inihash"
+	"PureChain/core"
+	"PureChain/core/types"
+	"PureChain/eth"
+	"PureChain/eth/ethconfig"
+	"PureChain/node"
+	"PureChain/params"
+)
+
+func newTestBackend(t *testing.T) (*node.Node, *eth.Ethereum) {
+	genesis := &core.Genesis{
+		Config:     params.AllEthashProtocolChanges,
+		GasLimit:   30_000_000,
+		Difficulty: big.NewInt(1),
+	}
+	// Create node
+	n, err := node.New(&node.Config{})
+	if err != nil {
+		t.Fatalf("can't create new node: %v", err)
+	}
+	// Create Ethereum Service with a remote-only sealer
+	config := &ethconfig.Config{Genesis: genesis}
+	config.Inihash.PowMode = inihash.ModeTest
+	ethservice, err := eth.New(n, config)
+	if err != nil {
+		t.Fatalf("can't create new ethereum service: %v", err)
+	}
+	if err := n.Start(); err != nil {
+		t.Fatalf("can't start test node: %v", err)
+	}
+	return n, ethservice
+}
+
+func TestInihashClient(t *testing.T) {
+	backend, ethservice := newTestBackend(t)
+	defer backend.Close()
+
+	rpcClient, err := backend.Attach()
+	if err != nil {
+		t.Fatalf("can't attach to test node: %v", err)
+	}
+	client := NewClient(rpcClient)
+	defer client.Close()
+
+	ctx := context.Background()
+	if _, err := client.GetWork(ctx); err == nil {
+		t.Fatalf("work retrieved before any was pushed")
+	}
+	// Push a trivially solvable block to the remote sealer
+	chain := ethservice.BlockChain()
+	header := &types.Header{
+		ParentHash: chain.CurrentBlock().Hash(),
+		Number:     big.NewInt(1),
+		Time:       chain.CurrentBlock().Time() + 10,
+		GasLimit:   chain.CurrentBlock().GasLimit(),
+		Difficulty: big.NewInt(1),
+	}
+	results := make(chan *types.Block, 1)
+	if err := ethservice.Engine().Seal(chain, types.NewBlockWithHeader(header), results, nil); err != nil {
+		t.Fatalf("can't push work: %v", err)
+	}
+	var work *Work
+	for i := 0; ; i++ {
+		if work, err = client.GetWork(ctx); err == nil {
+			break
+		}
+		if i == 50 {
+			t.Fatalf("can't retrieve work: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if sealhash := ethservice.Engine().SealHash(header); work.PowHash != sealhash {
+		t.Fatalf("work hash mismatch: have %x, want %x", work.PowHash, sealhash)
+	}
+	if work.Number != 1 || work.Timestamp != header.Time {
+		t.Fatalf("work mismatch: have #%d at %d, want #1 at %d", work.Number, work.Timestamp, header.Time)
+	}
+	// Unknown work is rejected, valid solutions carry both nonces into the block
+	if ok, err := client.SubmitWork(ctx, types.EncodeNonce(1), types.EncodeNonce(2), common.Hash{0x01}); err != nil || ok {
+		t.Fatalf("unknown work accepted: %v, %v", ok, err)
+	}
+	if ok, err := client.SubmitWork(ctx, types.EncodeNonce(1), types.EncodeNonce(2), work.PowHash); err != nil || !ok {
+		t.Fatalf("valid work rejected: %v, %v", ok, err)
+	}
+	select {
+	case block := <-results:
+		if block.Nonce() != 1 || block.Header().ExtraNonce != types.EncodeNonce(2) {
+			t.Fatalf("sealed nonces mismatch: have %d/%x, want 1/%x", block.Nonce(), block.Header().ExtraNonce, types.EncodeNonce(2))
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("sealed block not delivered")
+	}
+	// Remote hash rates are aggregated into the node's
+	if ok, err := client.SubmitHashrate(ctx, 100, common.Hash{0x02}); err != nil || !ok {
+		t.Fatalf("hashrate rejected: %v, %v", ok, err)
+	}
+	if rate, err := client.Hashrate(ctx); err != nil || rate != 100 {
+		t.Fatalf("hashrate mismatch: have %d, %v, want 100", rate, err)
+	}
+	reward, err := client.BlockReward(ctx, 1)
+	if err != nil {
+		t.Fatalf("can't retrieve block reward: %v", err)
+	}
+	if want := ethservice.Engine().(*inihash.Inihash).GetBlockReward(1); reward.Cmp(want) != 0 {
+		t.Fatalf("block reward mismatch: have %v, want %v", reward, want)
+	}
+}
@@ -0,0 +1,468 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !js
+// +build !js
+
+// Package boltdb implements the key-value database layer based on bbolt.
+//
+// Unlike LevelDB, bbolt is a single file B+tree without any background
+// compaction, trading some write throughput for predictable write latencies.
+package boltdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"PureChain/common"
+	"PureChain/ethdb"
+	"PureChain/log"
+	"PureChain/metrics"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// DatabaseFile is the name of the bbolt data file inside the database directory.
+	DatabaseFile = "chaindata.bolt"
+
+	// openTimeout is the time to wait for the file lock of the database to be
+	// released by another process before giving up.
+	openTimeout = 5 * time.Second
+
+	// largeMmapSize is the initial memory map size of the database on 64 bit
+	// platforms. Commits growing the file past the mapping wait for all the open
+	// read transactions, like the ones of iterators, so map a large address space
+	// upfront. It costs nothing but address space, except on Windows which
+	// preallocates the mapped size on disk.
+	largeMmapSize = 1 << 40
+
+	// metricsGatheringInterval specifies the interval to retrieve bbolt database
+	// transaction and page stats to report to the user.
+	metricsGatheringInterval = 3 * time.Second
+)
+
+var (
+	// bucket is the single bucket all the key-value pairs are stored in.
+	bucket = []byte("ethdb")
+
+	// errNotFound is returned if a key is requested that is not found in the
+	// database.
+	errNotFound = errors.New("not found")
+)
+
+// Database is a persistent key-value store backed by bbolt. Apart from basic data
+// storage functionality it also supports batch writes and iterating over the
+// keyspace in binary-alphabetical order.
+type Database struct {
+	fn string   // filename for reporting
+	db *bolt.DB // bbolt instance
+
+	diskSizeGauge  metrics.Gauge // Gauge for tracking the size of the database file
+	freePagesGauge metrics.Gauge // Gauge for tracking the number of free pages
+	writeMeter     metrics.Meter // Meter for measuring the number of page writes
+	writeTimeMeter metrics.Meter // Meter for measuring the time spent writing pages
+
+	quitLock sync.Mutex      // Mutex protecting the quit channel access
+	quitChan chan chan error // Quit channel to stop the metrics collection before closing the database
+
+	log log.Logger // Contextual logger tracking the database path
+}
+
+// New returns a wrapped bbolt object stored in the given directory. The namespace
+// is the prefix that the metrics reporting should use for surfacing internal stats.
+func New(dir string, namespace string, readonly bool) (*Database, error) {
+	logger := log.New("database", dir)
+	if !readonly {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, err
+		}
+	}
+	options := &bolt.Options{
+		Timeout:        openTimeout,
+		NoFreelistSync: true,
+		FreelistType:   bolt.FreelistMapType,
+		ReadOnly:       readonly,
+	}
+	if strconv.IntSize == 64 && runtime.GOOS != "windows" {
+		options.InitialMmapSize = largeMmapSize
+	}
+	db, err := bolt.Open(filepath.Join(dir, DatabaseFile), 0600, options)
+	if err != nil {
+		return nil, err
+	}
+	if !readonly {
+		if err := db.Update(func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(bucket)
+			return err
+		}); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	logCtx := []interface{}{"engine", "bolt"}
+	if readonly {
+		logCtx = append(logCtx, "readonly", "true")
+	}
+	logger.Info("Opened key-value database", logCtx...)
+
+	// Assemble the wrapper with all the registered metrics
+	bdb := &Database{
+		fn:       dir,
+		db:       db,
+		log:      logger,
+		quitChan: make(chan chan error),
+	}
+	bdb.diskSizeGauge = metrics.NewRegisteredGauge(namespace+"disk/size", nil)
+	bdb.freePagesGauge = metrics.NewRegisteredGauge(namespace+"disk/freepages", nil)
+	bdb.writeMeter = metrics.NewRegisteredMeter(namespace+"disk/write", nil)
+	bdb.writeTimeMeter = metrics.NewRegisteredMeter(namespace+"disk/writetime", nil)
+
+	// Start up the metrics gathering and return
+	go bdb.meter(metricsGatheringInterval)
+	return bdb, nil
+}
+
+// Close stops the metrics collection and closes all io accesses to the underlying
+// key-value store. It waits for the iterators still open to be released.
+func (db *Database) Close() error {
+	db.quitLock.Lock()
+	defer db.quitLock.Unlock()
+
+	if db.quitChan != nil {
+		errc := make(chan error)
+		db.quitChan <- errc
+		if err := <-errc; err != nil {
+			db.log.Error("Metrics collection failed", "err", err)
+		}
+		db.quitChan = nil
+	}
+	return db.db.Close()
+}
+
+// lookup retrieves the value of the key from the bucket, distinguishing between
+// missing keys and empty values.
+func lookup(b *bolt.Bucket, key []byte) ([]byte, bool) {
+	if b == nil {
+		return nil, false
+	}
+	k, v := b.Cursor().Seek(key)
+	if k == nil || !bytes.Equal(k, key) {
+		return nil, false
+	}
+	return v, true
+}
+
+// Has retrieves if a key is present in the key-value store.
+func (db *Database) Has(key []byte) (bool, error) {
+	var found bool
+	err := db.db.View(func(tx *bolt.Tx) error {
+		_, found = lookup(tx.Bucket(bucket), key)
+		return nil
+	})
+	return found, err
+}
+
+// Get retrieves the given key if it's present in the key-value store.
+func (db *Database) Get(key []byte) ([]byte, error) {
+	var dat []byte
+	err := db.db.View(func(tx *bolt.Tx) error {
+		v, found := lookup(tx.Bucket(bucket), key)
+		if !found {
+			return errNotFound
+		}
+		// Values are only valid during the transaction, copy them out
+		dat = append([]byte{}, v...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dat, nil
+}
+
+// Put inserts the given value into the key-value store. Every single write is
+// committed in its own transaction, use a batch to write many keys at once.
+func (db *Database) Put(key []byte, value []byte) error {
+	return db.commit([]keyvalue{{key, value, false}})
+}
+
+// Delete removes the key from the key-value store.
+func (db *Database) Delete(key []byte) error {
+	return db.commit([]keyvalue{{key, nil, true}})
+}
+
+// commit writes the given key-value tuples in a single transaction, preserving
+// the order in which they were issued.
+func (db *Database) commit(writes []keyvalue) error {
+	if len(writes) == 0 {
+		return nil
+	}
+	return db.db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket(bucket)
+		for _, kv := range writes {
+			if err := kv.apply(bkt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// NewBatch creates a write-only key-value store that buffers changes to its host
+// database until a final write is called.
+func (db *Database) NewBatch() ethdb.Batch {
+	return &batch{db: db}
+}
+
+// NewIterator creates a binary-alphabetical iterator over a subset
+// of database content with a particular key prefix, starting at a particular
+// initial key (or after, if it does not exist).
+//
+// The iterator is a consistent snapshot of the database, held in a read
+// transaction until it is exhausted or released. Release iterators promptly,
+// as the database cannot be closed while they are open.
+func (db *Database) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
+	tx, err := db.db.Begin(false)
+	if err != nil {
+		return &iterator{err: err}
+	}
+	it := &iterator{
+		tx:     tx,
+		prefix: common.CopyBytes(prefix),
+		seek:   append(append([]byte{}, prefix...), start...),
+	}
+	if b := tx.Bucket(bucket); b != nil {
+		it.cursor = b.Cursor()
+	} else {
+		it.Release()
+	}
+	return it
+}
+
+// Stat returns a particular internal stat of the database. As bbolt has a single
+// set of statistics, the LevelDB property names are accepted for compatibility.
+func (db *Database) Stat(property string) (string, error) {
+	var bstats bolt.BucketStats
+	if err := db.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket(bucket); b != nil {
+			bstats = b.Stats()
+		}
+		return nil
+	}); err != nil {
+		return "", err
+	}
+	stats := db.db.Stats()
+	return fmt.Sprintf("Keys:%d Depth:%d BranchPages:%d LeafPages:%d LeafInuse(MB):%.5f FreePages:%d PendingPages:%d Writes:%d WriteTime:%v",
+		bstats.KeyN, bstats.Depth, bstats.BranchPageN, bstats.LeafPageN, float64(bstats.LeafInuse)/1024/1024,
+		stats.FreePageN, stats.PendingPageN, stats.TxStats.Write, stats.TxStats.WriteTime), nil
+}
+
+// Compact is a noop for bbolt. The B+tree reuses the pages freed by deletions and
+// overwrites, so there is no background work to flatten the data store.
+func (db *Database) Compact(start []byte, limit []byte) error {
+	return nil
+}
+
+// Path returns the path to the database directory.
+func (db *Database) Path() string {
+	return db.fn
+}
+
+// meter periodically retrieves internal bbolt counters and reports them to the
+// metrics subsystem.
+func (db *Database) meter(refresh time.Duration) {
+	var (
+		errc chan error
+		merr error
+		prev bolt.Stats
+	)
+	timer := time.NewTimer(refresh)
+	defer timer.Stop()
+
+	// Iterate ad infinitum and collect the stats
+	for errc == nil && merr == nil {
+		stats := db.db.Stats()
+		diff := stats.Sub(&prev)
+		prev = stats
+
+		if info, err := os.Stat(db.db.Path()); err != nil {
+			db.log.Error("Failed to read database file size", "err", err)
+			merr = err
+			continue
+		} else {
+			db.diskSizeGauge.Update(info.Size())
+		}
+		db.freePagesGauge.Update(int64(stats.FreePageN))
+		db.writeMeter.Mark(int64(diff.TxStats.Write))
+		db.writeTimeMeter.Mark(diff.TxStats.WriteTime.Nanoseconds())
+
+		// Sleep a bit, then repeat the stats collection
+		select {
+		case errc = <-db.quitChan:
+			// Quit requesting, stop hammering the database
+		case <-timer.C:
+			timer.Reset(refresh)
+			// Timeout, gather a new set of stats
+		}
+	}
+
+	if errc == nil {
+		errc = <-db.quitChan
+	}
+	errc <- merr
+}
+
+// keyvalue is a key-value tuple tagged with a deletion field to allow creating
+// bbolt write batches.
+type keyvalue struct {
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+// apply writes the key-value tuple into the bucket.
+func (kv keyvalue) apply(bkt *bolt.Bucket) error {
+	if kv.delete {
+		return bkt.Delete(kv.key)
+	}
+	return bkt.Put(kv.key, kv.value)
+}
+
+// batch is a write-only bbolt batch that commits changes to its host database
+// when Write is called. A batch cannot be used concurrently.
+type batch struct {
+	db     *Database
+	writes []keyvalue
+	size   int
+}
+
+// Put inserts the given value into the batch for later committing.
+func (b *batch) Put(key, value []byte) error {
+	b.writes = append(b.writes, keyvalue{common.CopyBytes(key), common.CopyBytes(value), false})
+	b.size += len(value)
+	return nil
+}
+
+// Delete inserts the a key removal into the batch for later committing.
+func (b *batch) Delete(key []byte) error {
+	b.writes = append(b.writes, keyvalue{common.CopyBytes(key), nil, true})
+	b.size += len(key)
+	return nil
+}
+
+// ValueSize retrieves the amount of data queued up for writing.
+func (b *batch) ValueSize() int {
+	return b.size
+}
+
+// Write flushes any accumulated data to disk in a single transaction.
+func (b *batch) Write() error {
+	return b.db.commit(b.writes)
+}
+
+// Reset resets the batch for reuse.
+func (b *batch) Reset() {
+	b.writes = b.writes[:0]
+	b.size = 0
+}
+
+// Replay replays the batch contents.
+func (b *batch) Replay(w ethdb.KeyValueWriter) error {
+	for _, kv := range b.writes {
+		if kv.delete {
+			if err := w.Delete(kv.key); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.Put(kv.key, kv.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// iterator can walk over the (potentially partial) keyspace of a bbolt database
+// snapshot, held in a read transaction.
+type iterator struct {
+	tx      *bolt.Tx
+	cursor  *bolt.Cursor
+	prefix  []byte
+	seek    []byte // Position to start the iteration at
+	started bool
+	key     []byte
+	value   []byte
+	err     error
+}
+
+// Next moves the iterator to the next key/value pair. It returns whether the
+// iterator is exhausted.
+func (it *iterator) Next() bool {
+	if it.cursor == nil {
+		return false
+	}
+	var k, v []byte
+	if it.started {
+		k, v = it.cursor.Next()
+	} else {
+		k, v = it.cursor.Seek(it.seek)
+		it.started = true
+	}
+	if k == nil || !bytes.HasPrefix(k, it.prefix) {
+		// Exhausted, don't hold the snapshot any longer
+		it.Release()
+		return false
+	}
+	it.key, it.value = k, v
+	return true
+}
+
+// Error returns any accumulated error. Exhausting all the key/value pairs
+// is not considered to be an error.
+func (it *iterator) Error() error {
+	return it.err
+}
+
+// Key returns the key of the current key/value pair, or nil if done. The caller
+// should not modify the contents of the returned slice, and its contents may
+// change on the next call to Next.
+func (it *iterator) Key() []byte {
+	return it.key
+}
+
+// Value returns the value of the current key/value pair, or nil if done. The
+// caller should not modify the contents of the returned slice, and its contents
+// may change on the next call to Next.
+func (it *iterator) Value() []byte {
+	return it.value
+}
+
+// Release releases associated resources. Release should always succeed and can
+// be called multiple times without causing error.
+func (it *iterator) Release() {
+	if it.tx != nil {
+		it.tx.Rollback()
+		it.tx = nil
+	}
+	it.cursor, it.key, it.value = nil, nil, nil
+}
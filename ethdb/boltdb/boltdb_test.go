@@ -0,0 +1,172 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package boltdb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"PureChain/common"
+	"PureChain/ethdb"
+	"PureChain/ethdb/dbtest"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBoltDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boltdb-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var n int
+	t.Run("DatabaseSuite", func(t *testing.T) {
+		dbtest.TestDatabaseSuite(t, func() ethdb.KeyValueStore {
+			n++
+			db, err := New(fmt.Sprintf("%s/%d", dir, n), "", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return db
+		})
+	})
+}
+
+// Tests that iterators are consistent snapshots of the database, unaffected by
+// the writes made while iterating.
+func TestBoltDBIteratorSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boltdb-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := New(dir, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	items := 3000
+	batch := db.NewBatch()
+	for i := 0; i < items; i++ {
+		batch.Put([]byte(fmt.Sprintf("a%06d", i)), []byte{byte(i)})
+	}
+	batch.Put([]byte("b"), nil)
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	// Single writes are visible to the iterator
+	if err := db.Put([]byte(fmt.Sprintf("a%06d", items)), []byte{byte(items)}); err != nil {
+		t.Fatal(err)
+	}
+	items++
+
+	it := db.NewIterator([]byte("a"), nil)
+	defer it.Release()
+
+	var i int
+	for ; it.Next(); i++ {
+		if want := fmt.Sprintf("a%06d", i); string(it.Key()) != want {
+			t.Fatalf("item %d: key mismatch: have %s, want %s", i, it.Key(), want)
+		}
+		if it.Value()[0] != byte(i) {
+			t.Fatalf("item %d: value mismatch: have %x, want %x", i, it.Value(), byte(i))
+		}
+		// Mutate the database behind the iterator, both singly and in batches
+		if err := db.Delete([]byte(fmt.Sprintf("a%06d", i+1))); err != nil {
+			t.Fatal(err)
+		}
+		if i%500 == 0 {
+			batch.Reset()
+			batch.Put([]byte(fmt.Sprintf("a%06d-new", i)), []byte{0xff})
+			if err := batch.Write(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := it.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if i != items {
+		t.Fatalf("iteration terminated prematurely: have %d, want %d", i, items)
+	}
+	// The writes are all visible once the iteration is done
+	if has, _ := db.Has([]byte(fmt.Sprintf("a%06d", 1))); has {
+		t.Fatalf("deleted item still present")
+	}
+	if has, _ := db.Has([]byte(fmt.Sprintf("a%06d-new", 500))); !has {
+		t.Fatalf("inserted item missing")
+	}
+}
+
+// Tests that single writes are committed right away instead of being held in
+// memory until a later batch.
+func TestBoltDBSingleWrites(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boltdb-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := New(dir, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.Put([]byte("key"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put([]byte("gone"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete([]byte("gone")); err != nil {
+		t.Fatal(err)
+	}
+	// Look the writes up in the committed bucket, bypassing the wrapper
+	var (
+		value     []byte
+		key, gone bool
+	)
+	db.db.View(func(tx *bolt.Tx) error {
+		value, key = lookup(tx.Bucket(bucket), []byte("key"))
+		value = common.CopyBytes(value)
+		_, gone = lookup(tx.Bucket(bucket), []byte("gone"))
+		return nil
+	})
+	if !key || string(value) != "value" {
+		t.Fatalf("single write not committed: have %q", value)
+	}
+	if gone {
+		t.Fatalf("single deletion not committed")
+	}
+	// Batches are only committed when written
+	batch := db.NewBatch()
+	batch.Put([]byte("batched"), []byte("value"))
+	if has, _ := db.Has([]byte("batched")); has {
+		t.Fatalf("batched write committed before the batch was written")
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if has, _ := db.Has([]byte("batched")); !has {
+		t.Fatalf("batched write lost")
+	}
+}
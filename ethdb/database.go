@@ -81,6 +81,10 @@ type AncientReader interface {
 
 	// AncientSize returns the ancient size of the specified category.
 	AncientSize(kind string) (uint64, error)
+
+	// AncientTail returns the number of the first item retained in the ancient
+	// store for the specified category.
+	AncientTail(kind string) (uint64, error)
 }
 
 // AncientWriter contains the methods required to write to immutable ancient data.
@@ -92,6 +96,19 @@ type AncientWriter interface {
 	// TruncateAncients discards all but the first n ancient data from the ancient store.
 	TruncateAncients(n uint64) error
 
+	// TruncateAncientTail discards the ancient data of the specified category below
+	// the given item number, returning the number of the first retained item. The
+	// deletion may be coarse grained, retaining some items below the tail.
+	TruncateAncientTail(kind string, tail uint64) (uint64, error)
+
+	// AppendAncientItem injects a binary blob at the end of an auxiliary table,
+	// which is not kept in lockstep with the chain data.
+	AppendAncientItem(kind string, number uint64, blob []byte) error
+
+	// TruncateAncientItems discards all but the first n items of an auxiliary
+	// table.
+	TruncateAncientItems(kind string, items uint64) error
+
 	// Sync flushes all in-memory ancient store data to disk.
 	Sync() error
 }
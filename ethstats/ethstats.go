@@ -34,6 +34,8 @@ import (
 	"PureChain/common"
 	"PureChain/common/mclock"
 	"PureChain/consensus"
+	"PureChain/consensus/dpos"
+	"PureChain/consensus/inihash"
 	"PureChain/core"
 	"PureChain/core/types"
 	"PureChain/eth/downloader"
@@ -81,6 +83,12 @@ type fullNodeBackend interface {
 	SuggestPrice(ctx context.Context) (*big.Int, error)
 }
 
+// systemPoolBackend is implemented by backends exposing the system transaction
+// lane of the pool, holding the local PoR challenges waiting for inclusion.
+type systemPoolBackend interface {
+	TxPoolSystemContent() map[common.Address]types.Transactions
+}
+
 // Service implements an Ethereum netstats reporting daemon that pushes local
 // chain statistics up to a monitoring server.
 type Service struct {
@@ -92,6 +100,8 @@ type Service struct {
 	pass string // Password to authorize access to the monitoring page
 	host string // Remote address of the monitoring service
 
+	inichain bool // Whether to send the INIChain extension reports
+
 	pongCh chan struct{} // Pong notifications are fed into this channel
 	histCh chan []uint64 // History request block numbers are fed into this channel
 
@@ -144,8 +154,9 @@ func (w *connWrapper) Close() error {
 	return w.conn.Close()
 }
 
-// New returns a monitoring service ready for stats reporting.
-func New(node *node.Node, backend backend, engine consensus.Engine, url string) error {
+// New returns a monitoring service ready for stats reporting. If inichain is set,
+// every block report is followed by an INIChain extension report.
+func New(node *node.Node, backend backend, engine consensus.Engine, url string, inichain bool) error {
 	// Parse the netstats connection url
 	re := regexp.MustCompile("([^:@]*)(:([^@]*))?@(.+)")
 	parts := re.FindStringSubmatch(url)
@@ -153,14 +164,15 @@ func New(node *node.Node, backend backend, engine consensus.Engine, url string)
 		return fmt.Errorf("invalid netstats url: \"%s\", should be nodename:secret@host:port", url)
 	}
 	ethstats := &Service{
-		backend: backend,
-		engine:  engine,
-		server:  node.Server(),
-		node:    parts[1],
-		pass:    parts[3],
-		host:    parts[4],
-		pongCh:  make(chan struct{}),
-		histCh:  make(chan []uint64, 1),
+		backend:  backend,
+		engine:   engine,
+		server:   node.Server(),
+		node:     parts[1],
+		pass:     parts[3],
+		host:     parts[4],
+		inichain: inichain,
+		pongCh:   make(chan struct{}),
+		histCh:   make(chan []uint64, 1),
 	}
 
 	node.RegisterLifecycle(ethstats)
@@ -588,7 +600,13 @@ func (s *Service) reportBlock(conn *connWrapper, block *types.Block) error {
 	report := map[string][]interface{}{
 		"emit": {"block", stats},
 	}
-	return conn.WriteJSON(report)
+	if err := conn.WriteJSON(report); err != nil {
+		return err
+	}
+	if s.inichain {
+		return s.reportINIChain(conn, block)
+	}
+	return nil
 }
 
 // assembleBlockStats retrieves any required metadata to report a single block
@@ -785,3 +803,88 @@ func (s *Service) reportStats(conn *connWrapper) error {
 	}
 	return conn.WriteJSON(report)
 }
+
+// inichainStats is the INIChain extension information reported about a block
+// and the local node.
+type inichainStats struct {
+	Number         *big.Int             `json:"number"`
+	Hash           common.Hash          `json:"hash"`
+	Provider       common.Address       `json:"provider"`
+	TeamAddress    common.Address       `json:"teamAddress"`
+	TeamRate       uint64               `json:"teamRate"`
+	ValidatorRate  uint64               `json:"validatorRate"`
+	InTurn         bool                 `json:"inTurn"`
+	Challenges     []*dpos.PorChallenge `json:"challenges"`     // PoR challenge transactions included in the block
+	Validators     []common.Address     `json:"validators"`     // Validators the local node holds keys of
+	OpenChallenges []*dpos.PorChallenge `json:"openChallenges"` // Local PoR challenges waiting for inclusion
+	Hashrate       float64              `json:"hashrate"`       // Local inihash hashrate
+}
+
+// reportINIChain reports the INIChain specific fields of the given block, or of
+// the current head if block is nil, along with the local validator and PoR
+// status to the stats server.
+func (s *Service) reportINIChain(conn *connWrapper, block *types.Block) error {
+	details := s.assembleINIChainStats(block)
+
+	log.Trace("Sending INIChain stats to ethstats", "number", details.Number, "hash", details.Hash)
+
+	stats := map[string]interface{}{
+		"id":       s.node,
+		"inichain": details,
+	}
+	report := map[string][]interface{}{
+		"emit": {"inichain", stats},
+	}
+	return conn.WriteJSON(report)
+}
+
+// assembleINIChainStats gathers the INIChain extension stats of a single block.
+// If block is nil, the current head is processed.
+func (s *Service) assembleINIChainStats(block *types.Block) *inichainStats {
+	var header *types.Header
+	if fullBackend, ok := s.backend.(fullNodeBackend); ok && block == nil {
+		block = fullBackend.CurrentBlock()
+	}
+	if block != nil {
+		header = block.Header()
+	} else {
+		header = s.backend.CurrentHeader()
+	}
+	stats := &inichainStats{
+		Number:         header.Number,
+		Hash:           header.Hash(),
+		Provider:       header.Provider,
+		TeamAddress:    header.TeamAddress,
+		TeamRate:       header.TeamRate,
+		ValidatorRate:  header.ValidatorRate,
+		Challenges:     []*dpos.PorChallenge{},
+		Validators:     []common.Address{},
+		OpenChallenges: []*dpos.PorChallenge{},
+	}
+	switch engine := s.engine.(type) {
+	case *dpos.Dpos:
+		stats.InTurn = header.Difficulty.Cmp(dpos.DiffInTurn) == 0
+		stats.Validators = engine.LocalValidators()
+
+		// Light nodes would need on-demand lookups for transactions, skip
+		if block != nil {
+			for _, tx := range block.Transactions() {
+				if challenge, ok := engine.DecodePorChallenge(tx); ok {
+					stats.Challenges = append(stats.Challenges, challenge)
+				}
+			}
+		}
+		if pool, ok := s.backend.(systemPoolBackend); ok {
+			for _, txs := range pool.TxPoolSystemContent() {
+				for _, tx := range txs {
+					if challenge, ok := engine.DecodePorChallenge(tx); ok {
+						stats.OpenChallenges = append(stats.OpenChallenges, challenge)
+					}
+				}
+			}
+		}
+	case *inihash.Inihash:
+		stats.Hashrate = engine.Hashrate()
+	}
+	return stats
+}
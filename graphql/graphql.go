@@ -21,23 +21,30 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
 	"time"
 
 	"PureChain"
 	"PureChain/common"
 	"PureChain/common/hexutil"
+	"PureChain/consensus"
+	"PureChain/consensus/dpos"
 	"PureChain/core/rawdb"
 	"PureChain/core/state"
 	"PureChain/core/types"
 	"PureChain/core/vm"
 	"PureChain/eth/filters"
+	"PureChain/eth/tracers"
 	"PureChain/internal/ethapi"
+	"PureChain/params"
 	"PureChain/rpc"
 )
 
 var (
 	errBlockInvariant = errors.New("block objects must be instantiated with at least one of num or hash")
+	errNoDpos         = errors.New("dpos engine is not available")
+	errNoRewards      = errors.New("block rewards are not available on this node")
 )
 
 type Long int64
@@ -96,6 +103,14 @@ func (a *Account) Balance(ctx context.Context) (hexutil.Big, error) {
 	return hexutil.Big(*state.GetBalance(a.address)), nil
 }
 
+func (a *Account) LockBalance(ctx context.Context) (hexutil.Big, error) {
+	state, err := a.getState(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*state.GetLockBalance(a.address)), nil
+}
+
 func (a *Account) TransactionCount(ctx context.Context) (hexutil.Uint64, error) {
 	state, err := a.getState(ctx)
 	if err != nil {
@@ -408,6 +423,22 @@ func (t *Transaction) V(ctx context.Context) (hexutil.Big, error) {
 	return hexutil.Big(*v), nil
 }
 
+func (t *Transaction) IsSystem(ctx context.Context) (bool, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil || t.block == nil {
+		return false, err
+	}
+	posa, ok := t.backend.Engine().(consensus.PoSA)
+	if !ok {
+		return false, nil
+	}
+	header, err := t.block.resolveHeader(ctx)
+	if err != nil || header == nil {
+		return false, err
+	}
+	return posa.IsSystemTransaction(tx, header)
+}
+
 type BlockType int
 
 // Block represents an Ethereum block.
@@ -560,6 +591,46 @@ func (b *Block) Nonce(ctx context.Context) (hexutil.Bytes, error) {
 	return header.Nonce[:], nil
 }
 
+func (b *Block) ExtraNonce(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return header.ExtraNonce[:], nil
+}
+
+func (b *Block) Provider(ctx context.Context) (common.Address, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return header.Provider, nil
+}
+
+func (b *Block) TeamAddress(ctx context.Context) (common.Address, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return header.TeamAddress, nil
+}
+
+func (b *Block) ValidatorRate(ctx context.Context) (Long, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return Long(header.ValidatorRate), nil
+}
+
+func (b *Block) TeamRate(ctx context.Context) (Long, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return Long(header.TeamRate), nil
+}
+
 func (b *Block) MixHash(ctx context.Context) (common.Hash, error) {
 	header, err := b.resolveHeader(ctx)
 	if err != nil {
@@ -654,6 +725,56 @@ func (b *Block) TotalDifficulty(ctx context.Context) (hexutil.Big, error) {
 	return hexutil.Big(*b.backend.GetTd(ctx, h)), nil
 }
 
+// Reward represents a balance change applied by the consensus engine while
+// finalizing a block.
+type Reward struct {
+	kind  consensus.BalanceChangeKind
+	from  common.Address
+	to    common.Address
+	value *big.Int
+}
+
+func (r *Reward) Type() string {
+	return string(r.kind)
+}
+
+func (r *Reward) From() common.Address {
+	return r.from
+}
+
+func (r *Reward) To() common.Address {
+	return r.to
+}
+
+func (r *Reward) Value() hexutil.Big {
+	return hexutil.Big(*r.value)
+}
+
+func (b *Block) Rewards(ctx context.Context) ([]*Reward, error) {
+	backend, ok := b.backend.(tracers.Backend)
+	if !ok {
+		return nil, errNoRewards
+	}
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	frames, err := tracers.NewAPI(backend).TraceBlockRewards(ctx, rpc.BlockNumber(header.Number.Int64()), nil)
+	if err != nil {
+		return nil, err
+	}
+	rewards := make([]*Reward, 0, len(frames))
+	for _, frame := range frames {
+		rewards = append(rewards, &Reward{
+			kind:  frame.Type,
+			from:  frame.From,
+			to:    frame.To,
+			value: (*big.Int)(frame.Value),
+		})
+	}
+	return rewards, nil
+}
+
 // BlockNumberArgs encapsulates arguments to accessors that specify a block number.
 type BlockNumberArgs struct {
 	// TODO: Ideally we could use input unions to allow the query to specify the
@@ -1115,6 +1236,94 @@ func (r *Resolver) ChainID(ctx context.Context) (hexutil.Big, error) {
 	return hexutil.Big(*r.backend.ChainConfig().ChainID), nil
 }
 
+// Provider represents a running PoR provider of the dpos engine.
+type Provider struct {
+	address     common.Address
+	votingPower *big.Int
+}
+
+func (p *Provider) Address() common.Address {
+	return p.address
+}
+
+func (p *Provider) VotingPower() hexutil.Big {
+	return hexutil.Big(*p.votingPower)
+}
+
+// dposHeader resolves the dpos engine together with the header of the block a
+// dpos query was issued at.
+func (r *Resolver) dposHeader(ctx context.Context, args BlockNumberArgs) (*dpos.Dpos, *types.Header, error) {
+	engine, ok := r.backend.Engine().(*dpos.Dpos)
+	if !ok {
+		return nil, nil, errNoDpos
+	}
+	header, err := r.backend.HeaderByNumberOrHash(ctx, args.NumberOrLatest())
+	if err != nil {
+		return nil, nil, err
+	}
+	if header == nil {
+		return nil, nil, errors.New("header not found")
+	}
+	return engine, header, nil
+}
+
+func (r *Resolver) Validators(ctx context.Context, args BlockNumberArgs) ([]common.Address, error) {
+	engine, header, err := r.dposHeader(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return engine.Validators(&chainReader{ctx: ctx, backend: r.backend}, header)
+}
+
+func (r *Resolver) Providers(ctx context.Context, args BlockNumberArgs) ([]*Provider, error) {
+	engine, header, err := r.dposHeader(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := engine.Providers(&chainReader{ctx: ctx, backend: r.backend}, header)
+	if err != nil {
+		return nil, err
+	}
+	providers := make([]*Provider, 0, len(infos))
+	for _, info := range infos {
+		providers = append(providers, &Provider{address: info.ProviderAddress, votingPower: info.VotingPower})
+	}
+	return providers, nil
+}
+
+// chainReader adapts the backend to the chain reader expected by the consensus
+// engine.
+type chainReader struct {
+	ctx     context.Context
+	backend ethapi.Backend
+}
+
+func (c *chainReader) Config() *params.ChainConfig {
+	return c.backend.ChainConfig()
+}
+
+func (c *chainReader) CurrentHeader() *types.Header {
+	return c.backend.CurrentHeader()
+}
+
+func (c *chainReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	header, _ := c.backend.HeaderByHash(c.ctx, hash)
+	if header == nil || header.Number.Uint64() != number {
+		return nil
+	}
+	return header
+}
+
+func (c *chainReader) GetHeaderByNumber(number uint64) *types.Header {
+	header, _ := c.backend.HeaderByNumber(c.ctx, rpc.BlockNumber(number))
+	return header
+}
+
+func (c *chainReader) GetHeaderByHash(hash common.Hash) *types.Header {
+	header, _ := c.backend.HeaderByHash(c.ctx, hash)
+	return header
+}
+
 // SyncState represents the synchronisation status returned from the `syncing` accessor.
 type SyncState struct {
 	progress ethereum.SyncProgress
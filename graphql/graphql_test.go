@@ -17,6 +17,9 @@
 package graphql
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math/big"
@@ -25,7 +28,11 @@ import (
 	"testing"
 	"time"
 
+	"PureChain/accounts/abi/bind/backends"
 	"PureChain/common"
+	"PureChain/common/hexutil"
+	"PureChain/consensus"
+	"PureChain/consensus/dpos/systemcontract"
 	"PureChain/consensus/ethash"
 	"PureChain/core"
 	"PureChain/core/types"
@@ -354,3 +361,188 @@ func createGQLServiceWithTransactions(t *testing.T, stack *node.Node) {
 		t.Fatalf("could not create graphql service: %v", err)
 	}
 }
+
+// Tests the dpos specific fields of the schema on a chain sealed by a simulated
+// dpos backend and imported into a full node.
+func TestGraphQLDpos(t *testing.T) {
+	var (
+		valKey, _  = crypto.GenerateKey()
+		userKey, _ = crypto.GenerateKey()
+		validator  = crypto.PubkeyToAddress(valKey.PublicKey)
+		user       = crypto.PubkeyToAddress(userKey.PublicKey)
+		alloc      = core.GenesisAlloc{user: {Balance: big.NewInt(params.Ether)}}
+	)
+	sim, err := backends.NewSimulatedDposBackend(alloc, 10000000, 0, valKey)
+	if err != nil {
+		t.Fatalf("could not create dpos backend: %v", err)
+	}
+	defer sim.Close()
+
+	// Seal a transfer in every block and a PoR challenge of the validator, a zero
+	// priced call into a system contract that is still an ordinary transaction.
+	var (
+		ctx       = context.Background()
+		signer    = types.LatestSignerForChainID(big.NewInt(1337))
+		recipient = common.HexToAddress("0xb0b")
+		hashes    []common.Hash
+	)
+	for i := uint64(0); i < 4; i++ {
+		head, _ := sim.HeaderByNumber(ctx, nil)
+		tx, _ := types.SignNewTx(userKey, signer, &types.DynamicFeeTx{
+			ChainID:   big.NewInt(1337),
+			Nonce:     i,
+			GasTipCap: big.NewInt(params.GWei),
+			GasFeeCap: new(big.Int).Add(head.BaseFee, big.NewInt(2*params.GWei)),
+			Gas:       params.TxGas,
+			To:        &recipient,
+			Value:     big.NewInt(1),
+		})
+		if err := sim.SendTransaction(ctx, tx); err != nil {
+			t.Fatalf("could not send transaction: %v", err)
+		}
+		if i == 1 {
+			hashes = append(hashes, tx.Hash())
+
+			data := append(crypto.Keccak256([]byte("validatorNotSubmitResult(address)"))[:4], common.LeftPadBytes(validator.Bytes(), 32)...)
+			challenge, _ := types.SignTx(types.NewTransaction(0, systemcontract.ValidatorFactoryContractAddr, new(big.Int), 1000000, new(big.Int), data), signer, valKey)
+			if err := sim.SendTransaction(ctx, challenge); err != nil {
+				t.Fatalf("could not send challenge: %v", err)
+			}
+			hashes = append(hashes, challenge.Hash())
+		}
+		sim.Commit()
+	}
+	// Import the sealed chain into an archive node serving graphql
+	stack := createNode(t, false, false)
+	defer stack.Close()
+
+	ethBackend, err := eth.New(stack, &ethconfig.Config{
+		Genesis:        backends.SimulatedDposGenesis(alloc, 10000000, 0, valKey),
+		NetworkId:      1337,
+		NoPruning:      true,
+		TrieCleanCache: 5,
+		TrieDirtyCache: 5,
+		TrieTimeout:    60 * time.Minute,
+		SnapshotCache:  5,
+	})
+	if err != nil {
+		t.Fatalf("could not create eth backend: %v", err)
+	}
+	blocks := make([]*types.Block, 0, 4)
+	for i := uint64(1); i <= 4; i++ {
+		blocks = append(blocks, sim.Blockchain().GetBlockByNumber(i))
+	}
+	if _, err := ethBackend.BlockChain().InsertChain(blocks); err != nil {
+		t.Fatalf("could not import blocks: %v", err)
+	}
+	if err := New(stack, ethBackend.APIBackend, []string{}, []string{}); err != nil {
+		t.Fatalf("could not create graphql service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	query := func(query string, result interface{}) {
+		body, _ := json.Marshal(map[string]string{"query": query})
+		resp, err := http.Post(fmt.Sprintf("%s/graphql", stack.HTTPEndpoint()), "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("could not post: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var reply struct {
+			Data   json.RawMessage `json:"data"`
+			Errors []interface{}   `json:"errors"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+			t.Fatalf("could not decode reply to %s: %v", query, err)
+		}
+		if len(reply.Errors) > 0 {
+			t.Fatalf("query %s failed: %v", query, reply.Errors)
+		}
+		if err := json.Unmarshal(reply.Data, result); err != nil {
+			t.Fatalf("could not decode %s: %v", reply.Data, err)
+		}
+	}
+	// The header fields and rewards of a block match the sealed header
+	var header struct {
+		Block struct {
+			Provider      common.Address
+			TeamAddress   common.Address
+			ValidatorRate uint64
+			TeamRate      uint64
+			Miner         struct {
+				Address     common.Address
+				LockBalance hexutil.Big
+			}
+			Rewards []struct {
+				Type  string
+				From  common.Address
+				To    common.Address
+				Value hexutil.Big
+			}
+		}
+	}
+	query(`{block(number:4){provider teamAddress validatorRate teamRate miner{address lockBalance} rewards{type from to value}}}`, &header)
+
+	want := blocks[3].Header()
+	if have := header.Block; have.Provider != want.Provider || have.TeamAddress != want.TeamAddress || have.ValidatorRate != want.ValidatorRate || have.TeamRate != want.TeamRate {
+		t.Errorf("header fields mismatch: have %+v, want provider %x team %x rates %d/%d", have, want.Provider, want.TeamAddress, want.ValidatorRate, want.TeamRate)
+	}
+	if header.Block.Miner.Address != validator {
+		t.Errorf("miner mismatch: have %x, want %x", header.Block.Miner.Address, validator)
+	}
+	// Rewards are only locked a day into the chain
+	if lock := header.Block.Miner.LockBalance.ToInt(); lock.Sign() != 0 {
+		t.Errorf("lock balance mismatch: have %v, want 0", lock)
+	}
+	// The tips of the four blocks are swept out of the system account
+	swept := new(big.Int).SetUint64(4 * params.TxGas * params.GWei)
+	found := false
+	for _, reward := range header.Block.Rewards {
+		if reward.Type == "FEE_SWEEP" {
+			found = true
+			if reward.From != consensus.SystemAddress || reward.To != (common.Address{}) || reward.Value.ToInt().Cmp(swept) != 0 {
+				t.Errorf("fee sweep mismatch: have %x -> %x %v, want %v", reward.From, reward.To, reward.Value.ToInt(), swept)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("fee sweep missing from rewards %+v", header.Block.Rewards)
+	}
+	// Neither the transfer nor the challenge are system transactions
+	var txs struct {
+		Block struct {
+			Transactions []struct {
+				Hash     common.Hash
+				IsSystem bool
+			}
+		}
+	}
+	query(`{block(number:2){transactions{hash isSystem}}}`, &txs)
+	if len(txs.Block.Transactions) != len(hashes) {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(txs.Block.Transactions), len(hashes))
+	}
+	for i, tx := range txs.Block.Transactions {
+		if tx.Hash != hashes[i] || tx.IsSystem {
+			t.Errorf("transaction %d mismatch: have %x system %v, want %x", i, tx.Hash, tx.IsSystem, hashes[i])
+		}
+	}
+	// The genesis validator keeps sealing without any PoR provider
+	var engine struct {
+		Validators []common.Address
+		Genesis    []common.Address
+		Providers  []struct {
+			Address common.Address
+		}
+	}
+	query(`{validators providers{address} genesis: validators(block:0)}`, &engine)
+	if len(engine.Validators) != 1 || engine.Validators[0] != validator {
+		t.Errorf("validators mismatch: have %x, want [%x]", engine.Validators, validator)
+	}
+	if len(engine.Genesis) != 1 || engine.Genesis[0] != validator {
+		t.Errorf("genesis validators mismatch: have %x, want [%x]", engine.Genesis, validator)
+	}
+	if len(engine.Providers) != 0 {
+		t.Errorf("providers mismatch: have %+v, want none", engine.Providers)
+	}
+}
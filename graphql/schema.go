@@ -42,6 +42,9 @@ const schema string = `
         address: Address!
         # Balance is the balance of the account, in wei.
         balance: BigInt!
+        # LockBalance is the part of the account's funds locked by the consensus
+        # engine until released by later rewards, in wei.
+        lockBalance: BigInt!
         # TransactionCount is the number of transactions sent from this account,
         # or in the case of a contract, the number of contracts created. Otherwise
         # known as the nonce.
@@ -127,6 +130,10 @@ const schema string = `
         #Envelope transaction support
         type: Int
         accessList: [AccessTuple!]
+        # IsSystem is true if the transaction is a system transaction of the
+        # consensus engine, applied while finalizing its block. It is always
+        # false for pending transactions.
+        isSystem: Boolean!
     }
 
     # BlockFilterCriteria encapsulates log filter criteria for a filter applied
@@ -149,6 +156,29 @@ const schema string = `
         topics: [[Bytes32!]!]
     }
 
+    # Reward is a balance change applied by the consensus engine outside of any
+    # transaction while finalizing a block.
+    type Reward {
+        # Type is the kind of the balance change, e.g. REWARD, LOCK, UNLOCK,
+        # FEE_SWEEP, BASE_FEE or BURN.
+        type: String!
+        # From is the account the value was taken from, the zero address for
+        # minted rewards.
+        from: Address!
+        # To is the account the value was paid to.
+        to: Address!
+        # Value is the amount of the balance change, in wei.
+        value: BigInt!
+    }
+
+    # Provider is a running PoR provider of the dpos engine.
+    type Provider {
+        # Address is the address owning the provider.
+        address: Address!
+        # VotingPower is the weight of the provider in the provider selection.
+        votingPower: BigInt!
+    }
+
     # Block is an Ethereum block.
     type Block {
         # Number is the number of this block, starting at 0 for the genesis block.
@@ -159,6 +189,16 @@ const schema string = `
         parent: Block
         # Nonce is the block nonce, an 8 byte sequence determined by the miner.
         nonce: Bytes!
+        # ExtraNonce is the additional 8 byte nonce sequence of the block.
+        extraNonce: Bytes!
+        # Provider is the PoR provider selected for this block.
+        provider: Address!
+        # TeamAddress is the account receiving the team share of the rewards.
+        teamAddress: Address!
+        # ValidatorRate is the validator share of the block rewards.
+        validatorRate: Long!
+        # TeamRate is the team share of the block rewards.
+        teamRate: Long!
         # TransactionsRoot is the keccak256 hash of the root of the trie of transactions in this block.
         transactionsRoot: Bytes32!
         # TransactionCount is the number of transactions in this block. if
@@ -188,6 +228,10 @@ const schema string = `
         # TotalDifficulty is the sum of all difficulty values up to and including
         # this block.
         totalDifficulty: BigInt!
+        # Rewards breaks down the balance changes applied by the consensus engine
+        # while finalizing this block. Computing them replays the block, so the
+        # state of its parent has to be available.
+        rewards: [Reward!]!
         # OmmerCount is the number of ommers (AKA uncles) associated with this
         # block. If ommers are unavailable, this field will be null.
         ommerCount: Int
@@ -323,6 +367,12 @@ const schema string = `
         syncing: SyncState
         # ChainID returns the current chain ID for transaction replay protection.
         chainID: BigInt!
+        # Validators returns the validator set of the dpos engine at the given
+        # block, or at the latest block if none is supplied.
+        validators(block: Long): [Address!]!
+        # Providers returns the running PoR providers of the dpos engine as of the
+        # given block, or the latest block if none is supplied.
+        providers(block: Long): [Provider!]!
     }
 
     type Mutation {
@@ -0,0 +1,491 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"PureChain/common"
+	"PureChain/common/gopool"
+	"PureChain/common/hexutil"
+	"PureChain/consensus"
+	"PureChain/consensus/misc"
+	"PureChain/core"
+	"PureChain/core/state"
+	"PureChain/core/types"
+	"PureChain/core/vm"
+	"PureChain/crypto"
+	"PureChain/log"
+	"PureChain/params"
+	"PureChain/rpc"
+)
+
+const (
+	// maxSimulateBlocks is the maximum number of blocks simulated in one call.
+	maxSimulateBlocks = 256
+
+	// maxSimulateCalls is the maximum number of calls simulated in one call,
+	// across all blocks.
+	maxSimulateCalls = 1000
+
+	// simulateTimeout is the time after which a simulation is aborted.
+	simulateTimeout = 5 * time.Second
+
+	// simulateBlockTime is the default time between simulated blocks for engines
+	// without a fixed block period.
+	simulateBlockTime = 12
+)
+
+// BlockOverrides is the set of header fields to override in a simulated block.
+type BlockOverrides struct {
+	Number     *hexutil.Big    `json:"number"`
+	Time       *hexutil.Uint64 `json:"time"`
+	GasLimit   *hexutil.Uint64 `json:"gasLimit"`
+	Coinbase   *common.Address `json:"coinbase"`
+	Provider   *common.Address `json:"provider"`
+	Difficulty *hexutil.Big    `json:"difficulty"`
+	BaseFee    *hexutil.Big    `json:"baseFee"`
+}
+
+// Apply overrides the given header fields.
+func (o *BlockOverrides) Apply(header *types.Header) {
+	if o == nil {
+		return
+	}
+	if o.Number != nil {
+		header.Number = new(big.Int).Set(o.Number.ToInt())
+	}
+	if o.Time != nil {
+		header.Time = uint64(*o.Time)
+	}
+	if o.GasLimit != nil {
+		header.GasLimit = uint64(*o.GasLimit)
+	}
+	if o.Coinbase != nil {
+		header.Coinbase = *o.Coinbase
+	}
+	if o.Provider != nil {
+		header.Provider = *o.Provider
+	}
+	if o.Difficulty != nil {
+		header.Difficulty = new(big.Int).Set(o.Difficulty.ToInt())
+	}
+	if o.BaseFee != nil {
+		header.BaseFee = new(big.Int).Set(o.BaseFee.ToInt())
+	}
+}
+
+// SimulateBlock is a hypothetical block of calls to simulate. The state
+// overrides are applied before the calls of the block are executed.
+type SimulateBlock struct {
+	BlockOverrides *BlockOverrides `json:"blockOverrides"`
+	StateOverrides *StateOverride  `json:"stateOverrides"`
+	Calls          []CallArgs      `json:"calls"`
+}
+
+// SimulatedCallResult is the outcome of a call executed in a simulated block.
+type SimulatedCallResult struct {
+	ReturnData      hexutil.Bytes   `json:"returnData"`
+	Logs            []*types.Log    `json:"logs"`
+	GasUsed         hexutil.Uint64  `json:"gasUsed"`
+	Status          hexutil.Uint64  `json:"status"`
+	ContractAddress *common.Address `json:"contractAddress"`
+	Error           string          `json:"error,omitempty"`
+}
+
+// SimulatedBalanceChange is a balance change applied by the consensus engine
+// while finalizing a simulated block.
+type SimulatedBalanceChange struct {
+	Type  consensus.BalanceChangeKind `json:"type"`
+	From  common.Address              `json:"from"`
+	To    common.Address              `json:"to"`
+	Value *hexutil.Big                `json:"value"`
+}
+
+// simulateTracer collects the balance changes reported by the consensus engine.
+type simulateTracer struct {
+	changes []*SimulatedBalanceChange
+}
+
+// CaptureBalanceChange implements consensus.BalanceTracer.
+func (t *simulateTracer) CaptureBalanceChange(kind consensus.BalanceChangeKind, from, to common.Address, value *big.Int) {
+	t.changes = append(t.changes, &SimulatedBalanceChange{Type: kind, From: from, To: to, Value: (*hexutil.Big)(value)})
+}
+
+// simulatedChain is the chain reader handed to the EVM and the consensus engine
+// while simulating, extending the base block with the simulated headers.
+type simulatedChain struct {
+	ctx     context.Context
+	b       Backend
+	base    *types.Header
+	headers map[common.Hash]*types.Header  // Simulated headers by hash
+	numbers map[uint64]*types.Header       // Simulated headers by number
+	states  map[common.Hash]*state.StateDB // Post states of the simulated blocks by hash
+	tracer  consensus.BalanceTracer
+}
+
+// add extends the chain with a simulated header and its post state.
+func (c *simulatedChain) add(header *types.Header, statedb *state.StateDB) {
+	hash := header.Hash()
+	c.headers[hash] = header
+	c.numbers[header.Number.Uint64()] = header
+	c.states[hash] = statedb.Copy()
+}
+
+func (c *simulatedChain) Engine() consensus.Engine {
+	return c.b.Engine()
+}
+
+func (c *simulatedChain) Config() *params.ChainConfig {
+	return c.b.ChainConfig()
+}
+
+func (c *simulatedChain) CurrentHeader() *types.Header {
+	return c.b.CurrentHeader()
+}
+
+func (c *simulatedChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.GetHeaderByHash(hash); header != nil && header.Number.Uint64() == number {
+		return header
+	}
+	return nil
+}
+
+func (c *simulatedChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number > c.base.Number.Uint64() {
+		return c.numbers[number]
+	}
+	header, _ := c.b.HeaderByNumber(c.ctx, rpc.BlockNumber(number))
+	return header
+}
+
+func (c *simulatedChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	if header, ok := c.headers[hash]; ok {
+		return header
+	}
+	header, _ := c.b.HeaderByHash(c.ctx, hash)
+	return header
+}
+
+func (c *simulatedChain) BalanceTracer() consensus.BalanceTracer {
+	return c.tracer
+}
+
+func (c *simulatedChain) Simulated(header *types.Header) bool {
+	_, ok := c.states[header.Hash()]
+	return ok
+}
+
+func (c *simulatedChain) SimulatedState(header *types.Header) *state.StateDB {
+	if statedb, ok := c.states[header.Hash()]; ok {
+		return statedb.Copy()
+	}
+	return nil
+}
+
+// SimulateBlocks executes the calls of a sequence of hypothetical blocks on top
+// of the given block, each block seeing the state left by the previous ones and
+// being finalized by the consensus engine. Per block, the header, the results
+// of the calls and the balance changes of the engine are returned.
+//
+// Note, the simulated blocks are not signed nor persisted. Blocks overriding the
+// number beyond the next one are preceded by empty blocks filling the gap, which
+// are returned too. The logs of a call carry a placeholder transaction hash
+// derived from the block number and the position of the call.
+func (s *PublicBlockChainAPI) SimulateBlocks(ctx context.Context, blocks []SimulateBlock, blockNrOrHash *rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	if len(blocks) == 0 {
+		return nil, errors.New("no blocks to simulate")
+	}
+	if len(blocks) > maxSimulateBlocks {
+		return nil, fmt.Errorf("too many blocks to simulate: %d > %d", len(blocks), maxSimulateBlocks)
+	}
+	calls := 0
+	for _, block := range blocks {
+		calls += len(block.Calls)
+	}
+	if calls > maxSimulateCalls {
+		return nil, fmt.Errorf("too many calls to simulate: %d > %d", calls, maxSimulateCalls)
+	}
+	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
+	return DoSimulateBlocks(ctx, s.b, blocks, bNrOrHash, simulateTimeout, s.b.RPCGasCap())
+}
+
+// DoSimulateBlocks executes the simulated blocks on top of the given block.
+func DoSimulateBlocks(ctx context.Context, b Backend, blocks []SimulateBlock, blockNrOrHash rpc.BlockNumberOrHash, timeout time.Duration, globalGasCap uint64) ([]map[string]interface{}, error) {
+	defer func(start time.Time) { log.Debug("Simulating blocks finished", "runtime", time.Since(start)) }(time.Now())
+
+	statedb, base, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	// Setup context so it may be cancelled when the simulation has completed
+	// or the timeout expired.
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	chain := &simulatedChain{
+		ctx:     ctx,
+		b:       b,
+		base:    base,
+		headers: make(map[common.Hash]*types.Header),
+		numbers: make(map[uint64]*types.Header),
+		states:  make(map[common.Hash]*state.StateDB),
+	}
+	var (
+		parent  = base
+		results = make([]map[string]interface{}, 0, len(blocks))
+	)
+	for i, block := range blocks {
+		number := new(big.Int).Add(parent.Number, common.Big1)
+		if block.BlockOverrides != nil && block.BlockOverrides.Number != nil {
+			number = block.BlockOverrides.Number.ToInt()
+		}
+		if number.Cmp(parent.Number) <= 0 {
+			return nil, fmt.Errorf("block %d: number %v not above parent number %v", i, number, parent.Number)
+		}
+		if gap := new(big.Int).Sub(number, parent.Number); gap.Cmp(big.NewInt(int64(maxSimulateBlocks-len(results)))) > 0 {
+			return nil, fmt.Errorf("block %d: too many blocks to simulate up to number %v", i, number)
+		}
+		// Fill the gap up to an overridden number with empty blocks
+		for parent.Number.Uint64()+1 < number.Uint64() {
+			header, err := simulatedHeader(chain, parent, new(big.Int).Add(parent.Number, common.Big1))
+			if err != nil {
+				return nil, fmt.Errorf("block %d: %w", i, err)
+			}
+			result, err := simulateBlock(ctx, b, chain, statedb, header, nil, globalGasCap)
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("simulation aborted (timeout = %v)", timeout)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("block %d: gap block %v: %w", i, header.Number, err)
+			}
+			chain.add(header, statedb)
+
+			results = append(results, result)
+			parent = header
+		}
+		header, err := simulatedHeader(chain, parent, number)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		block.BlockOverrides.Apply(header)
+
+		if header.Time <= parent.Time {
+			return nil, fmt.Errorf("block %d: timestamp %d not above parent timestamp %d", i, header.Time, parent.Time)
+		}
+		if err := block.StateOverrides.Apply(statedb); err != nil {
+			return nil, fmt.Errorf("block %d: %v", i, err)
+		}
+		result, err := simulateBlock(ctx, b, chain, statedb, header, block.Calls, globalGasCap)
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("simulation aborted (timeout = %v)", timeout)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		chain.add(header, statedb)
+
+		results = append(results, result)
+		parent = header
+	}
+	return results, nil
+}
+
+// simulatedHeader creates the default header of a simulated block on top of the
+// given parent, inheriting its fields but for the ones determined by the engine
+// and the execution.
+func simulatedHeader(chain *simulatedChain, parent *types.Header, number *big.Int) (*types.Header, error) {
+	config := chain.Config()
+
+	period := uint64(simulateBlockTime)
+	if config.Dpos != nil && config.Dpos.Period > 0 {
+		period = config.Dpos.Period
+	}
+	header := &types.Header{
+		ParentHash:    parent.Hash(),
+		UncleHash:     types.EmptyUncleHash,
+		Coinbase:      parent.Coinbase,
+		Difficulty:    new(big.Int).Set(parent.Difficulty),
+		Number:        new(big.Int).Set(number),
+		GasLimit:      parent.GasLimit,
+		Time:          parent.Time + period,
+		Provider:      parent.Provider,
+		TeamAddress:   parent.TeamAddress,
+		ValidatorRate: parent.ValidatorRate,
+		TeamRate:      parent.TeamRate,
+	}
+	if config.IsLondon(header.Number) {
+		header.BaseFee = misc.CalcBaseFee(config, parent)
+	}
+	if simulator, ok := chain.Engine().(consensus.BlockSimulator); ok {
+		if err := simulator.PrepareSimulated(chain, header); err != nil {
+			return nil, err
+		}
+	}
+	return header, nil
+}
+
+// simulateBlock executes the calls of a simulated block and finalizes it. The
+// header is completed with the post state of the block.
+func simulateBlock(ctx context.Context, b Backend, chain *simulatedChain, statedb *state.StateDB, header *types.Header, calls []CallArgs, globalGasCap uint64) (map[string]interface{}, error) {
+	var (
+		config  = b.ChainConfig()
+		engine  = b.Engine()
+		tracer  = new(simulateTracer)
+		gp      = new(core.GasPool).AddGas(header.GasLimit)
+		usedGas = new(uint64)
+
+		txs       = make([]*types.Transaction, 0, len(calls))
+		systemTxs = make([]*types.Transaction, 0)
+		receipts  = make([]*types.Receipt, 0, len(calls))
+		results   = make([]*SimulatedCallResult, 0, len(calls))
+	)
+	chain.tracer = tracer
+
+	if posa, ok := engine.(consensus.PoSA); ok {
+		if err := posa.PreHandle(chain, header, statedb); err != nil {
+			return nil, err
+		}
+	}
+	evm := vm.NewEVM(core.NewEVMBlockContext(header, chain, &header.Coinbase), vm.TxContext{}, statedb, config, vm.Config{NoBaseFee: true})
+
+	// Wait for the context to be done and cancel the evm. Even if the
+	// EVM has finished, cancelling may be done (repeatedly)
+	gopool.Submit(func() {
+		<-ctx.Done()
+		evm.Cancel()
+	})
+	for i, args := range calls {
+		// Calls without a gas allowance may use up the remaining block gas
+		if args.Gas == nil {
+			gas := hexutil.Uint64(gp.Gas())
+			args.Gas = &gas
+		}
+		var (
+			msg    = args.ToMessage(globalGasCap, header.BaseFee)
+			nonce  = statedb.GetNonce(msg.From())
+			txHash = simulatedTxHash(header.Number.Uint64(), i)
+		)
+		statedb.Prepare(txHash, common.Hash{}, i)
+		evm.Reset(core.NewEVMTxContext(msg), statedb)
+
+		result, err := core.ApplyMessage(evm, msg, gp)
+		if evm.Cancelled() {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w (supplied gas %d)", i, err, msg.Gas())
+		}
+		if config.IsByzantium(header.Number) {
+			statedb.Finalise(true)
+		} else {
+			statedb.IntermediateRoot(config.IsEIP158(header.Number))
+		}
+		*usedGas += result.UsedGas
+
+		receipt := &types.Receipt{
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: *usedGas,
+			TxHash:            txHash,
+			GasUsed:           result.UsedGas,
+			Logs:              statedb.GetLogs(txHash),
+			BlockNumber:       header.Number,
+			TransactionIndex:  uint(i),
+		}
+		call := &SimulatedCallResult{
+			ReturnData: result.Return(),
+			Logs:       receipt.Logs,
+			GasUsed:    hexutil.Uint64(result.UsedGas),
+			Status:     hexutil.Uint64(types.ReceiptStatusSuccessful),
+		}
+		if result.Failed() {
+			receipt.Status = types.ReceiptStatusFailed
+			call.Status = hexutil.Uint64(types.ReceiptStatusFailed)
+			call.ReturnData = result.Revert()
+			call.Error = result.Err.Error()
+			if len(result.Revert()) > 0 {
+				call.Error = newRevertError(result).Error()
+			}
+		} else if msg.To() == nil {
+			address := crypto.CreateAddress(msg.From(), nonce)
+			receipt.ContractAddress, call.ContractAddress = address, &address
+		}
+		if call.Logs == nil {
+			call.Logs = []*types.Log{}
+		}
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+
+		txs = append(txs, types.NewTx(&types.LegacyTx{Nonce: nonce, To: msg.To(), Value: msg.Value(), Gas: msg.Gas(), GasPrice: msg.GasPrice(), Data: msg.Data()}))
+		receipts = append(receipts, receipt)
+		results = append(results, call)
+	}
+	var err error
+	if simulator, ok := engine.(consensus.BlockSimulator); ok {
+		err = simulator.FinalizeSimulated(chain, header, statedb, &txs, &receipts, usedGas)
+	} else {
+		err = engine.Finalize(chain, header, statedb, &txs, nil, &receipts, &systemTxs, usedGas, true)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("finalize: %w", err)
+	}
+	header.GasUsed = *usedGas
+	header.Bloom = types.CreateBloom(receipts)
+	header.Root = statedb.IntermediateRoot(config.IsEIP158(header.Number))
+
+	// The statedb is shared by all the blocks, number the logs from the block start
+	var (
+		hash  = header.Hash()
+		index uint
+	)
+	for _, receipt := range receipts {
+		for _, l := range receipt.Logs {
+			l.BlockHash = hash
+			l.Index = index
+			index++
+		}
+	}
+	changes := tracer.changes
+	if changes == nil {
+		changes = []*SimulatedBalanceChange{}
+	}
+	fields := RPCMarshalHeader(header)
+	fields["calls"] = results
+	fields["balanceChanges"] = changes
+	return fields, nil
+}
+
+// simulatedTxHash returns the placeholder hash of the call at the given position
+// of a simulated block.
+func simulatedTxHash(number uint64, index int) common.Hash {
+	var blob [16]byte
+	binary.BigEndian.PutUint64(blob[:8], number)
+	binary.BigEndian.PutUint64(blob[8:], uint64(index))
+	return crypto.Keccak256Hash(blob[:])
+}
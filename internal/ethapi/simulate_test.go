@@ -0,0 +1,226 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi_test
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"PureChain/accounts/abi/bind/backends"
+	"PureChain/common"
+	"PureChain/common/hexutil"
+	"PureChain/consensus"
+	"PureChain/core"
+	"PureChain/core/types"
+	"PureChain/crypto"
+	"PureChain/eth"
+	"PureChain/eth/ethconfig"
+	"PureChain/internal/ethapi"
+	"PureChain/node"
+	"PureChain/params"
+)
+
+// simulateTestCode deploys a contract logging the first word of the calldata.
+// It returns the block number, or reverts if the word is zero.
+var simulateTestCode = common.FromHex("601f600c600039601f6000f3" + "60003580600052602060" + "00a0156019574360005260206000f35b60206000fd")
+
+// simulatedBlock is the part of a simulated block checked by the tests.
+type simulatedBlock struct {
+	Number         *hexutil.Big                    `json:"number"`
+	Hash           common.Hash                     `json:"hash"`
+	ParentHash     common.Hash                     `json:"parentHash"`
+	Miner          common.Address                  `json:"miner"`
+	Provider       common.Address                  `json:"provider"`
+	Time           hexutil.Uint64                  `json:"timestamp"`
+	Extra          hexutil.Bytes                   `json:"extraData"`
+	GasUsed        hexutil.Uint64                  `json:"gasUsed"`
+	Calls          []ethapi.SimulatedCallResult    `json:"calls"`
+	BalanceChanges []ethapi.SimulatedBalanceChange `json:"balanceChanges"`
+}
+
+// Tests that blocks simulated on top of a dpos chain see the state left by the
+// previous ones, honour the block overrides and are finalized by the engine
+// across an epoch.
+func TestSimulateBlocksDpos(t *testing.T) {
+	var (
+		valKey, _  = crypto.GenerateKey()
+		userKey, _ = crypto.GenerateKey()
+		validator  = crypto.PubkeyToAddress(valKey.PublicKey)
+		user       = crypto.PubkeyToAddress(userKey.PublicKey)
+		alloc      = core.GenesisAlloc{user: {Balance: big.NewInt(params.Ether)}}
+		epoch      = uint64(4)
+	)
+	sim, err := backends.NewSimulatedDposBackend(alloc, 10000000, epoch, valKey)
+	if err != nil {
+		t.Fatalf("could not create dpos backend: %v", err)
+	}
+	defer sim.Close()
+
+	head, _ := sim.HeaderByNumber(context.Background(), nil)
+	tx, _ := types.SignTx(types.NewTransaction(0, common.Address{0x1}, big.NewInt(1), params.TxGas, new(big.Int).Mul(head.BaseFee, big.NewInt(2)), nil), types.NewEIP155Signer(big.NewInt(1337)), userKey)
+	if err := sim.SendTransaction(context.Background(), tx); err != nil {
+		t.Fatalf("could not send transaction: %v", err)
+	}
+	sim.Commit()
+
+	// Import the sealed chain into a node serving the simulation
+	stack, err := node.New(&node.Config{})
+	if err != nil {
+		t.Fatalf("could not create node: %v", err)
+	}
+	defer stack.Close()
+
+	ethBackend, err := eth.New(stack, &ethconfig.Config{
+		Genesis:        backends.SimulatedDposGenesis(alloc, 10000000, epoch, valKey),
+		NetworkId:      1337,
+		NoPruning:      true,
+		TrieCleanCache: 5,
+		TrieDirtyCache: 5,
+		TrieTimeout:    60 * time.Minute,
+		SnapshotCache:  5,
+	})
+	if err != nil {
+		t.Fatalf("could not create eth backend: %v", err)
+	}
+	if _, err := ethBackend.BlockChain().InsertChain(types.Blocks{sim.Blockchain().GetBlockByNumber(1)}); err != nil {
+		t.Fatalf("could not import blocks: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	client, err := stack.Attach()
+	if err != nil {
+		t.Fatalf("could not attach to node: %v", err)
+	}
+	defer client.Close()
+
+	// Deploy the contract in the first block, call it in the next one with the
+	// header overridden, and once more after a gap spanning the epoch block
+	var (
+		contract  = crypto.CreateAddress(user, 1)
+		coinbase  = common.Address{0xc0}
+		provider  = common.Address{0xa0}
+		timestamp = hexutil.Uint64(head.Time + 1000)
+		number    = (*hexutil.Big)(big.NewInt(5))
+		tip       = big.NewInt(params.GWei)
+
+		call = func(word byte, tip *big.Int) ethapi.CallArgs {
+			args := ethapi.CallArgs{From: &user, To: &contract}
+			data := hexutil.Bytes(common.LeftPadBytes([]byte{word}, 32))
+			args.Data = &data
+			if tip != nil {
+				gas := hexutil.Uint64(100000)
+				args.Gas = &gas
+				args.MaxFeePerGas = (*hexutil.Big)(big.NewInt(100 * params.GWei))
+				args.MaxPriorityFeePerGas = (*hexutil.Big)(tip)
+			}
+			return args
+		}
+		code = hexutil.Bytes(simulateTestCode)
+	)
+	sealed := ethBackend.BlockChain().CurrentHeader()
+	blocks := []ethapi.SimulateBlock{
+		{Calls: []ethapi.CallArgs{{From: &user, Data: &code}}},
+		{
+			BlockOverrides: &ethapi.BlockOverrides{Time: &timestamp, Coinbase: &coinbase, Provider: &provider},
+			Calls:          []ethapi.CallArgs{call(7, tip), call(0, nil)},
+		},
+		{
+			BlockOverrides: &ethapi.BlockOverrides{Number: number},
+			Calls:          []ethapi.CallArgs{call(9, nil)},
+		},
+	}
+	var results []*simulatedBlock
+	if err := client.Call(&results, "eth_simulateBlocks", blocks, "latest"); err != nil {
+		t.Fatalf("could not simulate blocks: %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("simulated block count mismatch: have %d, want %d", len(results), 4)
+	}
+	parent := sealed.Hash()
+	for i, block := range results {
+		if have, want := block.Number.ToInt().Uint64(), sealed.Number.Uint64()+uint64(i)+1; have != want {
+			t.Errorf("block %d: number mismatch: have %d, want %d", i, have, want)
+		}
+		if block.ParentHash != parent {
+			t.Errorf("block %d: parent hash mismatch: have %x, want %x", i, block.ParentHash, parent)
+		}
+		parent = block.Hash
+	}
+	// The deployment carries over to the next blocks
+	deploy := results[0].Calls[0]
+	if deploy.Status != hexutil.Uint64(types.ReceiptStatusSuccessful) || deploy.ContractAddress == nil || *deploy.ContractAddress != contract {
+		t.Errorf("deployment mismatch: status %d, address %v, error %q", deploy.Status, deploy.ContractAddress, deploy.Error)
+	}
+	if results[0].Miner != validator {
+		t.Errorf("default coinbase mismatch: have %x, want %x", results[0].Miner, validator)
+	}
+	// The overridden header is executed against and the calls of the block are
+	// reported separately, logs and gas included
+	block := results[1]
+	if block.Miner != coinbase || block.Provider != provider || block.Time != timestamp {
+		t.Errorf("overrides mismatch: miner %x, provider %x, time %d", block.Miner, block.Provider, block.Time)
+	}
+	if len(block.Calls) != 2 {
+		t.Fatalf("call count mismatch: have %d, want %d", len(block.Calls), 2)
+	}
+	logged := block.Calls[0]
+	if logged.Status != hexutil.Uint64(types.ReceiptStatusSuccessful) || new(big.Int).SetBytes(logged.ReturnData).Uint64() != 3 {
+		t.Errorf("call mismatch: status %d, return %x, error %q", logged.Status, logged.ReturnData, logged.Error)
+	}
+	if len(logged.Logs) != 1 || logged.Logs[0].Address != contract || logged.Logs[0].Data[31] != 7 || logged.Logs[0].Index != 0 || logged.Logs[0].BlockHash != block.Hash {
+		t.Errorf("call logs mismatch: %+v", logged.Logs)
+	}
+	if logged.GasUsed <= hexutil.Uint64(params.TxGas) || block.GasUsed <= logged.GasUsed {
+		t.Errorf("gas used mismatch: call %d, block %d", logged.GasUsed, block.GasUsed)
+	}
+	reverted := block.Calls[1]
+	if reverted.Status != hexutil.Uint64(types.ReceiptStatusFailed) || !strings.Contains(reverted.Error, "execution reverted") || len(reverted.Logs) != 0 {
+		t.Errorf("reverted call mismatch: status %d, error %q, logs %d", reverted.Status, reverted.Error, len(reverted.Logs))
+	}
+	// The epoch block filling the gap names the validators, and the tips paid so
+	// far are swept by the engine once past the third block
+	gap := results[2]
+	if len(gap.Calls) != 0 || !strings.Contains(gap.Extra.String(), strings.ToLower(validator.Hex()[2:])) {
+		t.Errorf("epoch block mismatch: calls %d, extra %x", len(gap.Calls), gap.Extra)
+	}
+	collected, err := sim.BalanceAt(context.Background(), consensus.SystemAddress, nil)
+	if err != nil {
+		t.Fatalf("could not retrieve collected fees: %v", err)
+	}
+	swept := new(big.Int).Add(collected, new(big.Int).Mul(tip, new(big.Int).SetUint64(uint64(logged.GasUsed))))
+	var sweeps []ethapi.SimulatedBalanceChange
+	for _, change := range gap.BalanceChanges {
+		if change.Type == consensus.BalanceChangeFeeSweep {
+			sweeps = append(sweeps, change)
+		}
+	}
+	if len(sweeps) != 1 || sweeps[0].From != consensus.SystemAddress || sweeps[0].Value.ToInt().Cmp(swept) != 0 {
+		t.Errorf("fee sweep mismatch: have %+v, want %v", sweeps, swept)
+	}
+	// The logs are numbered from the start of every block
+	last := results[3]
+	if len(last.Calls) != 1 || new(big.Int).SetBytes(last.Calls[0].ReturnData).Uint64() != number.ToInt().Uint64() {
+		t.Fatalf("last block calls mismatch: %+v", last.Calls)
+	}
+	if logs := last.Calls[0].Logs; len(logs) != 1 || logs[0].Index != 0 || logs[0].BlockNumber != number.ToInt().Uint64() {
+		t.Errorf("last block logs mismatch: %+v", logs)
+	}
+}
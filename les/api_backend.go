@@ -63,6 +63,17 @@ func (b *LesApiBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumb
 	if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
 		return b.eth.blockchain.CurrentHeader(), nil
 	}
+	if number == rpc.FinalizedBlockNumber {
+		engine, ok := b.eth.engine.(consensus.FastFinality)
+		if !ok {
+			return nil, errors.New("finalized block not supported by the consensus engine")
+		}
+		header := engine.GetFinalizedHeader(b.eth.blockchain, b.eth.blockchain.CurrentHeader())
+		if header == nil {
+			return nil, errors.New("finalized block not found")
+		}
+		return header, nil
+	}
 	return b.eth.blockchain.GetHeaderByNumberOdr(ctx, uint64(number))
 }
 func (b *LesApiBackend) PosEtherbase() []common.Address {
@@ -215,6 +226,10 @@ func (b *LesApiBackend) TxPoolContent() (map[common.Address]types.Transactions,
 	return b.eth.txPool.Content()
 }
 
+func (b *LesApiBackend) TxPoolSystemContent() map[common.Address]types.Transactions {
+	return make(map[common.Address]types.Transactions)
+}
+
 func (b *LesApiBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return b.eth.txPool.SubscribeNewTxsEvent(ch)
 }
@@ -258,6 +273,10 @@ func (b *LesApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
 
+func (b *LesApiBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return b.gpo.SuggestTipCap(ctx)
+}
+
 func (b *LesApiBackend) ChainDb() ethdb.Database {
 	return b.eth.chainDb
 }
@@ -506,21 +506,22 @@ func (lc *LightChain) Config() *params.ChainConfig { return lc.hc.Config() }
 // SyncCheckpoint fetches the checkpoint point block header according to
 // the checkpoint provided by the remote peer.
 //
-// Note if we are running the clique, fetches the last epoch snapshot header
-// which covered by checkpoint.
+// Note if we are running the clique or dpos, fetches the last epoch snapshot
+// header which covered by checkpoint.
 func (lc *LightChain) SyncCheckpoint(ctx context.Context, checkpoint *params.TrustedCheckpoint) bool {
 	// Ensure the remote checkpoint head is ahead of us
 	head := lc.CurrentHeader().Number.Uint64()
 
-	latest := (checkpoint.SectionIndex+1)*lc.indexerConfig.ChtSize - 1
-	if clique := lc.hc.Config().Clique; clique != nil {
-		latest -= latest % clique.Epoch // epoch snapshot for clique
-	}
+	latest := checkpoint.HeadNumber(lc.hc.Config(), lc.indexerConfig.ChtSize)
 	if head >= latest {
 		return true
 	}
-	// Retrieve the latest useful header and update to it
+	// Retrieve the latest useful header and update to it. The header is proven by
+	// the CHT, so the engine can verify its descendants from it.
 	if header, err := GetHeaderByNumber(ctx, lc.odr, latest); header != nil && err == nil {
+		if truster, ok := lc.engine.(consensus.CheckpointTruster); ok {
+			truster.TrustCheckpoint(&params.EngineCheckpoint{Number: header.Number.Uint64(), Hash: header.Hash()})
+		}
 		lc.chainmu.Lock()
 		defer lc.chainmu.Unlock()
 
@@ -25,6 +25,7 @@ import (
 	"PureChain/common"
 	"PureChain/common/hexutil"
 	"PureChain/consensus"
+	"PureChain/consensus/dpos"
 	"PureChain/core"
 	"PureChain/core/state"
 	"PureChain/core/types"
@@ -220,6 +221,12 @@ func (miner *Miner) PendingBlock() *types.Block {
 	return miner.worker.chain.CurrentBlock()
 }
 
+// ValidatorSchedule returns the sealing schedule of the local dpos validators
+// for the block on top of the current head.
+func (miner *Miner) ValidatorSchedule() (*dpos.ValidatorSchedule, error) {
+	return miner.worker.validatorSchedule()
+}
+
 func (miner *Miner) SetEtherbase(addr common.Address) {
 	miner.coinbase = addr
 	is_in := false
@@ -27,6 +27,7 @@ import (
 
 	"PureChain/common"
 	"PureChain/consensus"
+	"PureChain/consensus/misc"
 	"PureChain/consensus/parlia"
 	"PureChain/core"
 	"PureChain/core/state"
@@ -187,7 +188,6 @@ type worker struct {
 	coinbase    common.Address
 	posCoinbase []common.Address
 	extra       []byte
-	minerIndex  int
 
 	pendingMu    sync.RWMutex
 	pendingTasks map[common.Hash]*task
@@ -324,6 +324,20 @@ func (w *worker) pending() (*types.Block, *state.StateDB) {
 	return w.snapshotBlock, w.snapshotState.Copy()
 }
 
+// validatorSchedule returns the sealing schedule of the local validators for
+// the block on top of the current head.
+func (w *worker) validatorSchedule() (*dpos.ValidatorSchedule, error) {
+	engine, ok := w.engine.(*dpos.Dpos)
+	if !ok {
+		return nil, errors.New("consensus engine is not dpos")
+	}
+	w.mu.RLock()
+	candidates := append([]common.Address{}, w.posCoinbase...)
+	w.mu.RUnlock()
+
+	return engine.ScheduleValidators(w.chain, w.chain.CurrentHeader(), candidates)
+}
+
 // pendingBlock returns pending block.
 func (w *worker) pendingBlock() *types.Block {
 	// return a snapshot to avoid contention on currentMu mutex
@@ -566,7 +580,7 @@ func (w *worker) mainLoop() {
 					acc, _ := types.Sender(w.current.signer, tx)
 					txs[acc] = append(txs[acc], tx)
 				}
-				txset := types.NewTransactionsByPriceAndNonce(w.current.signer, txs)
+				txset := types.NewTransactionsByPriceAndNonce(w.current.signer, txs, w.current.header.BaseFee)
 				tcount := w.current.tcount
 				w.commitTransactions(txset, coinbase, nil)
 
@@ -980,6 +994,10 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 			Extra:      w.extra,
 			Time:       uint64(timestamp),
 		}
+		// Set baseFee if we are on an EIP-1559 chain
+		if w.chainConfig.IsLondon(header.Number) {
+			header.BaseFee = misc.CalcBaseFee(w.chainConfig, parent.Header())
+		}
 
 		// Only set the coinbase if our consensus engine is running (avoid spurious block rewards)
 		if w.isRunning() {
@@ -990,26 +1008,23 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 			header.Coinbase = w.coinbase
 		}
 		var realMiner common.Address
-		if dpos, ok := w.engine.(*dpos.Dpos); ok {
-			realMiner = w.posCoinbase[(w.minerIndex % len(w.posCoinbase))]
-			w.minerIndex += 1
-			if w.minerIndex > len(w.posCoinbase) {
-				w.minerIndex = w.minerIndex % len(w.posCoinbase)
+		if engine, ok := w.engine.(*dpos.Dpos); ok {
+			// Only prepare the header for the local validator allowed to seal
+			// the block soonest, all the others would produce wasted seals.
+			schedule, err := engine.ScheduleValidators(w.chain, parent.Header(), w.posCoinbase)
+			if err != nil {
+				log.Error("Failed to schedule validators", "err", err)
+				return
 			}
-			if uint64(time.Now().Unix()) < parent.Time() || (uint64(time.Now().Unix())-parent.Time()) <= 20 {
-				addr_res := dpos.CheckHasInTurn(w.chain, w.posCoinbase, header)
-				if addr_res != (common.Address{}) {
-					realMiner = addr_res
-				}
-			} else {
-				log.Info("skip in turn account")
+			if schedule.Validator == (common.Address{}) {
+				log.Info("No local validator eligible to seal", "number", num)
+				return
 			}
-			exist := dpos.Authorize(realMiner, nil, nil)
-			if !exist {
-				log.Error("sign key not exists")
+			realMiner = schedule.Validator
+			if !engine.Authorize(realMiner, nil, nil) {
+				log.Error("sign key not exists", "validator", realMiner)
 				return
 			}
-
 		}
 		if err := w.engine.Prepare(w.chain, header); err != nil {
 			log.Error("Failed to prepare header for mining", "err", err)
@@ -1073,14 +1088,36 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		//log.Info("first state root1", "block", w.current.header.Number.String(), "hash", tmp_root.String())
 		// Create por challenge transaction
 		if dpos, ok := w.engine.(*dpos.Dpos); ok {
-			nonceDiff := uint64(0)
+			pool := w.eth.TxPool()
 
-			// Fill the block with all available pending transactions.
-			tPending, err := w.eth.TxPool().Pending()
-			if err != nil {
-				log.Error("Failed to fetch pending transactions", "err", err)
+			// Move the finished challenges into the system lane of the pool, their
+			// nonces follow the system transactions already waiting there.
+			for len(w.challengeCh) > 0 {
+				oneData := <-w.challengeCh
+				nonceDiff := pool.SystemNonce(header.Coinbase, env.state) - env.state.GetNonce(header.Coinbase)
+				tx, err := dpos.CreateChallengeFinish(oneData.Validator, oneData.Provider, oneData.Seed, oneData.challengeAmount, oneData.rootHash, oneData.challengeState, nonceDiff, env.state, header)
+				if err != nil {
+					log.Error("Failed to create challenge finish transaction", "provider", oneData.Provider, "err", err)
+					continue
+				}
+				if errs := pool.AddSystem([]*types.Transaction{tx}); errs[0] != nil {
+					log.Warn("Failed to add challenge finish transaction", "hash", tx.Hash(), "err", errs[0])
+				}
+			}
+			// Commit the waiting system transactions of the validator ahead of any
+			// ordinary transaction, so they are never delayed by the pending ones.
+			if sysTxs := pool.SystemPending(env.state)[header.Coinbase]; len(sysTxs) > 0 {
+				// System transactions are zero priced, order them without the base fee
+				// to not drop them as underpriced after London.
+				consTxs := map[common.Address]types.Transactions{header.Coinbase: sysTxs}
+				txs := types.NewTransactionsByPriceAndNonce(w.current.signer, consTxs, nil)
+				if w.commitTransactions(txs, header.Coinbase, interrupt) {
+					return
+				}
 			}
-			if w.porWork.CanLock(header.Coinbase) && len(w.challengeCh) == 0 && len(tPending[header.Coinbase]) == 0 {
+			// Create por challenge transaction once every system and pending
+			// transaction of the validator has been applied.
+			if w.porWork.CanLock(header.Coinbase) && len(w.challengeCh) == 0 && pool.SystemNonce(header.Coinbase, env.state) == env.state.GetNonce(header.Coinbase) {
 				//tmp_root = w.current.state.IntermediateRoot(true)
 				//log.Info("first state root2", "block", w.current.header.Number.String(), "hash", tmp_root.String())
 				tx, seed, provider, err, trxType := dpos.TryCreateChallenge(w.chain, header, env.state)
@@ -1093,13 +1130,12 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 					consTxs[realMiner] = tTxs
 					//tmp_root = w.current.state.IntermediateRoot(true)
 					//log.Info("first state root4", "block", w.current.header.Number.String(), "hash", tmp_root.String())
-					txs := types.NewTransactionsByPriceAndNonce(w.current.signer, consTxs)
+					txs := types.NewTransactionsByPriceAndNonce(w.current.signer, consTxs, nil)
 					if w.commitTransactions(txs, header.Coinbase, interrupt) {
 						return
 					}
 					//tmp_root = w.current.state.IntermediateRoot(true)
 					//log.Info("first state root5", "block", w.current.header.Number.String(), "hash", tmp_root.String())
-					nonceDiff++
 				} else if err == nil && trxType == 1 {
 					seedSignature, err := dpos.SignSeed(header, seed)
 					if err != nil {
@@ -1111,7 +1147,7 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 					consTxs[realMiner] = tTxs
 					//tmp_root = w.current.state.IntermediateRoot(true)
 					//log.Info("first state root6", "block", w.current.header.Number.String(), "hash", tmp_root.String())
-					txs := types.NewTransactionsByPriceAndNonce(w.current.signer, consTxs)
+					txs := types.NewTransactionsByPriceAndNonce(w.current.signer, consTxs, nil)
 
 					if w.commitTransactions(txs, header.Coinbase, interrupt) {
 						return
@@ -1121,39 +1157,10 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 					w.porWork.AddLock(header.Coinbase)
 					w.porWork.ChallengeChan <- challengeTask{Seed: seed, Provider: provider, SeedSignature: hex.EncodeToString(seedSignature), TaskBlockNumber: header.Number.Uint64(), TransactionHash: tx.Hash(), Validator: realMiner}
 
-					nonceDiff++
 				} else {
 					log.Debug(err.Error())
 				}
 			}
-
-			tTxs := make(types.Transactions, 0, 0)
-			for len(w.challengeCh) > 0 {
-				if len(w.challengeCh) == 0 {
-					break
-				}
-				oneData := <-w.challengeCh
-				//tmp_root = w.current.state.IntermediateRoot(true)
-				//log.Info("first state root challenge finish 8", "block", w.current.header.Number.String(), "hash", tmp_root.String())
-				tx, err := dpos.CreateChallengeFinish(oneData.Validator, oneData.Provider, oneData.Seed, oneData.challengeAmount, oneData.rootHash, oneData.challengeState, nonceDiff, env.state, header)
-				if err == nil {
-
-					tTxs = append(tTxs, tx)
-
-					nonceDiff++
-				}
-				//tmp_root = w.current.state.IntermediateRoot(true)
-				//log.Info("first state root challenge finish 9", "block", w.current.header.Number.String(), "hash", tmp_root.String())
-
-			}
-			if len(tTxs) > 0 {
-
-				w.eth.TxPool().AddLocals(tTxs)
-
-			}
-			//tmp_root = w.current.state.IntermediateRoot(true)
-			//log.Info("first state root challenge finish 10", "block", w.current.header.Number.String(), "hash", tmp_root.String())
-
 		}
 
 		// Fill the block with all available pending transactions.
@@ -1177,7 +1184,7 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 				//tmp_root = w.current.state.IntermediateRoot(true)
 				//log.Info("first state root challenge 11", "block", w.current.header.Number.String(), "hash", tmp_root.String())
 
-				txs := types.NewTransactionsByPriceAndNonce(w.current.signer, localTxs)
+				txs := types.NewTransactionsByPriceAndNonce(w.current.signer, localTxs, w.current.header.BaseFee)
 
 				if w.commitTransactions(txs, header.Coinbase, interrupt) {
 					return
@@ -1190,7 +1197,7 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 				//tmp_root = w.current.state.IntermediateRoot(true)
 				//log.Info("first state root challenge finish 13", "block", w.current.header.Number.String(), "hash", tmp_root.String())
 
-				txs := types.NewTransactionsByPriceAndNonce(w.current.signer, remoteTxs)
+				txs := types.NewTransactionsByPriceAndNonce(w.current.signer, remoteTxs, w.current.header.BaseFee)
 				if w.commitTransactions(txs, header.Coinbase, interrupt) {
 					return
 				}
@@ -1264,7 +1271,12 @@ func (w *worker) postSideBlock(event core.ChainSideEvent) {
 func totalFees(block *types.Block, receipts []*types.Receipt) *big.Float {
 	feesWei := new(big.Int)
 	for i, tx := range block.Transactions() {
-		feesWei.Add(feesWei, new(big.Int).Mul(new(big.Int).SetUint64(receipts[i].GasUsed), tx.GasPrice()))
+		// Only the tip goes to the producer after London, system calls pay nothing
+		minerFee, err := tx.EffectiveGasTip(block.BaseFee())
+		if err != nil {
+			continue
+		}
+		feesWei.Add(feesWei, new(big.Int).Mul(new(big.Int).SetUint64(receipts[i].GasUsed), minerFee))
 	}
 	return new(big.Float).Quo(new(big.Float).SetInt(feesWei), new(big.Float).SetInt(big.NewInt(params.Ether)))
 }
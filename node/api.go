@@ -182,10 +182,16 @@ func (api *privateAdminAPI) StartHTTP(host *string, port *int, cors *string, api
 	}
 
 	// Determine config.
+	secret, err := api.node.obtainJWTSecret(api.node.config.HTTPJWTAuth)
+	if err != nil {
+		return false, err
+	}
 	config := httpConfig{
 		CorsAllowedOrigins: api.node.config.HTTPCors,
 		Vhosts:             api.node.config.HTTPVirtualHosts,
 		Modules:            api.node.config.HTTPModules,
+		limits:             api.node.rpcLimits(api.node.config.HTTPMethodAllowlist, api.node.config.HTTPMethodDenylist),
+		jwtSecret:          secret,
 	}
 	if cors != nil {
 		config.CorsAllowedOrigins = nil
@@ -256,9 +262,15 @@ func (api *privateAdminAPI) StartWS(host *string, port *int, allowedOrigins *str
 	}
 
 	// Determine config.
+	secret, err := api.node.obtainJWTSecret(api.node.config.WSJWTAuth)
+	if err != nil {
+		return false, err
+	}
 	config := wsConfig{
-		Modules: api.node.config.WSModules,
-		Origins: api.node.config.WSOrigins,
+		Modules:   api.node.config.WSModules,
+		Origins:   api.node.config.WSOrigins,
+		limits:    api.node.rpcLimits(api.node.config.WSMethodAllowlist, api.node.config.WSMethodDenylist),
+		jwtSecret: secret,
 		// ExposeAll: api.node.config.WSExposeAll,
 	}
 	if apis != nil {
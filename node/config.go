@@ -18,6 +18,7 @@ package node
 
 import (
 	"crypto/ecdsa"
+	"crypto/rand"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -32,6 +33,7 @@ import (
 	"PureChain/accounts/scwallet"
 	"PureChain/accounts/usbwallet"
 	"PureChain/common"
+	"PureChain/common/hexutil"
 	"PureChain/crypto"
 	"PureChain/log"
 	"PureChain/p2p"
@@ -45,6 +47,7 @@ const (
 	datadirStaticNodes     = "static-nodes.json"  // Path within the datadir to the static node list
 	datadirTrustedNodes    = "trusted-nodes.json" // Path within the datadir to the trusted node list
 	datadirNodeDatabase    = "nodes"              // Path within the datadir to store the node infos
+	datadirJWTSecret       = "jwtsecret"          // Path within the datadir to the RPC authentication secret
 )
 
 // Config represents a small collection of configuration values to fine tune the
@@ -70,6 +73,10 @@ type Config struct {
 	// in memory.
 	DataDir string
 
+	// DBEngine is the key-value database engine of the persistent databases. If
+	// empty, the engine of a preexisting database is used, defaulting to leveldb.
+	DBEngine string `toml:",omitempty"`
+
 	// Configuration of peer-to-peer networking.
 	P2P p2p.Config
 
@@ -148,6 +155,15 @@ type Config struct {
 	// HTTPPathPrefix specifies a path prefix on which http-rpc is to be served.
 	HTTPPathPrefix string `toml:",omitempty"`
 
+	// HTTPMethodAllowlist is the list of methods served via the HTTP RPC interface,
+	// on top of the module selection. Entries are either method names, namespace
+	// wildcards like "eth_*" or "*". If the list is empty, all methods are served.
+	HTTPMethodAllowlist []string `toml:",omitempty"`
+
+	// HTTPMethodDenylist is the list of methods never served via the HTTP RPC
+	// interface, taking precedence over the allow list.
+	HTTPMethodDenylist []string `toml:",omitempty"`
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string
@@ -177,6 +193,45 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// WSMethodAllowlist is the list of methods served via the websocket RPC
+	// interface, in the same format as HTTPMethodAllowlist.
+	WSMethodAllowlist []string `toml:",omitempty"`
+
+	// WSMethodDenylist is the list of methods never served via the websocket RPC
+	// interface, taking precedence over the allow list.
+	WSMethodDenylist []string `toml:",omitempty"`
+
+	// RPCBatchLimit is the maximum number of calls in a JSON-RPC batch served via
+	// HTTP or websocket. Zero means no limit.
+	RPCBatchLimit int `toml:",omitempty"`
+
+	// RPCResponseMaxSize is the maximum size in bytes of a JSON-RPC response, or of
+	// all responses of a batch combined, served via HTTP or websocket. Zero means
+	// no limit.
+	RPCResponseMaxSize int `toml:",omitempty"`
+
+	// RPCRateLimit is the number of JSON-RPC calls per second every client may make
+	// via HTTP or websocket. Clients are identified by their IP address, or their
+	// authenticated identity. Zero means no limit.
+	RPCRateLimit float64 `toml:",omitempty"`
+
+	// RPCRateBurst is the number of calls a client may make in a burst above the
+	// rate limit.
+	RPCRateBurst int `toml:",omitempty"`
+
+	// HTTPJWTAuth requires the requests to the HTTP RPC interface to carry a fresh
+	// HS256 JSON Web Token signed with the JWT secret.
+	HTTPJWTAuth bool `toml:",omitempty"`
+
+	// WSJWTAuth requires the connections to the websocket RPC interface to carry a
+	// fresh HS256 JSON Web Token signed with the JWT secret.
+	WSJWTAuth bool `toml:",omitempty"`
+
+	// JWTSecret is the path to the hex encoded 32 byte secret authenticating RPC
+	// requests. If empty, the secret is stored in the data directory and generated
+	// on first use.
+	JWTSecret string `toml:",omitempty"`
+
 	// GraphQLCors is the Cross-Origin Resource Sharing header to send to requesting
 	// clients. Please be aware that CORS is a browser enforced security, it's fully
 	// useless for custom HTTP clients.
@@ -393,6 +448,45 @@ func (c *Config) NodeKey() *ecdsa.PrivateKey {
 	return key
 }
 
+// JWTSecretKey retrieves the secret authenticating RPC requests from the
+// configured file, generating and persisting a new one if it doesn't exist yet.
+func (c *Config) JWTSecretKey() ([]byte, error) {
+	path := c.JWTSecret
+	if path == "" {
+		path = c.ResolvePath(datadirJWTSecret)
+	}
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err == nil {
+			secret := common.FromHex(strings.TrimSpace(string(data)))
+			if len(secret) != 32 {
+				return nil, fmt.Errorf("invalid JWT secret in %s: have %d bytes, want 32", path, len(secret))
+			}
+			return secret, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	// No persistent secret found, generate and store a new one.
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if path == "" {
+		log.Warn("Using ephemeral JWT secret, RPC clients can't authenticate without a datadir")
+		return secret, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path, []byte(hexutil.Encode(secret)), 0600); err != nil {
+		return nil, err
+	}
+	log.Info("Generated JWT secret", "path", path)
+	return secret, nil
+}
+
 // StaticNodes returns a list of node enode URLs configured as static nodes.
 func (c *Config) StaticNodes() []*enode.Node {
 	return c.parsePersistentNodes(&c.staticNodesWarning, c.ResolvePath(datadirStaticNodes))
@@ -160,3 +160,46 @@ func TestNodeKeyPersistency(t *testing.T) {
 		t.Fatalf("ephemeral node key persisted to disk")
 	}
 }
+
+// Tests that the JWT secret is generated on first use and persisted, and that
+// explicitly configured secrets are loaded and validated.
+func TestJWTSecretPersistency(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node-test")
+	if err != nil {
+		t.Fatalf("failed to create temporary data directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Configure a node with no secret and ensure one is generated and persisted
+	config := &Config{Name: "unit-test", DataDir: dir}
+	secret1, err := config.JWTSecretKey()
+	if err != nil {
+		t.Fatalf("failed to generate JWT secret: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "unit-test", datadirJWTSecret)); err != nil {
+		t.Fatalf("JWT secret not persisted to data directory: %v", err)
+	}
+	secret2, err := config.JWTSecretKey()
+	if err != nil {
+		t.Fatalf("failed to load persisted JWT secret: %v", err)
+	}
+	if !bytes.Equal(secret1, secret2) {
+		t.Fatalf("persisted JWT secret mismatch: have %x, want %x", secret2, secret1)
+	}
+	// Configure an explicit secret file and ensure it is used
+	path := filepath.Join(dir, "secret.hex")
+	ioutil.WriteFile(path, []byte("0x0102030405060708091011121314151617181920212223242526272829303132\n"), 0600)
+
+	config = &Config{Name: "unit-test", DataDir: dir, JWTSecret: path}
+	secret, err := config.JWTSecretKey()
+	if err != nil {
+		t.Fatalf("failed to load configured JWT secret: %v", err)
+	}
+	if secret[0] != 0x01 || secret[31] != 0x32 {
+		t.Fatalf("configured JWT secret mismatch: have %x", secret)
+	}
+	ioutil.WriteFile(path, []byte("0x0102"), 0600)
+	if _, err := config.JWTSecretKey(); err == nil {
+		t.Fatalf("short JWT secret accepted")
+	}
+}
@@ -57,6 +57,7 @@ type Node struct {
 	ws            *httpServer //
 	ipc           *ipcServer  // Stores information about the ipc http server
 	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
+	jwtSecret     []byte      // Secret authenticating RPC requests, loaded on first use
 
 	databases map[*closeTrackingDB]struct{} // All open databases
 }
@@ -360,11 +361,17 @@ func (n *Node) startRPC() error {
 
 	// Configure HTTP.
 	if n.config.HTTPHost != "" {
+		secret, err := n.obtainJWTSecret(n.config.HTTPJWTAuth)
+		if err != nil {
+			return err
+		}
 		config := httpConfig{
 			CorsAllowedOrigins: n.config.HTTPCors,
 			Vhosts:             n.config.HTTPVirtualHosts,
 			Modules:            n.config.HTTPModules,
 			prefix:             n.config.HTTPPathPrefix,
+			limits:             n.rpcLimits(n.config.HTTPMethodAllowlist, n.config.HTTPMethodDenylist),
+			jwtSecret:          secret,
 		}
 		if err := n.http.setListenAddr(n.config.HTTPHost, n.config.HTTPPort); err != nil {
 			return err
@@ -376,11 +383,17 @@ func (n *Node) startRPC() error {
 
 	// Configure WebSocket.
 	if n.config.WSHost != "" {
+		secret, err := n.obtainJWTSecret(n.config.WSJWTAuth)
+		if err != nil {
+			return err
+		}
 		server := n.wsServerForPort(n.config.WSPort)
 		config := wsConfig{
-			Modules: n.config.WSModules,
-			Origins: n.config.WSOrigins,
-			prefix:  n.config.WSPathPrefix,
+			Modules:   n.config.WSModules,
+			Origins:   n.config.WSOrigins,
+			prefix:    n.config.WSPathPrefix,
+			limits:    n.rpcLimits(n.config.WSMethodAllowlist, n.config.WSMethodDenylist),
+			jwtSecret: secret,
 		}
 		if err := server.setListenAddr(n.config.WSHost, n.config.WSPort); err != nil {
 			return err
@@ -396,6 +409,35 @@ func (n *Node) startRPC() error {
 	return n.ws.start()
 }
 
+// rpcLimits assembles the request limits of a public RPC endpoint with the
+// given method filter.
+func (n *Node) rpcLimits(allow, deny []string) rpcLimits {
+	return rpcLimits{
+		BatchItems:   n.config.RPCBatchLimit,
+		ResponseSize: n.config.RPCResponseMaxSize,
+		RateLimit:    n.config.RPCRateLimit,
+		RateBurst:    n.config.RPCRateBurst,
+		Allow:        allow,
+		Deny:         deny,
+	}
+}
+
+// obtainJWTSecret returns the secret authenticating the requests of an RPC
+// endpoint, or nil if authentication is not enabled on it.
+func (n *Node) obtainJWTSecret(enabled bool) ([]byte, error) {
+	if !enabled {
+		return nil, nil
+	}
+	if n.jwtSecret == nil {
+		secret, err := n.config.JWTSecretKey()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JWT secret: %v", err)
+		}
+		n.jwtSecret = secret
+	}
+	return n.jwtSecret, nil
+}
+
 func (n *Node) wsServerForPort(port int) *httpServer {
 	if n.config.HTTPHost == "" || n.http.port == port {
 		return n.http
@@ -569,7 +611,7 @@ func (n *Node) OpenDatabase(name string, cache, handles int, namespace string, r
 	if n.config.DataDir == "" {
 		db = rawdb.NewMemoryDatabase()
 	} else {
-		db, err = rawdb.NewLevelDBDatabase(n.ResolvePath(name), cache, handles, namespace, readonly)
+		db, err = rawdb.OpenDatabase(n.config.DBEngine, n.ResolvePath(name), cache, handles, namespace, readonly)
 	}
 
 	if err == nil {
@@ -602,7 +644,7 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, freezer,
 		case !filepath.IsAbs(freezer):
 			freezer = n.ResolvePath(freezer)
 		}
-		db, err = rawdb.NewLevelDBDatabaseWithFreezer(root, cache, handles, freezer, namespace, readonly)
+		db, err = rawdb.OpenDatabaseWithFreezer(n.config.DBEngine, root, cache, handles, freezer, namespace, readonly)
 	}
 
 	if err == nil {
@@ -40,13 +40,34 @@ type httpConfig struct {
 	CorsAllowedOrigins []string
 	Vhosts             []string
 	prefix             string // path prefix on which to mount http handler
+	limits             rpcLimits
+	jwtSecret          []byte // secret authenticating the requests, nil if open
 }
 
 // wsConfig is the JSON-RPC/Websocket configuration
 type wsConfig struct {
-	Origins []string
-	Modules []string
-	prefix  string // path prefix on which to mount ws handler
+	Origins   []string
+	Modules   []string
+	prefix    string // path prefix on which to mount ws handler
+	limits    rpcLimits
+	jwtSecret []byte // secret authenticating the connections, nil if open
+}
+
+// rpcLimits is the request limits and access control of a JSON-RPC endpoint.
+type rpcLimits struct {
+	BatchItems   int
+	ResponseSize int
+	RateLimit    float64
+	RateBurst    int
+	Allow        []string
+	Deny         []string
+}
+
+// apply configures the limits on the RPC server.
+func (l rpcLimits) apply(srv *rpc.Server) {
+	srv.SetRequestLimits(l.BatchItems, l.ResponseSize)
+	srv.SetRateLimit(l.RateLimit, l.RateBurst)
+	srv.SetMethodFilter(l.Allow, l.Deny)
 }
 
 type rpcHandler struct {
@@ -280,9 +301,11 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
-	if err := RegisterApisFromWhitelist(apis, config.Modules, srv, false); err != nil {
+	if err := RegisterApisFromWhitelist(transportApis(apis, config.jwtSecret), config.Modules, srv, false); err != nil {
 		return err
 	}
+	config.limits.apply(srv)
+	srv.SetJWTSecret(config.jwtSecret)
 	h.httpConfig = config
 	h.httpHandler.Store(&rpcHandler{
 		Handler: NewHTTPHandlerStack(srv, config.CorsAllowedOrigins, config.Vhosts),
@@ -312,9 +335,11 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
-	if err := RegisterApisFromWhitelist(apis, config.Modules, srv, false); err != nil {
+	if err := RegisterApisFromWhitelist(transportApis(apis, config.jwtSecret), config.Modules, srv, false); err != nil {
 		return err
 	}
+	config.limits.apply(srv)
+	srv.SetJWTSecret(config.jwtSecret)
 	h.wsConfig = config
 	h.wsHandler.Store(&rpcHandler{
 		Handler: srv.WebsocketHandler(config.Origins),
@@ -515,6 +540,21 @@ func (is *ipcServer) stop() error {
 	return err
 }
 
+// transportApis filters out the APIs to be served only over local or authenticated
+// transports, unless the server authenticates its requests with the given secret.
+func transportApis(apis []rpc.API, jwtSecret []byte) []rpc.API {
+	if len(jwtSecret) > 0 {
+		return apis
+	}
+	var served []rpc.API
+	for _, api := range apis {
+		if !api.Authenticated {
+			served = append(served, api)
+		}
+	}
+	return served
+}
+
 // RegisterApisFromWhitelist checks the given modules' availability, generates a whitelist based on the allowed modules,
 // and then registers all of the APIs exposed by the services.
 func RegisterApisFromWhitelist(apis []rpc.API, modules []string, srv *rpc.Server, exposeAll bool) error {
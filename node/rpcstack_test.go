@@ -18,7 +18,9 @@ package node
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -58,6 +60,73 @@ func TestVhosts(t *testing.T) {
 	assert.Equal(t, resp2.StatusCode, http.StatusForbidden)
 }
 
+// TestHTTPLimits makes sure the request limits are applied to the http server.
+func TestHTTPLimits(t *testing.T) {
+	limits := rpcLimits{RateLimit: 0.001, RateBurst: 1}
+	srv := createAndStartServer(t, &httpConfig{limits: limits}, false, &wsConfig{})
+	defer srv.stop()
+	url := "http://" + srv.listenAddr()
+
+	body, _ := ioutil.ReadAll(rpcRequest(t, url).Body)
+	assert.Contains(t, string(body), `"result"`)
+
+	body, _ = ioutil.ReadAll(rpcRequest(t, url).Body)
+	assert.Contains(t, string(body), "rate limit exceeded")
+}
+
+// TestHTTPJWTAuth makes sure the http server requires tokens if configured.
+func TestHTTPJWTAuth(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	srv := createAndStartServer(t, &httpConfig{jwtSecret: secret}, true, &wsConfig{Origins: []string{"*"}, jwtSecret: secret})
+	defer srv.stop()
+	url := "http://" + srv.listenAddr()
+
+	resp := rpcRequest(t, url)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Error(t, wsRequest(t, "ws://"+srv.listenAddr(), ""))
+
+	client, err := rpc.DialHTTP(url, rpc.WithHTTPAuth(rpc.NewJWTAuth(secret)))
+	assert.NoError(t, err)
+	defer client.Close()
+	assert.NoError(t, client.Call(nil, "rpc_modules"))
+
+	wsClient, err := rpc.DialWebsocket(context.Background(), "ws://"+srv.listenAddr(), "", rpc.WithHTTPAuth(rpc.NewJWTAuth(secret)))
+	assert.NoError(t, err)
+	defer wsClient.Close()
+	assert.NoError(t, wsClient.Call(nil, "rpc_modules"))
+}
+
+// authTestService is an API only served over local or authenticated transports.
+type authTestService struct{}
+
+func (s *authTestService) Secret() string { return "secret" }
+
+// TestAuthenticatedApis makes sure the APIs restricted to authenticated transports
+// are only served over http and websocket when requests are authenticated.
+func TestAuthenticatedApis(t *testing.T) {
+	apis := []rpc.API{{Namespace: "auth", Service: new(authTestService), Authenticated: true}}
+	for _, secret := range [][]byte{nil, []byte("0123456789abcdef0123456789abcdef")} {
+		srv := newHTTPServer(testlog.Logger(t, log.LvlDebug), rpc.DefaultHTTPTimeouts)
+		assert.NoError(t, srv.enableRPC(apis, httpConfig{Modules: []string{"auth"}, jwtSecret: secret}))
+		assert.NoError(t, srv.setListenAddr("localhost", 0))
+		assert.NoError(t, srv.start())
+
+		client, err := rpc.DialHTTP("http://"+srv.listenAddr(), rpc.WithHTTPAuth(rpc.NewJWTAuth(secret)))
+		assert.NoError(t, err)
+
+		var result string
+		err = client.Call(&result, "auth_secret")
+		if secret == nil {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, "secret", result)
+		}
+		client.Close()
+		srv.stop()
+	}
+}
+
 type originTest struct {
 	spec    string
 	expOk   []string
@@ -32,6 +32,22 @@ const (
 	// considered probably final and its rotated bits are calculated.
 	BloomConfirms = 256
 
+	// LogIndexBlocks is the number of blocks a single section of the address and
+	// topic log index covers.
+	LogIndexBlocks uint64 = 4096
+
+	// LogIndexConfirms is the number of confirmation blocks before a log index
+	// section is considered probably final and is indexed.
+	LogIndexConfirms = 256
+
+	// TxHistoryBlocks is the number of blocks a single section of the account
+	// transaction history index covers.
+	TxHistoryBlocks uint64 = 128
+
+	// TxHistoryConfirms is the number of confirmation blocks before a transaction
+	// history section is indexed. More recent blocks are scanned on demand.
+	TxHistoryConfirms = 64
+
 	// CHTFrequency is the block frequency for creating CHTs
 	CHTFrequency = 32768
 
@@ -0,0 +1,117 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// jwtExpiryTimeout is the maximum difference between the issuance time of a
+// token and the time it is presented at.
+const jwtExpiryTimeout = 60 * time.Second
+
+var (
+	errMissingToken = errors.New("missing token")
+	errStaleToken   = errors.New("stale token")
+	errFutureToken  = errors.New("token issued in the future")
+)
+
+// HTTPAuth adds authentication headers to the HTTP requests of a client,
+// including the handshakes of websocket connections.
+type HTTPAuth func(header http.Header) error
+
+// NewJWTAuth creates an HTTPAuth presenting HS256 tokens signed with the given
+// secret. Every request carries a freshly issued token.
+func NewJWTAuth(secret []byte) HTTPAuth {
+	return NewJWTAuthWithSubject(secret, "")
+}
+
+// NewJWTAuthWithSubject creates an HTTPAuth presenting HS256 tokens signed with
+// the given secret and identifying the client as subject, which the server
+// accounts rate limits to.
+func NewJWTAuthWithSubject(secret []byte, subject string) HTTPAuth {
+	return func(header http.Header) error {
+		claims := &jwt.StandardClaims{
+			IssuedAt: time.Now().Unix(),
+			Subject:  subject,
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+		if err != nil {
+			return fmt.Errorf("failed to sign token: %v", err)
+		}
+		header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+// ClientOption is a configuration option of the HTTP and websocket clients.
+type ClientOption func(*clientConfig)
+
+// clientConfig is the configuration assembled from the client options.
+type clientConfig struct {
+	httpAuth HTTPAuth
+}
+
+// WithHTTPAuth makes the client authenticate every request with the given auth.
+func WithHTTPAuth(auth HTTPAuth) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.httpAuth = auth
+	}
+}
+
+// newClientConfig assembles the configuration from the given options.
+func newClientConfig(options []ClientOption) *clientConfig {
+	cfg := new(clientConfig)
+	for _, option := range options {
+		option(cfg)
+	}
+	return cfg
+}
+
+// verifyJWT checks that the request carries a fresh HS256 token signed with the
+// given secret, returning the subject it was issued to.
+func verifyJWT(secret []byte, r *http.Request) (string, error) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return "", errMissingToken
+	}
+	var (
+		claims jwt.StandardClaims
+		parser = jwt.Parser{ValidMethods: []string{jwt.SigningMethodHS256.Alg()}, SkipClaimsValidation: true}
+	)
+	_, err := parser.ParseWithClaims(strings.TrimPrefix(auth, "Bearer "), &claims, func(*jwt.Token) (interface{}, error) {
+		return secret, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	// Tokens carry no expiry, their issuance time must be close to now instead
+	issued := time.Unix(claims.IssuedAt, 0)
+	switch {
+	case claims.IssuedAt == 0 || time.Since(issued) > jwtExpiryTimeout:
+		return "", errStaleToken
+	case time.Until(issued) > jwtExpiryTimeout:
+		return "", errFutureToken
+	}
+	return claims.Subject, nil
+}
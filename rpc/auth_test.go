@@ -0,0 +1,149 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var testJWTSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestHTTPJWTAuth(t *testing.T) {
+	server := newTestServer()
+	server.SetJWTSecret(testJWTSecret)
+	defer server.Stop()
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	tests := []struct {
+		options []ClientOption
+		ok      bool
+	}{
+		{nil, false},
+		{[]ClientOption{WithHTTPAuth(NewJWTAuth([]byte("wrong secret")))}, false},
+		{[]ClientOption{WithHTTPAuth(NewJWTAuth(testJWTSecret))}, true},
+	}
+	for i, tt := range tests {
+		client, err := DialHTTP(ts.URL, tt.options...)
+		if err != nil {
+			t.Fatalf("test %d: can't dial: %v", i, err)
+		}
+		err = client.Call(nil, "test_noArgsRets")
+		client.Close()
+
+		if tt.ok && err != nil {
+			t.Errorf("test %d: authenticated call failed: %v", i, err)
+		}
+		if !tt.ok {
+			if httpErr, ok := err.(HTTPError); !ok || httpErr.StatusCode != http.StatusUnauthorized {
+				t.Errorf("test %d: unauthenticated call error mismatch: have %v, want status %d", i, err, http.StatusUnauthorized)
+			}
+		}
+	}
+}
+
+func TestWebsocketJWTAuth(t *testing.T) {
+	server := newTestServer()
+	server.SetJWTSecret(testJWTSecret)
+	defer server.Stop()
+
+	ts := httptest.NewServer(server.WebsocketHandler([]string{"*"}))
+	defer ts.Close()
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http")
+	if _, err := DialWebsocket(context.Background(), url, ""); err == nil {
+		t.Fatalf("unauthenticated websocket connection accepted")
+	}
+	client, err := DialWebsocket(context.Background(), url, "", WithHTTPAuth(NewJWTAuth(testJWTSecret)))
+	if err != nil {
+		t.Fatalf("authenticated websocket connection rejected: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.Call(nil, "test_noArgsRets"); err != nil {
+		t.Fatalf("authenticated call failed: %v", err)
+	}
+}
+
+func TestJWTSubjectRateLimit(t *testing.T) {
+	server := newTestServer()
+	server.SetJWTSecret(testJWTSecret)
+	server.SetRateLimit(0.001, 1)
+	defer server.Stop()
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	// Clients sharing an address are limited by the subject of their tokens
+	alice, _ := DialHTTP(ts.URL, WithHTTPAuth(NewJWTAuthWithSubject(testJWTSecret, "alice")))
+	defer alice.Close()
+	bob, _ := DialHTTP(ts.URL, WithHTTPAuth(NewJWTAuthWithSubject(testJWTSecret, "bob")))
+	defer bob.Close()
+
+	if err := alice.Call(nil, "test_noArgsRets"); err != nil {
+		t.Fatalf("first call of alice failed: %v", err)
+	}
+	if err := bob.Call(nil, "test_noArgsRets"); err != nil {
+		t.Fatalf("first call of bob failed: %v", err)
+	}
+	if err := alice.Call(nil, "test_noArgsRets"); err == nil {
+		t.Fatalf("second call of alice not rate limited")
+	}
+}
+
+func TestVerifyJWT(t *testing.T) {
+	sign := func(method jwt.SigningMethod, issued time.Time) string {
+		claims := &jwt.StandardClaims{IssuedAt: issued.Unix(), Subject: "test"}
+		token, err := jwt.NewWithClaims(method, claims).SignedString(testJWTSecret)
+		if err != nil {
+			t.Fatalf("can't sign token: %v", err)
+		}
+		return token
+	}
+	tests := []struct {
+		token string
+		ok    bool
+	}{
+		{sign(jwt.SigningMethodHS256, time.Now()), true},
+		{sign(jwt.SigningMethodHS256, time.Now().Add(-30*time.Second)), true},
+		{sign(jwt.SigningMethodHS256, time.Now().Add(-2*jwtExpiryTimeout)), false},
+		{sign(jwt.SigningMethodHS256, time.Now().Add(2*jwtExpiryTimeout)), false},
+		{sign(jwt.SigningMethodHS512, time.Now()), false},
+		{"", false},
+	}
+	for i, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "http://localhost", nil)
+		if tt.token != "" {
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		subject, err := verifyJWT(testJWTSecret, r)
+		if tt.ok && (err != nil || subject != "test") {
+			t.Errorf("test %d: valid token rejected: %q, %v", i, subject, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("test %d: invalid token accepted", i)
+		}
+	}
+}
@@ -437,6 +437,39 @@ func TestClientSubscriptionUnsubscribeServer(t *testing.T) {
 	}
 }
 
+// This test checks that subscriptions ended by the server deliver their pending
+// notifications before the error.
+func TestClientSubscriptionServerFailure(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	nc := make(chan int)
+	count := 10
+	sub, err := client.Subscribe(context.Background(), "nftest", nc, "failingSubscription", count, 0)
+	if err != nil {
+		t.Fatal("can't subscribe:", err)
+	}
+	for i := 0; i < count; i++ {
+		if val := <-nc; val != i {
+			t.Fatalf("value mismatch: got %d, want %d", val, i)
+		}
+	}
+	select {
+	case err := <-sub.Err():
+		if err == nil || err.Error() != "subscription failed" {
+			t.Fatalf("wrong subscription error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscription not ended within 1s after failure")
+	}
+	// The server forgot about the subscription
+	if err := client.Call(new(bool), "nftest_unsubscribe", sub.subid); err == nil {
+		t.Fatal("unsubscribe of failed subscription succeeded")
+	}
+}
+
 // This checks that the subscribed channel can be closed after Unsubscribe.
 // It is the reproducer for https://github.com/Project-DeCloud/chain/issues/22322
 func TestClientSubscriptionChannelClose(t *testing.T) {
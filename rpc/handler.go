@@ -63,6 +63,8 @@ type handler struct {
 	conn           jsonWriter                     // where responses will be sent
 	log            log.Logger
 	allowSubscribe bool
+	limits         *limits // restrictions on incoming calls
+	client         string  // identity the calls are accounted to
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
@@ -73,7 +75,7 @@ type callProc struct {
 	notifiers []*Notifier
 }
 
-func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry) *handler {
+func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, limits *limits) *handler {
 	rootCtx, cancelRoot := context.WithCancel(connCtx)
 	h := &handler{
 		reg:            reg,
@@ -86,6 +88,8 @@ func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *
 		allowSubscribe: true,
 		serverSubs:     make(map[ID]*Subscription),
 		log:            log.Root(),
+		limits:         limits,
+		client:         clientID(connCtx, conn),
 	}
 	if conn.remoteAddr() != "" {
 		h.log = h.log.New("conn", conn.remoteAddr())
@@ -103,6 +107,13 @@ func (h *handler) handleBatch(ctx context.Context, msgs []*jsonrpcMessage) {
 		})
 		return
 	}
+	// Reject batches exceeding the size limit as a whole:
+	if h.limits != nil && h.limits.batchItems > 0 && len(msgs) > h.limits.batchItems {
+		h.startCallProc(func(cp *callProc) {
+			h.conn.writeJSON(cp.ctx, errorMessage(&limitExceededError{"batch too large"}))
+		})
+		return
+	}
 
 	// Handle non-call messages first:
 	calls := make([]*jsonrpcMessage, 0, len(msgs))
@@ -116,9 +127,23 @@ func (h *handler) handleBatch(ctx context.Context, msgs []*jsonrpcMessage) {
 	}
 	// Process calls on a goroutine because they may block indefinitely:
 	h.startCallProc(func(cp *callProc) {
-		answers := make([]*jsonrpcMessage, 0, len(msgs))
+		var (
+			answers = make([]*jsonrpcMessage, 0, len(msgs))
+			size    int
+		)
 		for _, msg := range calls {
+			// Once the responses exceed the size limit, skip the remaining calls
+			if h.responseTooLarge(size) {
+				if msg.isCall() {
+					answers = append(answers, msg.errorResponse(&limitExceededError{"response too large"}))
+				}
+				continue
+			}
 			if answer := h.handleCallMsg(cp, ctx, msg); answer != nil {
+				size += len(answer.Result)
+				if h.responseTooLarge(size) {
+					answer = msg.errorResponse(&limitExceededError{"response too large"})
+				}
 				answers = append(answers, answer)
 			}
 		}
@@ -139,6 +164,9 @@ func (h *handler) handleMsg(ctx context.Context, msg *jsonrpcMessage) {
 	}
 	h.startCallProc(func(cp *callProc) {
 		answer := h.handleCallMsg(cp, ctx, msg)
+		if answer != nil && h.responseTooLarge(len(answer.Result)) {
+			answer = msg.errorResponse(&limitExceededError{"response too large"})
+		}
 		h.addSubscriptions(cp.notifiers)
 		if answer != nil {
 			h.conn.writeJSON(cp.ctx, answer)
@@ -149,6 +177,12 @@ func (h *handler) handleMsg(ctx context.Context, msg *jsonrpcMessage) {
 	})
 }
 
+// responseTooLarge reports whether a response of the given size exceeds the
+// response size limit.
+func (h *handler) responseTooLarge(size int) bool {
+	return h.limits != nil && h.limits.responseSize > 0 && size > h.limits.responseSize
+}
+
 // close cancels all requests except for inflightReq and waits for
 // call goroutines to shut down.
 func (h *handler) close(err error, inflightReq *requestOp) {
@@ -256,9 +290,17 @@ func (h *handler) handleSubscriptionResult(msg *jsonrpcMessage) {
 		h.log.Debug("Dropping invalid subscription message")
 		return
 	}
-	if h.clientSubs[result.ID] != nil {
-		h.clientSubs[result.ID].deliver(result.Result)
+	sub := h.clientSubs[result.ID]
+	if sub == nil {
+		return
+	}
+	if result.Error != nil {
+		// The server ended the subscription, there's nothing left to unsubscribe
+		delete(h.clientSubs, result.ID)
+		sub.close(result.Error)
+		return
 	}
+	sub.deliver(result.Result)
 }
 
 // handleResponse processes method call responses.
@@ -322,6 +364,9 @@ func (h *handler) handleCallMsg(ctx *callProc, reqCtx context.Context, msg *json
 
 // handleCall processes method calls.
 func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+	if err := h.limits.check(h.client, msg.Method); err != nil {
+		return msg.errorResponse(err)
+	}
 	if msg.isSubscribe() {
 		return h.handleSubscribe(cp, msg)
 	}
@@ -413,6 +458,18 @@ func (h *handler) unsubscribe(ctx context.Context, id ID) (bool, error) {
 	return true, nil
 }
 
+// dropSubscription removes a subscription ended by the server and closes its
+// error channel.
+func (h *handler) dropSubscription(id ID) {
+	h.subLock.Lock()
+	defer h.subLock.Unlock()
+
+	if s := h.serverSubs[id]; s != nil {
+		close(s.err)
+		delete(h.serverSubs, id)
+	}
+}
+
 type idForLog struct{ json.RawMessage }
 
 func (id idForLog) String() string {
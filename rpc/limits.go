@@ -0,0 +1,153 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// limits are the restrictions a server imposes on the calls it serves. The zero
+// value imposes no restrictions.
+type limits struct {
+	batchItems   int           // Maximum number of calls in a batch, zero for unlimited
+	responseSize int           // Maximum size of a response or batch of responses in bytes, zero for unlimited
+	rate         *rateLimiter  // Per client call rate limiter, nil for unlimited
+	methods      *methodFilter // Methods served to the clients, nil for all
+}
+
+// check returns an error if the method may not be called by the given client.
+func (l *limits) check(client string, method string) error {
+	if l == nil {
+		return nil
+	}
+	if l.methods != nil && !l.methods.allowed(method) {
+		return &methodNotAllowedError{method: method}
+	}
+	if l.rate != nil && client != "" && !l.rate.allow(client) {
+		return &limitExceededError{"rate limit exceeded"}
+	}
+	return nil
+}
+
+// clientIDKey is the context key under which transports store the identity of an
+// authenticated client, which rate limits are accounted to instead of its address.
+type clientIDKey struct{}
+
+// clientID returns the identity rate limits of the client behind the given
+// connection are accounted to, or an empty string if it cannot be identified.
+func clientID(ctx context.Context, conn jsonWriter) string {
+	if id, ok := ctx.Value(clientIDKey{}).(string); ok && id != "" {
+		return id
+	}
+	addr := conn.remoteAddr()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// rateLimiter is a set of token buckets limiting the call rate of each client.
+type rateLimiter struct {
+	limit rate.Limit
+	burst int
+	idle  time.Duration // Time after which an unused bucket is full again
+
+	lock    sync.Mutex
+	clients map[string]*clientBucket
+	pruned  time.Time
+}
+
+type clientBucket struct {
+	limiter *rate.Limiter
+	used    time.Time
+}
+
+// newRateLimiter creates a limiter allowing limit calls per second per client,
+// with bursts of up to burst calls.
+func newRateLimiter(limit float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		limit:   rate.Limit(limit),
+		burst:   burst,
+		idle:    time.Duration(float64(burst) / limit * float64(time.Second)),
+		clients: make(map[string]*clientBucket),
+		pruned:  time.Now(),
+	}
+}
+
+// allow consumes a token from the bucket of the client, reporting whether one
+// was available.
+func (l *rateLimiter) allow(client string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.pruned) > l.idle {
+		// Buckets idle long enough to be full are equivalent to new ones, drop them
+		for id, bucket := range l.clients {
+			if now.Sub(bucket.used) > l.idle {
+				delete(l.clients, id)
+			}
+		}
+		l.pruned = now
+	}
+	bucket := l.clients[client]
+	if bucket == nil {
+		bucket = &clientBucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[client] = bucket
+	}
+	bucket.used = now
+	return bucket.limiter.AllowN(now, 1)
+}
+
+// methodFilter decides which methods are served based on allow and deny lists.
+// Entries are either full method names, namespace wildcards like "debug_*" or
+// "*" for all methods.
+type methodFilter struct {
+	allow []string // Methods served, empty for all
+	deny  []string // Methods never served, taking precedence over allow
+}
+
+// allowed reports whether the method passes the filter.
+func (f *methodFilter) allowed(method string) bool {
+	if matchMethod(f.deny, method) {
+		return false
+	}
+	return len(f.allow) == 0 || matchMethod(f.allow, method)
+}
+
+// matchMethod reports whether the method matches any of the patterns.
+func matchMethod(patterns []string, method string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(method, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		} else if pattern == method {
+			return true
+		}
+	}
+	return false
+}
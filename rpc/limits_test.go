@@ -0,0 +1,141 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// limitErrorCode extracts the error code of a failed call.
+func limitErrorCode(err error) int {
+	if rpcErr, ok := err.(Error); ok {
+		return rpcErr.ErrorCode()
+	}
+	return 0
+}
+
+func TestServerRequestLimits(t *testing.T) {
+	server := newTestServer()
+	server.SetRequestLimits(2, 100)
+	defer server.Stop()
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client, err := DialHTTP(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// Batches within the limit are served, larger ones rejected as a whole
+	batch := []BatchElem{
+		{Method: "test_echo", Args: []interface{}{"a", 1}, Result: new(echoResult)},
+		{Method: "test_echo", Args: []interface{}{"b", 2}, Result: new(echoResult)},
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+	for i, elem := range batch {
+		if elem.Error != nil {
+			t.Fatalf("batch call %d failed: %v", i, elem.Error)
+		}
+	}
+	batch = append(batch, BatchElem{Method: "test_echo", Args: []interface{}{"c", 3}, Result: new(echoResult)})
+	if err := client.BatchCall(batch); err == nil {
+		t.Fatalf("oversized batch served")
+	}
+	// Responses exceeding the size limit are replaced by errors
+	var result echoResult
+	err = client.Call(&result, "test_echo", strings.Repeat("x", 100), 1)
+	if code := limitErrorCode(err); code != -32005 {
+		t.Fatalf("oversized response error mismatch: have %v (code %d), want code -32005", err, code)
+	}
+	batch = []BatchElem{
+		{Method: "test_echo", Args: []interface{}{strings.Repeat("x", 30), 1}, Result: new(echoResult)},
+		{Method: "test_echo", Args: []interface{}{strings.Repeat("x", 30), 2}, Result: new(echoResult)},
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+	if batch[0].Error != nil {
+		t.Fatalf("first batch call failed: %v", batch[0].Error)
+	}
+	if code := limitErrorCode(batch[1].Error); code != -32005 {
+		t.Fatalf("oversized batch response error mismatch: have %v (code %d), want code -32005", batch[1].Error, code)
+	}
+}
+
+func TestServerRateLimit(t *testing.T) {
+	server := newTestServer()
+	server.SetRateLimit(0.001, 3)
+	defer server.Stop()
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client, err := DialHTTP(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for i := 0; i < 3; i++ {
+		if err := client.Call(nil, "test_noArgsRets"); err != nil {
+			t.Fatalf("call %d within burst failed: %v", i, err)
+		}
+	}
+	err = client.Call(nil, "test_noArgsRets")
+	if code := limitErrorCode(err); code != -32005 {
+		t.Fatalf("rate limit error mismatch: have %v (code %d), want code -32005", err, code)
+	}
+	// Other clients have their own buckets
+	if !server.limits.rate.allow("10.0.0.1") {
+		t.Fatalf("independent client rate limited")
+	}
+}
+
+func TestServerMethodFilter(t *testing.T) {
+	server := newTestServer()
+	server.SetMethodFilter([]string{"test_*", "rpc_modules"}, []string{"test_sleep"})
+	defer server.Stop()
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	tests := []struct {
+		method  string
+		args    []interface{}
+		allowed bool
+	}{
+		{"test_noArgsRets", nil, true},
+		{"rpc_modules", nil, true},
+		{"test_sleep", []interface{}{0}, false},
+		{"nftest_echo", []interface{}{"a", 1}, false},
+	}
+	for _, tt := range tests {
+		err := client.Call(nil, tt.method, tt.args...)
+		if tt.allowed && err != nil {
+			t.Errorf("%s: allowed call failed: %v", tt.method, err)
+		}
+		if !tt.allowed && (err == nil || !strings.Contains(err.Error(), "not allowed")) {
+			t.Errorf("%s: filtered call error mismatch: have %v", tt.method, err)
+		}
+	}
+}
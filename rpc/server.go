@@ -19,6 +19,7 @@ package rpc
 import (
 	"context"
 	"io"
+	"net/http"
 	"sync/atomic"
 
 	"PureChain/log"
@@ -46,6 +47,8 @@ type Server struct {
 	idgen    func() ID
 	run      int32
 	codecs   mapset.Set
+	limits   limits
+	secret   []byte // JWT secret authenticating HTTP and websocket requests, nil if open
 }
 
 // NewServer creates a new server instance with no registered handlers.
@@ -66,12 +69,58 @@ func (s *Server) RegisterName(name string, receiver interface{}) error {
 	return s.services.registerName(name, receiver)
 }
 
+// SetRequestLimits sets the maximum number of calls in a batch and the maximum
+// size in bytes of a response, or of all responses of a batch combined. Zero
+// disables the respective limit. It must be called before serving any requests.
+func (s *Server) SetRequestLimits(batchItems, responseSize int) {
+	s.limits.batchItems = batchItems
+	s.limits.responseSize = responseSize
+}
+
+// SetRateLimit limits every client to limit calls per second, with bursts of up
+// to burst calls. Clients are identified by their authenticated identity if any,
+// or by their IP address otherwise. A zero limit disables rate limiting. It must
+// be called before serving any requests.
+func (s *Server) SetRateLimit(limit float64, burst int) {
+	if limit <= 0 {
+		s.limits.rate = nil
+		return
+	}
+	s.limits.rate = newRateLimiter(limit, burst)
+}
+
+// SetMethodFilter restricts the methods served to those matching the allow list,
+// if it isn't empty, and not matching the deny list. Entries are either method
+// names, namespace wildcards like "debug_*" or "*". It must be called before
+// serving any requests.
+func (s *Server) SetMethodFilter(allow, deny []string) {
+	if len(allow) == 0 && len(deny) == 0 {
+		s.limits.methods = nil
+		return
+	}
+	s.limits.methods = &methodFilter{allow: allow, deny: deny}
+}
+
+// SetJWTSecret makes the server require HTTP and websocket requests to carry a
+// fresh HS256 token signed with the given secret. The subject of the token, if
+// any, identifies the client for rate limiting. It must be called before serving
+// any requests.
+func (s *Server) SetJWTSecret(secret []byte) {
+	s.secret = secret
+}
+
 // ServeCodec reads incoming requests from codec, calls the appropriate callback and writes
 // the response back using the given codec. It will block until the codec is closed or the
 // server is stopped. In either case the codec is closed.
 //
 // Note that codec options are no longer supported.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
+	s.serveCodec(context.Background(), codec)
+}
+
+// serveCodec serves the requests of the codec like ServeCodec, deriving the
+// contexts of the calls from the given connection context.
+func (s *Server) serveCodec(ctx context.Context, codec ServerCodec) {
 	defer codec.close()
 
 	// Don't serve if server is stopped.
@@ -83,7 +132,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(ctx, codec, s.idgen, &s.services, &s.limits)
 	<-codec.closed()
 	c.Close()
 }
@@ -97,7 +146,7 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 		return
 	}
 
-	h := newHandler(ctx, codec, s.idgen, &s.services)
+	h := newHandler(ctx, codec, s.idgen, &s.services, &s.limits)
 	h.allowSubscribe = false
 	defer h.close(io.EOF, nil)
 
@@ -115,6 +164,22 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 	}
 }
 
+// authenticate checks the credentials of an HTTP or websocket request if the
+// server requires any, returning the context to serve the request with.
+func (s *Server) authenticate(ctx context.Context, r *http.Request) (context.Context, error) {
+	if s.secret == nil {
+		return ctx, nil
+	}
+	subject, err := verifyJWT(s.secret, r)
+	if err != nil {
+		return ctx, err
+	}
+	if subject != "" {
+		ctx = context.WithValue(ctx, clientIDKey{}, subject)
+	}
+	return ctx, nil
+}
+
 // Stop stops reading new requests, waits for stopPendingRequestTimeout to allow pending
 // requests to finish, then closes all codecs which will cancel pending requests and
 // subscriptions.
@@ -97,6 +97,7 @@ type Notifier struct {
 	mu           sync.Mutex
 	sub          *Subscription
 	buffer       []json.RawMessage
+	failure      *jsonError
 	callReturned bool
 	activated    bool
 }
@@ -134,6 +135,9 @@ func (n *Notifier) Notify(id ID, data interface{}) error {
 	} else if n.sub.ID != id {
 		panic("Notify with wrong ID")
 	}
+	if n.failure != nil {
+		return nil
+	}
 	if n.activated {
 		return n.send(n.sub, enc)
 	}
@@ -141,6 +145,34 @@ func (n *Notifier) Notify(id ID, data interface{}) error {
 	return nil
 }
 
+// Fail ends the subscription with the given error, delivered to the client as the
+// final notification of the subscription. Later notifications are dropped.
+func (n *Notifier) Fail(id ID, err error) error {
+	n.mu.Lock()
+	if n.sub == nil {
+		n.mu.Unlock()
+		panic("can't Fail before subscription is created")
+	} else if n.sub.ID != id {
+		n.mu.Unlock()
+		panic("Fail with wrong ID")
+	}
+	if n.failure != nil {
+		n.mu.Unlock()
+		return nil
+	}
+	n.failure = errorMessage(err).Error
+
+	var sendErr error
+	if n.activated {
+		sendErr = n.sendFailure(n.sub)
+	}
+	n.mu.Unlock()
+
+	// The subscription is over, the client doesn't need to unsubscribe
+	n.h.dropSubscription(id)
+	return sendErr
+}
+
 // Closed returns a channel that is closed when the RPC connection is closed.
 // Deprecated: use subscription error channel
 func (n *Notifier) Closed() <-chan interface{} {
@@ -153,6 +185,9 @@ func (n *Notifier) takeSubscription() *Subscription {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 	n.callReturned = true
+	if n.failure != nil {
+		return nil // Failed before activation, nothing to track
+	}
 	return n.sub
 }
 
@@ -169,11 +204,22 @@ func (n *Notifier) activate() error {
 		}
 	}
 	n.activated = true
+	if n.failure != nil {
+		return n.sendFailure(n.sub)
+	}
 	return nil
 }
 
 func (n *Notifier) send(sub *Subscription, data json.RawMessage) error {
-	params, _ := json.Marshal(&subscriptionResult{ID: string(sub.ID), Result: data})
+	return n.sendResult(&subscriptionResult{ID: string(sub.ID), Result: data})
+}
+
+func (n *Notifier) sendFailure(sub *Subscription) error {
+	return n.sendResult(&subscriptionResult{ID: string(sub.ID), Error: n.failure})
+}
+
+func (n *Notifier) sendResult(result *subscriptionResult) error {
+	params, _ := json.Marshal(result)
 	ctx := context.Background()
 	return n.h.conn.writeJSON(ctx, &jsonrpcMessage{
 		Version: vsn,
@@ -344,6 +390,16 @@ func (sub *ClientSubscription) forward() (unsubscribeServer bool, err error) {
 				// Exiting because Unsubscribe was called, unsubscribe on server.
 				return true, nil
 			}
+			if _, failed := err.(*jsonError); failed {
+				// The server ended the subscription, hand out the queued items first.
+				for buffer.Len() > 0 {
+					cases[2].Send = reflect.ValueOf(buffer.Front().Value)
+					if chosen, _, _ := reflect.Select([]reflect.SelectCase{cases[0], cases[2]}); chosen == 0 {
+						break
+					}
+					buffer.Remove(buffer.Front())
+				}
+			}
 			return false, err
 
 		case 1: // <-sub.in
@@ -75,6 +75,7 @@ type Database struct {
 	newest  common.Hash                 // Newest tracked node, flush-list tail
 
 	preimages map[common.Hash][]byte // Preimages of nodes from the secure trie
+	flushHook func(common.Hash)      // Callback invoked before a node is persisted
 
 	gctime  time.Duration      // Time spent on garbage collection since last commit
 	gcnodes uint64             // Nodes garbage collected since last commit
@@ -636,6 +637,9 @@ func (db *Database) Cap(limit common.StorageSize) error {
 		// Fetch the oldest referenced node and push into the batch
 		node := db.dirties[oldest]
 		rawdb.WriteTrieNode(batch, oldest, node.rlp())
+		if db.flushHook != nil {
+			db.flushHook(oldest)
+		}
 
 		// If we exceeded the ideal batch size, commit and reset
 		if batch.ValueSize() >= ethdb.IdealBatchSize {
@@ -782,6 +786,9 @@ func (db *Database) commit(hash common.Hash, batch ethdb.Batch, uncacher *cleane
 	}
 	// If we've reached an optimal batch size, commit and start over
 	rawdb.WriteTrieNode(batch, hash, node.rlp())
+	if db.flushHook != nil {
+		db.flushHook(hash)
+	}
 	if callback != nil {
 		callback(hash)
 	}
@@ -846,6 +853,25 @@ func (c *cleaner) Delete(key []byte) error {
 	panic("not implemented")
 }
 
+// SetFlushHook installs a callback which is invoked with the hash of every
+// trie node right before it is written to disk by Cap or Commit. Passing nil
+// removes the hook.
+//
+// Note, this method is a non-synchronized mutator. It is unsafe to call this
+// concurrently with other mutators.
+func (db *Database) SetFlushHook(hook func(hash common.Hash)) {
+	db.flushHook = hook
+}
+
+// Evict removes a trie node from the clean cache. It is used by callers that
+// delete nodes from the disk database directly, so that the deleted nodes are
+// not served from memory afterwards.
+func (db *Database) Evict(hash common.Hash) {
+	if db.cleans != nil {
+		db.cleans.Del(hash[:])
+	}
+}
+
 // Size returns the current storage size of the memory cache in front of the
 // persistent database layer.
 func (db *Database) Size() (common.StorageSize, common.StorageSize) {